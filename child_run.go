@@ -0,0 +1,96 @@
+package langsmith
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/langchain-ai/langsmith-go/internal/traceutil"
+)
+
+type childRunKey struct{}
+
+// ChildRun is a run traced under the run a context belongs to, such as an
+// evaluator run started by [Evaluate]. A nil *ChildRun is valid and does
+// nothing, so callers need not check whether the context was traced.
+type ChildRun struct {
+	client      *Client
+	id          uuid.UUID
+	traceID     uuid.UUID
+	dottedOrder string
+	project     string
+}
+
+// StartChildRun starts a run of runType (such as "llm" or "tool") as a
+// child of the run ctx belongs to, and returns a context for runs nested
+// under it. If ctx belongs to no run, or the run cannot be recorded, it
+// returns ctx and a nil *ChildRun. Call [ChildRun.End] when the work is
+// done:
+//
+//	ctx, run := langsmith.StartChildRun(ctx, "judge", "llm", inputs)
+//	reply, err := callModel(ctx)
+//	run.End(map[string]any{"output": reply}, err)
+func StartChildRun(ctx context.Context, name, runType string, inputs map[string]any) (context.Context, *ChildRun) {
+	parent, _ := ctx.Value(childRunKey{}).(*ChildRun)
+	if parent == nil {
+		return ctx, nil
+	}
+	id := uuid.New()
+	start := time.Now()
+	child := &ChildRun{
+		client:      parent.client,
+		id:          id,
+		traceID:     parent.traceID,
+		dottedOrder: parent.dottedOrder + "." + traceutil.DottedOrder(start, id),
+		project:     parent.project,
+	}
+	err := child.client.CreateRunContext(ctx, &RunCreate{
+		ID:          id,
+		TraceID:     child.traceID,
+		ParentRunID: &parent.id,
+		Name:        name,
+		RunType:     runType,
+		Inputs:      inputs,
+		StartTime:   start,
+		DottedOrder: child.dottedOrder,
+		SessionName: child.project,
+	})
+	if err != nil {
+		return ctx, nil
+	}
+	return withChildRun(ctx, child), child
+}
+
+// ID returns the run's ID, or "" for a nil run.
+func (r *ChildRun) ID() string {
+	if r == nil {
+		return ""
+	}
+	return r.id.String()
+}
+
+// End records the run's outputs, or err if it failed, and its end time.
+func (r *ChildRun) End(outputs map[string]any, err error) {
+	if r == nil {
+		return
+	}
+	update := &RunUpdate{
+		ID:          r.id,
+		TraceID:     r.traceID,
+		DottedOrder: r.dottedOrder,
+		EndTime:     time.Now(),
+		SessionName: r.project,
+	}
+	if err != nil {
+		update.Error = err.Error()
+	} else {
+		update.Outputs = outputs
+	}
+	_ = r.client.UpdateRun(update)
+}
+
+// withChildRun returns a copy of ctx that belongs to run.
+func withChildRun(ctx context.Context, run *ChildRun) context.Context {
+	return context.WithValue(ctx, childRunKey{}, run)
+}
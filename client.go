@@ -160,6 +160,16 @@ func (r *Client) CreateRun(run *RunCreate) error {
 	return tc.CreateRun(run)
 }
 
+// CreateRunContext enqueues a run create, adding the thread set on ctx with
+// [WithThread] to the run's metadata.
+func (r *Client) CreateRunContext(ctx context.Context, run *RunCreate) error {
+	tc, err := r.tracing()
+	if err != nil {
+		return err
+	}
+	return tc.CreateRunContext(ctx, run)
+}
+
 // UpdateRun enqueues a run update (patch) for multipart ingestion.
 func (r *Client) UpdateRun(run *RunUpdate) error {
 	tc, err := r.tracing()
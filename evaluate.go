@@ -0,0 +1,633 @@
+package langsmith
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/langchain-ai/langsmith-go/internal/traceutil"
+	"github.com/langchain-ai/langsmith-go/shared"
+)
+
+// Target is the application an experiment evaluates. It is called with each
+// example's inputs and returns the outputs to score. ctx belongs to the
+// example's run in the experiment; runs started from it with StartChildRun
+// are traced under that run.
+//
+// ctx carries no OpenTelemetry span for the example's run. Spans recorded by
+// OpenTelemetry instrumentation, such as the traceopenai and traceanthropic
+// wrappers, are not nested under it: they form traces of their own (or join
+// a span already on the ctx passed to Evaluate), and their tokens and cost
+// don't count toward the experiment's. Record model calls with
+// StartChildRun to keep them in the experiment.
+type Target func(ctx context.Context, inputs map[string]any) (map[string]any, error)
+
+// EvaluateData selects the examples an experiment runs over.
+type EvaluateData struct {
+	// Dataset is the name or UUID of the dataset.
+	Dataset string
+	// Version pins the dataset to a version tag (e.g. "prod") or an RFC 3339
+	// timestamp. Empty uses the latest version.
+	Version string
+	// Splits restricts the experiment to examples in these splits.
+	Splits []string
+	// Examples, when set, are run instead of listing the dataset's examples.
+	// Dataset may then be empty; the examples' dataset is used.
+	Examples []Example
+}
+
+// EvaluateOptions configures [Evaluate].
+type EvaluateOptions struct {
+	// Client sends the experiment to LangSmith. If nil, a client configured
+	// from the environment is created and closed when the experiment ends.
+	// A client passed in is not closed; call [Client.Close] to flush the
+	// experiment's runs before exiting.
+	Client *Client
+	// ExperimentPrefix starts the experiment's name, which is followed by a
+	// random suffix. Defaults to "experiment".
+	ExperimentPrefix string
+	// Description describes the experiment.
+	Description string
+	// Metadata is recorded on the experiment and on every run.
+	Metadata map[string]any
+	// MaxConcurrency is how many examples run at once. Zero runs them one
+	// at a time.
+	MaxConcurrency int
+	// RunName names the target's runs. Defaults to "Target".
+	RunName string
+	// EvaluatorsProject is the tracing project evaluator runs are recorded
+	// in. Defaults to "evaluators".
+	EvaluatorsProject string
+	// SummaryEvaluators score the experiment as a whole once every example
+	// has run. Their results are recorded as feedback on the experiment and
+	// returned by [ExperimentResults.Summary].
+	SummaryEvaluators []SummaryEvaluator
+	// NumRepetitions is how many times the target runs on each example.
+	// Zero runs it once.
+	NumRepetitions int
+	// Experiment, when set, is the name or UUID of an existing experiment
+	// over the dataset to resume instead of creating one. Only the
+	// repetitions of each example without a run that finished without an
+	// error are run. The rows cover only the runs this call makes, while the
+	// SummaryEvaluators score these along with the runs that completed
+	// earlier. ExperimentPrefix and Description are then ignored, and
+	// Metadata is added to the experiment's metadata on the new runs.
+	Experiment string
+	// Cache, when set, keeps the target's outputs so that re-running an
+	// experiment, for instance with new evaluators, does not call the
+	// target again for examples it already succeeded on. Outputs are keyed
+	// by TargetVersion, example ID and repetition.
+	Cache TargetCache
+	// TargetVersion identifies the target's code and configuration in
+	// cache keys. Change it when the target changes.
+	TargetVersion string
+}
+
+// ExperimentResultRow is the outcome of running the target on one example.
+type ExperimentResultRow struct {
+	Run     RunView
+	Example Example
+	// Repetition numbers the example's runs from 0 to NumRepetitions-1. It
+	// is recorded in the run's metadata as "repetition".
+	Repetition        int
+	EvaluationResults []EvaluationResult
+	// Err reports evaluators that failed and results that could not be
+	// recorded. The target's own error is in Run.Error.
+	Err error
+}
+
+// ExperimentResults iterates over an experiment's rows as examples complete.
+type ExperimentResults struct {
+	// ExperimentName is the experiment (tracing project) name.
+	ExperimentName string
+	// ExperimentID is the experiment (tracing project) UUID.
+	ExperimentID string
+	// DatasetID is the UUID of the dataset the experiment ran over.
+	DatasetID string
+
+	mu         sync.Mutex
+	cond       *sync.Cond
+	rows       []ExperimentResultRow
+	done       bool
+	err        error
+	next       int
+	cur        ExperimentResultRow
+	summary    []EvaluationResult
+	summaryErr error
+}
+
+// Next waits for the next row and reports whether there is one. It returns
+// false when the experiment has finished and every row has been read.
+func (r *ExperimentResults) Next() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for r.next >= len(r.rows) && !r.done {
+		r.cond.Wait()
+	}
+	if r.next >= len(r.rows) {
+		return false
+	}
+	r.cur = r.rows[r.next]
+	r.next++
+	return true
+}
+
+// Current returns the row read by the last call to Next.
+func (r *ExperimentResults) Current() ExperimentResultRow {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.cur
+}
+
+// Err returns the error that stopped the experiment early, if any.
+func (r *ExperimentResults) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+// Wait blocks until the experiment has finished and returns all its rows.
+func (r *ExperimentResults) Wait() ([]ExperimentResultRow, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for !r.done {
+		r.cond.Wait()
+	}
+	return append([]ExperimentResultRow(nil), r.rows...), r.err
+}
+
+// Summary blocks until the experiment has finished and returns the results
+// of its summary evaluators. The error reports summary evaluators that
+// failed and results that could not be recorded. An experiment stopped
+// early is not summarized.
+func (r *ExperimentResults) Summary() ([]EvaluationResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for !r.done {
+		r.cond.Wait()
+	}
+	return append([]EvaluationResult(nil), r.summary...), r.summaryErr
+}
+
+// snapshot returns the rows added so far.
+func (r *ExperimentResults) snapshot() []ExperimentResultRow {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]ExperimentResultRow(nil), r.rows...)
+}
+
+func (r *ExperimentResults) add(row ExperimentResultRow) {
+	r.mu.Lock()
+	r.rows = append(r.rows, row)
+	r.mu.Unlock()
+	r.cond.Broadcast()
+}
+
+func (r *ExperimentResults) finish(err error, summary []EvaluationResult, summaryErr error) {
+	r.mu.Lock()
+	r.done = true
+	r.err = err
+	r.summary, r.summaryErr = summary, summaryErr
+	r.mu.Unlock()
+	r.cond.Broadcast()
+}
+
+// Evaluate runs an experiment: it creates an experiment (a tracing project
+// linked to the dataset), calls target on every selected example, records
+// each call as a root run linked to its example, scores the runs with
+// evaluators and records their results as feedback on the runs. Each
+// evaluator call is itself traced, in the EvaluatorsProject, and the
+// feedback links to it as its source run. Once every example has run, the
+// SummaryEvaluators score the experiment as a whole.
+//
+// Evaluate returns once the experiment has been created; examples run in
+// the background and their rows can be read from the returned
+// [ExperimentResults] as they complete. Errors resolving the dataset or
+// creating the experiment are returned directly.
+func Evaluate(ctx context.Context, target Target, data EvaluateData, evaluators []RunEvaluator, opts EvaluateOptions) (*ExperimentResults, error) {
+	if target == nil {
+		return nil, errors.New("langsmith: Evaluate: missing target")
+	}
+	client := opts.Client
+	if client == nil {
+		client = NewClient()
+	}
+	closeClient := func() {
+		if opts.Client == nil {
+			client.Close()
+		}
+	}
+
+	datasetID, examples, err := resolveExamples(ctx, client, data)
+	if err != nil {
+		closeClient()
+		return nil, err
+	}
+
+	var session *TracerSessionWithoutVirtualFields
+	var metadata map[string]any
+	// completed are the repetitions of each example that a resumed
+	// experiment's earlier invocations finished.
+	completed := map[string]map[int]bool{}
+	// earlier are the rows of a resumed experiment's earlier invocations,
+	// which the summary evaluators score with this invocation's.
+	var earlier []ExperimentResultRow
+	if opts.Experiment != "" {
+		var runs []RunView
+		session, err = findExperiment(ctx, client, opts.Experiment, datasetID)
+		if err == nil {
+			runs, err = completedRuns(ctx, client, session)
+		}
+		if err == nil && len(opts.SummaryEvaluators) > 0 {
+			earlier, err = completedRows(ctx, client, datasetID, examples, runs)
+		}
+		for i, rep := range repetitions(runs) {
+			ex := runs[i].ReferenceExampleID
+			if completed[ex] == nil {
+				completed[ex] = map[int]bool{}
+			}
+			completed[ex][rep] = true
+		}
+		if err != nil {
+			closeClient()
+			return nil, err
+		}
+		metadata, _ = session.Extra["metadata"].(map[string]any)
+		metadata = maps.Clone(metadata)
+		if metadata == nil {
+			metadata = make(map[string]any, len(opts.Metadata))
+		}
+		maps.Copy(metadata, opts.Metadata)
+	} else {
+		metadata = experimentMetadata(data, examples, opts.Metadata)
+		session, err = newExperiment(ctx, client, datasetID, metadata, opts)
+		if err != nil {
+			closeClient()
+			return nil, err
+		}
+	}
+
+	results := &ExperimentResults{
+		ExperimentName: session.Name,
+		ExperimentID:   session.ID,
+		DatasetID:      datasetID,
+	}
+	results.cond = sync.NewCond(&results.mu)
+	e := &experiment{
+		client:            client,
+		target:            target,
+		evaluators:        evaluators,
+		session:           session,
+		metadata:          metadata,
+		runName:           opts.RunName,
+		evalsProject:      opts.EvaluatorsProject,
+		summaryEvaluators: opts.SummaryEvaluators,
+		cache:             opts.Cache,
+		targetVersion:     opts.TargetVersion,
+	}
+	if e.runName == "" {
+		e.runName = "Target"
+	}
+	if e.evalsProject == "" {
+		e.evalsProject = "evaluators"
+	}
+
+	go func() {
+		defer closeClient()
+		workers := max(opts.MaxConcurrency, 1)
+		sem := make(chan struct{}, workers)
+		var wg sync.WaitGroup
+	loop:
+		for i := range examples {
+			for rep := range max(opts.NumRepetitions, 1) {
+				if completed[examples[i].ID][rep] {
+					continue
+				}
+				select {
+				case sem <- struct{}{}:
+				case <-ctx.Done():
+					break loop
+				}
+				wg.Add(1)
+				go func(ex Example, rep int) {
+					defer func() { <-sem; wg.Done() }()
+					results.add(e.runExample(ctx, ex, rep))
+				}(examples[i], rep)
+			}
+		}
+		wg.Wait()
+		err := ctx.Err()
+		var summary []EvaluationResult
+		var summaryErr error
+		if err == nil && len(opts.SummaryEvaluators) > 0 {
+			summary, summaryErr = e.summarize(ctx, append(earlier, results.snapshot()...))
+		}
+		// Closing the experiment is best effort: its runs are recorded.
+		_, _ = client.Sessions.Update(context.WithoutCancel(ctx), session.ID, SessionUpdateParams{EndTime: F(time.Now())})
+		results.finish(err, summary, summaryErr)
+	}()
+	return results, nil
+}
+
+// experimentMetadata returns the metadata of a new experiment over
+// examples: extra, and the dataset version and splits.
+func experimentMetadata(data EvaluateData, examples []Example, extra map[string]any) map[string]any {
+	metadata := make(map[string]any, len(extra)+2)
+	maps.Copy(metadata, extra)
+	if data.Version != "" {
+		metadata["dataset_version"] = data.Version
+	} else if v := latestModified(examples); !v.IsZero() {
+		metadata["dataset_version"] = v.UTC().Format(time.RFC3339Nano)
+	}
+	if len(data.Splits) > 0 {
+		metadata["dataset_splits"] = data.Splits
+	}
+	return metadata
+}
+
+// newExperiment creates an experiment over the dataset.
+func newExperiment(ctx context.Context, client *Client, datasetID string, metadata map[string]any, opts EvaluateOptions) (*TracerSessionWithoutVirtualFields, error) {
+	prefix := opts.ExperimentPrefix
+	if prefix == "" {
+		prefix = "experiment"
+	}
+	params := SessionNewParams{
+		Name:               F(prefix + "-" + uuid.NewString()[:8]),
+		ReferenceDatasetID: F(datasetID),
+		StartTime:          F(time.Now()),
+		Extra:              F(map[string]any{"metadata": metadata}),
+	}
+	if opts.Description != "" {
+		params.Description = F(opts.Description)
+	}
+	if opts.NumRepetitions > 1 {
+		params.NumRepetitions = F(int64(opts.NumRepetitions))
+	}
+	session, err := client.Sessions.New(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("langsmith: create experiment: %w", err)
+	}
+	return session, nil
+}
+
+// experiment holds what every example of a running experiment shares.
+type experiment struct {
+	client     *Client
+	target     Target
+	evaluators []RunEvaluator
+	session    *TracerSessionWithoutVirtualFields
+	metadata   map[string]any
+	runName    string
+	// evalsProject is the project evaluator runs are traced in.
+	evalsProject      string
+	summaryEvaluators []SummaryEvaluator
+	cache             TargetCache
+	targetVersion     string
+}
+
+// repetitionKey is the run metadata key Evaluate records each run's
+// repetition under, so that resuming an experiment knows which repetitions
+// are done.
+const repetitionKey = "repetition"
+
+// runExample runs the target on ex as a root run of the experiment, then
+// evaluates the run and records the results as feedback. rep is the run's
+// repetition. The run is recorded before the target is
+// called, and the target's context belongs to it, so the runs the target
+// traces (with StartChildRun) nest under it and count towards the
+// experiment's tokens and cost.
+func (e *experiment) runExample(ctx context.Context, ex Example, rep int) ExperimentResultRow {
+	var errs []error
+	cacheKey := fmt.Sprintf("%s/%s/%d", e.targetVersion, ex.ID, rep)
+	var outputs map[string]any
+	cached := false
+	if e.cache != nil {
+		outputs, cached = e.cache.Get(cacheKey)
+	}
+
+	metadata := make(map[string]any, len(e.metadata)+3)
+	for k, v := range e.metadata {
+		metadata[k] = v
+	}
+	metadata[repetitionKey] = rep
+	if !e.session.StartTime.IsZero() {
+		metadata["experiment_start_time"] = e.session.StartTime.UTC().Format(time.RFC3339Nano)
+	}
+	if cached {
+		metadata["cache_hit"] = true
+	}
+	runID := uuid.New()
+	run := RunView{
+		ID:                 runID.String(),
+		TraceID:            runID.String(),
+		Name:               e.runName,
+		RunType:            "chain",
+		Inputs:             ex.Inputs,
+		StartTime:          time.Now(),
+		Metadata:           metadata,
+		ReferenceExampleID: ex.ID,
+		SessionID:          e.session.ID,
+	}
+	root, err := e.startRun(ctx, run)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("record run: %w", err))
+	}
+
+	var targetErr error
+	if !cached {
+		targetCtx := ctx
+		if root != nil {
+			targetCtx = withChildRun(ctx, root)
+		}
+		outputs, targetErr = e.target(targetCtx, ex.Inputs)
+		if e.cache != nil && targetErr == nil {
+			if err := e.cache.Put(cacheKey, outputs); err != nil {
+				errs = append(errs, fmt.Errorf("cache outputs: %w", err))
+			}
+		}
+	}
+	run.Outputs = outputs
+	run.EndTime = time.Now()
+	if targetErr != nil {
+		run.Error = targetErr.Error()
+	}
+	if root != nil {
+		if err := e.endRun(root, run); err != nil {
+			errs = append(errs, fmt.Errorf("record run: %w", err))
+		}
+	}
+
+	row := ExperimentResultRow{Run: run, Example: ex, Repetition: rep}
+	e.evaluate(ctx, &row, &ex)
+	row.Err = errors.Join(append(errs, row.Err)...)
+	return row
+}
+
+// evaluate runs the evaluators on row's run and records their results as
+// feedback, adding the results to row and what failed to row.Err.
+func (e *experiment) evaluate(ctx context.Context, row *ExperimentResultRow, ex *Example) {
+	errs := []error{row.Err}
+	for _, evaluator := range e.evaluators {
+		res, err := traceEvaluator(ctx, e.client, e.evalsProject, evaluator, row.Run, ex)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("evaluator %s: %w", evaluatorName(evaluator), err))
+			continue
+		}
+		for _, r := range res {
+			if err := e.createFeedback(ctx, row.Run, r); err != nil {
+				errs = append(errs, fmt.Errorf("record feedback %q: %w", r.Key, err))
+			}
+		}
+		row.EvaluationResults = append(row.EvaluationResults, res...)
+	}
+	row.Err = errors.Join(errs...)
+}
+
+// startRun records the start of run, a root run of the experiment, and
+// returns it as the parent of the runs traced under it.
+func (e *experiment) startRun(ctx context.Context, run RunView) (*ChildRun, error) {
+	runID, err := uuid.Parse(run.ID)
+	if err != nil {
+		return nil, err
+	}
+	sessionID, err := uuid.Parse(run.SessionID)
+	if err != nil {
+		return nil, fmt.Errorf("experiment ID: %w", err)
+	}
+	exampleID, err := uuid.Parse(run.ReferenceExampleID)
+	if err != nil {
+		return nil, fmt.Errorf("example ID: %w", err)
+	}
+	root := &ChildRun{
+		client:      e.client,
+		id:          runID,
+		traceID:     runID,
+		dottedOrder: traceutil.DottedOrder(run.StartTime, runID),
+		project:     e.session.Name,
+	}
+	err = e.client.CreateRunContext(ctx, &RunCreate{
+		ID:                 runID,
+		TraceID:            runID,
+		Name:               run.Name,
+		RunType:            run.RunType,
+		Inputs:             run.Inputs,
+		Extra:              map[string]any{"metadata": run.Metadata},
+		StartTime:          run.StartTime,
+		DottedOrder:        root.dottedOrder,
+		SessionName:        e.session.Name,
+		SessionID:          &sessionID,
+		ReferenceExampleID: &exampleID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return root, nil
+}
+
+// endRun records the outputs or error and end time of run, started as root.
+func (e *experiment) endRun(root *ChildRun, run RunView) error {
+	outputs := run.Outputs
+	if outputs == nil && run.Error == "" {
+		outputs = map[string]any{}
+	}
+	return e.client.UpdateRun(&RunUpdate{
+		ID:          root.id,
+		TraceID:     root.traceID,
+		DottedOrder: root.dottedOrder,
+		Outputs:     outputs,
+		EndTime:     run.EndTime,
+		Error:       run.Error,
+		SessionName: root.project,
+	})
+}
+
+// createFeedback records r on run. The feedback has an ID derived from the
+// run and key, so feedback recorded when the run was scored before, by
+// Evaluate or EvaluateExisting, is updated rather than duplicated.
+func (e *experiment) createFeedback(ctx context.Context, run RunView, r EvaluationResult) error {
+	fb, err := r.FeedbackParam(run)
+	if err != nil {
+		return err
+	}
+	id := feedbackID(run, r.Key)
+	fb.ID = F(id)
+	_, err = e.client.Feedback.New(ctx, FeedbackNewParams{FeedbackCreateSchema: fb})
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusConflict {
+		return err
+	}
+	update, err := r.feedbackUpdate()
+	if err != nil {
+		return err
+	}
+	_, err = e.client.Feedback.Update(ctx, id, update)
+	return err
+}
+
+// resolveExamples returns the dataset ID and examples data selects.
+func resolveExamples(ctx context.Context, client *Client, data EvaluateData) (string, []Example, error) {
+	if len(data.Examples) > 0 {
+		datasetID := data.Examples[0].DatasetID
+		for _, ex := range data.Examples {
+			if ex.DatasetID != datasetID {
+				return "", nil, errors.New("langsmith: Evaluate: examples come from more than one dataset")
+			}
+		}
+		return datasetID, data.Examples, nil
+	}
+	if data.Dataset == "" {
+		return "", nil, errors.New("langsmith: Evaluate: missing dataset")
+	}
+
+	datasetID := data.Dataset
+	if _, err := uuid.Parse(data.Dataset); err != nil {
+		page, err := client.Datasets.List(ctx, DatasetListParams{Name: F(data.Dataset), Limit: F(int64(1))})
+		if err != nil {
+			return "", nil, fmt.Errorf("langsmith: find dataset %q: %w", data.Dataset, err)
+		}
+		if len(page.Items) == 0 {
+			return "", nil, fmt.Errorf("langsmith: dataset %q not found", data.Dataset)
+		}
+		datasetID = page.Items[0].ID
+	}
+
+	query := ExampleListParams{Dataset: F(datasetID)}
+	if data.Version != "" {
+		query.AsOf = F[ExampleListParamsAsOfUnion](shared.UnionString(data.Version))
+	}
+	if len(data.Splits) > 0 {
+		query.Splits = F(data.Splits)
+	}
+	var examples []Example
+	iter := client.Examples.ListAutoPaging(ctx, query)
+	for iter.Next() {
+		examples = append(examples, iter.Current())
+	}
+	if err := iter.Err(); err != nil {
+		return "", nil, fmt.Errorf("langsmith: list examples: %w", err)
+	}
+	if len(examples) == 0 {
+		return "", nil, fmt.Errorf("langsmith: dataset %q has no matching examples", data.Dataset)
+	}
+	return datasetID, examples, nil
+}
+
+func latestModified(examples []Example) time.Time {
+	var latest time.Time
+	for _, ex := range examples {
+		t := ex.ModifiedAt
+		if t.IsZero() {
+			t = ex.CreatedAt
+		}
+		if t.After(latest) {
+			latest = t
+		}
+	}
+	return latest
+}
@@ -0,0 +1,343 @@
+package langsmith
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PairwiseEvaluator compares the runs that two or more experiments made on
+// the same example, for instance to say which output is better.
+type PairwiseEvaluator interface {
+	EvaluatePairwise(ctx context.Context, runs []RunView, example *Example) (ComparisonResult, error)
+}
+
+// PairwiseEvaluatorFunc adapts a function to a [PairwiseEvaluator].
+type PairwiseEvaluatorFunc func(ctx context.Context, runs []RunView, example *Example) (ComparisonResult, error)
+
+// EvaluatePairwise calls f(ctx, runs, example).
+func (f PairwiseEvaluatorFunc) EvaluatePairwise(ctx context.Context, runs []RunView, example *Example) (ComparisonResult, error) {
+	return f(ctx, runs, example)
+}
+
+// NewPairwiseEvaluator returns a [PairwiseEvaluator] named name that calls
+// fn.
+func NewPairwiseEvaluator(name string, fn PairwiseEvaluatorFunc) PairwiseEvaluator {
+	return namedPairwiseEvaluator{name, fn}
+}
+
+type namedPairwiseEvaluator struct {
+	name string
+	PairwiseEvaluatorFunc
+}
+
+func (e namedPairwiseEvaluator) Name() string { return e.name }
+
+// ComparisonResult is a pairwise evaluator's verdict on the runs of one
+// example.
+type ComparisonResult struct {
+	// Key names the metric, e.g. "preference".
+	Key string
+	// Scores maps run IDs to scores, numbers or bools, such as 1 for the
+	// preferred run and 0 for the others.
+	Scores map[string]any
+	// Comment explains the verdict.
+	Comment string
+	// SourceRunID is the run that produced the verdict, such as an LLM
+	// judge's call. When empty, it is set to the evaluator's run.
+	SourceRunID string
+}
+
+// EvaluateComparativeOptions configures [EvaluateComparative].
+type EvaluateComparativeOptions struct {
+	// Client reads the experiments and records the comparison. If nil, a
+	// client configured from the environment is created and closed when
+	// the comparison ends.
+	Client *Client
+	// ExperimentPrefix starts the comparative experiment's name, which is
+	// followed by a random suffix. Defaults to the experiments' names
+	// joined with " vs. ".
+	ExperimentPrefix string
+	// Description describes the comparative experiment.
+	Description string
+	// Metadata is recorded on the comparative experiment.
+	Metadata map[string]any
+	// MaxConcurrency is how many examples are compared at once. Zero
+	// compares them one at a time.
+	MaxConcurrency int
+	// RandomizeOrder shuffles the runs each evaluator call sees, so that
+	// evaluators such as LLM judges that favor a position do not always
+	// favor the same experiment.
+	RandomizeOrder bool
+	// EvaluatorsProject is the tracing project evaluator runs are recorded
+	// in. Defaults to "evaluators".
+	EvaluatorsProject string
+}
+
+// ComparativeResultRow is the outcome of comparing the runs of one example.
+type ComparativeResultRow struct {
+	Example Example
+	// Runs are the runs compared, in the order of the experiment IDs.
+	Runs    []RunView
+	Results []ComparisonResult
+	// Err reports evaluators that failed and results that could not be
+	// recorded.
+	Err error
+}
+
+// ComparativeResults is the outcome of a comparative experiment.
+type ComparativeResults struct {
+	// ComparativeExperimentID is the comparative experiment's UUID.
+	ComparativeExperimentID string
+	// Name is the comparative experiment's name.
+	Name string
+	// DatasetID is the UUID of the dataset the experiments ran over.
+	DatasetID string
+	// Rows holds a row for every example each experiment ran on, in the
+	// order the dataset lists them.
+	Rows []ComparativeResultRow
+}
+
+// EvaluateComparative compares two or more experiments over the same
+// dataset. It creates a comparative experiment, fetches each experiment's
+// run on every example and calls the evaluators with the runs of each
+// example. Their scores are recorded as feedback on the runs, tied to the
+// comparative experiment; the feedback of one evaluator call shares a
+// feedback group. Each evaluator call is traced in the EvaluatorsProject
+// and the feedback links to it as its source run.
+//
+// Examples some experiment has no run on are skipped; when an experiment
+// ran an example more than once, its first run is compared. Unlike
+// [Evaluate], EvaluateComparative returns once every example is compared.
+func EvaluateComparative(ctx context.Context, experimentIDs []string, evaluators []PairwiseEvaluator, opts EvaluateComparativeOptions) (*ComparativeResults, error) {
+	if len(experimentIDs) < 2 {
+		return nil, errors.New("langsmith: EvaluateComparative: need at least two experiments")
+	}
+	client := opts.Client
+	if client == nil {
+		client = NewClient()
+		defer client.Close()
+	}
+
+	var datasetID string
+	names := make([]string, len(experimentIDs))
+	for i, id := range experimentIDs {
+		session, err := client.Sessions.Get(ctx, id, SessionGetParams{})
+		if err != nil {
+			return nil, fmt.Errorf("langsmith: get experiment %s: %w", id, err)
+		}
+		if session.ReferenceDatasetID == "" {
+			return nil, fmt.Errorf("langsmith: %s is not an experiment over a dataset", session.Name)
+		}
+		if datasetID != "" && session.ReferenceDatasetID != datasetID {
+			return nil, fmt.Errorf("langsmith: experiments %s and %s are over different datasets", names[0], session.Name)
+		}
+		datasetID = session.ReferenceDatasetID
+		names[i] = session.Name
+	}
+
+	prefix := opts.ExperimentPrefix
+	if prefix == "" {
+		prefix = strings.Join(names, " vs. ")
+	}
+	params := DatasetComparativeNewParams{
+		ExperimentIDs:      F(experimentIDs),
+		Name:               F(prefix + "-" + uuid.NewString()[:8]),
+		ReferenceDatasetID: F(datasetID),
+		CreatedAt:          F(time.Now()),
+	}
+	if opts.Description != "" {
+		params.Description = F(opts.Description)
+	}
+	if len(opts.Metadata) > 0 {
+		params.Extra = F(map[string]any{"metadata": opts.Metadata})
+	}
+	comparative, err := client.Datasets.Comparative.New(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("langsmith: create comparative experiment: %w", err)
+	}
+
+	rows, err := experimentRuns(ctx, client, datasetID, experimentIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &comparison{
+		client:       client,
+		id:           comparative.ID,
+		evaluators:   evaluators,
+		randomize:    opts.RandomizeOrder,
+		evalsProject: opts.EvaluatorsProject,
+	}
+	if c.evalsProject == "" {
+		c.evalsProject = "evaluators"
+	}
+	sem := make(chan struct{}, max(opts.MaxConcurrency, 1))
+	var wg sync.WaitGroup
+	for i := range rows {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return nil, ctx.Err()
+		}
+		wg.Add(1)
+		go func(row *ComparativeResultRow) {
+			defer func() { <-sem; wg.Done() }()
+			c.compare(ctx, row)
+		}(&rows[i])
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return &ComparativeResults{
+		ComparativeExperimentID: comparative.ID,
+		Name:                    comparative.Name,
+		DatasetID:               datasetID,
+		Rows:                    rows,
+	}, nil
+}
+
+// experimentRuns returns a row for every example of the dataset that each
+// experiment has a run on, with the runs in the order of experimentIDs.
+func experimentRuns(ctx context.Context, client *Client, datasetID string, experimentIDs []string) ([]ComparativeResultRow, error) {
+	iter := client.Datasets.ExperimentRuns.QueryAutoPaging(ctx, datasetID, DatasetExperimentRunQueryParams{
+		ExperimentIDs: F(experimentIDs),
+		Selects: F([]RunSelectField{
+			RunSelectFieldID, RunSelectFieldTraceID, RunSelectFieldName, RunSelectFieldRunType,
+			RunSelectFieldInputs, RunSelectFieldOutputs, RunSelectFieldError,
+			RunSelectFieldStartTime, RunSelectFieldEndTime, RunSelectFieldMetadata,
+			RunSelectFieldProjectID, RunSelectFieldReferenceExampleID,
+			RunSelectFieldPromptTokens, RunSelectFieldCompletionTokens, RunSelectFieldTotalTokens,
+		}),
+	})
+	var rows []ComparativeResultRow
+	for iter.Next() {
+		item := iter.Current()
+		byExperiment := make(map[string]RunView, len(item.Runs))
+		for _, r := range item.Runs {
+			if _, seen := byExperiment[r.ProjectID]; !seen {
+				byExperiment[r.ProjectID] = runView(r)
+			}
+		}
+		row := ComparativeResultRow{Example: Example{
+			ID:         item.ID,
+			DatasetID:  item.DatasetID,
+			Name:       item.Name,
+			Inputs:     asMap(item.Inputs),
+			Outputs:    asMap(item.Outputs),
+			Metadata:   asMap(item.Metadata),
+			CreatedAt:  item.CreatedAt,
+			ModifiedAt: item.ModifiedAt,
+		}}
+		for _, id := range experimentIDs {
+			if run, ok := byExperiment[id]; ok {
+				row.Runs = append(row.Runs, run)
+			}
+		}
+		if len(row.Runs) == len(experimentIDs) {
+			rows = append(rows, row)
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("langsmith: list experiment runs: %w", err)
+	}
+	return rows, nil
+}
+
+func asMap(v any) map[string]any {
+	m, _ := v.(map[string]any)
+	return m
+}
+
+// comparison holds what every example of a running comparison shares.
+type comparison struct {
+	client       *Client
+	id           string
+	evaluators   []PairwiseEvaluator
+	randomize    bool
+	evalsProject string
+}
+
+// compare runs the evaluators on row's runs and records their scores as
+// feedback.
+func (c *comparison) compare(ctx context.Context, row *ComparativeResultRow) {
+	runIDs := make([]string, len(row.Runs))
+	byID := make(map[string]RunView, len(row.Runs))
+	for i, run := range row.Runs {
+		runIDs[i] = run.ID
+		byID[run.ID] = run
+	}
+	var errs []error
+	for _, ev := range c.evaluators {
+		runs := append([]RunView(nil), row.Runs...)
+		if c.randomize {
+			rand.Shuffle(len(runs), func(i, j int) { runs[i], runs[j] = runs[j], runs[i] })
+		}
+		name := evaluatorName(ev)
+		inputs := map[string]any{"runs": runs, "example": row.Example}
+		metadata := map[string]any{"comparative_experiment_id": c.id, "target_run_ids": runIDs}
+		var verdict ComparisonResult
+		res, err := traceEvaluation(ctx, c.client, c.evalsProject, name, inputs, metadata, func(ctx context.Context) ([]EvaluationResult, error) {
+			var err error
+			verdict, err = ev.EvaluatePairwise(ctx, runs, &row.Example)
+			if err != nil {
+				return nil, err
+			}
+			return []EvaluationResult{{Key: verdict.Key, Value: verdict.Scores, Comment: verdict.Comment, SourceRunID: verdict.SourceRunID}}, nil
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("evaluator %s: %w", name, err))
+			continue
+		}
+		verdict.SourceRunID = res[0].SourceRunID
+		if err := c.createFeedback(ctx, byID, verdict); err != nil {
+			errs = append(errs, fmt.Errorf("record feedback %q: %w", verdict.Key, err))
+		}
+		row.Results = append(row.Results, verdict)
+	}
+	row.Err = errors.Join(errs...)
+}
+
+// createFeedback records each run's score as feedback on the run, tied to
+// the comparative experiment and grouped with the other runs' feedback.
+func (c *comparison) createFeedback(ctx context.Context, runs map[string]RunView, verdict ComparisonResult) error {
+	group := uuid.NewString()
+	var errs []error
+	for _, runID := range sortedRunIDs(verdict.Scores) {
+		run, ok := runs[runID]
+		if !ok {
+			errs = append(errs, fmt.Errorf("score for run %s, which was not compared", runID))
+			continue
+		}
+		r := EvaluationResult{Key: verdict.Key, Score: verdict.Scores[runID], Comment: verdict.Comment, SourceRunID: verdict.SourceRunID}
+		fb, err := r.FeedbackParam(run)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		fb.ComparativeExperimentID = F(c.id)
+		fb.FeedbackGroupID = F(group)
+		if _, err := c.client.Feedback.New(ctx, FeedbackNewParams{FeedbackCreateSchema: fb}); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func sortedRunIDs(scores map[string]any) []string {
+	ids := make([]string, 0, len(scores))
+	for id := range scores {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids
+}
@@ -0,0 +1,144 @@
+package langsmith_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/langchain-ai/langsmith-go"
+	"github.com/langchain-ai/langsmith-go/option"
+)
+
+const (
+	expA         = "00000000-0000-0000-0000-0000000000a0"
+	expB         = "00000000-0000-0000-0000-0000000000b0"
+	comparisonID = "00000000-0000-0000-0000-0000000000c0"
+)
+
+func TestEvaluateComparative(t *testing.T) {
+	var mu sync.Mutex
+	var comparative, query map[string]any
+	var feedback []map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v1/sessions/"+expA:
+			_, _ = w.Write([]byte(`{"id": "` + expA + `", "name": "gpt", "tenant_id": "` + expA + `", "reference_dataset_id": "` + evalDatasetID + `"}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v1/sessions/"+expB:
+			_, _ = w.Write([]byte(`{"id": "` + expB + `", "name": "claude", "tenant_id": "` + expB + `", "reference_dataset_id": "` + evalDatasetID + `"}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/api/v1/datasets/comparative":
+			_ = json.NewDecoder(r.Body).Decode(&comparative)
+			_ = json.NewEncoder(w).Encode(map[string]any{"id": comparisonID, "name": comparative["name"], "reference_dataset_id": evalDatasetID, "tenant_id": expA})
+		case r.Method == http.MethodPost && r.URL.Path == "/api/v2/datasets/"+evalDatasetID+"/experiment-runs":
+			_ = json.NewDecoder(r.Body).Decode(&query)
+			_, _ = w.Write([]byte(`{"items": [
+				{"id": "00000000-0000-0000-0000-000000000a01", "dataset_id": "` + evalDatasetID + `", "inputs": {"q": "hi"}, "runs": [
+					{"id": "00000000-0000-0000-0000-0000000001b1", "project_id": "` + expB + `", "outputs": {"a": "hello there"}},
+					{"id": "00000000-0000-0000-0000-0000000001a1", "project_id": "` + expA + `", "outputs": {"a": "hey"}}
+				]},
+				{"id": "00000000-0000-0000-0000-000000000a02", "dataset_id": "` + evalDatasetID + `", "inputs": {"q": "bye"}, "runs": [
+					{"id": "00000000-0000-0000-0000-0000000002a1", "project_id": "` + expA + `", "outputs": {"a": "goodbye"}},
+					{"id": "00000000-0000-0000-0000-0000000002b1", "project_id": "` + expB + `", "outputs": {"a": "bye"}}
+				]},
+				{"id": "00000000-0000-0000-0000-000000000a03", "dataset_id": "` + evalDatasetID + `", "inputs": {"q": "?"}, "runs": [
+					{"id": "00000000-0000-0000-0000-0000000003a1", "project_id": "` + expA + `", "outputs": {"a": "?"}}
+				]}
+			]}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/api/v1/feedback":
+			var fb map[string]any
+			_ = json.NewDecoder(r.Body).Decode(&fb)
+			feedback = append(feedback, fb)
+			_, _ = w.Write([]byte(`{}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/runs/multipart":
+			w.WriteHeader(http.StatusAccepted)
+		default:
+			t.Errorf("unexpected %s %s", r.Method, r.URL.Path)
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	client := langsmith.NewClient(option.WithBaseURL(srv.URL), option.WithAPIKey("test-api-key"), option.WithMaxRetries(0))
+	defer client.Close()
+
+	// Prefers the longer answer, whatever order the runs come in.
+	longer := langsmith.NewPairwiseEvaluator("longer", func(ctx context.Context, runs []langsmith.RunView, example *langsmith.Example) (langsmith.ComparisonResult, error) {
+		best := runs[0]
+		for _, run := range runs[1:] {
+			if len(run.Outputs["a"].(string)) > len(best.Outputs["a"].(string)) {
+				best = run
+			}
+		}
+		scores := map[string]any{}
+		for _, run := range runs {
+			scores[run.ID] = run.ID == best.ID
+		}
+		return langsmith.ComparisonResult{Key: "longer", Scores: scores}, nil
+	})
+	results, err := langsmith.EvaluateComparative(context.Background(), []string{expA, expB}, []langsmith.PairwiseEvaluator{longer}, langsmith.EvaluateComparativeOptions{
+		Client:         client,
+		MaxConcurrency: 2,
+		RandomizeOrder: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results.ComparativeExperimentID != comparisonID || !strings.HasPrefix(results.Name, "gpt vs. claude-") || results.DatasetID != evalDatasetID {
+		t.Errorf("results = %+v", results)
+	}
+	if len(results.Rows) != 2 {
+		t.Fatalf("got %d rows, want 2 (the example without a run of each experiment skipped)", len(results.Rows))
+	}
+	for _, row := range results.Rows {
+		if row.Err != nil {
+			t.Errorf("row %s: %v", row.Example.ID, row.Err)
+		}
+		if row.Runs[0].SessionID != expA || row.Runs[1].SessionID != expB || len(row.Results) != 1 || row.Results[0].SourceRunID == "" {
+			t.Errorf("row = %+v", row)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if ids, _ := comparative["experiment_ids"].([]any); len(ids) != 2 || comparative["reference_dataset_id"] != evalDatasetID {
+		t.Errorf("comparative experiment = %v", comparative)
+	}
+	if ids, _ := query["experiment_ids"].([]any); len(ids) != 2 {
+		t.Errorf("experiment runs query = %v", query)
+	}
+	if len(feedback) != 4 {
+		t.Fatalf("got %d feedback, want 4", len(feedback))
+	}
+	groups := map[any][]string{}
+	scores := map[string]any{}
+	for _, fb := range feedback {
+		if fb["comparative_experiment_id"] != comparisonID || fb["key"] != "longer" {
+			t.Errorf("feedback = %v", fb)
+		}
+		groups[fb["feedback_group_id"]] = append(groups[fb["feedback_group_id"]], fb["run_id"].(string))
+		scores[fb["run_id"].(string)] = fb["score"]
+	}
+	if len(groups) != 2 {
+		t.Errorf("feedback groups = %v", groups)
+	}
+	want := map[string]any{
+		"00000000-0000-0000-0000-0000000001a1": false, "00000000-0000-0000-0000-0000000001b1": true,
+		"00000000-0000-0000-0000-0000000002a1": true, "00000000-0000-0000-0000-0000000002b1": false,
+	}
+	for id, s := range want {
+		if scores[id] != s {
+			t.Errorf("score of %s = %v, want %v", id, scores[id], s)
+		}
+	}
+}
+
+func TestEvaluateComparativeNeedsTwo(t *testing.T) {
+	if _, err := langsmith.EvaluateComparative(context.Background(), []string{expA}, nil, langsmith.EvaluateComparativeOptions{}); err == nil {
+		t.Error("want an error comparing one experiment")
+	}
+}
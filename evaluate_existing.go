@@ -0,0 +1,196 @@
+package langsmith
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/langchain-ai/langsmith-go/shared"
+)
+
+// EvaluateExistingOptions configures [EvaluateExisting].
+type EvaluateExistingOptions struct {
+	// Client reads the experiment and records the feedback. If nil, a
+	// client configured from the environment is created and closed when the
+	// evaluation ends.
+	Client *Client
+	// MaxConcurrency is how many runs are evaluated at once. Zero evaluates
+	// them one at a time.
+	MaxConcurrency int
+	// EvaluatorsProject is the tracing project evaluator runs are recorded
+	// in. Defaults to "evaluators".
+	EvaluatorsProject string
+	// SummaryEvaluators score the experiment as a whole once every run has
+	// been evaluated.
+	SummaryEvaluators []SummaryEvaluator
+}
+
+// EvaluateExisting scores the root runs of an existing experiment, named or
+// identified by nameOrID, with evaluators, without running its target
+// again. Use it to add a new evaluator to past experiments.
+//
+// Each run is evaluated against the example it ran on, as of the dataset
+// version the experiment recorded; a run whose example cannot be found is
+// evaluated without one. Feedback IDs are derived from the run and the
+// feedback key, so evaluating an experiment again updates the feedback it
+// recorded before instead of adding more.
+//
+// Like [Evaluate], EvaluateExisting returns once the runs have been listed
+// and evaluates them in the background. The rows' Repetition is always 0.
+func EvaluateExisting(ctx context.Context, nameOrID string, evaluators []RunEvaluator, opts EvaluateExistingOptions) (*ExperimentResults, error) {
+	if nameOrID == "" {
+		return nil, errors.New("langsmith: EvaluateExisting: missing experiment")
+	}
+	client := opts.Client
+	if client == nil {
+		client = NewClient()
+	}
+	closeClient := func() {
+		if opts.Client == nil {
+			client.Close()
+		}
+	}
+
+	session, err := findExperiment(ctx, client, nameOrID, "")
+	if err != nil {
+		closeClient()
+		return nil, err
+	}
+	runs, err := experimentRootRuns(ctx, client, session)
+	if err != nil {
+		closeClient()
+		return nil, err
+	}
+	version, _ := session.Extra["metadata"].(map[string]any)["dataset_version"].(string)
+	examples, err := referencedExamples(ctx, client, session.ReferenceDatasetID, version, runs)
+	if err != nil {
+		closeClient()
+		return nil, err
+	}
+
+	results := &ExperimentResults{
+		ExperimentName: session.Name,
+		ExperimentID:   session.ID,
+		DatasetID:      session.ReferenceDatasetID,
+	}
+	results.cond = sync.NewCond(&results.mu)
+	e := &experiment{
+		client:            client,
+		evaluators:        evaluators,
+		session:           session,
+		evalsProject:      opts.EvaluatorsProject,
+		summaryEvaluators: opts.SummaryEvaluators,
+	}
+	if e.evalsProject == "" {
+		e.evalsProject = "evaluators"
+	}
+
+	go func() {
+		defer closeClient()
+		sem := make(chan struct{}, max(opts.MaxConcurrency, 1))
+		var wg sync.WaitGroup
+	loop:
+		for _, run := range runs {
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				break loop
+			}
+			wg.Add(1)
+			go func(run RunView) {
+				defer func() { <-sem; wg.Done() }()
+				row := ExperimentResultRow{Run: run}
+				ex, ok := examples[run.ReferenceExampleID]
+				if ok {
+					row.Example = ex
+					e.evaluate(ctx, &row, &ex)
+				} else {
+					e.evaluate(ctx, &row, nil)
+				}
+				results.add(row)
+			}(run)
+		}
+		wg.Wait()
+		err := ctx.Err()
+		var summary []EvaluationResult
+		var summaryErr error
+		if err == nil && len(opts.SummaryEvaluators) > 0 {
+			summary, summaryErr = e.summarize(ctx, results.snapshot())
+		}
+		results.finish(err, summary, summaryErr)
+	}()
+	return results, nil
+}
+
+// rootRunSelects are the fields of an experiment's root runs that make up
+// their RunView.
+var rootRunSelects = []RunSelectField{
+	RunSelectFieldID, RunSelectFieldTraceID, RunSelectFieldName, RunSelectFieldRunType,
+	RunSelectFieldInputs, RunSelectFieldOutputs, RunSelectFieldError,
+	RunSelectFieldStartTime, RunSelectFieldEndTime, RunSelectFieldExtra, RunSelectFieldMetadata,
+	RunSelectFieldPromptTokens, RunSelectFieldCompletionTokens, RunSelectFieldTotalTokens,
+	RunSelectFieldReferenceExampleID, RunSelectFieldProjectID,
+}
+
+// experimentRootRuns returns the root runs of the experiment over its
+// dataset's examples.
+func experimentRootRuns(ctx context.Context, client *Client, session *TracerSessionWithoutVirtualFields) ([]RunView, error) {
+	params := RunQueryV2Params{
+		ProjectIDs:         F([]string{session.ID}),
+		ReferenceDatasetID: F(session.ReferenceDatasetID),
+		IsRoot:             F(true),
+		Selects:            F(rootRunSelects),
+	}
+	if !session.StartTime.IsZero() {
+		params.MinStartTime = F(session.StartTime)
+	}
+	var runs []RunView
+	iter := client.Runs.QueryV2AutoPaging(ctx, params)
+	for iter.Next() {
+		run := runView(iter.Current())
+		if run.SessionID == "" {
+			run.SessionID = session.ID
+		}
+		runs = append(runs, run)
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("langsmith: list experiment runs: %w", err)
+	}
+	return runs, nil
+}
+
+// exampleBatch is how many examples are requested by ID at once.
+const exampleBatch = 100
+
+// referencedExamples returns the examples of the dataset the runs ran on, by
+// ID, as of version if set.
+func referencedExamples(ctx context.Context, client *Client, datasetID, version string, runs []RunView) (map[string]Example, error) {
+	seen := map[string]bool{}
+	var ids []string
+	for _, run := range runs {
+		if id := run.ReferenceExampleID; id != "" && !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	examples := make(map[string]Example, len(ids))
+	for start := 0; start < len(ids); start += exampleBatch {
+		query := ExampleListParams{
+			Dataset: F(datasetID),
+			ID:      F(ids[start:min(start+exampleBatch, len(ids))]),
+		}
+		if version != "" {
+			query.AsOf = F[ExampleListParamsAsOfUnion](shared.UnionString(version))
+		}
+		iter := client.Examples.ListAutoPaging(ctx, query)
+		for iter.Next() {
+			ex := iter.Current()
+			examples[ex.ID] = ex
+		}
+		if err := iter.Err(); err != nil {
+			return nil, fmt.Errorf("langsmith: list examples: %w", err)
+		}
+	}
+	return examples, nil
+}
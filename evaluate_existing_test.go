@@ -0,0 +1,152 @@
+package langsmith_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/langchain-ai/langsmith-go"
+	"github.com/langchain-ai/langsmith-go/option"
+)
+
+func TestEvaluateExisting(t *testing.T) {
+	var mu sync.Mutex
+	var runsQuery map[string]any
+	var examplesQuery string
+	feedback := map[string]map[string]any{}
+	var created, updated int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v1/sessions/"+evalSessionID:
+			_, _ = w.Write([]byte(`{"id": "` + evalSessionID + `", "name": "gpt", "tenant_id": "` + evalSessionID + `", "reference_dataset_id": "` + evalDatasetID + `", "extra": {"metadata": {"dataset_version": "2025-01-02T00:00:00Z"}}}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/api/v2/runs/query":
+			_ = json.NewDecoder(r.Body).Decode(&runsQuery)
+			_, _ = w.Write([]byte(`{"items": [
+				{"id": "00000000-0000-0000-0000-0000000001a1", "trace_id": "00000000-0000-0000-0000-0000000001a1", "name": "Target", "run_type": "chain", "inputs": {"q": "2+2"}, "outputs": {"a": "4"}, "reference_example_id": "00000000-0000-0000-0000-000000000a01", "project_id": "` + evalSessionID + `"},
+				{"id": "00000000-0000-0000-0000-0000000001a2", "trace_id": "00000000-0000-0000-0000-0000000001a2", "name": "Target", "run_type": "chain", "inputs": {"q": "3+3"}, "outputs": {"a": "5"}, "reference_example_id": "00000000-0000-0000-0000-000000000a02", "project_id": "` + evalSessionID + `"},
+				{"id": "00000000-0000-0000-0000-0000000001a3", "trace_id": "00000000-0000-0000-0000-0000000001a3", "name": "Target", "run_type": "chain", "inputs": {"q": "?"}, "outputs": {"a": "?"}, "reference_example_id": "00000000-0000-0000-0000-000000000a09", "project_id": "` + evalSessionID + `"}
+			]}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v1/examples":
+			if r.URL.Query().Get("offset") != "" {
+				_, _ = w.Write([]byte(`[]`))
+				return
+			}
+			examplesQuery = r.URL.RawQuery
+			_, _ = w.Write([]byte(`[
+				{"id": "00000000-0000-0000-0000-000000000a01", "dataset_id": "` + evalDatasetID + `", "inputs": {"q": "2+2"}, "outputs": {"a": "4"}},
+				{"id": "00000000-0000-0000-0000-000000000a02", "dataset_id": "` + evalDatasetID + `", "inputs": {"q": "3+3"}, "outputs": {"a": "6"}}
+			]`))
+		case r.Method == http.MethodPost && r.URL.Path == "/api/v1/feedback":
+			var fb map[string]any
+			_ = json.NewDecoder(r.Body).Decode(&fb)
+			id, _ := fb["id"].(string)
+			if _, ok := feedback[id]; ok {
+				w.WriteHeader(http.StatusConflict)
+				_, _ = w.Write([]byte(`{"detail": "feedback already exists"}`))
+				return
+			}
+			created++
+			feedback[id] = fb
+			_, _ = w.Write([]byte(`{}`))
+		case r.Method == http.MethodPatch && strings.HasPrefix(r.URL.Path, "/api/v1/feedback/"):
+			var update map[string]any
+			_ = json.NewDecoder(r.Body).Decode(&update)
+			fb, ok := feedback[strings.TrimPrefix(r.URL.Path, "/api/v1/feedback/")]
+			if !ok {
+				http.Error(w, "not found", http.StatusNotFound)
+				return
+			}
+			updated++
+			fb["score"] = update["score"]
+			_, _ = w.Write([]byte(`{}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/runs/multipart":
+			w.WriteHeader(http.StatusAccepted)
+		default:
+			t.Errorf("unexpected %s %s", r.Method, r.URL.Path)
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	client := langsmith.NewClient(option.WithBaseURL(srv.URL), option.WithAPIKey("test-api-key"), option.WithMaxRetries(0))
+	defer client.Close()
+
+	score := true
+	correct := langsmith.NewRunEvaluator("correct", func(ctx context.Context, run langsmith.RunView, example *langsmith.Example) ([]langsmith.EvaluationResult, error) {
+		if example == nil {
+			return []langsmith.EvaluationResult{{Key: "has_example", Score: false}}, nil
+		}
+		return []langsmith.EvaluationResult{{Key: "correct", Score: score && run.Outputs["a"] == example.Outputs["a"]}}, nil
+	})
+	evaluate := func() []langsmith.ExperimentResultRow {
+		t.Helper()
+		results, err := langsmith.EvaluateExisting(context.Background(), evalSessionID, []langsmith.RunEvaluator{correct}, langsmith.EvaluateExistingOptions{
+			Client:         client,
+			MaxConcurrency: 2,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if results.ExperimentName != "gpt" || results.DatasetID != evalDatasetID {
+			t.Errorf("results = %+v", results)
+		}
+		rows, err := results.Wait()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(rows) != 3 {
+			t.Fatalf("got %d rows, want 3", len(rows))
+		}
+		for _, row := range rows {
+			if row.Err != nil {
+				t.Errorf("row %s: %v", row.Run.ID, row.Err)
+			}
+		}
+		return rows
+	}
+
+	evaluate()
+	mu.Lock()
+	if runsQuery["is_root"] != true || runsQuery["reference_dataset_id"] != evalDatasetID {
+		t.Errorf("runs query = %v", runsQuery)
+	}
+	if !strings.Contains(examplesQuery, "as_of=2025-01-02") || strings.Count(examplesQuery, "id=") != 3 {
+		t.Errorf("examples query = %q", examplesQuery)
+	}
+	want := map[string]any{
+		"00000000-0000-0000-0000-0000000001a1/correct":     true,
+		"00000000-0000-0000-0000-0000000001a2/correct":     false,
+		"00000000-0000-0000-0000-0000000001a3/has_example": false,
+	}
+	checkScores := func() {
+		t.Helper()
+		if len(feedback) != len(want) {
+			t.Errorf("got %d feedback, want %d", len(feedback), len(want))
+		}
+		for _, fb := range feedback {
+			k := fb["run_id"].(string) + "/" + fb["key"].(string)
+			if fb["score"] != want[k] || fb["session_id"] != evalSessionID {
+				t.Errorf("feedback %s = %v, want score %v", k, fb, want[k])
+			}
+		}
+	}
+	checkScores()
+	mu.Unlock()
+
+	// Evaluating again updates the feedback instead of adding more.
+	score = false
+	want["00000000-0000-0000-0000-0000000001a1/correct"] = false
+	evaluate()
+	mu.Lock()
+	defer mu.Unlock()
+	if created != 3 || updated != 3 {
+		t.Errorf("created %d and updated %d feedback, want 3 of each", created, updated)
+	}
+	checkScores()
+}
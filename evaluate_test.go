@@ -0,0 +1,785 @@
+package langsmith_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/langchain-ai/langsmith-go"
+	"github.com/langchain-ai/langsmith-go/option"
+)
+
+const (
+	evalDatasetID = "00000000-0000-0000-0000-0000000000d1"
+	evalSessionID = "00000000-0000-0000-0000-0000000000e1"
+)
+
+// evalServer fakes the LangSmith endpoints an experiment uses, recording
+// what it is sent.
+type evalServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	queries  map[string]string
+	session  map[string]any
+	ended    bool
+	feedback []map[string]any
+	runs     []map[string]any
+	// existing are the root runs of the experiment before it started.
+	existing []map[string]any
+}
+
+func newEvalServer(t *testing.T, examples []map[string]any) *evalServer {
+	t.Helper()
+	s := &evalServer{queries: map[string]string{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v1/datasets":
+			s.queries["datasets"] = r.URL.RawQuery
+			_ = json.NewEncoder(w).Encode([]any{map[string]any{"id": evalDatasetID, "name": r.URL.Query().Get("name")}})
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v1/examples":
+			if r.URL.Query().Get("offset") != "" {
+				_, _ = w.Write([]byte(`[]`))
+				return
+			}
+			s.queries["examples"] = r.URL.RawQuery
+			_ = json.NewEncoder(w).Encode(examples)
+		case r.Method == http.MethodPost && r.URL.Path == "/api/v1/sessions":
+			_ = json.NewDecoder(r.Body).Decode(&s.session)
+			_ = json.NewEncoder(w).Encode(map[string]any{"id": evalSessionID, "name": s.session["name"], "tenant_id": evalSessionID, "start_time": s.session["start_time"]})
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v1/sessions":
+			s.queries["sessions"] = r.URL.RawQuery
+			_, _ = w.Write([]byte(`[{"id": "` + evalSessionID + `", "name": "old", "tenant_id": "` + evalSessionID + `", "reference_dataset_id": "` + evalDatasetID + `", "extra": {"metadata": {"model": "old"}}}]`))
+		case r.Method == http.MethodPost && r.URL.Path == "/api/v2/runs/query":
+			var q map[string]any
+			_ = json.NewDecoder(r.Body).Decode(&q)
+			if q["is_root"] != true || q["has_error"] != false {
+				t.Errorf("runs query = %v", q)
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{"items": s.existing})
+		case r.Method == http.MethodPatch && r.URL.Path == "/api/v1/sessions/"+evalSessionID:
+			s.ended = true
+			_, _ = w.Write([]byte(`{}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/api/v1/feedback":
+			var fb map[string]any
+			_ = json.NewDecoder(r.Body).Decode(&fb)
+			s.feedback = append(s.feedback, fb)
+			_, _ = w.Write([]byte(`{}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/runs/multipart":
+			_, params, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+			mr := multipart.NewReader(r.Body, params["boundary"])
+			for {
+				part, err := mr.NextPart()
+				if err != nil {
+					break
+				}
+				name := part.FormName()
+				if strings.Count(name, ".") != 1 {
+					continue
+				}
+				var run map[string]any
+				b, _ := io.ReadAll(part)
+				_ = json.Unmarshal(b, &run)
+				switch {
+				case strings.HasPrefix(name, "post."):
+					s.runs = append(s.runs, run)
+				case strings.HasPrefix(name, "patch."):
+					// Patches sent apart from their post update it.
+					for _, posted := range s.runs {
+						if posted["id"] == run["id"] {
+							maps.Copy(posted, run)
+						}
+					}
+				}
+			}
+			w.WriteHeader(http.StatusAccepted)
+		default:
+			t.Errorf("unexpected %s %s", r.Method, r.URL.Path)
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *evalServer) client() *langsmith.Client {
+	return langsmith.NewClient(
+		option.WithBaseURL(s.URL),
+		option.WithAPIKey("test-api-key"),
+		option.WithMaxRetries(0),
+	)
+}
+
+func evalExamples() []map[string]any {
+	return []map[string]any{
+		{"id": "00000000-0000-0000-0000-000000000a01", "dataset_id": evalDatasetID, "name": "#1", "inputs": map[string]any{"question": "2+2"}, "outputs": map[string]any{"answer": "4"}, "modified_at": "2025-01-02T00:00:00Z"},
+		{"id": "00000000-0000-0000-0000-000000000a02", "dataset_id": evalDatasetID, "name": "#2", "inputs": map[string]any{"question": "3+3"}, "outputs": map[string]any{"answer": "6"}, "modified_at": "2025-01-01T00:00:00Z"},
+		{"id": "00000000-0000-0000-0000-000000000a03", "dataset_id": evalDatasetID, "name": "#3", "inputs": map[string]any{"question": "boom"}, "outputs": map[string]any{"answer": "?"}, "modified_at": "2025-01-01T00:00:00Z"},
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	t.Setenv("LANGSMITH_DISABLE_RUN_COMPRESSION", "true")
+	srv := newEvalServer(t, evalExamples())
+	client := srv.client()
+
+	target := func(ctx context.Context, inputs map[string]any) (map[string]any, error) {
+		switch inputs["question"] {
+		case "2+2":
+			return map[string]any{"answer": "4"}, nil
+		case "3+3":
+			return map[string]any{"answer": "5"}, nil
+		}
+		return nil, errors.New("cannot answer")
+	}
+	exactMatch := langsmith.NewRunEvaluator("exact_match", func(ctx context.Context, run langsmith.RunView, example *langsmith.Example) ([]langsmith.EvaluationResult, error) {
+		if run.Error != "" {
+			return nil, errors.New("no outputs")
+		}
+		ok := run.Outputs["answer"] == example.Outputs["answer"]
+		return []langsmith.EvaluationResult{{Key: "exact_match", Score: ok, Comment: "compared answers"}}, nil
+	})
+
+	results, err := langsmith.Evaluate(context.Background(), target, langsmith.EvaluateData{
+		Dataset: "math",
+		Version: "prod",
+		Splits:  []string{"test"},
+	}, []langsmith.RunEvaluator{exactMatch}, langsmith.EvaluateOptions{
+		Client:           client,
+		ExperimentPrefix: "math-eval",
+		Metadata:         map[string]any{"model": "fake"},
+		MaxConcurrency:   2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(results.ExperimentName, "math-eval-") || results.ExperimentID != evalSessionID || results.DatasetID != evalDatasetID {
+		t.Errorf("results = %q %q %q", results.ExperimentName, results.ExperimentID, results.DatasetID)
+	}
+
+	scores := map[string]any{}
+	var failed []string
+	for results.Next() {
+		row := results.Current()
+		if row.Run.ReferenceExampleID != row.Example.ID || row.Run.SessionID != evalSessionID {
+			t.Errorf("row run = %+v, example %s", row.Run, row.Example.ID)
+		}
+		if row.Run.Error != "" {
+			failed = append(failed, row.Example.ID)
+			if row.Err == nil {
+				t.Error("want the evaluator error on the failed row")
+			}
+			continue
+		}
+		if row.Err != nil {
+			t.Errorf("row %s: %v", row.Example.ID, row.Err)
+		}
+		if len(row.EvaluationResults) == 1 {
+			scores[row.Example.ID] = row.EvaluationResults[0].Score
+		}
+	}
+	if err := results.Err(); err != nil {
+		t.Fatal(err)
+	}
+	client.Close()
+
+	if scores["00000000-0000-0000-0000-000000000a01"] != true || scores["00000000-0000-0000-0000-000000000a02"] != false {
+		t.Errorf("scores = %v", scores)
+	}
+	if len(failed) != 1 || failed[0] != "00000000-0000-0000-0000-000000000a03" {
+		t.Errorf("failed = %v", failed)
+	}
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if q := srv.queries["datasets"]; !strings.Contains(q, "name=math") {
+		t.Errorf("datasets query = %q", q)
+	}
+	if q := srv.queries["examples"]; !strings.Contains(q, "dataset="+evalDatasetID) || !strings.Contains(q, "as_of=prod") || !strings.Contains(q, "splits=test") {
+		t.Errorf("examples query = %q", q)
+	}
+	if srv.session["reference_dataset_id"] != evalDatasetID {
+		t.Errorf("session = %v", srv.session)
+	}
+	metadata, _ := srv.session["extra"].(map[string]any)["metadata"].(map[string]any)
+	if metadata["model"] != "fake" || metadata["dataset_version"] != "prod" {
+		t.Errorf("session metadata = %v", metadata)
+	}
+	if !srv.ended {
+		t.Error("experiment end time not recorded")
+	}
+
+	var linked []string
+	targetRuns, evaluatorRuns := map[string]bool{}, map[string]map[string]any{}
+	for _, run := range srv.runs {
+		if run["session_name"] == "evaluators" {
+			evaluatorRuns[run["id"].(string)] = run
+			continue
+		}
+		if run["session_id"] != evalSessionID || run["session_name"] != results.ExperimentName {
+			t.Errorf("run = %v", run)
+		}
+		linked = append(linked, run["reference_example_id"].(string))
+		targetRuns[run["id"].(string)] = true
+	}
+	sort.Strings(linked)
+	if want := "00000000-0000-0000-0000-000000000a01,00000000-0000-0000-0000-000000000a02,00000000-0000-0000-0000-000000000a03"; strings.Join(linked, ",") != want {
+		t.Errorf("runs linked to %v", linked)
+	}
+	if len(evaluatorRuns) != 3 {
+		t.Errorf("got %d evaluator runs, want 3", len(evaluatorRuns))
+	}
+
+	if len(srv.feedback) != 2 {
+		t.Fatalf("got %d feedback, want 2: %v", len(srv.feedback), srv.feedback)
+	}
+	ids := map[any]bool{}
+	for _, fb := range srv.feedback {
+		if fb["key"] != "exact_match" || fb["session_id"] != evalSessionID || fb["comment"] != "compared answers" {
+			t.Errorf("feedback = %v", fb)
+		}
+		// A later EvaluateExisting derives the same ID and updates the
+		// feedback instead of adding to it.
+		if id, _ := fb["id"].(string); id == "" || ids[id] {
+			t.Errorf("feedback ID = %v, want a distinct ID per run", fb["id"])
+		}
+		ids[fb["id"]] = true
+		if !targetRuns[fb["run_id"].(string)] {
+			t.Errorf("feedback on unknown run %v", fb["run_id"])
+		}
+		source, _ := fb["feedback_source"].(map[string]any)
+		meta, _ := source["metadata"].(map[string]any)
+		sourceRun, _ := meta["__run"].(map[string]any)
+		if source["type"] != "model" || evaluatorRuns[sourceRun["run_id"].(string)] == nil {
+			t.Errorf("feedback source = %v", source)
+		}
+	}
+}
+
+func TestEvaluationResultFeedbackParam(t *testing.T) {
+	run := langsmith.RunView{ID: "run-1", TraceID: "trace-1", SessionID: "session-1"}
+	fb, err := langsmith.EvaluationResult{
+		Key:         "helpfulness",
+		Score:       0.5,
+		Value:       "partly",
+		Correction:  map[string]any{"answer": "4"},
+		SourceRunID: "judge-1",
+		FeedbackConfig: &langsmith.FeedbackCreateSchemaFeedbackConfigParam{
+			Type: langsmith.F(langsmith.FeedbackCreateSchemaFeedbackConfigTypeContinuous),
+			Min:  langsmith.F(0.0),
+			Max:  langsmith.F(1.0),
+		},
+	}.FeedbackParam(run)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(fb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]any
+	_ = json.Unmarshal(b, &got)
+	want := map[string]any{
+		"key":             "helpfulness",
+		"run_id":          "run-1",
+		"trace_id":        "trace-1",
+		"session_id":      "session-1",
+		"score":           0.5,
+		"value":           "partly",
+		"correction":      map[string]any{"answer": "4"},
+		"feedback_config": map[string]any{"type": "continuous", "min": 0.0, "max": 1.0},
+		"feedback_source": map[string]any{"type": "model", "metadata": map[string]any{"__run": map[string]any{"run_id": "judge-1"}}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("feedback =\n%v\nwant\n%v", got, want)
+	}
+
+	if _, err := (langsmith.EvaluationResult{Key: "k", Score: "high"}).FeedbackParam(run); err == nil {
+		t.Error("want an error for a string score")
+	}
+	if _, err := (langsmith.EvaluationResult{Score: 1}).FeedbackParam(run); err == nil {
+		t.Error("want an error for a missing key")
+	}
+}
+
+func TestEvaluateExamples(t *testing.T) {
+	srv := newEvalServer(t, nil)
+	client := srv.client()
+	defer client.Close()
+
+	examples := []langsmith.Example{
+		{ID: "00000000-0000-0000-0000-000000000a01", DatasetID: evalDatasetID, Inputs: map[string]any{"x": 1.0}},
+		{ID: "00000000-0000-0000-0000-000000000a02", DatasetID: evalDatasetID, Inputs: map[string]any{"x": 2.0}},
+	}
+	var mu sync.Mutex
+	var seen []float64
+	results, err := langsmith.Evaluate(context.Background(), func(ctx context.Context, inputs map[string]any) (map[string]any, error) {
+		mu.Lock()
+		seen = append(seen, inputs["x"].(float64))
+		mu.Unlock()
+		return inputs, nil
+	}, langsmith.EvaluateData{Examples: examples}, nil, langsmith.EvaluateOptions{Client: client})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := results.Wait()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || len(seen) != 2 {
+		t.Errorf("got %d rows for inputs %v", len(rows), seen)
+	}
+	if _, ok := srv.queries["examples"]; ok {
+		t.Error("listed examples although they were given")
+	}
+}
+
+func TestEvaluateChildRun(t *testing.T) {
+	t.Setenv("LANGSMITH_DISABLE_RUN_COMPRESSION", "true")
+	srv := newEvalServer(t, nil)
+	client := srv.client()
+
+	judge := langsmith.NewRunEvaluator("judge", func(ctx context.Context, run langsmith.RunView, example *langsmith.Example) ([]langsmith.EvaluationResult, error) {
+		_, call := langsmith.StartChildRun(ctx, "model", "llm", map[string]any{"prompt": "grade"})
+		call.End(map[string]any{"output": "pass"}, nil)
+		return []langsmith.EvaluationResult{{Key: "judged", Score: true, SourceRunID: call.ID()}}, nil
+	})
+	examples := []langsmith.Example{{ID: "00000000-0000-0000-0000-000000000a01", DatasetID: evalDatasetID, Inputs: map[string]any{"x": 1.0}}}
+	results, err := langsmith.Evaluate(context.Background(), func(ctx context.Context, inputs map[string]any) (map[string]any, error) {
+		return inputs, nil
+	}, langsmith.EvaluateData{Examples: examples}, []langsmith.RunEvaluator{judge}, langsmith.EvaluateOptions{Client: client})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := results.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	client.Close()
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	byName := map[string]map[string]any{}
+	for _, run := range srv.runs {
+		byName[run["name"].(string)] = run
+	}
+	evaluator, call := byName["judge"], byName["model"]
+	if evaluator == nil || call == nil {
+		t.Fatalf("runs = %v", srv.runs)
+	}
+	if call["parent_run_id"] != evaluator["id"] || call["trace_id"] != evaluator["id"] || call["session_name"] != "evaluators" || call["run_type"] != "llm" {
+		t.Errorf("child run = %v, evaluator run = %v", call, evaluator)
+	}
+	if !strings.HasPrefix(call["dotted_order"].(string), evaluator["dotted_order"].(string)+".") {
+		t.Errorf("dotted order %v is not under %v", call["dotted_order"], evaluator["dotted_order"])
+	}
+	if len(srv.feedback) != 1 {
+		t.Fatalf("feedback = %v", srv.feedback)
+	}
+	source := srv.feedback[0]["feedback_source"].(map[string]any)["metadata"].(map[string]any)["__run"].(map[string]any)
+	if source["run_id"] != call["id"] {
+		t.Errorf("feedback source run = %v, want %v", source["run_id"], call["id"])
+	}
+
+	if ctx, run := langsmith.StartChildRun(context.Background(), "orphan", "llm", nil); run != nil || ctx != context.Background() || run.ID() != "" {
+		t.Error("want no child run outside a traced run")
+	}
+}
+
+func TestEvaluateSummary(t *testing.T) {
+	t.Setenv("LANGSMITH_DISABLE_RUN_COMPRESSION", "true")
+	srv := newEvalServer(t, nil)
+	client := srv.client()
+
+	examples := []langsmith.Example{
+		{ID: "00000000-0000-0000-0000-000000000a01", DatasetID: evalDatasetID, Inputs: map[string]any{"x": 1.0}, Outputs: map[string]any{"x": 1.0}},
+		{ID: "00000000-0000-0000-0000-000000000a02", DatasetID: evalDatasetID, Inputs: map[string]any{"x": 2.0}, Outputs: map[string]any{"x": 3.0}},
+	}
+	passRate := langsmith.NewSummaryEvaluator("pass_rate", func(ctx context.Context, runs []langsmith.RunView, examples []langsmith.Example) ([]langsmith.EvaluationResult, error) {
+		passed := 0
+		for i, run := range runs {
+			if run.ReferenceExampleID != examples[i].ID {
+				t.Errorf("run %d ran on %s, not %s", i, run.ReferenceExampleID, examples[i].ID)
+			}
+			if run.Outputs["x"] == examples[i].Outputs["x"] {
+				passed++
+			}
+		}
+		return []langsmith.EvaluationResult{{Key: "pass_rate", Score: float64(passed) / float64(len(runs))}}, nil
+	})
+	broken := langsmith.SummaryEvaluatorFunc(func(ctx context.Context, runs []langsmith.RunView, examples []langsmith.Example) ([]langsmith.EvaluationResult, error) {
+		return nil, errors.New("no metric")
+	})
+	results, err := langsmith.Evaluate(context.Background(), func(ctx context.Context, inputs map[string]any) (map[string]any, error) {
+		return inputs, nil
+	}, langsmith.EvaluateData{Examples: examples}, nil, langsmith.EvaluateOptions{
+		Client:            client,
+		MaxConcurrency:    2,
+		SummaryEvaluators: []langsmith.SummaryEvaluator{passRate, broken},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	summary, err := results.Summary()
+	if err == nil || !strings.Contains(err.Error(), "no metric") {
+		t.Errorf("summary error = %v", err)
+	}
+	if len(summary) != 1 || summary[0].Key != "pass_rate" || summary[0].Score != 0.5 || summary[0].SourceRunID == "" {
+		t.Errorf("summary = %+v", summary)
+	}
+	client.Close()
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if len(srv.feedback) != 1 {
+		t.Fatalf("feedback = %v", srv.feedback)
+	}
+	fb := srv.feedback[0]
+	if _, ok := fb["run_id"]; ok || fb["session_id"] != evalSessionID || fb["key"] != "pass_rate" || fb["score"] != 0.5 {
+		t.Errorf("summary feedback = %v", fb)
+	}
+	var traced bool
+	for _, run := range srv.runs {
+		if run["name"] == "pass_rate" && run["session_name"] == "evaluators" && run["id"] == summary[0].SourceRunID {
+			traced = true
+		}
+	}
+	if !traced {
+		t.Errorf("summary evaluator run not traced: %v", srv.runs)
+	}
+}
+
+func TestEvaluateTargetRunsNestUnderExperimentRun(t *testing.T) {
+	t.Setenv("LANGSMITH_DISABLE_RUN_COMPRESSION", "true")
+	srv := newEvalServer(t, nil)
+	client := srv.client()
+
+	target := func(ctx context.Context, inputs map[string]any) (map[string]any, error) {
+		_, llm := langsmith.StartChildRun(ctx, "model", "llm", inputs)
+		if llm == nil {
+			return nil, errors.New("target context belongs to no run")
+		}
+		llm.End(map[string]any{"answer": "4"}, nil)
+		return map[string]any{"answer": "4"}, nil
+	}
+	results, err := langsmith.Evaluate(context.Background(), target, langsmith.EvaluateData{Examples: []langsmith.Example{
+		{ID: "00000000-0000-0000-0000-000000000a01", DatasetID: evalDatasetID, Inputs: map[string]any{"question": "2+2"}},
+	}}, nil, langsmith.EvaluateOptions{Client: client})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := results.Wait()
+	if err != nil || len(rows) != 1 || rows[0].Err != nil {
+		t.Fatalf("rows = %+v, err = %v", rows, err)
+	}
+	client.Close()
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	root := rows[0].Run
+	var child map[string]any
+	for _, run := range srv.runs {
+		if run["name"] == "model" {
+			child = run
+		}
+	}
+	if child == nil || child["parent_run_id"] != root.ID || child["trace_id"] != root.ID || child["session_name"] != results.ExperimentName {
+		t.Fatalf("target's run = %v, want it under the experiment run %s", child, root.ID)
+	}
+	if !strings.HasPrefix(child["dotted_order"].(string), srv.runs[0]["dotted_order"].(string)+".") {
+		t.Errorf("dotted order %v does not extend the root's %v", child["dotted_order"], srv.runs[0]["dotted_order"])
+	}
+	if srv.runs[0]["id"] != root.ID || srv.runs[0]["end_time"] == nil {
+		t.Errorf("experiment run = %v", srv.runs[0])
+	}
+}
+
+func TestEvaluateTargetOTelSpansAreSeparateTraces(t *testing.T) {
+	t.Setenv("LANGSMITH_DISABLE_RUN_COMPRESSION", "true")
+	srv := newEvalServer(t, nil)
+	client := srv.client()
+	defer client.Close()
+
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	defer tp.Shutdown(context.Background())
+	target := func(ctx context.Context, inputs map[string]any) (map[string]any, error) {
+		_, span := tp.Tracer("test").Start(ctx, "model")
+		span.End()
+		return map[string]any{"answer": "4"}, nil
+	}
+	results, err := langsmith.Evaluate(context.Background(), target, langsmith.EvaluateData{Examples: []langsmith.Example{
+		{ID: "00000000-0000-0000-0000-000000000a01", DatasetID: evalDatasetID, Inputs: map[string]any{"question": "2+2"}},
+	}}, nil, langsmith.EvaluateOptions{Client: client})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := results.Wait(); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	if spans[0].Parent.IsValid() {
+		t.Errorf("span parent = %v, want a trace of its own", spans[0].Parent)
+	}
+}
+
+func TestEvaluateRepetitionsAndCache(t *testing.T) {
+	t.Setenv("LANGSMITH_DISABLE_RUN_COMPRESSION", "true")
+	srv := newEvalServer(t, nil)
+	client := srv.client()
+	defer client.Close()
+
+	examples := []langsmith.Example{
+		{ID: "00000000-0000-0000-0000-000000000a01", DatasetID: evalDatasetID, Inputs: map[string]any{"x": 1.0}},
+		{ID: "00000000-0000-0000-0000-000000000a02", DatasetID: evalDatasetID, Inputs: map[string]any{"x": 2.0}},
+	}
+	var mu sync.Mutex
+	calls := 0
+	target := func(ctx context.Context, inputs map[string]any) (map[string]any, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		if inputs["x"] == 2.0 {
+			return nil, errors.New("flaky")
+		}
+		return map[string]any{"y": calls}, nil
+	}
+	opts := langsmith.EvaluateOptions{
+		Client:         client,
+		NumRepetitions: 2,
+		Cache:          langsmith.FileCache{Dir: t.TempDir()},
+		TargetVersion:  "v1",
+	}
+	run := func() []langsmith.ExperimentResultRow {
+		t.Helper()
+		results, err := langsmith.Evaluate(context.Background(), target, langsmith.EvaluateData{Examples: examples}, nil, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows, err := results.Wait()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return rows
+	}
+
+	rows := run()
+	if len(rows) != 4 || calls != 4 {
+		t.Fatalf("got %d rows from %d calls, want 4 of each", len(rows), calls)
+	}
+	reps := map[string][]int{}
+	for _, row := range rows {
+		reps[row.Example.ID] = append(reps[row.Example.ID], row.Repetition)
+	}
+	for id, r := range reps {
+		sort.Ints(r)
+		if !reflect.DeepEqual(r, []int{0, 1}) {
+			t.Errorf("repetitions of %s = %v", id, r)
+		}
+	}
+	srv.mu.Lock()
+	if srv.session["num_repetitions"] != 2.0 {
+		t.Errorf("session = %v", srv.session)
+	}
+	srv.mu.Unlock()
+
+	// Outputs are cached per repetition; failures are not cached.
+	calls = 0
+	rows = run()
+	if calls != 2 {
+		t.Errorf("re-run called the target %d times, want 2 (the failures)", calls)
+	}
+	outputs := map[float64]bool{}
+	for _, row := range rows {
+		hit, _ := row.Run.Metadata["cache_hit"].(bool)
+		if failed := row.Example.Inputs["x"] == 2.0; hit == failed {
+			t.Errorf("row %s repetition %d: cache hit %v", row.Example.ID, row.Repetition, hit)
+		}
+		if hit {
+			outputs[row.Run.Outputs["y"].(float64)] = true
+		}
+	}
+	if len(outputs) != 2 {
+		t.Errorf("cached outputs = %v, want one per repetition", outputs)
+	}
+}
+
+func TestEvaluateResume(t *testing.T) {
+	t.Setenv("LANGSMITH_DISABLE_RUN_COMPRESSION", "true")
+	srv := newEvalServer(t, nil)
+	srv.existing = []map[string]any{
+		{"id": "00000000-0000-0000-0000-0000000001a1", "reference_example_id": "00000000-0000-0000-0000-000000000a01", "end_time": "2025-01-01T00:00:00Z"},
+		{"id": "00000000-0000-0000-0000-0000000001a2", "reference_example_id": "00000000-0000-0000-0000-000000000a02", "end_time": "2025-01-01T00:00:00Z"},
+		{"id": "00000000-0000-0000-0000-0000000001a3", "reference_example_id": "00000000-0000-0000-0000-000000000a02"},
+	}
+	client := srv.client()
+	defer client.Close()
+
+	examples := []langsmith.Example{
+		{ID: "00000000-0000-0000-0000-000000000a01", DatasetID: evalDatasetID, Inputs: map[string]any{"x": 1.0}},
+		{ID: "00000000-0000-0000-0000-000000000a02", DatasetID: evalDatasetID, Inputs: map[string]any{"x": 2.0}},
+		{ID: "00000000-0000-0000-0000-000000000a03", DatasetID: evalDatasetID, Inputs: map[string]any{"x": 3.0}},
+	}
+	var summarized []string
+	count := langsmith.NewSummaryEvaluator("runs", func(ctx context.Context, runs []langsmith.RunView, examples []langsmith.Example) ([]langsmith.EvaluationResult, error) {
+		for i, run := range runs {
+			summarized = append(summarized, examples[i].ID)
+			if run.ReferenceExampleID != examples[i].ID {
+				t.Errorf("run %s paired with example %s", run.ID, examples[i].ID)
+			}
+		}
+		return []langsmith.EvaluationResult{{Key: "runs", Score: len(runs)}}, nil
+	})
+	results, err := langsmith.Evaluate(context.Background(), func(ctx context.Context, inputs map[string]any) (map[string]any, error) {
+		return inputs, nil
+	}, langsmith.EvaluateData{Examples: examples}, nil, langsmith.EvaluateOptions{
+		Client:            client,
+		Experiment:        "old",
+		NumRepetitions:    2,
+		Metadata:          map[string]any{"resumed": true},
+		SummaryEvaluators: []langsmith.SummaryEvaluator{count},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := results.Wait()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results.ExperimentID != evalSessionID || results.ExperimentName != "old" {
+		t.Errorf("resumed %s (%s)", results.ExperimentName, results.ExperimentID)
+	}
+	got := map[string][]int{}
+	for _, row := range rows {
+		got[row.Example.ID] = append(got[row.Example.ID], row.Repetition)
+		if row.Run.Metadata["model"] != "old" || row.Run.Metadata["resumed"] != true {
+			t.Errorf("run metadata = %v", row.Run.Metadata)
+		}
+	}
+	for _, r := range got {
+		sort.Ints(r)
+	}
+	want := map[string][]int{
+		"00000000-0000-0000-0000-000000000a01": {1},
+		"00000000-0000-0000-0000-000000000a02": {1},
+		"00000000-0000-0000-0000-000000000a03": {0, 1},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("repetitions run = %v, want %v", got, want)
+	}
+	// The summary covers the two runs completed earlier and the four new.
+	sort.Strings(summarized)
+	if want := []string{
+		"00000000-0000-0000-0000-000000000a01", "00000000-0000-0000-0000-000000000a01",
+		"00000000-0000-0000-0000-000000000a02", "00000000-0000-0000-0000-000000000a02",
+		"00000000-0000-0000-0000-000000000a03", "00000000-0000-0000-0000-000000000a03",
+	}; !reflect.DeepEqual(summarized, want) {
+		t.Errorf("summarized runs on %v, want %v", summarized, want)
+	}
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if srv.session != nil {
+		t.Error("created an experiment while resuming")
+	}
+	if q := srv.queries["sessions"]; !strings.Contains(q, "name=old") {
+		t.Errorf("sessions query = %q", q)
+	}
+}
+
+func TestEvaluateResumeCachesByRepetition(t *testing.T) {
+	t.Setenv("LANGSMITH_DISABLE_RUN_COMPRESSION", "true")
+	const a01, a02 = "00000000-0000-0000-0000-000000000a01", "00000000-0000-0000-0000-000000000a02"
+	srv := newEvalServer(t, nil)
+	srv.existing = []map[string]any{
+		// a01's repetition 0 failed; its repetition 1 completed.
+		{"id": "00000000-0000-0000-0000-0000000001a1", "reference_example_id": a01, "end_time": "2025-01-01T00:00:00Z", "extra": map[string]any{"metadata": map[string]any{"repetition": 1}}},
+		// a02's run predates recording repetitions.
+		{"id": "00000000-0000-0000-0000-0000000001a2", "reference_example_id": a02, "end_time": "2025-01-01T00:00:00Z"},
+	}
+	client := srv.client()
+	defer client.Close()
+
+	cache := langsmith.FileCache{Dir: t.TempDir()}
+	for key, outputs := range map[string]map[string]any{
+		"v1/" + a01 + "/1": {"y": "a01 rep 1"},
+		"v1/" + a02 + "/0": {"y": "a02 rep 0"},
+		"v1/" + a02 + "/1": {"y": "a02 rep 1"},
+	} {
+		if err := cache.Put(key, outputs); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var calls []any
+	results, err := langsmith.Evaluate(context.Background(), func(ctx context.Context, inputs map[string]any) (map[string]any, error) {
+		calls = append(calls, inputs["x"])
+		return map[string]any{"y": "fresh"}, nil
+	}, langsmith.EvaluateData{Examples: []langsmith.Example{
+		{ID: a01, DatasetID: evalDatasetID, Inputs: map[string]any{"x": 1.0}},
+		{ID: a02, DatasetID: evalDatasetID, Inputs: map[string]any{"x": 2.0}},
+	}}, nil, langsmith.EvaluateOptions{
+		Client:         client,
+		Experiment:     "old",
+		NumRepetitions: 2,
+		Cache:          cache,
+		TargetVersion:  "v1",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := results.Wait()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]any{}
+	for _, row := range rows {
+		got[fmt.Sprintf("%s/%d", row.Example.ID, row.Repetition)] = row.Run.Outputs["y"]
+		if row.Run.Metadata["repetition"] != row.Repetition {
+			t.Errorf("run metadata = %v, want repetition %d", row.Run.Metadata, row.Repetition)
+		}
+	}
+	want := map[string]any{a01 + "/0": "fresh", a02 + "/1": "a02 rep 1"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("outputs by repetition = %v, want %v", got, want)
+	}
+	if !reflect.DeepEqual(calls, []any{1.0}) {
+		t.Errorf("target called on %v, want only a01's failed repetition", calls)
+	}
+}
+
+func TestEvaluateDatasetNotFound(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`[]`))
+	}))
+	defer srv.Close()
+	client := langsmith.NewClient(option.WithBaseURL(srv.URL), option.WithAPIKey("test-api-key"), option.WithMaxRetries(0))
+	defer client.Close()
+
+	_, err := langsmith.Evaluate(context.Background(), func(ctx context.Context, inputs map[string]any) (map[string]any, error) {
+		return nil, nil
+	}, langsmith.EvaluateData{Dataset: "missing"}, nil, langsmith.EvaluateOptions{Client: client})
+	if err == nil || !strings.Contains(err.Error(), `dataset "missing" not found`) {
+		t.Errorf("err = %v", err)
+	}
+}
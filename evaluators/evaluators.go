@@ -0,0 +1,126 @@
+// Package evaluators provides heuristic evaluators for experiments run with
+// langsmith.Evaluate. Each evaluator is a [langsmith.RunEvaluator] that
+// records one feedback key per run:
+//
+//	results, err := langsmith.Evaluate(ctx, target, langsmith.EvaluateData{Dataset: "qa"},
+//		[]langsmith.RunEvaluator{
+//			evaluators.NormalizedMatch{OutputKey: "answer", ReferenceKey: "answer"},
+//			evaluators.Latency{Max: 2 * time.Second},
+//			evaluators.JSONSchema{Schema: evaluators.SchemaFor[Answer]()},
+//		}, langsmith.EvaluateOptions{})
+//
+// Reference evaluators compare a value of the run's outputs with a value of
+// the example's reference outputs; reference-free evaluators check the run
+// alone. OutputKey and ReferenceKey select the values: the value under that
+// key, or, when the key is empty, the only value of single-key outputs and
+// otherwise the whole outputs map.
+//
+// [LLMJudge] grades runs with a chat model against a rubric, written inline
+// or pulled from the prompt hub with [PullPrompt]:
+//
+//	rubric, err := evaluators.PullPrompt(ctx, client, "my-team/correctness")
+//	judge := evaluators.LLMJudge{
+//		Key:    "correctness",
+//		Model:  evaluators.OpenAI(&openaiClient.Chat.Completions, "gpt-4o"),
+//		Prompt: rubric,
+//	}
+//
+// [PreferenceJudge] asks a model which of several experiments' runs is
+// better, for langsmith.EvaluateComparative.
+package evaluators
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+
+	"github.com/langchain-ai/langsmith-go"
+)
+
+// errNoReference is returned by reference evaluators run on an example
+// without reference outputs.
+var errNoReference = errors.New("example has no reference outputs")
+
+// selectValue returns the value of outputs that key selects.
+func selectValue(outputs map[string]any, key string) (any, bool) {
+	if key != "" {
+		v, ok := outputs[key]
+		return v, ok
+	}
+	if len(outputs) == 1 {
+		for _, v := range outputs {
+			return v, true
+		}
+	}
+	return outputs, outputs != nil
+}
+
+// values returns the run's output and the example's reference values.
+func values(run langsmith.RunView, example *langsmith.Example, outputKey, referenceKey string) (output, reference any, err error) {
+	output, ok := selectValue(run.Outputs, outputKey)
+	if !ok {
+		return nil, nil, fmt.Errorf("run has no output %q", outputKey)
+	}
+	if example == nil || example.Outputs == nil {
+		return nil, nil, errNoReference
+	}
+	reference, ok = selectValue(example.Outputs, referenceKey)
+	if !ok {
+		return nil, nil, fmt.Errorf("example has no reference output %q", referenceKey)
+	}
+	return output, reference, nil
+}
+
+// text returns v as a string: strings as is, other values as JSON.
+func text(v any) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+// normalize round-trips v through JSON so values that encode alike, such
+// as int 1 and float64 1, compare equal.
+func normalize(v any) any {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var out any
+	if json.Unmarshal(b, &out) != nil {
+		return v
+	}
+	return out
+}
+
+func equalJSON(a, b any) bool {
+	return reflect.DeepEqual(normalize(a), normalize(b))
+}
+
+// result returns a single evaluation result.
+func result(key string, score any, comment string) []langsmith.EvaluationResult {
+	return []langsmith.EvaluationResult{{Key: key, Score: score, Comment: comment}}
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// or returns s, or def if s is empty.
+func or(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
@@ -0,0 +1,152 @@
+package evaluators
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/langchain-ai/langsmith-go"
+)
+
+func run(outputs map[string]any) langsmith.RunView {
+	return langsmith.RunView{ID: "run-1", Outputs: outputs}
+}
+
+func example(outputs map[string]any) *langsmith.Example {
+	return &langsmith.Example{ID: "example-1", Outputs: outputs}
+}
+
+// score runs e and returns its single result's score.
+func score(t *testing.T, e langsmith.RunEvaluator, r langsmith.RunView, ex *langsmith.Example) any {
+	t.Helper()
+	res, err := e.Evaluate(context.Background(), r, ex)
+	if err != nil {
+		t.Fatalf("%T: %v", e, err)
+	}
+	if len(res) != 1 {
+		t.Fatalf("%T: got %d results, want 1", e, len(res))
+	}
+	if want := e.(interface{ Name() string }).Name(); res[0].Key != want {
+		t.Errorf("%T: key = %q, want %q", e, res[0].Key, want)
+	}
+	return res[0].Score
+}
+
+func TestReferenceEvaluators(t *testing.T) {
+	tests := []struct {
+		name      string
+		evaluator langsmith.RunEvaluator
+		output    map[string]any
+		reference map[string]any
+		want      any
+	}{
+		{"exact match", ExactMatch{}, map[string]any{"answer": "4"}, map[string]any{"answer": "4"}, true},
+		{"exact match numbers", ExactMatch{}, map[string]any{"n": 1}, map[string]any{"n": 1.0}, true},
+		{"exact match maps", ExactMatch{}, map[string]any{"a": 1, "b": 2}, map[string]any{"b": 2, "a": 1}, true},
+		{"exact mismatch", ExactMatch{OutputKey: "answer", ReferenceKey: "expected"}, map[string]any{"answer": "4", "reasoning": "2+2"}, map[string]any{"expected": "5"}, false},
+		{"normalized match", NormalizedMatch{}, map[string]any{"answer": "  The  Capital is PARIS "}, map[string]any{"answer": "the capital is paris"}, true},
+		{"normalized punctuation", NormalizedMatch{IgnorePunctuation: true}, map[string]any{"answer": "Paris."}, map[string]any{"answer": "paris"}, true},
+		{"normalized keeps punctuation", NormalizedMatch{}, map[string]any{"answer": "Paris."}, map[string]any{"answer": "paris"}, false},
+		{"contains all from reference", Contains{IgnoreCase: true}, map[string]any{"answer": "Paris and Lyon"}, map[string]any{"answer": []any{"paris", "lyon"}}, true},
+		{"contains all missing", Contains{Substrings: []string{"Paris", "Nice"}}, map[string]any{"answer": "Paris and Lyon"}, nil, false},
+		{"contains any", Contains{Substrings: []string{"Nice", "Lyon"}, Any: true}, map[string]any{"answer": "Paris and Lyon"}, nil, true},
+		{"numeric tolerance", NumericTolerance{Tolerance: 0.01}, map[string]any{"answer": "3.141"}, map[string]any{"answer": 3.14159}, true},
+		{"numeric relative", NumericTolerance{Tolerance: 0.1, Relative: true}, map[string]any{"answer": 120}, map[string]any{"answer": 100}, false},
+		{"numeric not a number", NumericTolerance{}, map[string]any{"answer": "many"}, map[string]any{"answer": 3}, false},
+		{"similarity identical", StringSimilarity{}, map[string]any{"answer": "kitten"}, map[string]any{"answer": "kitten"}, 1.0},
+		{"similarity", StringSimilarity{}, map[string]any{"answer": "kitten"}, map[string]any{"answer": "sitting"}, 1 - 3.0/7},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := score(t, tt.evaluator, run(tt.output), example(tt.reference)); got != tt.want {
+				t.Errorf("score = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReferenceEvaluatorNeedsReference(t *testing.T) {
+	if _, err := (ExactMatch{}).Evaluate(context.Background(), run(map[string]any{"a": 1}), nil); err == nil {
+		t.Error("want an error without an example")
+	}
+	if _, err := (ExactMatch{ReferenceKey: "b"}).Evaluate(context.Background(), run(map[string]any{"a": 1}), example(map[string]any{"a": 1})); err == nil {
+		t.Error("want an error for a missing reference key")
+	}
+}
+
+func TestRegex(t *testing.T) {
+	e := Regex{Pattern: regexp.MustCompile(`^\d{3}-\d{4}$`)}
+	if got := score(t, e, run(map[string]any{"phone": "555-1234"}), nil); got != true {
+		t.Errorf("score = %v, want true", got)
+	}
+	if got := score(t, e, run(map[string]any{"phone": "call me"}), nil); got != false {
+		t.Errorf("score = %v, want false", got)
+	}
+}
+
+func TestThresholds(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	r := langsmith.RunView{StartTime: start, EndTime: start.Add(1500 * time.Millisecond)}
+	if got := score(t, Latency{Max: time.Second}, r, nil); got != false {
+		t.Errorf("latency within 1s = %v, want false", got)
+	}
+	if got := score(t, Latency{}, r, nil); got != 1.5 {
+		t.Errorf("latency = %v, want 1.5", got)
+	}
+
+	r.Outputs = map[string]any{"usage_metadata": map[string]any{"input_tokens": 80, "output_tokens": 40}}
+	if got := score(t, TokenBudget{MaxTokens: 100}, r, nil); got != false {
+		t.Errorf("token budget = %v, want false", got)
+	}
+	r.TotalTokens = 90
+	if got := score(t, TokenBudget{MaxTokens: 100}, r, nil); got != true {
+		t.Errorf("token budget with TotalTokens = %v, want true", got)
+	}
+	if _, err := (TokenBudget{MaxTokens: 100}).Evaluate(context.Background(), run(nil), nil); err == nil {
+		t.Error("want an error for a run without usage")
+	}
+}
+
+func TestToolTrajectory(t *testing.T) {
+	outputs := map[string]any{"messages": []any{
+		map[string]any{"role": "user", "content": "Weather in Paris and Lyon?"},
+		map[string]any{"role": "assistant", "content": nil, "tool_calls": []any{
+			map[string]any{"id": "1", "type": "function", "function": map[string]any{"name": "geocode", "arguments": "{}"}},
+			map[string]any{"id": "2", "type": "function", "function": map[string]any{"name": "weather", "arguments": "{}"}},
+		}},
+		map[string]any{"role": "tool", "tool_call_id": "2", "name": "weather", "content": "sunny"},
+		map[string]any{"role": "assistant", "tool_calls": []any{
+			map[string]any{"id": "3", "type": "function", "function": map[string]any{"name": "weather", "arguments": "{}"}},
+		}},
+		map[string]any{"role": "assistant", "content": "Sunny in both."},
+	}}
+	tests := []struct {
+		mode     TrajectoryMode
+		expected []string
+		want     bool
+	}{
+		{"", []string{"geocode", "weather", "weather"}, true},
+		{TrajectoryStrict, []string{"weather", "geocode", "weather"}, false},
+		{TrajectoryUnordered, []string{"weather", "geocode", "weather"}, true},
+		{TrajectorySuperset, []string{"weather"}, true},
+		{TrajectorySubset, []string{"weather"}, false},
+		{TrajectorySubset, []string{"geocode", "weather", "weather", "search"}, true},
+	}
+	for _, tt := range tests {
+		e := ToolTrajectory{Mode: tt.mode, Expected: tt.expected}
+		if got := score(t, e, run(outputs), nil); got != tt.want {
+			t.Errorf("%s %v: score = %v, want %v", e.Name(), tt.expected, got, tt.want)
+		}
+	}
+
+	// Expected calls from the reference outputs, LangChain style.
+	reference := map[string]any{"trajectory": []any{
+		map[string]any{"name": "geocode", "args": map[string]any{}, "type": "tool_call"},
+		"weather",
+		"weather",
+	}}
+	if got := score(t, ToolTrajectory{}, run(outputs), example(reference)); got != true {
+		t.Errorf("reference trajectory score = %v, want true", got)
+	}
+}
@@ -0,0 +1,396 @@
+package evaluators
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/invopop/jsonschema"
+
+	"github.com/langchain-ai/langsmith-go"
+)
+
+// JSONSchema scores whether the output is valid JSON that conforms to a
+// schema. A string output is parsed as JSON; any other output is checked as
+// is. With no Schema, it only checks that a string output parses.
+//
+// The check covers the JSON Schema keywords that describe data shapes:
+// type, enum and const; the numeric, string length and pattern, array and
+// object constraints; properties, additionalProperties, patternProperties
+// and required; allOf, anyOf, oneOf, not and if/then/else; and local $refs.
+// Formats are not checked.
+type JSONSchema struct {
+	// Key is the feedback key. Defaults to "json_schema", or "json_valid"
+	// without a Schema.
+	Key       string
+	Schema    *jsonschema.Schema
+	OutputKey string
+}
+
+// SchemaFor returns the JSON schema of T, as encoding/json encodes it, for
+// use with [JSONSchema]. Fields are required unless tagged omitempty, and
+// objects do not allow additional properties.
+func SchemaFor[T any]() *jsonschema.Schema {
+	r := jsonschema.Reflector{DoNotReference: true}
+	return r.Reflect(new(T))
+}
+
+// Name returns the feedback key.
+func (e JSONSchema) Name() string {
+	if e.Schema == nil {
+		return or(e.Key, "json_valid")
+	}
+	return or(e.Key, "json_schema")
+}
+
+// Evaluate implements [langsmith.RunEvaluator].
+func (e JSONSchema) Evaluate(ctx context.Context, run langsmith.RunView, example *langsmith.Example) ([]langsmith.EvaluationResult, error) {
+	output, ok := selectValue(run.Outputs, e.OutputKey)
+	if !ok {
+		return nil, fmt.Errorf("run has no output %q", e.OutputKey)
+	}
+	var doc any
+	if s, ok := output.(string); ok {
+		if err := json.Unmarshal([]byte(s), &doc); err != nil {
+			return result(e.Name(), false, "invalid JSON: "+err.Error()), nil
+		}
+	} else {
+		doc = normalize(output)
+	}
+	if e.Schema == nil {
+		return result(e.Name(), true, ""), nil
+	}
+	if err := validate(e.Schema, e.Schema, doc, "$"); err != nil {
+		return result(e.Name(), false, err.Error()), nil
+	}
+	return result(e.Name(), true, ""), nil
+}
+
+// validate checks v, decoded from JSON, against s, resolving $refs in root.
+// It returns the first violation found.
+func validate(s, root *jsonschema.Schema, v any, path string) error {
+	if s == nil || s == jsonschema.TrueSchema {
+		return nil
+	}
+	if s == jsonschema.FalseSchema {
+		return fmt.Errorf("%s: not allowed", path)
+	}
+	if b, ok := booleanSchema(s); ok {
+		if !b {
+			return fmt.Errorf("%s: not allowed", path)
+		}
+		return nil
+	}
+	if s.Ref != "" {
+		ref, err := resolveRef(root, s.Ref)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if err := validate(ref, root, v, path); err != nil {
+			return err
+		}
+	}
+
+	if s.Type != "" && !hasType(v, s.Type) {
+		return fmt.Errorf("%s: got %s, want %s", path, typeOf(v), s.Type)
+	}
+	if len(s.Enum) > 0 {
+		found := false
+		for _, e := range s.Enum {
+			if reflect.DeepEqual(normalize(e), v) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s: %s is not one of %s", path, text(v), text(s.Enum))
+		}
+	}
+	if s.Const != nil && !reflect.DeepEqual(normalize(s.Const), v) {
+		return fmt.Errorf("%s: %s is not %s", path, text(v), text(s.Const))
+	}
+
+	var err error
+	switch v := v.(type) {
+	case float64:
+		err = validateNumber(s, v, path)
+	case string:
+		err = validateString(s, v, path)
+	case []any:
+		err = validateArray(s, root, v, path)
+	case map[string]any:
+		err = validateObject(s, root, v, path)
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, sub := range s.AllOf {
+		if err := validate(sub, root, v, path); err != nil {
+			return err
+		}
+	}
+	if len(s.AnyOf) > 0 {
+		var first error
+		for _, sub := range s.AnyOf {
+			if err := validate(sub, root, v, path); err == nil {
+				first = nil
+				break
+			} else if first == nil {
+				first = err
+			}
+		}
+		if first != nil {
+			return fmt.Errorf("%s: matches no anyOf schema (%v)", path, first)
+		}
+	}
+	if len(s.OneOf) > 0 {
+		matched := 0
+		for _, sub := range s.OneOf {
+			if validate(sub, root, v, path) == nil {
+				matched++
+			}
+		}
+		if matched != 1 {
+			return fmt.Errorf("%s: matches %d oneOf schemas, want 1", path, matched)
+		}
+	}
+	if s.Not != nil && validate(s.Not, root, v, path) == nil {
+		return fmt.Errorf("%s: matches a schema it must not", path)
+	}
+	if s.If != nil {
+		if validate(s.If, root, v, path) == nil {
+			return validate(s.Then, root, v, path)
+		}
+		return validate(s.Else, root, v, path)
+	}
+	return nil
+}
+
+func validateNumber(s *jsonschema.Schema, v float64, path string) error {
+	bound := func(n json.Number, ok func(limit float64) bool, desc string) error {
+		if n == "" {
+			return nil
+		}
+		limit, err := n.Float64()
+		if err != nil || ok(limit) {
+			return nil
+		}
+		return fmt.Errorf("%s: %g is not %s %s", path, v, desc, n)
+	}
+	if err := bound(s.Minimum, func(l float64) bool { return v >= l }, ">="); err != nil {
+		return err
+	}
+	if err := bound(s.Maximum, func(l float64) bool { return v <= l }, "<="); err != nil {
+		return err
+	}
+	if err := bound(s.ExclusiveMinimum, func(l float64) bool { return v > l }, ">"); err != nil {
+		return err
+	}
+	if err := bound(s.ExclusiveMaximum, func(l float64) bool { return v < l }, "<"); err != nil {
+		return err
+	}
+	if s.MultipleOf != "" {
+		if m, err := s.MultipleOf.Float64(); err == nil && m != 0 {
+			if q := v / m; math.Abs(q-math.Round(q)) > 1e-9 {
+				return fmt.Errorf("%s: %g is not a multiple of %s", path, v, s.MultipleOf)
+			}
+		}
+	}
+	return nil
+}
+
+func validateString(s *jsonschema.Schema, v, path string) error {
+	n := uint64(utf8.RuneCountInString(v))
+	if s.MinLength != nil && n < *s.MinLength {
+		return fmt.Errorf("%s: shorter than %d characters", path, *s.MinLength)
+	}
+	if s.MaxLength != nil && n > *s.MaxLength {
+		return fmt.Errorf("%s: longer than %d characters", path, *s.MaxLength)
+	}
+	if s.Pattern != "" {
+		re, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return fmt.Errorf("%s: invalid pattern %q: %w", path, s.Pattern, err)
+		}
+		if !re.MatchString(v) {
+			return fmt.Errorf("%s: %q does not match %q", path, v, s.Pattern)
+		}
+	}
+	return nil
+}
+
+func validateArray(s *jsonschema.Schema, root *jsonschema.Schema, v []any, path string) error {
+	n := uint64(len(v))
+	if s.MinItems != nil && n < *s.MinItems {
+		return fmt.Errorf("%s: fewer than %d items", path, *s.MinItems)
+	}
+	if s.MaxItems != nil && n > *s.MaxItems {
+		return fmt.Errorf("%s: more than %d items", path, *s.MaxItems)
+	}
+	for i, item := range v {
+		sub := s.Items
+		if i < len(s.PrefixItems) {
+			sub = s.PrefixItems[i]
+		}
+		if err := validate(sub, root, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+			return err
+		}
+	}
+	if s.UniqueItems {
+		for i := range v {
+			for j := i + 1; j < len(v); j++ {
+				if reflect.DeepEqual(v[i], v[j]) {
+					return fmt.Errorf("%s: items %d and %d are equal", path, i, j)
+				}
+			}
+		}
+	}
+	if s.Contains != nil {
+		matched := uint64(0)
+		for _, item := range v {
+			if validate(s.Contains, root, item, path) == nil {
+				matched++
+			}
+		}
+		minContains := uint64(1)
+		if s.MinContains != nil {
+			minContains = *s.MinContains
+		}
+		if matched < minContains || (s.MaxContains != nil && matched > *s.MaxContains) {
+			return fmt.Errorf("%s: %d items match contains", path, matched)
+		}
+	}
+	return nil
+}
+
+func validateObject(s *jsonschema.Schema, root *jsonschema.Schema, v map[string]any, path string) error {
+	n := uint64(len(v))
+	if s.MinProperties != nil && n < *s.MinProperties {
+		return fmt.Errorf("%s: fewer than %d properties", path, *s.MinProperties)
+	}
+	if s.MaxProperties != nil && n > *s.MaxProperties {
+		return fmt.Errorf("%s: more than %d properties", path, *s.MaxProperties)
+	}
+	for _, name := range s.Required {
+		if _, ok := v[name]; !ok {
+			return fmt.Errorf("%s: missing required property %q", path, name)
+		}
+	}
+	for name, deps := range s.DependentRequired {
+		if _, ok := v[name]; !ok {
+			continue
+		}
+		for _, dep := range deps {
+			if _, ok := v[dep]; !ok {
+				return fmt.Errorf("%s: property %q requires %q", path, name, dep)
+			}
+		}
+	}
+	for _, name := range sortedKeys(v) {
+		child := path + "." + name
+		matched := false
+		if s.Properties != nil {
+			if sub, ok := s.Properties.Get(name); ok {
+				matched = true
+				if err := validate(sub, root, v[name], child); err != nil {
+					return err
+				}
+			}
+		}
+		for pattern, sub := range s.PatternProperties {
+			if re, err := regexp.Compile(pattern); err == nil && re.MatchString(name) {
+				matched = true
+				if err := validate(sub, root, v[name], child); err != nil {
+					return err
+				}
+			}
+		}
+		if !matched && s.AdditionalProperties != nil {
+			if b, ok := booleanSchema(s.AdditionalProperties); ok && !b {
+				return fmt.Errorf("%s: unexpected property %q", path, name)
+			}
+			if err := validate(s.AdditionalProperties, root, v[name], child); err != nil {
+				return err
+			}
+		}
+		if s.PropertyNames != nil {
+			if err := validate(s.PropertyNames, root, name, child); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// booleanSchema reports whether s is the schema true or false, and which.
+func booleanSchema(s *jsonschema.Schema) (value, ok bool) {
+	switch s {
+	case jsonschema.TrueSchema:
+		return true, true
+	case jsonschema.FalseSchema:
+		return false, true
+	}
+	// Schemas decoded from JSON are not the package's singletons.
+	if s.Type != "" || s.Ref != "" || s.Properties != nil {
+		return false, false
+	}
+	b, err := json.Marshal(s)
+	if err != nil {
+		return false, false
+	}
+	switch string(b) {
+	case "true":
+		return true, true
+	case "false":
+		return false, true
+	}
+	return false, false
+}
+
+// resolveRef resolves a local reference such as "#/$defs/Answer".
+func resolveRef(root *jsonschema.Schema, ref string) (*jsonschema.Schema, error) {
+	for _, prefix := range []string{"#/$defs/", "#/definitions/"} {
+		if name, ok := strings.CutPrefix(ref, prefix); ok {
+			if s, ok := root.Definitions[name]; ok {
+				return s, nil
+			}
+		}
+	}
+	if ref == "#" {
+		return root, nil
+	}
+	return nil, fmt.Errorf("cannot resolve $ref %q", ref)
+}
+
+func hasType(v any, t string) bool {
+	if t == "integer" {
+		f, ok := v.(float64)
+		return ok && f == math.Trunc(f)
+	}
+	return typeOf(v) == t
+}
+
+// typeOf returns the JSON type of v, decoded from JSON.
+func typeOf(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
@@ -0,0 +1,85 @@
+package evaluators
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/invopop/jsonschema"
+)
+
+type answer struct {
+	Answer     string   `json:"answer" jsonschema:"minLength=1"`
+	Confidence float64  `json:"confidence" jsonschema:"minimum=0,maximum=1"`
+	Sources    []string `json:"sources,omitempty"`
+	Verdict    string   `json:"verdict" jsonschema:"enum=correct,enum=incorrect"`
+}
+
+func TestJSONSchema(t *testing.T) {
+	e := JSONSchema{Schema: SchemaFor[answer]()}
+	tests := []struct {
+		name    string
+		output  any
+		want    bool
+		comment string
+	}{
+		{"valid string", `{"answer":"4","confidence":0.9,"verdict":"correct"}`, true, ""},
+		{"valid map", map[string]any{"answer": "4", "confidence": 1, "verdict": "incorrect", "sources": []string{"a"}}, true, ""},
+		{"invalid JSON", `{"answer":`, false, "invalid JSON"},
+		{"missing required", `{"answer":"4","verdict":"correct"}`, false, `missing required property "confidence"`},
+		{"out of range", `{"answer":"4","confidence":2,"verdict":"correct"}`, false, "$.confidence: 2 is not <= 1"},
+		{"wrong type", `{"answer":4,"confidence":0.5,"verdict":"correct"}`, false, "$.answer: got number, want string"},
+		{"not in enum", `{"answer":"4","confidence":0.5,"verdict":"maybe"}`, false, "$.verdict"},
+		{"additional property", `{"answer":"4","confidence":0.5,"verdict":"correct","extra":1}`, false, `unexpected property "extra"`},
+		{"item type", `{"answer":"4","confidence":0.5,"verdict":"correct","sources":[1]}`, false, "$.sources[0]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := e.Evaluate(context.Background(), run(map[string]any{"output": tt.output}), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res[0].Key != "json_schema" || res[0].Score != tt.want || !strings.Contains(res[0].Comment, tt.comment) {
+				t.Errorf("result = %+v, want score %v and comment containing %q", res[0], tt.want, tt.comment)
+			}
+		})
+	}
+}
+
+func TestJSONSchemaDecoded(t *testing.T) {
+	var s jsonschema.Schema
+	err := json.Unmarshal([]byte(`{
+		"$defs": {"id": {"type": "integer"}},
+		"type": "object",
+		"properties": {
+			"ids": {"type": "array", "items": {"$ref": "#/$defs/id"}, "uniqueItems": true},
+			"kind": {"oneOf": [{"const": "a"}, {"const": "b"}]}
+		},
+		"additionalProperties": false
+	}`), &s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := JSONSchema{Schema: &s}
+	for output, want := range map[string]bool{
+		`{"ids":[1,2],"kind":"a"}`: true,
+		`{"ids":[1,1]}`:            false,
+		`{"ids":[1.5]}`:            false,
+		`{"kind":"c"}`:             false,
+		`{"other":true}`:           false,
+	} {
+		if got := score(t, e, run(map[string]any{"output": output}), nil); got != want {
+			t.Errorf("%s: score = %v, want %v", output, got, want)
+		}
+	}
+}
+
+func TestJSONValid(t *testing.T) {
+	if got := score(t, JSONSchema{}, run(map[string]any{"output": `[1, 2]`}), nil); got != true {
+		t.Errorf("score = %v, want true", got)
+	}
+	if got := score(t, JSONSchema{}, run(map[string]any{"output": `[1, 2`}), nil); got != false {
+		t.Errorf("score = %v, want false", got)
+	}
+}
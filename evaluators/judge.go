@@ -0,0 +1,519 @@
+package evaluators
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/invopop/jsonschema"
+
+	"github.com/langchain-ai/langsmith-go"
+)
+
+// Message is a chat message of a prompt or a model request.
+type Message struct {
+	// Role is "system", "user" or "assistant".
+	Role    string
+	Content string
+}
+
+// Prompt is the rubric an [LLMJudge] asks its model to apply: chat messages
+// whose contents are templates. The variables are {inputs}, {outputs} and
+// {reference_outputs}, the run's inputs and outputs and the example's
+// reference outputs as JSON, and each input and output key on its own;
+// a dotted name such as {inputs.question} selects a nested value.
+type Prompt struct {
+	Messages []Message
+	// TemplateFormat is "f-string" (the default), where {name} is a
+	// variable and {{ and }} are literal braces, or "mustache", where
+	// {{name}} is a variable.
+	TemplateFormat string
+	// Schema is the output schema a structured prompt declares, if any.
+	Schema *jsonschema.Schema
+}
+
+// PullPrompt loads a prompt from a commit of a LangSmith prompt repository.
+// ref is "owner/repo:commit", where the owner defaults to the current
+// workspace and the commit to the latest one. Chat prompts, structured
+// prompts, string prompts and prompts saved with a model are supported.
+func PullPrompt(ctx context.Context, client *langsmith.Client, ref string) (Prompt, error) {
+	owner, repo := "-", ref
+	if o, r, ok := strings.Cut(ref, "/"); ok {
+		owner, repo = o, r
+	}
+	commit := "latest"
+	if r, c, ok := strings.Cut(repo, ":"); ok {
+		repo, commit = r, c
+	}
+	res, err := client.Commits.Get(ctx, owner, repo, commit, langsmith.CommitGetParams{})
+	if err != nil {
+		return Prompt{}, err
+	}
+	manifest, _ := normalize(res.Manifest).(map[string]any)
+	p, err := parsePrompt(manifest)
+	if err != nil {
+		return Prompt{}, fmt.Errorf("prompt %s: %w", ref, err)
+	}
+	return p, nil
+}
+
+// parsePrompt converts a serialized LangChain prompt into a Prompt.
+func parsePrompt(manifest map[string]any) (Prompt, error) {
+	kwargs, _ := manifest["kwargs"].(map[string]any)
+	switch class := lcClass(manifest); class {
+	case "RunnableSequence":
+		first, _ := kwargs["first"].(map[string]any)
+		return parsePrompt(first)
+	case "ChatPromptTemplate", "StructuredPrompt":
+		var p Prompt
+		p.TemplateFormat, _ = kwargs["template_format"].(string)
+		var literal []bool
+		messages, _ := kwargs["messages"].([]any)
+		for _, m := range messages {
+			m, _ := m.(map[string]any)
+			msg, format, isLiteral, err := parseMessage(m)
+			if err != nil {
+				return Prompt{}, err
+			}
+			if format != "" {
+				p.TemplateFormat = format
+			}
+			p.Messages = append(p.Messages, msg)
+			literal = append(literal, isLiteral)
+		}
+		// Escape literal messages so they render as is.
+		for i, isLiteral := range literal {
+			if isLiteral && p.TemplateFormat != "mustache" {
+				p.Messages[i].Content = strings.NewReplacer("{", "{{", "}", "}}").Replace(p.Messages[i].Content)
+			}
+		}
+		if schema, ok := kwargs["schema_"].(map[string]any); ok {
+			var s jsonschema.Schema
+			b, _ := json.Marshal(schema)
+			if err := json.Unmarshal(b, &s); err != nil {
+				return Prompt{}, fmt.Errorf("schema: %w", err)
+			}
+			p.Schema = &s
+		}
+		return p, nil
+	case "PromptTemplate":
+		template, format := promptTemplate(manifest)
+		return Prompt{Messages: []Message{{Role: "user", Content: template}}, TemplateFormat: format}, nil
+	case "":
+		return Prompt{}, errors.New("not a serialized prompt")
+	default:
+		return Prompt{}, fmt.Errorf("unsupported prompt type %s", class)
+	}
+}
+
+// parseMessage converts a serialized message template, or a literal
+// message.
+func parseMessage(m map[string]any) (msg Message, format string, literal bool, err error) {
+	kwargs, _ := m["kwargs"].(map[string]any)
+	class := lcClass(m)
+	switch class {
+	case "SystemMessagePromptTemplate", "SystemMessage":
+		msg.Role = "system"
+	case "HumanMessagePromptTemplate", "HumanMessage":
+		msg.Role = "user"
+	case "AIMessagePromptTemplate", "AIMessage":
+		msg.Role = "assistant"
+	case "ChatMessagePromptTemplate", "ChatMessage":
+		msg.Role = text(kwargs["role"])
+	default:
+		return Message{}, "", false, fmt.Errorf("unsupported message type %q", class)
+	}
+	if content, ok := kwargs["content"].(string); ok {
+		msg.Content = content
+		return msg, "", true, nil
+	}
+	var parts []string
+	switch prompt := kwargs["prompt"].(type) {
+	case map[string]any:
+		var t string
+		t, format = promptTemplate(prompt)
+		parts = append(parts, t)
+	case []any:
+		// A multimodal template: keep its text parts.
+		for _, p := range prompt {
+			p, _ := p.(map[string]any)
+			if lcClass(p) == "PromptTemplate" {
+				var t string
+				t, format = promptTemplate(p)
+				parts = append(parts, t)
+			}
+		}
+	}
+	msg.Content = strings.Join(parts, "\n")
+	return msg, format, false, nil
+}
+
+// promptTemplate returns a serialized PromptTemplate's template and format.
+func promptTemplate(m map[string]any) (template, format string) {
+	kwargs, _ := m["kwargs"].(map[string]any)
+	template, _ = kwargs["template"].(string)
+	format, _ = kwargs["template_format"].(string)
+	return template, format
+}
+
+// lcClass returns the class name of a serialized LangChain object.
+func lcClass(m map[string]any) string {
+	id, _ := m["id"].([]any)
+	if len(id) == 0 {
+		return ""
+	}
+	return text(id[len(id)-1])
+}
+
+// render fills the template's variables from vars.
+func render(template, format string, vars map[string]any) (string, error) {
+	var b strings.Builder
+	switch format {
+	case "", "f-string":
+		for i := 0; i < len(template); i++ {
+			c := template[i]
+			switch {
+			case c == '{' && strings.HasPrefix(template[i:], "{{"):
+				b.WriteByte('{')
+				i++
+			case c == '}' && strings.HasPrefix(template[i:], "}}"):
+				b.WriteByte('}')
+				i++
+			case c == '{':
+				end := strings.IndexByte(template[i:], '}')
+				if end < 0 {
+					return "", fmt.Errorf("unclosed variable at offset %d", i)
+				}
+				v, err := lookup(vars, template[i+1:i+end])
+				if err != nil {
+					return "", err
+				}
+				b.WriteString(v)
+				i += end
+			default:
+				b.WriteByte(c)
+			}
+		}
+	case "mustache":
+		for {
+			start := strings.Index(template, "{{")
+			if start < 0 {
+				b.WriteString(template)
+				break
+			}
+			end := strings.Index(template[start:], "}}")
+			if end < 0 {
+				return "", fmt.Errorf("unclosed variable at offset %d", start)
+			}
+			b.WriteString(template[:start])
+			name := strings.Trim(template[start+2:start+end], "{} ")
+			v, err := lookup(vars, name)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(v)
+			template = template[start+end+2:]
+			template = strings.TrimPrefix(template, "}")
+		}
+	default:
+		return "", fmt.Errorf("unsupported template format %q", format)
+	}
+	return b.String(), nil
+}
+
+// lookup returns the text of the variable name, which may be dotted.
+func lookup(vars map[string]any, name string) (string, error) {
+	name = strings.TrimSpace(name)
+	var v any = vars
+	for _, part := range strings.Split(name, ".") {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return "", fmt.Errorf("unknown variable %q", name)
+		}
+		if v, ok = m[part]; !ok {
+			return "", fmt.Errorf("unknown variable %q", name)
+		}
+	}
+	return text(v), nil
+}
+
+// ModelRequest is a request an [LLMJudge] makes of its [Model].
+type ModelRequest struct {
+	Messages []Message
+	// Schema is the JSON schema of the reply. Models that support
+	// structured output should constrain their reply to it.
+	Schema *jsonschema.Schema
+}
+
+// Model is a chat model an [LLMJudge] asks for judgements. It returns the
+// text of the model's reply. [OpenAI], [Anthropic] and [Gemini] adapt the
+// providers' SDK clients.
+type Model interface {
+	Generate(ctx context.Context, req ModelRequest) (string, error)
+}
+
+// ModelFunc adapts a function to a [Model].
+type ModelFunc func(ctx context.Context, req ModelRequest) (string, error)
+
+// Generate calls f.
+func (f ModelFunc) Generate(ctx context.Context, req ModelRequest) (string, error) {
+	return f(ctx, req)
+}
+
+// LLMJudge asks a model to grade the run against a rubric, and scores the
+// run with the model's judgement. The model replies with a JSON object
+// whose "score" is the score and whose "reasoning", if any, becomes the
+// feedback comment.
+//
+// By default the score is pass/fail. With Choices, the model picks one of
+// them; with Continuous, it picks a number from ScoreMin to ScoreMax, which
+// is scaled to 0 to 1.
+//
+// When run by langsmith.Evaluate, each model call is traced as an "llm"
+// child of the evaluator's run and the feedback's source run is the call
+// that produced the judgement.
+type LLMJudge struct {
+	// Key is the feedback key. Defaults to "llm_judge".
+	Key    string
+	Model  Model
+	Prompt Prompt
+	// Schema is the schema of the model's reply. Defaults to the prompt's
+	// schema, or an object with "reasoning" and a "score" that fits the
+	// scoring options.
+	Schema     *jsonschema.Schema
+	Choices    []float64
+	Continuous bool
+	// ScoreMin and ScoreMax are the range of continuous scores. Both zero
+	// means 0 to 1.
+	ScoreMin, ScoreMax float64
+	// MaxAttempts is how many times the model is asked when it fails or
+	// its reply cannot be used. Defaults to 3. SDK clients retry transient
+	// HTTP errors themselves, so this mainly covers replies that do not
+	// parse or do not fit the schema.
+	MaxAttempts int
+}
+
+// Name returns the feedback key.
+func (e LLMJudge) Name() string { return or(e.Key, "llm_judge") }
+
+// Evaluate implements [langsmith.RunEvaluator].
+func (e LLMJudge) Evaluate(ctx context.Context, run langsmith.RunView, example *langsmith.Example) ([]langsmith.EvaluationResult, error) {
+	if e.Model == nil {
+		return nil, errors.New("LLMJudge has no model")
+	}
+	if len(e.Prompt.Messages) == 0 {
+		return nil, errors.New("LLMJudge has no prompt")
+	}
+	messages, err := e.messages(run, example)
+	if err != nil {
+		return nil, err
+	}
+	var res langsmith.EvaluationResult
+	sourceRunID, err := ask(ctx, e.Model, messages, e.schema(), e.MaxAttempts, func(doc map[string]any) error {
+		var err error
+		res, err = e.judgement(doc)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	res.SourceRunID = sourceRunID
+	return []langsmith.EvaluationResult{res}, nil
+}
+
+// ask asks model for a reply that fits schema and that use accepts, trying
+// up to attempts times (3 if zero). After a reply that cannot be used, the
+// model is told why and asked again. Each call is traced as an "llm" child
+// of the run ctx belongs to; ask returns the ID of the call whose reply was
+// used.
+func ask(ctx context.Context, model Model, messages []Message, schema *jsonschema.Schema, attempts int, use func(doc map[string]any) error) (string, error) {
+	if attempts <= 0 {
+		attempts = 3
+	}
+	var errs []error
+	for range attempts {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		callCtx, call := langsmith.StartChildRun(ctx, modelName(model), "llm", map[string]any{"messages": messages})
+		reply, err := model.Generate(callCtx, ModelRequest{Messages: messages, Schema: schema})
+		call.End(map[string]any{"output": reply}, err)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		doc, err := parseReply(reply)
+		if err == nil {
+			err = validate(schema, schema, doc, "$")
+		}
+		if err == nil {
+			err = use(doc)
+		}
+		if err != nil {
+			errs = append(errs, err)
+			messages = append(slices.Clip(messages),
+				Message{Role: "assistant", Content: reply},
+				Message{Role: "user", Content: fmt.Sprintf("That reply cannot be used: %v. Reply with only a JSON object that matches this schema: %s", err, text(schema))},
+			)
+			continue
+		}
+		return call.ID(), nil
+	}
+	return "", fmt.Errorf("no judgement after %d attempts: %w", attempts, errors.Join(errs...))
+}
+
+// messages renders the prompt for run and example.
+func (e LLMJudge) messages(run langsmith.RunView, example *langsmith.Example) ([]Message, error) {
+	vars := map[string]any{}
+	for k, v := range run.Inputs {
+		vars[k] = v
+	}
+	for k, v := range run.Outputs {
+		vars[k] = v
+	}
+	vars["inputs"] = normalize(run.Inputs)
+	vars["outputs"] = normalize(run.Outputs)
+	if example != nil && example.Outputs != nil {
+		vars["reference_outputs"] = normalize(example.Outputs)
+	}
+	return e.Prompt.render(vars)
+}
+
+// render fills the prompt's variables from vars.
+func (p Prompt) render(vars map[string]any) ([]Message, error) {
+	messages := make([]Message, len(p.Messages))
+	for i, m := range p.Messages {
+		content, err := render(m.Content, p.TemplateFormat, vars)
+		if err != nil {
+			return nil, fmt.Errorf("prompt message %d: %w", i, err)
+		}
+		messages[i] = Message{Role: m.Role, Content: content}
+	}
+	return messages, nil
+}
+
+// schema returns the schema of the model's reply.
+func (e LLMJudge) schema() *jsonschema.Schema {
+	if e.Schema != nil {
+		return e.Schema
+	}
+	if e.Prompt.Schema != nil {
+		return e.Prompt.Schema
+	}
+	score := map[string]any{"type": "boolean", "description": "Whether the output passes the rubric."}
+	switch {
+	case len(e.Choices) > 0:
+		score = map[string]any{"type": "number", "enum": e.Choices, "description": "The score, one of the allowed choices."}
+	case e.Continuous:
+		lo, hi := e.scoreRange()
+		score = map[string]any{"type": "number", "minimum": lo, "maximum": hi, "description": fmt.Sprintf("The score, from %g to %g.", lo, hi)}
+	}
+	b, _ := json.Marshal(map[string]any{
+		"type":  "object",
+		"title": "judgement",
+		"properties": map[string]any{
+			"reasoning": map[string]any{"type": "string", "description": "The reasoning behind the score."},
+			"score":     score,
+		},
+		"required":             []string{"reasoning", "score"},
+		"additionalProperties": false,
+	})
+	var s jsonschema.Schema
+	_ = json.Unmarshal(b, &s)
+	return &s
+}
+
+func (e LLMJudge) scoreRange() (lo, hi float64) {
+	if e.ScoreMin == 0 && e.ScoreMax == 0 {
+		return 0, 1
+	}
+	return e.ScoreMin, e.ScoreMax
+}
+
+// judgement converts the model's reply into a result.
+func (e LLMJudge) judgement(doc map[string]any) (langsmith.EvaluationResult, error) {
+	raw, ok := doc["score"]
+	if !ok {
+		return langsmith.EvaluationResult{}, errors.New(`reply has no "score"`)
+	}
+	score, err := e.score(raw)
+	if err != nil {
+		return langsmith.EvaluationResult{}, err
+	}
+	res := langsmith.EvaluationResult{Key: e.Name(), Score: score}
+	if reasoning, ok := doc["reasoning"]; ok {
+		res.Comment = text(reasoning)
+	}
+	if e.Continuous {
+		res.FeedbackConfig = &langsmith.FeedbackCreateSchemaFeedbackConfigParam{
+			Type: langsmith.F(langsmith.FeedbackCreateSchemaFeedbackConfigTypeContinuous),
+			Min:  langsmith.F(0.0),
+			Max:  langsmith.F(1.0),
+		}
+	}
+	return res, nil
+}
+
+// score normalizes the model's score.
+func (e LLMJudge) score(v any) (any, error) {
+	if s, ok := v.(string); ok {
+		switch strings.ToLower(strings.TrimSpace(s)) {
+		case "true", "yes", "pass", "correct":
+			v = true
+		case "false", "no", "fail", "incorrect":
+			v = false
+		}
+	}
+	if b, ok := v.(bool); ok {
+		if e.Continuous || len(e.Choices) > 0 {
+			return nil, fmt.Errorf("score %v is not a number", b)
+		}
+		return b, nil
+	}
+	n, ok := number(v)
+	if !ok {
+		return nil, fmt.Errorf("score %s is not a number or boolean", text(v))
+	}
+	switch {
+	case len(e.Choices) > 0:
+		if !slices.Contains(e.Choices, n) {
+			return nil, fmt.Errorf("score %g is not one of %v", n, e.Choices)
+		}
+		return n, nil
+	case e.Continuous:
+		lo, hi := e.scoreRange()
+		if hi == lo {
+			return nil, fmt.Errorf("empty score range %g to %g", lo, hi)
+		}
+		return math.Min(math.Max((n-lo)/(hi-lo), 0), 1), nil
+	case n == 0 || n == 1:
+		return n == 1, nil
+	}
+	return nil, fmt.Errorf("score %g is not pass/fail", n)
+}
+
+// parseReply extracts the JSON object from the model's reply, which may be
+// wrapped in prose or a code fence.
+func parseReply(reply string) (map[string]any, error) {
+	start, end := strings.IndexByte(reply, '{'), strings.LastIndexByte(reply, '}')
+	if start < 0 || end < start {
+		return nil, errors.New("reply has no JSON object")
+	}
+	var doc map[string]any
+	if err := json.Unmarshal([]byte(reply[start:end+1]), &doc); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	return doc, nil
+}
+
+// modelName returns the name a model's calls are traced under.
+func modelName(m Model) string {
+	if n, ok := m.(interface{ Name() string }); ok && n.Name() != "" {
+		return n.Name()
+	}
+	return "judge"
+}
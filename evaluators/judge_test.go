@@ -0,0 +1,291 @@
+package evaluators
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	anthropicoption "github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/openai/openai-go/v3"
+	openaioption "github.com/openai/openai-go/v3/option"
+	"google.golang.org/genai"
+
+	"github.com/langchain-ai/langsmith-go"
+	"github.com/langchain-ai/langsmith-go/option"
+)
+
+// replies returns a model that replies with each reply in turn, recording
+// the requests it gets.
+func replies(requests *[]ModelRequest, replies ...string) Model {
+	return ModelFunc(func(ctx context.Context, req ModelRequest) (string, error) {
+		*requests = append(*requests, req)
+		reply := replies[0]
+		if len(replies) > 1 {
+			replies = replies[1:]
+		}
+		if reply == "error" {
+			return "", errors.New("model unavailable")
+		}
+		return reply, nil
+	})
+}
+
+var rubric = Prompt{Messages: []Message{
+	{Role: "system", Content: "Grade the answer. Reply in JSON like {{\"score\": true}}."},
+	{Role: "user", Content: "Question: {question}\nAnswer: {outputs.answer}\nReference: {reference_outputs}"},
+}}
+
+func TestLLMJudge(t *testing.T) {
+	var requests []ModelRequest
+	e := LLMJudge{Key: "correctness", Model: replies(&requests, "```json\n{\"reasoning\": \"4 is right\", \"score\": true}\n```"), Prompt: rubric}
+	r := langsmith.RunView{ID: "run-1", Inputs: map[string]any{"question": "2+2"}, Outputs: map[string]any{"answer": "4"}}
+	res, err := e.Evaluate(context.Background(), r, example(map[string]any{"answer": "4"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res) != 1 || res[0].Key != "correctness" || res[0].Score != true || res[0].Comment != "4 is right" {
+		t.Errorf("results = %+v", res)
+	}
+	if res[0].SourceRunID != "" {
+		t.Errorf("untraced judgement has source run %q", res[0].SourceRunID)
+	}
+
+	req := requests[0]
+	if got := req.Messages[0].Content; got != `Grade the answer. Reply in JSON like {"score": true}.` {
+		t.Errorf("system message = %q", got)
+	}
+	if got, want := req.Messages[1].Content, "Question: 2+2\nAnswer: 4\nReference: {\"answer\":\"4\"}"; got != want {
+		t.Errorf("user message = %q, want %q", got, want)
+	}
+	score, ok := req.Schema.Properties.Get("score")
+	if !ok || score.Type != "boolean" {
+		t.Errorf("schema = %+v", req.Schema)
+	}
+}
+
+func TestLLMJudgeRetries(t *testing.T) {
+	var requests []ModelRequest
+	e := LLMJudge{Model: replies(&requests, "error", "I think it passes.", `{"reasoning": "fine", "score": true}`), Prompt: rubric}
+	r := langsmith.RunView{Inputs: map[string]any{"question": "2+2"}, Outputs: map[string]any{"answer": "4"}}
+	if got := score(t, e, r, example(map[string]any{"answer": "4"})); got != true {
+		t.Errorf("score = %v, want true", got)
+	}
+	if len(requests) != 3 {
+		t.Fatalf("got %d requests, want 3", len(requests))
+	}
+	retry := requests[2].Messages
+	if len(retry) != 4 || retry[2].Content != "I think it passes." || !strings.Contains(retry[3].Content, "no JSON object") {
+		t.Errorf("retry messages = %+v", retry)
+	}
+
+	requests = nil
+	e.Model = replies(&requests, `{"score": 3}`)
+	_, err := e.Evaluate(context.Background(), r, example(map[string]any{"answer": "4"}))
+	if err == nil || len(requests) != 3 || !strings.Contains(err.Error(), "after 3 attempts") {
+		t.Errorf("err = %v after %d requests", err, len(requests))
+	}
+}
+
+func TestLLMJudgeScores(t *testing.T) {
+	r := langsmith.RunView{Inputs: map[string]any{"question": "2+2"}, Outputs: map[string]any{"answer": "4"}}
+	ex := example(map[string]any{"answer": "4"})
+	var requests []ModelRequest
+
+	continuous := LLMJudge{Model: replies(&requests, `{"reasoning": "good", "score": 7}`), Prompt: rubric, Continuous: true, ScoreMin: 1, ScoreMax: 10}
+	res, err := continuous.Evaluate(context.Background(), r, ex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res[0].Score != 6.0/9 || res[0].FeedbackConfig == nil {
+		t.Errorf("continuous result = %+v", res[0])
+	}
+	s, _ := requests[0].Schema.Properties.Get("score")
+	if s.Minimum != "1" || s.Maximum != "10" {
+		t.Errorf("continuous score schema = %+v", s)
+	}
+
+	choices := LLMJudge{Model: replies(&requests, `{"reasoning": "meh", "score": 0.7}`, `{"reasoning": "meh", "score": 0.5}`), Prompt: rubric, Choices: []float64{0, 0.5, 1}}
+	if got := score(t, choices, r, ex); got != 0.5 {
+		t.Errorf("choice score = %v, want 0.5", got)
+	}
+
+	if _, err := (LLMJudge{Model: replies(&requests, `{}`), Prompt: Prompt{Messages: []Message{{Role: "user", Content: "{missing}"}}}}).Evaluate(context.Background(), r, ex); err == nil || !strings.Contains(err.Error(), `unknown variable "missing"`) {
+		t.Errorf("err = %v, want an unknown variable error", err)
+	}
+}
+
+func TestRender(t *testing.T) {
+	vars := map[string]any{"q": "why?", "inputs": map[string]any{"n": 2.0}}
+	tests := []struct {
+		template, format, want string
+	}{
+		{"{q} {inputs.n} {{literal}}", "", "why? 2 {literal}"},
+		{"{{q}} {{ inputs.n }} {{{q}}}", "mustache", "why? 2 why?"},
+		{"{inputs}", "f-string", `{"n":2}`},
+	}
+	for _, tt := range tests {
+		got, err := render(tt.template, tt.format, vars)
+		if err != nil || got != tt.want {
+			t.Errorf("render(%q, %q) = %q, %v, want %q", tt.template, tt.format, got, err, tt.want)
+		}
+	}
+	if _, err := render("{q", "", vars); err == nil {
+		t.Error("want an error for an unclosed variable")
+	}
+}
+
+func TestPullPrompt(t *testing.T) {
+	manifest := `{
+		"lc": 1, "type": "constructor", "id": ["langchain", "prompts", "chat", "ChatPromptTemplate"],
+		"kwargs": {
+			"input_variables": ["question", "outputs"],
+			"messages": [
+				{"lc": 1, "type": "constructor", "id": ["langchain", "schema", "messages", "SystemMessage"],
+				 "kwargs": {"content": "Reply as {\"score\": bool}."}},
+				{"lc": 1, "type": "constructor", "id": ["langchain", "prompts", "chat", "HumanMessagePromptTemplate"],
+				 "kwargs": {"prompt": {"lc": 1, "type": "constructor", "id": ["langchain", "prompts", "prompt", "PromptTemplate"],
+				   "kwargs": {"template": "Q: {question} A: {outputs}", "template_format": "f-string"}}}}
+			]
+		}
+	}`
+	var path string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"commit_hash": "abc123", "manifest": ` + manifest + `}`))
+	}))
+	defer srv.Close()
+	client := langsmith.NewClient(option.WithBaseURL(srv.URL), option.WithAPIKey("test-api-key"), option.WithMaxRetries(0))
+
+	p, err := PullPrompt(context.Background(), client, "judges/correctness:abc123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path != "/api/v1/commits/judges/correctness/abc123" {
+		t.Errorf("path = %s", path)
+	}
+	if len(p.Messages) != 2 || p.Messages[0].Role != "system" || p.Messages[1].Role != "user" || p.TemplateFormat != "f-string" {
+		t.Fatalf("prompt = %+v", p)
+	}
+	e := LLMJudge{Prompt: p}
+	msgs, err := e.messages(langsmith.RunView{Inputs: map[string]any{"question": "2+2"}, Outputs: map[string]any{"answer": "4"}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msgs[0].Content != `Reply as {"score": bool}.` || msgs[1].Content != `Q: 2+2 A: {"answer":"4"}` {
+		t.Errorf("messages = %+v", msgs)
+	}
+
+	if _, err := PullPrompt(context.Background(), client, "correctness"); err != nil || path != "/api/v1/commits/-/correctness/latest" {
+		t.Errorf("default ref: path = %s, err = %v", path, err)
+	}
+}
+
+type fakeOpenAI struct {
+	params openai.ChatCompletionNewParams
+}
+
+func (f *fakeOpenAI) New(ctx context.Context, body openai.ChatCompletionNewParams, opts ...openaioption.RequestOption) (*openai.ChatCompletion, error) {
+	f.params = body
+	var resp openai.ChatCompletion
+	err := json.Unmarshal([]byte(`{"choices": [{"message": {"role": "assistant", "content": "{\"score\": true}"}}]}`), &resp)
+	return &resp, err
+}
+
+type fakeAnthropic struct{ params anthropic.MessageNewParams }
+
+func (f *fakeAnthropic) New(ctx context.Context, body anthropic.MessageNewParams, opts ...anthropicoption.RequestOption) (*anthropic.Message, error) {
+	f.params = body
+	var resp anthropic.Message
+	err := json.Unmarshal([]byte(`{"content": [{"type": "tool_use", "id": "t1", "name": "judgement", "input": {"score": true}}]}`), &resp)
+	return &resp, err
+}
+
+type fakeGemini struct {
+	model    string
+	contents []*genai.Content
+	config   *genai.GenerateContentConfig
+}
+
+func (f *fakeGemini) GenerateContent(ctx context.Context, model string, contents []*genai.Content, config *genai.GenerateContentConfig) (*genai.GenerateContentResponse, error) {
+	f.model, f.contents, f.config = model, contents, config
+	return &genai.GenerateContentResponse{Candidates: []*genai.Candidate{{
+		Content: genai.NewContentFromText(`{"score": true}`, genai.RoleModel),
+	}}}, nil
+}
+
+func TestModels(t *testing.T) {
+	req := ModelRequest{
+		Messages: []Message{{Role: "system", Content: "grade"}, {Role: "user", Content: "answer"}},
+		Schema:   LLMJudge{}.schema(),
+	}
+
+	oa := &fakeOpenAI{}
+	if reply, err := OpenAI(oa, "gpt-4o").Generate(context.Background(), req); err != nil || reply != `{"score": true}` {
+		t.Errorf("openai reply = %q, %v", reply, err)
+	}
+	if oa.params.Model != "gpt-4o" || len(oa.params.Messages) != 2 || oa.params.ResponseFormat.OfJSONSchema == nil {
+		t.Errorf("openai params = %+v", oa.params)
+	}
+
+	an := &fakeAnthropic{}
+	if reply, err := Anthropic(an, "claude-sonnet-4-5").Generate(context.Background(), req); err != nil || reply != `{"score": true}` {
+		t.Errorf("anthropic reply = %q, %v", reply, err)
+	}
+	if len(an.params.System) != 1 || len(an.params.Messages) != 1 || len(an.params.Tools) != 1 || an.params.ToolChoice.OfTool == nil {
+		t.Errorf("anthropic params = %+v", an.params)
+	}
+	if b, _ := json.Marshal(an.params.Tools[0]); !strings.Contains(string(b), `"additionalProperties":false`) {
+		t.Errorf("anthropic tool = %s", b)
+	}
+
+	ge := &fakeGemini{}
+	if reply, err := Gemini(ge, "gemini-2.5-flash").Generate(context.Background(), req); err != nil || reply != `{"score": true}` {
+		t.Errorf("gemini reply = %q, %v", reply, err)
+	}
+	if ge.model != "gemini-2.5-flash" || len(ge.contents) != 1 || ge.config.SystemInstruction == nil || ge.config.ResponseMIMEType != "application/json" {
+		t.Errorf("gemini request = %+v", ge)
+	}
+}
+
+func TestPreferenceJudge(t *testing.T) {
+	runs := []langsmith.RunView{
+		{ID: "run-a", Inputs: map[string]any{"q": "hi"}, Outputs: map[string]any{"answer": "hey"}},
+		{ID: "run-b", Inputs: map[string]any{"q": "hi"}, Outputs: map[string]any{"answer": "hello there"}},
+	}
+	ex := &langsmith.Example{ID: "example-1", Inputs: map[string]any{"q": "hi"}}
+	var requests []ModelRequest
+	e := PreferenceJudge{Model: replies(&requests, `{"reasoning": "B is friendlier", "preferred": "C"}`, `{"reasoning": "B is friendlier", "preferred": "B"}`)}
+	res, err := e.EvaluatePairwise(context.Background(), runs, ex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Key != "preference" || res.Comment != "B is friendlier" || res.Scores["run-a"] != 0.0 || res.Scores["run-b"] != 1.0 {
+		t.Errorf("result = %+v", res)
+	}
+	if len(requests) != 2 {
+		t.Errorf("got %d requests, want a retry after the invalid choice", len(requests))
+	}
+	if got := requests[0].Messages[1].Content; got != "[User input]\n{\"q\":\"hi\"}\n\n[Response A]\n{\"answer\":\"hey\"}\n\n[Response B]\n{\"answer\":\"hello there\"}" {
+		t.Errorf("user message = %q", got)
+	}
+
+	requests = nil
+	tie := PreferenceJudge{Key: "better", AllowTie: true, Model: replies(&requests, `{"reasoning": "same", "preferred": "tie"}`),
+		Prompt: Prompt{Messages: []Message{{Role: "user", Content: "{q}: {outputs_a} or {outputs_b}?"}}}}
+	res, err = tie.EvaluatePairwise(context.Background(), runs, ex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Key != "better" || res.Scores["run-a"] != 0.5 || res.Scores["run-b"] != 0.5 {
+		t.Errorf("tie result = %+v", res)
+	}
+	if got := requests[0].Messages[0].Content; got != `hi: {"answer":"hey"} or {"answer":"hello there"}?` {
+		t.Errorf("message = %q", got)
+	}
+}
@@ -0,0 +1,286 @@
+package evaluators
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/langchain-ai/langsmith-go"
+)
+
+// ExactMatch scores whether the output equals the reference output. Values
+// are compared as JSON, so maps and lists compare by content.
+type ExactMatch struct {
+	// Key is the feedback key. Defaults to "exact_match".
+	Key          string
+	OutputKey    string
+	ReferenceKey string
+}
+
+// Name returns the feedback key.
+func (e ExactMatch) Name() string { return or(e.Key, "exact_match") }
+
+// Evaluate implements [langsmith.RunEvaluator].
+func (e ExactMatch) Evaluate(ctx context.Context, run langsmith.RunView, example *langsmith.Example) ([]langsmith.EvaluationResult, error) {
+	output, reference, err := values(run, example, e.OutputKey, e.ReferenceKey)
+	if err != nil {
+		return nil, err
+	}
+	return result(e.Name(), equalJSON(output, reference), ""), nil
+}
+
+// NormalizedMatch scores whether the output text equals the reference text
+// ignoring case and differences in whitespace.
+type NormalizedMatch struct {
+	// Key is the feedback key. Defaults to "normalized_match".
+	Key          string
+	OutputKey    string
+	ReferenceKey string
+	// IgnorePunctuation also ignores punctuation, so "Paris." matches
+	// "paris".
+	IgnorePunctuation bool
+}
+
+// Name returns the feedback key.
+func (e NormalizedMatch) Name() string { return or(e.Key, "normalized_match") }
+
+// Evaluate implements [langsmith.RunEvaluator].
+func (e NormalizedMatch) Evaluate(ctx context.Context, run langsmith.RunView, example *langsmith.Example) ([]langsmith.EvaluationResult, error) {
+	output, reference, err := values(run, example, e.OutputKey, e.ReferenceKey)
+	if err != nil {
+		return nil, err
+	}
+	return result(e.Name(), e.normalize(text(output)) == e.normalize(text(reference)), ""), nil
+}
+
+func (e NormalizedMatch) normalize(s string) string {
+	if e.IgnorePunctuation {
+		s = strings.Map(func(r rune) rune {
+			if unicode.IsPunct(r) {
+				return -1
+			}
+			return r
+		}, s)
+	}
+	return strings.Join(strings.Fields(strings.ToLower(s)), " ")
+}
+
+// Regex scores whether the output text matches a regular expression.
+type Regex struct {
+	// Key is the feedback key. Defaults to "regex_match".
+	Key       string
+	Pattern   *regexp.Regexp
+	OutputKey string
+}
+
+// Name returns the feedback key.
+func (e Regex) Name() string { return or(e.Key, "regex_match") }
+
+// Evaluate implements [langsmith.RunEvaluator].
+func (e Regex) Evaluate(ctx context.Context, run langsmith.RunView, example *langsmith.Example) ([]langsmith.EvaluationResult, error) {
+	if e.Pattern == nil {
+		return nil, errors.New("missing pattern")
+	}
+	output, ok := selectValue(run.Outputs, e.OutputKey)
+	if !ok {
+		return nil, fmt.Errorf("run has no output %q", e.OutputKey)
+	}
+	return result(e.Name(), e.Pattern.MatchString(text(output)), ""), nil
+}
+
+// Contains scores whether the output text contains all (or, with Any, at
+// least one) of a set of substrings.
+type Contains struct {
+	// Key is the feedback key. Defaults to "contains_all", or
+	// "contains_any" with Any.
+	Key string
+	// Substrings are the strings to look for. When empty, they are the
+	// reference output: a string or a list of strings.
+	Substrings   []string
+	Any          bool
+	IgnoreCase   bool
+	OutputKey    string
+	ReferenceKey string
+}
+
+// Name returns the feedback key.
+func (e Contains) Name() string {
+	if e.Any {
+		return or(e.Key, "contains_any")
+	}
+	return or(e.Key, "contains_all")
+}
+
+// Evaluate implements [langsmith.RunEvaluator].
+func (e Contains) Evaluate(ctx context.Context, run langsmith.RunView, example *langsmith.Example) ([]langsmith.EvaluationResult, error) {
+	output, ok := selectValue(run.Outputs, e.OutputKey)
+	if !ok {
+		return nil, fmt.Errorf("run has no output %q", e.OutputKey)
+	}
+	subs := e.Substrings
+	if len(subs) == 0 {
+		_, reference, err := values(run, example, e.OutputKey, e.ReferenceKey)
+		if err != nil {
+			return nil, err
+		}
+		switch r := reference.(type) {
+		case string:
+			subs = []string{r}
+		case []any:
+			for _, s := range r {
+				subs = append(subs, text(s))
+			}
+		case []string:
+			subs = r
+		default:
+			return nil, fmt.Errorf("reference output %v is not a string or list of strings", reference)
+		}
+	}
+
+	haystack := text(output)
+	if e.IgnoreCase {
+		haystack = strings.ToLower(haystack)
+	}
+	var found, missing []string
+	for _, s := range subs {
+		needle := s
+		if e.IgnoreCase {
+			needle = strings.ToLower(s)
+		}
+		if strings.Contains(haystack, needle) {
+			found = append(found, s)
+		} else {
+			missing = append(missing, s)
+		}
+	}
+	if e.Any {
+		if len(found) == 0 {
+			return result(e.Name(), false, "found none of "+strings.Join(subs, ", ")), nil
+		}
+		return result(e.Name(), true, "found "+strings.Join(found, ", ")), nil
+	}
+	if len(missing) > 0 {
+		return result(e.Name(), false, "missing "+strings.Join(missing, ", ")), nil
+	}
+	return result(e.Name(), true, ""), nil
+}
+
+// NumericTolerance scores whether the output number is within Tolerance of
+// the reference number. Numbers may also be given as numeric strings.
+type NumericTolerance struct {
+	// Key is the feedback key. Defaults to "numeric_match".
+	Key string
+	// Tolerance is the largest accepted difference. Zero requires equal
+	// numbers.
+	Tolerance float64
+	// Relative makes Tolerance a fraction of the reference number.
+	Relative     bool
+	OutputKey    string
+	ReferenceKey string
+}
+
+// Name returns the feedback key.
+func (e NumericTolerance) Name() string { return or(e.Key, "numeric_match") }
+
+// Evaluate implements [langsmith.RunEvaluator].
+func (e NumericTolerance) Evaluate(ctx context.Context, run langsmith.RunView, example *langsmith.Example) ([]langsmith.EvaluationResult, error) {
+	output, reference, err := values(run, example, e.OutputKey, e.ReferenceKey)
+	if err != nil {
+		return nil, err
+	}
+	want, ok := number(reference)
+	if !ok {
+		return nil, fmt.Errorf("reference output %v is not a number", reference)
+	}
+	got, ok := number(output)
+	if !ok {
+		return result(e.Name(), false, fmt.Sprintf("output %v is not a number", text(output))), nil
+	}
+	tolerance := e.Tolerance
+	if e.Relative {
+		tolerance *= math.Abs(want)
+	}
+	diff := math.Abs(got - want)
+	return result(e.Name(), diff <= tolerance, fmt.Sprintf("|%g - %g| = %g", got, want, diff)), nil
+}
+
+// number returns v as a float64 if it is a number or a numeric string.
+func number(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(n), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// StringSimilarity scores how similar the output text is to the reference
+// text, from 0 to 1, as one minus their Levenshtein (edit) distance over the
+// length of the longer text.
+type StringSimilarity struct {
+	// Key is the feedback key. Defaults to "string_similarity".
+	Key          string
+	OutputKey    string
+	ReferenceKey string
+	IgnoreCase   bool
+}
+
+// Name returns the feedback key.
+func (e StringSimilarity) Name() string { return or(e.Key, "string_similarity") }
+
+// Evaluate implements [langsmith.RunEvaluator].
+func (e StringSimilarity) Evaluate(ctx context.Context, run langsmith.RunView, example *langsmith.Example) ([]langsmith.EvaluationResult, error) {
+	output, reference, err := values(run, example, e.OutputKey, e.ReferenceKey)
+	if err != nil {
+		return nil, err
+	}
+	a, b := []rune(text(output)), []rune(text(reference))
+	if e.IgnoreCase {
+		a, b = []rune(strings.ToLower(string(a))), []rune(strings.ToLower(string(b)))
+	}
+	longest := max(len(a), len(b))
+	if longest == 0 {
+		return result(e.Name(), 1.0, ""), nil
+	}
+	return result(e.Name(), 1-float64(levenshtein(a, b))/float64(longest), ""), nil
+}
+
+// levenshtein returns the edit distance between a and b.
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,173 @@
+package evaluators
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	anthropicoption "github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/openai/openai-go/v3"
+	openaioption "github.com/openai/openai-go/v3/option"
+	"google.golang.org/genai"
+)
+
+// OpenAIChat is the Chat Completions API of an OpenAI client, such as
+// &client.Chat.Completions.
+type OpenAIChat interface {
+	New(ctx context.Context, body openai.ChatCompletionNewParams, opts ...openaioption.RequestOption) (*openai.ChatCompletion, error)
+}
+
+// AnthropicMessages is the Messages API of an Anthropic client, such as
+// &client.Messages.
+type AnthropicMessages interface {
+	New(ctx context.Context, body anthropic.MessageNewParams, opts ...anthropicoption.RequestOption) (*anthropic.Message, error)
+}
+
+// GeminiModels is the models API of a Gemini client, such as client.Models.
+type GeminiModels interface {
+	GenerateContent(ctx context.Context, model string, contents []*genai.Content, config *genai.GenerateContentConfig) (*genai.GenerateContentResponse, error)
+}
+
+// OpenAI returns a [Model] that calls model through chat, constraining its
+// reply with a JSON schema response format.
+func OpenAI(chat OpenAIChat, model string) Model {
+	return openaiModel{chat, model}
+}
+
+// Anthropic returns a [Model] that calls model through messages, making it
+// reply by calling a tool whose input schema is the reply schema. Replies
+// are limited to 1024 tokens.
+func Anthropic(messages AnthropicMessages, model string) Model {
+	return anthropicModel{messages, model}
+}
+
+// Gemini returns a [Model] that calls model through models, constraining its
+// reply with a JSON response schema.
+func Gemini(models GeminiModels, model string) Model {
+	return geminiModel{models, model}
+}
+
+// replyTool names the structured reply for APIs that ask for a name.
+const replyTool = "judgement"
+
+type openaiModel struct {
+	chat  OpenAIChat
+	model string
+}
+
+func (m openaiModel) Name() string { return m.model }
+
+func (m openaiModel) Generate(ctx context.Context, req ModelRequest) (string, error) {
+	params := openai.ChatCompletionNewParams{Model: m.model}
+	for _, msg := range req.Messages {
+		switch msg.Role {
+		case "system":
+			params.Messages = append(params.Messages, openai.SystemMessage(msg.Content))
+		case "assistant":
+			params.Messages = append(params.Messages, openai.AssistantMessage(msg.Content))
+		default:
+			params.Messages = append(params.Messages, openai.UserMessage(msg.Content))
+		}
+	}
+	if req.Schema != nil {
+		params.ResponseFormat = openai.ChatCompletionNewParamsResponseFormatUnion{
+			OfJSONSchema: &openai.ResponseFormatJSONSchemaParam{
+				JSONSchema: openai.ResponseFormatJSONSchemaJSONSchemaParam{Name: replyTool, Schema: req.Schema},
+			},
+		}
+	}
+	resp, err := m.chat.New(ctx, params)
+	if err != nil {
+		return "", err
+	}
+	if len(resp.Choices) == 0 {
+		return "", errors.New("openai: no choices")
+	}
+	msg := resp.Choices[0].Message
+	if msg.Refusal != "" {
+		return "", errors.New("openai: refused: " + msg.Refusal)
+	}
+	return msg.Content, nil
+}
+
+type anthropicModel struct {
+	messages AnthropicMessages
+	model    string
+}
+
+func (m anthropicModel) Name() string { return m.model }
+
+func (m anthropicModel) Generate(ctx context.Context, req ModelRequest) (string, error) {
+	params := anthropic.MessageNewParams{Model: anthropic.Model(m.model), MaxTokens: 1024}
+	for _, msg := range req.Messages {
+		switch msg.Role {
+		case "system":
+			params.System = append(params.System, anthropic.TextBlockParam{Text: msg.Content})
+		case "assistant":
+			params.Messages = append(params.Messages, anthropic.NewAssistantMessage(anthropic.NewTextBlock(msg.Content)))
+		default:
+			params.Messages = append(params.Messages, anthropic.NewUserMessage(anthropic.NewTextBlock(msg.Content)))
+		}
+	}
+	if req.Schema != nil {
+		input := anthropic.ToolInputSchemaParam{Properties: req.Schema.Properties, Required: req.Schema.Required}
+		if req.Schema.AdditionalProperties != nil {
+			input.ExtraFields = map[string]any{"additionalProperties": req.Schema.AdditionalProperties}
+		}
+		params.Tools = []anthropic.ToolUnionParam{{OfTool: &anthropic.ToolParam{
+			Name:        replyTool,
+			Description: anthropic.String("Record your judgement."),
+			InputSchema: input,
+		}}}
+		params.ToolChoice = anthropic.ToolChoiceParamOfTool(replyTool)
+	}
+	resp, err := m.messages.New(ctx, params)
+	if err != nil {
+		return "", err
+	}
+	var text strings.Builder
+	for _, block := range resp.Content {
+		switch block.Type {
+		case "tool_use":
+			return string(block.Input), nil
+		case "text":
+			text.WriteString(block.Text)
+		}
+	}
+	return text.String(), nil
+}
+
+type geminiModel struct {
+	models GeminiModels
+	model  string
+}
+
+func (m geminiModel) Name() string { return m.model }
+
+func (m geminiModel) Generate(ctx context.Context, req ModelRequest) (string, error) {
+	config := &genai.GenerateContentConfig{}
+	var contents []*genai.Content
+	for _, msg := range req.Messages {
+		switch msg.Role {
+		case "system":
+			if config.SystemInstruction == nil {
+				config.SystemInstruction = &genai.Content{}
+			}
+			config.SystemInstruction.Parts = append(config.SystemInstruction.Parts, genai.NewPartFromText(msg.Content))
+		case "assistant":
+			contents = append(contents, genai.NewContentFromText(msg.Content, genai.RoleModel))
+		default:
+			contents = append(contents, genai.NewContentFromText(msg.Content, genai.RoleUser))
+		}
+	}
+	if req.Schema != nil {
+		config.ResponseMIMEType = "application/json"
+		config.ResponseJsonSchema = req.Schema
+	}
+	resp, err := m.models.GenerateContent(ctx, m.model, contents, config)
+	if err != nil {
+		return "", err
+	}
+	return resp.Text(), nil
+}
@@ -0,0 +1,136 @@
+package evaluators
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/invopop/jsonschema"
+
+	"github.com/langchain-ai/langsmith-go"
+)
+
+// DefaultPreferencePrompt is the rubric a [PreferenceJudge] uses when it
+// has no Prompt.
+var DefaultPreferencePrompt = Prompt{Messages: []Message{
+	{Role: "system", Content: "Act as an impartial judge and compare the responses AI assistants gave to the user input below. " +
+		"Choose the response that follows the user's instructions and answers the user's question better, " +
+		"considering helpfulness, relevance, accuracy and level of detail. " +
+		"Do not let the order of the responses or their length influence your judgement."},
+	{Role: "user", Content: "[User input]\n{inputs}\n\n{responses}"},
+}}
+
+// PreferenceJudge asks a model which of the runs of an example has the
+// better output, for use with langsmith.EvaluateComparative. The preferred
+// run scores 1 and the others 0; a tie, allowed by AllowTie, scores every
+// run 0.5. The model's reasoning becomes the feedback comment.
+//
+// The prompt's variables are those of [Prompt], except that the runs'
+// outputs are {outputs_a}, {outputs_b} and so on, in the order the judge
+// gets the runs, and {responses} lists them all under [Response A],
+// [Response B] headings. The model replies with a JSON object whose
+// "preferred" is the letter of the better response, or "tie".
+type PreferenceJudge struct {
+	// Key is the feedback key. Defaults to "preference".
+	Key   string
+	Model Model
+	// Prompt is the rubric. Defaults to [DefaultPreferencePrompt].
+	Prompt   Prompt
+	AllowTie bool
+	// MaxAttempts is how many times the model is asked when it fails or
+	// its reply cannot be used. Defaults to 3.
+	MaxAttempts int
+}
+
+// Name returns the feedback key.
+func (e PreferenceJudge) Name() string { return or(e.Key, "preference") }
+
+// EvaluatePairwise implements [langsmith.PairwiseEvaluator].
+func (e PreferenceJudge) EvaluatePairwise(ctx context.Context, runs []langsmith.RunView, example *langsmith.Example) (langsmith.ComparisonResult, error) {
+	if e.Model == nil {
+		return langsmith.ComparisonResult{}, errors.New("PreferenceJudge has no model")
+	}
+	if len(runs) < 2 || len(runs) > 26 {
+		return langsmith.ComparisonResult{}, fmt.Errorf("cannot compare %d runs", len(runs))
+	}
+	prompt := e.Prompt
+	if len(prompt.Messages) == 0 {
+		prompt = DefaultPreferencePrompt
+	}
+
+	vars := map[string]any{}
+	var inputs map[string]any
+	if example != nil {
+		inputs = example.Inputs
+		if example.Outputs != nil {
+			vars["reference_outputs"] = normalize(example.Outputs)
+		}
+	}
+	if inputs == nil {
+		inputs = runs[0].Inputs
+	}
+	for k, v := range inputs {
+		vars[k] = v
+	}
+	vars["inputs"] = normalize(inputs)
+	letters := make([]string, len(runs))
+	var responses []string
+	for i, run := range runs {
+		letters[i] = string(rune('A' + i))
+		vars["outputs_"+strings.ToLower(letters[i])] = normalize(run.Outputs)
+		responses = append(responses, fmt.Sprintf("[Response %s]\n%s", letters[i], text(normalize(run.Outputs))))
+	}
+	vars["responses"] = strings.Join(responses, "\n\n")
+	messages, err := prompt.render(vars)
+	if err != nil {
+		return langsmith.ComparisonResult{}, err
+	}
+
+	choices := letters
+	if e.AllowTie {
+		choices = append(choices, "tie")
+	}
+	var res langsmith.ComparisonResult
+	sourceRunID, err := ask(ctx, e.Model, messages, preferenceSchema(choices), e.MaxAttempts, func(doc map[string]any) error {
+		preferred := strings.TrimSpace(text(doc["preferred"]))
+		res = langsmith.ComparisonResult{Key: e.Name(), Scores: map[string]any{}}
+		if reasoning, ok := doc["reasoning"]; ok {
+			res.Comment = text(reasoning)
+		}
+		for i, run := range runs {
+			switch preferred {
+			case "tie":
+				res.Scores[run.ID] = 0.5
+			case letters[i]:
+				res.Scores[run.ID] = 1.0
+			default:
+				res.Scores[run.ID] = 0.0
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return langsmith.ComparisonResult{}, err
+	}
+	res.SourceRunID = sourceRunID
+	return res, nil
+}
+
+// preferenceSchema returns the schema of a preference judge's reply.
+func preferenceSchema(choices []string) *jsonschema.Schema {
+	b, _ := json.Marshal(map[string]any{
+		"type":  "object",
+		"title": "preference",
+		"properties": map[string]any{
+			"reasoning": map[string]any{"type": "string", "description": "The reasoning behind the preference."},
+			"preferred": map[string]any{"type": "string", "enum": choices, "description": "The letter of the better response."},
+		},
+		"required":             []string{"reasoning", "preferred"},
+		"additionalProperties": false,
+	})
+	var s jsonschema.Schema
+	_ = json.Unmarshal(b, &s)
+	return &s
+}
@@ -0,0 +1,95 @@
+package evaluators
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/langchain-ai/langsmith-go"
+)
+
+// Latency scores whether the run finished within Max. With no Max, it
+// scores the run's latency in seconds.
+type Latency struct {
+	// Key is the feedback key. Defaults to "latency".
+	Key string
+	Max time.Duration
+}
+
+// Name returns the feedback key.
+func (e Latency) Name() string { return or(e.Key, "latency") }
+
+// Evaluate implements [langsmith.RunEvaluator].
+func (e Latency) Evaluate(ctx context.Context, run langsmith.RunView, example *langsmith.Example) ([]langsmith.EvaluationResult, error) {
+	if run.StartTime.IsZero() || run.EndTime.IsZero() {
+		return nil, errors.New("run has no start or end time")
+	}
+	d := run.EndTime.Sub(run.StartTime)
+	if e.Max == 0 {
+		return result(e.Name(), d.Seconds(), ""), nil
+	}
+	return []langsmith.EvaluationResult{{
+		Key:     e.Name(),
+		Score:   d <= e.Max,
+		Value:   d.Seconds(),
+		Comment: fmt.Sprintf("took %s, budget %s", d.Round(time.Millisecond), e.Max),
+	}}, nil
+}
+
+// TokenBudget scores whether the run used at most MaxTokens tokens. The
+// run's usage is its TotalTokens or, when that is unknown, the usage its
+// outputs report (usage_metadata or an OpenAI-style usage).
+//
+// Runs scored by [langsmith.Evaluate] have no TotalTokens, so there the
+// target must report its usage in its outputs; a run without it is an
+// error, not a score. Use [langsmith.EvaluateExisting] to budget the tokens
+// LangSmith recorded for the target's model calls.
+type TokenBudget struct {
+	// Key is the feedback key. Defaults to "token_budget".
+	Key       string
+	MaxTokens int64
+}
+
+// Name returns the feedback key.
+func (e TokenBudget) Name() string { return or(e.Key, "token_budget") }
+
+// Evaluate implements [langsmith.RunEvaluator].
+func (e TokenBudget) Evaluate(ctx context.Context, run langsmith.RunView, example *langsmith.Example) ([]langsmith.EvaluationResult, error) {
+	tokens := run.TotalTokens
+	if tokens == 0 {
+		tokens = outputTokens(run.Outputs)
+	}
+	if tokens == 0 {
+		return nil, errors.New("run has no token usage")
+	}
+	return []langsmith.EvaluationResult{{
+		Key:     e.Name(),
+		Score:   tokens <= e.MaxTokens,
+		Value:   tokens,
+		Comment: fmt.Sprintf("used %d tokens, budget %d", tokens, e.MaxTokens),
+	}}, nil
+}
+
+// outputTokens returns the total tokens reported in outputs, or 0.
+func outputTokens(outputs map[string]any) int64 {
+	for _, usageKey := range []string{"usage_metadata", "usage"} {
+		usage, ok := outputs[usageKey].(map[string]any)
+		if !ok {
+			continue
+		}
+		if n, ok := number(usage["total_tokens"]); ok && n > 0 {
+			return int64(n)
+		}
+		var total int64
+		for _, k := range []string{"input_tokens", "output_tokens", "prompt_tokens", "completion_tokens"} {
+			if n, ok := number(usage[k]); ok {
+				total += int64(n)
+			}
+		}
+		if total > 0 {
+			return total
+		}
+	}
+	return 0
+}
@@ -0,0 +1,150 @@
+package evaluators
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/langchain-ai/langsmith-go"
+)
+
+// TrajectoryMode is how [ToolTrajectory] compares tool call sequences.
+type TrajectoryMode string
+
+const (
+	// TrajectoryStrict requires the same tool calls in the same order.
+	TrajectoryStrict TrajectoryMode = "strict"
+	// TrajectoryUnordered requires the same tool calls in any order.
+	TrajectoryUnordered TrajectoryMode = "unordered"
+	// TrajectorySubset requires every tool call to be expected; expected
+	// calls may be skipped.
+	TrajectorySubset TrajectoryMode = "subset"
+	// TrajectorySuperset requires every expected tool call; other calls
+	// may be made too.
+	TrajectorySuperset TrajectoryMode = "superset"
+)
+
+// ToolTrajectory scores whether the tools an agent called match the
+// expected sequence of tool names.
+//
+// The calls are read from the run's outputs: the tool_calls of the
+// assistant messages in a "messages" list or a chat completion's choices,
+// a "tool_calls" list, or a "trajectory" list of tool names or calls.
+type ToolTrajectory struct {
+	// Key is the feedback key. Defaults to "trajectory_<mode>_match".
+	Key string
+	// Expected lists the expected tool names. When nil, they are read from
+	// the example's reference outputs the same way as the run's calls, or
+	// from the reference output under ReferenceKey.
+	Expected     []string
+	Mode         TrajectoryMode
+	ReferenceKey string
+}
+
+// Name returns the feedback key.
+func (e ToolTrajectory) Name() string {
+	return or(e.Key, "trajectory_"+string(e.mode())+"_match")
+}
+
+func (e ToolTrajectory) mode() TrajectoryMode {
+	if e.Mode == "" {
+		return TrajectoryStrict
+	}
+	return e.Mode
+}
+
+// Evaluate implements [langsmith.RunEvaluator].
+func (e ToolTrajectory) Evaluate(ctx context.Context, run langsmith.RunView, example *langsmith.Example) ([]langsmith.EvaluationResult, error) {
+	expected := e.Expected
+	if expected == nil {
+		if example == nil || example.Outputs == nil {
+			return nil, errNoReference
+		}
+		var reference any = example.Outputs
+		if e.ReferenceKey != "" {
+			reference = example.Outputs[e.ReferenceKey]
+		}
+		expected = toolNames(reference)
+	}
+	actual := toolNames(run.Outputs)
+
+	var ok bool
+	switch e.mode() {
+	case TrajectoryStrict:
+		ok = slices.Equal(actual, expected)
+	case TrajectoryUnordered:
+		ok = len(actual) == len(expected) && containsAll(actual, expected)
+	case TrajectorySubset:
+		ok = containsAll(expected, actual)
+	case TrajectorySuperset:
+		ok = containsAll(actual, expected)
+	default:
+		return nil, errors.New("unknown trajectory mode " + string(e.Mode))
+	}
+	comment := fmt.Sprintf("called [%s], expected [%s]", strings.Join(actual, ", "), strings.Join(expected, ", "))
+	return result(e.Name(), ok, comment), nil
+}
+
+// containsAll reports whether have contains every element of want, counting
+// repeats.
+func containsAll(have, want []string) bool {
+	counts := make(map[string]int, len(have))
+	for _, s := range have {
+		counts[s]++
+	}
+	for _, s := range want {
+		if counts[s] == 0 {
+			return false
+		}
+		counts[s]--
+	}
+	return true
+}
+
+// toolNames returns the names of the tool calls recorded in v, in order.
+func toolNames(v any) []string {
+	names := []string{}
+	switch v := v.(type) {
+	case map[string]any:
+		for _, k := range []string{"messages", "tool_calls", "trajectory"} {
+			if list, ok := v[k].([]any); ok {
+				return toolNames(list)
+			}
+		}
+		if choices, ok := v["choices"].([]any); ok && len(choices) > 0 {
+			if c, ok := choices[0].(map[string]any); ok {
+				return toolNames(c["message"])
+			}
+		}
+		if _, isMessage := v["role"]; isMessage {
+			return toolNames(v["tool_calls"])
+		}
+	case []any:
+		for _, item := range v {
+			switch item := item.(type) {
+			case string:
+				names = append(names, item)
+			case map[string]any:
+				_, hasRole := item["role"]
+				_, hasContent := item["content"]
+				fn, isFunction := item["function"].(map[string]any)
+				name, hasName := item["name"].(string)
+				switch {
+				case item["tool_calls"] != nil:
+					names = append(names, toolNames(item["tool_calls"])...)
+				case hasRole || hasContent:
+					// A message without tool calls, such as a tool result.
+				case isFunction:
+					names = append(names, text(fn["name"]))
+				case hasName:
+					names = append(names, name)
+				}
+			}
+		}
+	case []string:
+		names = append(names, v...)
+	}
+	return names
+}
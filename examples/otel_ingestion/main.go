@@ -168,11 +168,13 @@ func createChildSpans(ctx context.Context, tracer trace.Tracer, sessionID string
 	ctx, llmSpan2 := createLLMSpan(ctx, tracer, sessionID, "openai.llm.final",
 		"Based on the weather data, provide a summary.")
 
-	// NESTED CHILD: Retriever call inside LLM
+	// NESTED CHILD: Retriever call inside LLM. LangSmith renders a
+	// retriever's outputs as documents when they are in this shape;
+	// instrumentation.TraceRetriever produces it from []Document.
 	_, retrieverSpan := createRetrieverSpan(ctx, tracer, sessionID)
 	time.Sleep(retrieverSpanDuration)
 	retrieverSpan.SetAttributes(
-		attribute.String("gen_ai.completion", "Temperature: 72F, Sunny"),
+		attribute.String("gen_ai.completion", `{"documents":[{"page_content":"Temperature: 72F, Sunny","metadata":{"source":"forecast"}}]}`),
 	)
 	retrieverSpan.End()
 
@@ -213,9 +215,10 @@ func createRetrieverSpan(ctx context.Context, tracer trace.Tracer, sessionID str
 	return tracer.Start(ctx, "database.retriever",
 		trace.WithAttributes(
 			attribute.String("gen_ai.operation.name", "retrieval"),
+			attribute.String("langsmith.span.kind", "retriever"),
 			attribute.String("service.name", serviceName),
 			attribute.String("session.id", sessionID),
-			attribute.String("gen_ai.prompt", "weather forecast data"),
+			attribute.String("gen_ai.prompt", `{"query":"weather forecast data"}`),
 		),
 	)
 }
@@ -0,0 +1,403 @@
+package langsmith
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ExperimentReport is an experiment's results: a row per run of an example
+// and the experiment's aggregate stats. See [LoadExperimentReport].
+type ExperimentReport struct {
+	ExperimentID   string          `json:"experiment_id"`
+	ExperimentName string          `json:"experiment_name"`
+	DatasetID      string          `json:"dataset_id"`
+	Stats          ExperimentStats `json:"stats"`
+	Rows           []ReportRow     `json:"rows"`
+}
+
+// ExperimentStats are an experiment's aggregate stats.
+type ExperimentStats struct {
+	RunCount  int64   `json:"run_count"`
+	ErrorRate float64 `json:"error_rate"`
+	// LatencyP50 and LatencyP99 are run latency percentiles in seconds.
+	LatencyP50       float64 `json:"latency_p50"`
+	LatencyP99       float64 `json:"latency_p99"`
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	TotalTokens      int64   `json:"total_tokens"`
+	// TotalCost is the estimated cost in USD.
+	TotalCost float64 `json:"total_cost"`
+	// Feedback is the mean score of each feedback key.
+	Feedback map[string]float64 `json:"feedback"`
+}
+
+// ReportRow is one run of an experiment on an example.
+type ReportRow struct {
+	ExampleID        string         `json:"example_id"`
+	RunID            string         `json:"run_id"`
+	Inputs           map[string]any `json:"inputs"`
+	Outputs          map[string]any `json:"outputs"`
+	ReferenceOutputs map[string]any `json:"reference_outputs"`
+	// Feedback is the mean score of each feedback key on the run.
+	Feedback         map[string]float64 `json:"feedback,omitempty"`
+	LatencySeconds   float64            `json:"latency_seconds"`
+	PromptTokens     int64              `json:"prompt_tokens"`
+	CompletionTokens int64              `json:"completion_tokens"`
+	TotalTokens      int64              `json:"total_tokens"`
+	TotalCost        float64            `json:"total_cost"`
+	Error            string             `json:"error,omitempty"`
+}
+
+// LoadExperimentReport returns the results of the experiment named or
+// identified by nameOrID, which must be over a dataset. Feedback means are
+// the experiment's own stats; when LangSmith has not computed them, they
+// are averaged over the rows.
+func LoadExperimentReport(ctx context.Context, client *Client, nameOrID string) (*ExperimentReport, error) {
+	found, err := findExperiment(ctx, client, nameOrID, "")
+	if err != nil {
+		return nil, err
+	}
+	session, err := client.Sessions.Get(ctx, found.ID, SessionGetParams{IncludeStats: F(true)})
+	if err != nil {
+		return nil, fmt.Errorf("langsmith: get experiment stats: %w", err)
+	}
+	report := &ExperimentReport{
+		ExperimentID:   session.ID,
+		ExperimentName: session.Name,
+		DatasetID:      session.ReferenceDatasetID,
+		Stats: ExperimentStats{
+			RunCount:         session.RunCount,
+			ErrorRate:        session.ErrorRate,
+			LatencyP50:       session.LatencyP50,
+			LatencyP99:       session.LatencyP99,
+			PromptTokens:     session.PromptTokens,
+			CompletionTokens: session.CompletionTokens,
+			TotalTokens:      session.TotalTokens,
+			Feedback:         map[string]float64{},
+		},
+	}
+	if session.TotalCost != "" {
+		report.Stats.TotalCost, _ = strconv.ParseFloat(session.TotalCost, 64)
+	}
+	for key, stat := range session.FeedbackStats {
+		if avg, ok := asMap(stat)["avg"].(float64); ok {
+			report.Stats.Feedback[key] = avg
+		}
+	}
+
+	iter := client.Datasets.ExperimentRuns.QueryAutoPaging(ctx, report.DatasetID, DatasetExperimentRunQueryParams{
+		ExperimentIDs: F([]string{report.ExperimentID}),
+		Selects: F([]RunSelectField{
+			RunSelectFieldID, RunSelectFieldInputs, RunSelectFieldOutputs, RunSelectFieldError,
+			RunSelectFieldLatencySeconds, RunSelectFieldFeedbackStats, RunSelectFieldProjectID,
+			RunSelectFieldPromptTokens, RunSelectFieldCompletionTokens, RunSelectFieldTotalTokens,
+			RunSelectFieldTotalCost,
+		}),
+	})
+	for iter.Next() {
+		item := iter.Current()
+		for _, run := range item.Runs {
+			if run.ProjectID != "" && run.ProjectID != report.ExperimentID {
+				continue
+			}
+			row := ReportRow{
+				ExampleID:        item.ID,
+				RunID:            run.ID,
+				Inputs:           run.Inputs,
+				Outputs:          run.Outputs,
+				ReferenceOutputs: asMap(item.Outputs),
+				LatencySeconds:   run.LatencySeconds,
+				PromptTokens:     run.PromptTokens,
+				CompletionTokens: run.CompletionTokens,
+				TotalTokens:      run.TotalTokens,
+				TotalCost:        run.TotalCost,
+				Error:            run.Error,
+			}
+			if row.Inputs == nil {
+				row.Inputs = asMap(item.Inputs)
+			}
+			for key, stat := range run.FeedbackStats {
+				if stat.JSON.Avg.IsNull() {
+					continue
+				}
+				if row.Feedback == nil {
+					row.Feedback = map[string]float64{}
+				}
+				row.Feedback[key] = stat.Avg
+			}
+			report.Rows = append(report.Rows, row)
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("langsmith: list experiment runs: %w", err)
+	}
+
+	if len(report.Stats.Feedback) == 0 {
+		sums, counts := map[string]float64{}, map[string]int{}
+		for _, row := range report.Rows {
+			for key, score := range row.Feedback {
+				sums[key] += score
+				counts[key]++
+			}
+		}
+		for key, sum := range sums {
+			report.Stats.Feedback[key] = sum / float64(counts[key])
+		}
+	}
+	return report, nil
+}
+
+// WriteJSONL writes the report's rows to w as JSON lines.
+func (r *ExperimentReport) WriteJSONL(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	for _, row := range r.Rows {
+		if err := enc.Encode(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteCSV writes the report's rows to w as CSV with a header. Inputs,
+// outputs and reference outputs are JSON, and each feedback key has a
+// "feedback.<key>" column, empty where the run has no score.
+func (r *ExperimentReport) WriteCSV(w io.Writer) error {
+	keys := r.feedbackKeys()
+	header := []string{
+		"example_id", "run_id", "inputs", "outputs", "reference_outputs", "error",
+		"latency_seconds", "prompt_tokens", "completion_tokens", "total_tokens", "total_cost",
+	}
+	for _, key := range keys {
+		header = append(header, "feedback."+key)
+	}
+	cw := csv.NewWriter(w)
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, row := range r.Rows {
+		record := []string{row.ExampleID, row.RunID}
+		for _, m := range []map[string]any{row.Inputs, row.Outputs, row.ReferenceOutputs} {
+			b, err := json.Marshal(m)
+			if err != nil {
+				return err
+			}
+			record = append(record, string(b))
+		}
+		record = append(record,
+			row.Error,
+			formatFloat(row.LatencySeconds),
+			strconv.FormatInt(row.PromptTokens, 10),
+			strconv.FormatInt(row.CompletionTokens, 10),
+			strconv.FormatInt(row.TotalTokens, 10),
+			formatFloat(row.TotalCost),
+		)
+		for _, key := range keys {
+			score, ok := row.Feedback[key]
+			if ok {
+				record = append(record, formatFloat(score))
+			} else {
+				record = append(record, "")
+			}
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// feedbackKeys returns the feedback keys of the report's rows, sorted.
+func (r *ExperimentReport) feedbackKeys() []string {
+	keys := map[string]bool{}
+	for _, row := range r.Rows {
+		for key := range row.Feedback {
+			keys[key] = true
+		}
+	}
+	return slices.Sorted(maps.Keys(keys))
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// ErrRegression is reported by [CompareExperiments] and
+// [ExperimentComparison.Err] when a metric regresses.
+var ErrRegression = errors.New("langsmith: experiment regressed")
+
+// Threshold is how far a feedback key's mean score may move for the worse
+// from the baseline's before the experiment has regressed.
+type Threshold struct {
+	// MaxRegression is how much worse than the baseline's the mean score
+	// may be: how much lower, or higher if LowerIsBetter. Zero allows no
+	// regression.
+	MaxRegression float64
+	// LowerIsBetter is set for keys such as "hallucination" whose higher
+	// scores are worse.
+	LowerIsBetter bool
+}
+
+// MetricComparison compares a feedback key's mean score in an experiment
+// with the baseline's.
+type MetricComparison struct {
+	Key string
+	// Baseline and Experiment are the mean scores; a missing score is NaN.
+	Baseline   float64
+	Experiment float64
+	// Threshold is the key's threshold, or nil if the key is not gated.
+	Threshold *Threshold
+	// Regressed reports whether the key is gated, has a baseline score, and
+	// has no score in the experiment or one worse than the threshold allows.
+	Regressed bool
+}
+
+// Delta returns the experiment's mean score minus the baseline's.
+func (m MetricComparison) Delta() float64 { return m.Experiment - m.Baseline }
+
+// ExperimentComparison compares an experiment with a baseline experiment
+// over the same dataset.
+type ExperimentComparison struct {
+	Experiment *ExperimentReport
+	Baseline   *ExperimentReport
+	// Metrics compare the feedback keys of either experiment, sorted by key.
+	Metrics []MetricComparison
+}
+
+// CompareExperiments loads the experiment and the baseline experiment,
+// each named or identified, and compares their feedback with
+// [ExperimentReport.Compare]. If a metric regressed, it returns the
+// comparison together with an error wrapping [ErrRegression].
+func CompareExperiments(ctx context.Context, client *Client, experiment, baseline string, thresholds map[string]Threshold) (*ExperimentComparison, error) {
+	current, err := LoadExperimentReport(ctx, client, experiment)
+	if err != nil {
+		return nil, err
+	}
+	base, err := LoadExperimentReport(ctx, client, baseline)
+	if err != nil {
+		return nil, err
+	}
+	if current.DatasetID != base.DatasetID {
+		return nil, fmt.Errorf("langsmith: experiments %s and %s are over different datasets", current.ExperimentName, base.ExperimentName)
+	}
+	c := current.Compare(base, thresholds)
+	return c, c.Err()
+}
+
+// Compare compares the report's mean feedback scores with the baseline's.
+// Only the keys in thresholds are gated.
+func (r *ExperimentReport) Compare(baseline *ExperimentReport, thresholds map[string]Threshold) *ExperimentComparison {
+	keys := map[string]bool{}
+	for key := range r.Stats.Feedback {
+		keys[key] = true
+	}
+	for key := range baseline.Stats.Feedback {
+		keys[key] = true
+	}
+	c := &ExperimentComparison{Experiment: r, Baseline: baseline}
+	for _, key := range slices.Sorted(maps.Keys(keys)) {
+		m := MetricComparison{Key: key, Baseline: math.NaN(), Experiment: math.NaN()}
+		if score, ok := baseline.Stats.Feedback[key]; ok {
+			m.Baseline = score
+		}
+		if score, ok := r.Stats.Feedback[key]; ok {
+			m.Experiment = score
+		}
+		if t, ok := thresholds[key]; ok {
+			m.Threshold = &t
+			switch {
+			case math.IsNaN(m.Baseline):
+			case math.IsNaN(m.Experiment):
+				m.Regressed = true
+			case t.LowerIsBetter:
+				m.Regressed = m.Delta() > t.MaxRegression
+			default:
+				m.Regressed = -m.Delta() > t.MaxRegression
+			}
+		}
+		c.Metrics = append(c.Metrics, m)
+	}
+	return c
+}
+
+// Err returns an error wrapping [ErrRegression] that lists the metrics that
+// regressed, or nil if none did.
+func (c *ExperimentComparison) Err() error {
+	var regressed []string
+	for _, m := range c.Metrics {
+		if m.Regressed {
+			regressed = append(regressed, m.describe())
+		}
+	}
+	if len(regressed) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w from %s: %s", ErrRegression, c.Baseline.ExperimentName, strings.Join(regressed, "; "))
+}
+
+// describe describes the metric's change from the baseline.
+func (m MetricComparison) describe() string {
+	if math.IsNaN(m.Experiment) {
+		return fmt.Sprintf("%s has no score (baseline %.4g)", m.Key, m.Baseline)
+	}
+	return fmt.Sprintf("%s %.4g (baseline %.4g, %+.4g)", m.Key, m.Experiment, m.Baseline, m.Delta())
+}
+
+// junitSuites is the root of a JUnit XML report.
+type junitSuites struct {
+	XMLName xml.Name     `xml:"testsuites"`
+	Suites  []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+}
+
+// WriteJUnit writes the comparison to w as a JUnit XML report for CI, with
+// a test case per feedback key that fails if the key regressed.
+func (c *ExperimentComparison) WriteJUnit(w io.Writer) error {
+	suite := junitSuite{Name: c.Experiment.ExperimentName, Tests: len(c.Metrics)}
+	for _, m := range c.Metrics {
+		tc := junitCase{Name: m.Key, ClassName: "langsmith." + c.Experiment.ExperimentName, SystemOut: m.describe()}
+		if m.Regressed {
+			suite.Failures++
+			tc.Failure = &junitFailure{Message: m.describe(), Type: "regression"}
+		}
+		suite.Cases = append(suite.Cases, tc)
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(junitSuites{Suites: []junitSuite{suite}}); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,154 @@
+package langsmith_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/langchain-ai/langsmith-go"
+	"github.com/langchain-ai/langsmith-go/option"
+)
+
+func newReportServer(t *testing.T) *langsmith.Client {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v1/sessions/"+expA:
+			_, _ = w.Write([]byte(`{"id": "` + expA + `", "name": "candidate", "tenant_id": "` + expA + `", "reference_dataset_id": "` + evalDatasetID + `",
+				"run_count": 2, "error_rate": 0.5, "latency_p50": 1.5, "latency_p99": 2, "total_tokens": 30, "total_cost": "0.003",
+				"feedback_stats": {"correct": {"n": 2, "avg": 0.5}, "hallucination": {"n": 2, "avg": 0.4}}}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v1/sessions/"+expB:
+			// The baseline's feedback stats are not computed yet.
+			_, _ = w.Write([]byte(`{"id": "` + expB + `", "name": "baseline", "tenant_id": "` + expB + `", "reference_dataset_id": "` + evalDatasetID + `", "run_count": 2}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/api/v2/datasets/"+evalDatasetID+"/experiment-runs":
+			var q map[string]any
+			_ = json.NewDecoder(r.Body).Decode(&q)
+			experiment := q["experiment_ids"].([]any)[0].(string)
+			if experiment == expA {
+				_, _ = w.Write([]byte(`{"items": [
+					{"id": "00000000-0000-0000-0000-000000000a01", "dataset_id": "` + evalDatasetID + `", "inputs": {"q": "2+2"}, "outputs": {"a": "4"}, "runs": [
+						{"id": "00000000-0000-0000-0000-0000000001a1", "project_id": "` + expA + `", "inputs": {"q": "2+2"}, "outputs": {"a": "4"},
+						 "latency_seconds": 1, "total_tokens": 10, "total_cost": 0.001, "feedback_stats": {"correct": {"avg": 1}, "hallucination": {"avg": 0}}}
+					]},
+					{"id": "00000000-0000-0000-0000-000000000a02", "dataset_id": "` + evalDatasetID + `", "inputs": {"q": "3+3"}, "outputs": {"a": "6"}, "runs": [
+						{"id": "00000000-0000-0000-0000-0000000001a2", "project_id": "` + expA + `", "inputs": {"q": "3+3"}, "error": "timeout",
+						 "latency_seconds": 2, "total_tokens": 20, "total_cost": 0.002, "feedback_stats": {"correct": {"avg": 0}, "hallucination": {"avg": null}}}
+					]}
+				]}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"items": [
+				{"id": "00000000-0000-0000-0000-000000000a01", "dataset_id": "` + evalDatasetID + `", "inputs": {"q": "2+2"}, "outputs": {"a": "4"}, "runs": [
+					{"id": "00000000-0000-0000-0000-0000000001b1", "project_id": "` + expB + `", "outputs": {"a": "4"}, "feedback_stats": {"correct": {"avg": 1}, "hallucination": {"avg": 0}, "concise": {"avg": 1}}}
+				]},
+				{"id": "00000000-0000-0000-0000-000000000a02", "dataset_id": "` + evalDatasetID + `", "inputs": {"q": "3+3"}, "outputs": {"a": "6"}, "runs": [
+					{"id": "00000000-0000-0000-0000-0000000001b2", "project_id": "` + expB + `", "outputs": {"a": "6"}, "feedback_stats": {"correct": {"avg": 0.5}, "hallucination": {"avg": 0}}}
+				]}
+			]}`))
+		default:
+			t.Errorf("unexpected %s %s", r.Method, r.URL.Path)
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return langsmith.NewClient(option.WithBaseURL(srv.URL), option.WithAPIKey("test-api-key"), option.WithMaxRetries(0))
+}
+
+func TestLoadExperimentReport(t *testing.T) {
+	client := newReportServer(t)
+	report, err := langsmith.LoadExperimentReport(context.Background(), client, expA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats := report.Stats
+	if report.ExperimentName != "candidate" || stats.RunCount != 2 || stats.ErrorRate != 0.5 || stats.LatencyP50 != 1.5 || stats.TotalCost != 0.003 || stats.Feedback["correct"] != 0.5 {
+		t.Errorf("report = %+v", report)
+	}
+	if len(report.Rows) != 2 {
+		t.Fatalf("got %d rows, want 2", len(report.Rows))
+	}
+	if row := report.Rows[1]; row.Error != "timeout" || row.ReferenceOutputs["a"] != "6" || row.TotalTokens != 20 || len(row.Feedback) != 1 {
+		t.Errorf("row = %+v", row)
+	}
+
+	var jsonl bytes.Buffer
+	if err := report.WriteJSONL(&jsonl); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(jsonl.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d JSON lines, want 2", len(lines))
+	}
+	var first map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &first); err != nil {
+		t.Fatal(err)
+	}
+	if first["run_id"] != "00000000-0000-0000-0000-0000000001a1" || first["feedback"].(map[string]any)["correct"] != 1.0 {
+		t.Errorf("first line = %v", first)
+	}
+
+	var out bytes.Buffer
+	if err := report.WriteCSV(&out); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(&out).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("got %d CSV records, want 3", len(records))
+	}
+	if got := strings.Join(records[0][9:], ","); got != "total_tokens,total_cost,feedback.correct,feedback.hallucination" {
+		t.Errorf("header ends %q", got)
+	}
+	if got := strings.Join(records[2][2:], ","); got != `{"q":"3+3"},null,{"a":"6"},timeout,2,0,0,20,0.002,0,` {
+		t.Errorf("second row = %q", got)
+	}
+}
+
+func TestCompareExperiments(t *testing.T) {
+	client := newReportServer(t)
+	thresholds := map[string]langsmith.Threshold{
+		"correct":       {MaxRegression: 0.1},
+		"hallucination": {MaxRegression: 0.5, LowerIsBetter: true},
+		"concise":       {},
+	}
+	c, err := langsmith.CompareExperiments(context.Background(), client, expA, expB, thresholds)
+	if !errors.Is(err, langsmith.ErrRegression) {
+		t.Fatalf("err = %v, want a regression", err)
+	}
+	if !strings.Contains(err.Error(), "correct 0.5 (baseline 0.75, -0.25)") || !strings.Contains(err.Error(), "concise has no score") || strings.Contains(err.Error(), "hallucination") {
+		t.Errorf("err = %v", err)
+	}
+	regressed := map[string]bool{}
+	for _, m := range c.Metrics {
+		regressed[m.Key] = m.Regressed
+	}
+	if want := map[string]bool{"concise": true, "correct": true, "hallucination": false}; !reflect.DeepEqual(regressed, want) {
+		t.Errorf("regressed = %v, want %v", regressed, want)
+	}
+
+	var junit bytes.Buffer
+	if err := c.WriteJUnit(&junit); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`<testsuite name="candidate" tests="3" failures="2">`, `<testcase name="hallucination" classname="langsmith.candidate">`, `type="regression"`} {
+		if !strings.Contains(junit.String(), want) {
+			t.Errorf("JUnit report lacks %s:\n%s", want, junit.String())
+		}
+	}
+
+	// Within the thresholds, there is no regression.
+	c, err = langsmith.CompareExperiments(context.Background(), client, expA, expB, map[string]langsmith.Threshold{"correct": {MaxRegression: 0.3}})
+	if err != nil || c.Err() != nil {
+		t.Errorf("err = %v", err)
+	}
+}
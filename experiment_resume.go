@@ -0,0 +1,142 @@
+package langsmith
+
+import (
+	"context"
+	"fmt"
+	"maps"
+
+	"github.com/google/uuid"
+)
+
+// findExperiment returns the experiment named or identified by ref, which
+// must be over the dataset datasetID, if set, or else over some dataset.
+func findExperiment(ctx context.Context, client *Client, ref, datasetID string) (*TracerSessionWithoutVirtualFields, error) {
+	var session *TracerSession
+	if _, err := uuid.Parse(ref); err == nil {
+		session, err = client.Sessions.Get(ctx, ref, SessionGetParams{})
+		if err != nil {
+			return nil, fmt.Errorf("langsmith: get experiment %s: %w", ref, err)
+		}
+	} else {
+		page, err := client.Sessions.List(ctx, SessionListParams{Name: F(ref), Limit: F(int64(1))})
+		if err != nil {
+			return nil, fmt.Errorf("langsmith: find experiment %q: %w", ref, err)
+		}
+		if len(page.Items) == 0 {
+			return nil, fmt.Errorf("langsmith: experiment %q not found", ref)
+		}
+		session = &page.Items[0]
+	}
+	switch {
+	case session.ReferenceDatasetID == "":
+		return nil, fmt.Errorf("langsmith: %s is not an experiment over a dataset", session.Name)
+	case datasetID != "" && session.ReferenceDatasetID != datasetID:
+		return nil, fmt.Errorf("langsmith: experiment %s is not over dataset %s", session.Name, datasetID)
+	}
+	return &TracerSessionWithoutVirtualFields{
+		ID:                 session.ID,
+		TenantID:           session.TenantID,
+		Description:        session.Description,
+		Extra:              session.Extra,
+		Name:               session.Name,
+		ReferenceDatasetID: session.ReferenceDatasetID,
+		StartTime:          session.StartTime,
+	}, nil
+}
+
+// completedRuns returns the experiment's root runs on examples that
+// finished without an error.
+func completedRuns(ctx context.Context, client *Client, session *TracerSessionWithoutVirtualFields) ([]RunView, error) {
+	params := RunQueryV2Params{
+		ProjectIDs: F([]string{session.ID}),
+		IsRoot:     F(true),
+		HasError:   F(false),
+		Selects:    F(rootRunSelects),
+	}
+	if !session.StartTime.IsZero() {
+		params.MinStartTime = F(session.StartTime)
+	}
+	var runs []RunView
+	iter := client.Runs.QueryV2AutoPaging(ctx, params)
+	for iter.Next() {
+		run := runView(iter.Current())
+		if run.ReferenceExampleID == "" || run.EndTime.IsZero() {
+			continue
+		}
+		if run.SessionID == "" {
+			run.SessionID = session.ID
+		}
+		runs = append(runs, run)
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("langsmith: list experiment runs: %w", err)
+	}
+	return runs, nil
+}
+
+// completedRows pairs runs, completed by an earlier invocation of the
+// experiment, with their examples, for the summary evaluators. examples are
+// the examples this invocation runs on; others the runs ran on are fetched.
+func completedRows(ctx context.Context, client *Client, datasetID string, examples []Example, runs []RunView) ([]ExperimentResultRow, error) {
+	byID := make(map[string]Example, len(examples))
+	for _, ex := range examples {
+		byID[ex.ID] = ex
+	}
+	var missing []RunView
+	for _, run := range runs {
+		if _, ok := byID[run.ReferenceExampleID]; !ok {
+			missing = append(missing, run)
+		}
+	}
+	if len(missing) > 0 {
+		fetched, err := referencedExamples(ctx, client, datasetID, "", missing)
+		if err != nil {
+			return nil, err
+		}
+		maps.Copy(byID, fetched)
+	}
+	rows := make([]ExperimentResultRow, 0, len(runs))
+	reps := repetitions(runs)
+	for i, run := range runs {
+		ex, ok := byID[run.ReferenceExampleID]
+		if !ok {
+			continue
+		}
+		rows = append(rows, ExperimentResultRow{Run: run, Example: ex, Repetition: reps[i]})
+	}
+	return rows, nil
+}
+
+// repetitions returns the repetition each of runs ran as. Evaluate records
+// it in the run's metadata; runs without it take the lowest repetitions of
+// their example that no other run claims.
+func repetitions(runs []RunView) []int {
+	reps := make([]int, len(runs))
+	claimed := map[string]map[int]bool{}
+	var unnumbered []int
+	for i, run := range runs {
+		n, ok := run.Metadata[repetitionKey].(float64)
+		if !ok || n < 0 || n != float64(int(n)) {
+			unnumbered = append(unnumbered, i)
+			continue
+		}
+		reps[i] = int(n)
+		if claimed[run.ReferenceExampleID] == nil {
+			claimed[run.ReferenceExampleID] = map[int]bool{}
+		}
+		claimed[run.ReferenceExampleID][reps[i]] = true
+	}
+	for _, i := range unnumbered {
+		ex := runs[i].ReferenceExampleID
+		if claimed[ex] == nil {
+			claimed[ex] = map[int]bool{}
+		}
+		rep := 0
+		for claimed[ex][rep] {
+			rep++
+		}
+		reps[i] = rep
+		claimed[ex][rep] = true
+	}
+	return reps
+}
@@ -0,0 +1,169 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/langchain-ai/langsmith-go/lib/langsmithtracing"
+)
+
+// DefaultMaxAttachmentSize caps the decoded size of a single content part
+// handed to Config.Attachments when Config.MaxAttachmentSize is zero.
+const DefaultMaxAttachmentSize = 20 << 20
+
+// Attachment is a binary content part (image, audio, file) extracted from a
+// prompt or completion. It is the TracingClient's multipart attachment type,
+// so handlers can pass it to RunCreate.Attachments or RunUpdate.Attachments
+// unchanged.
+type Attachment = langsmithtracing.Attachment
+
+// AttachmentHandler receives the binary content parts of a traced call,
+// keyed by the names that gen_ai.prompt and gen_ai.completion reference as
+// "attachment:<name>". span identifies the call's span; the handler maps it
+// to the LangSmith run the attachments belong to. It is called once per call
+// that has content parts, after the span ends.
+type AttachmentHandler func(ctx context.Context, span trace.SpanContext, attachments map[string]Attachment)
+
+// mimeTypeKeys name the sibling of a base64 "data" field that gives its MIME
+// type: Anthropic image/document sources use media_type, Gemini inline data
+// mimeType or mime_type.
+var mimeTypeKeys = []string{"media_type", "mime_type", "mimeType"}
+
+// attachmentExtractor replaces base64 content in message JSON with
+// references and collects the decoded bytes.
+type attachmentExtractor struct {
+	prefix  string // "input" or "output"; names are prefix_N
+	maxSize int
+	keep    bool // false when no handler is configured
+	found   map[string]Attachment
+	n       int
+}
+
+// extractAttachments returns messagesJSON with every base64 content part
+// replaced: by "attachment:<name>" when the part is kept for upload, or by
+// an "[omitted …]" placeholder when no handler is configured or the part
+// exceeds the size cap. Recognized parts are data: URLs (OpenAI image_url,
+// file_data, input_image), {"data", "media_type"|"mime_type"|"mimeType"}
+// objects (Anthropic sources, Gemini inline data) and OpenAI input_audio.
+// The input is returned unchanged when it holds no such parts.
+func extractAttachments(messagesJSON, prefix string, cfg Config) (string, map[string]Attachment) {
+	if !strings.Contains(messagesJSON, ";base64,") && !strings.Contains(messagesJSON, `"data"`) {
+		return messagesJSON, nil
+	}
+	dec := json.NewDecoder(strings.NewReader(messagesJSON))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return messagesJSON, nil
+	}
+
+	x := &attachmentExtractor{prefix: prefix, maxSize: cfg.MaxAttachmentSize, keep: cfg.Attachments != nil}
+	if x.maxSize <= 0 {
+		x.maxSize = DefaultMaxAttachmentSize
+	}
+	v = x.walk("", v)
+	if x.n == 0 {
+		return messagesJSON, nil
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return messagesJSON, nil
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), x.found
+}
+
+func (x *attachmentExtractor) walk(key string, v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		if data, ok := v["data"].(string); ok {
+			mimeType := ""
+			for _, k := range mimeTypeKeys {
+				if s, ok := v[k].(string); ok && s != "" {
+					mimeType = s
+					break
+				}
+			}
+			if format, ok := v["format"].(string); ok && mimeType == "" && key == "input_audio" {
+				mimeType = "audio/" + format
+			}
+			if mimeType != "" {
+				if ref, ok := x.extract(mimeType, data); ok {
+					v["data"] = ref
+				}
+			}
+		}
+		// Visit keys in order so attachment names are deterministic.
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			v[k] = x.walk(k, v[k])
+		}
+		return v
+	case []any:
+		for i, child := range v {
+			v[i] = x.walk(key, child)
+		}
+		return v
+	case string:
+		if mimeType, data, ok := parseDataURL(v); ok {
+			if ref, ok := x.extract(mimeType, data); ok {
+				return ref
+			}
+		}
+	}
+	return v
+}
+
+// extract decodes base64 data and returns its replacement. ok is false when
+// data is not valid base64, in which case it is left in place.
+func (x *attachmentExtractor) extract(mimeType, data string) (string, bool) {
+	raw, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		if raw, err = base64.RawStdEncoding.DecodeString(data); err != nil {
+			return "", false
+		}
+	}
+	name := fmt.Sprintf("%s_%d", x.prefix, x.n)
+	x.n++
+	if !x.keep || len(raw) > x.maxSize {
+		return fmt.Sprintf("[omitted %s, %d bytes]", mimeType, len(raw)), true
+	}
+	if x.found == nil {
+		x.found = make(map[string]Attachment)
+	}
+	x.found[name] = Attachment{ContentType: mimeType, Data: raw}
+	return "attachment:" + name, true
+}
+
+// parseDataURL splits a base64 data URL ("data:image/png;base64,...") into
+// its MIME type and payload.
+func parseDataURL(s string) (mimeType, data string, ok bool) {
+	if !strings.HasPrefix(s, "data:") {
+		return "", "", false
+	}
+	meta, data, found := strings.Cut(s[len("data:"):], ",")
+	if !found || !strings.HasSuffix(meta, ";base64") {
+		return "", "", false
+	}
+	mimeType = strings.TrimSuffix(meta, ";base64")
+	if i := strings.IndexByte(mimeType, ';'); i >= 0 {
+		mimeType = mimeType[:i]
+	}
+	if mimeType == "" {
+		mimeType = "application/octet-stream"
+	}
+	return mimeType, data, true
+}
@@ -0,0 +1,139 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestExtractAttachments(t *testing.T) {
+	keep := Config{Attachments: func(context.Context, trace.SpanContext, map[string]Attachment) {}}
+	tests := []struct {
+		name     string
+		in       string
+		cfg      Config
+		want     string
+		wantType string
+	}{
+		{
+			name:     "openai image_url data URL",
+			in:       `{"messages":[{"role":"user","content":[{"type":"text","text":"what is this?"},{"type":"image_url","image_url":{"url":"data:image/png;base64,aGVsbG8="}}]}]}`,
+			cfg:      keep,
+			want:     `{"messages":[{"content":[{"text":"what is this?","type":"text"},{"image_url":{"url":"attachment:input_0"},"type":"image_url"}],"role":"user"}]}`,
+			wantType: "image/png",
+		},
+		{
+			name:     "anthropic base64 source",
+			in:       `{"messages":[{"role":"user","content":[{"type":"image","source":{"type":"base64","media_type":"image/jpeg","data":"aGVsbG8="}}]}]}`,
+			cfg:      keep,
+			want:     `{"messages":[{"content":[{"source":{"data":"attachment:input_0","media_type":"image/jpeg","type":"base64"},"type":"image"}],"role":"user"}]}`,
+			wantType: "image/jpeg",
+		},
+		{
+			name:     "openai input_audio",
+			in:       `{"messages":[{"role":"user","content":[{"type":"input_audio","input_audio":{"data":"aGVsbG8=","format":"wav"}}]}]}`,
+			cfg:      keep,
+			want:     `{"messages":[{"content":[{"input_audio":{"data":"attachment:input_0","format":"wav"},"type":"input_audio"}],"role":"user"}]}`,
+			wantType: "audio/wav",
+		},
+		{
+			name: "no handler omits data",
+			in:   `{"messages":[{"role":"user","content":[{"type":"image_url","image_url":{"url":"data:image/png;base64,aGVsbG8="}}]}]}`,
+			want: `{"messages":[{"content":[{"image_url":{"url":"[omitted image/png, 5 bytes]"},"type":"image_url"}],"role":"user"}]}`,
+		},
+		{
+			name: "oversized part omitted",
+			in:   `{"messages":[{"role":"user","content":[{"type":"image_url","image_url":{"url":"data:image/png;base64,aGVsbG8="}}]}]}`,
+			cfg:  Config{Attachments: keep.Attachments, MaxAttachmentSize: 4},
+			want: `{"messages":[{"content":[{"image_url":{"url":"[omitted image/png, 5 bytes]"},"type":"image_url"}],"role":"user"}]}`,
+		},
+		{
+			name: "plain text unchanged",
+			in:   `{"messages":[{"role":"user","content":"hi","data":"x"}]}`,
+			cfg:  keep,
+			want: `{"messages":[{"role":"user","content":"hi","data":"x"}]}`,
+		},
+		{
+			name: "remote image URL unchanged",
+			in:   `{"messages":[{"role":"user","content":[{"type":"image_url","image_url":{"url":"https://example.com/cat.png"}}]}]}`,
+			cfg:  keep,
+			want: `{"messages":[{"role":"user","content":[{"type":"image_url","image_url":{"url":"https://example.com/cat.png"}}]}]}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, found := extractAttachments(tt.in, "input", tt.cfg)
+			if got != tt.want {
+				t.Errorf("got  %s\nwant %s", got, tt.want)
+			}
+			if tt.wantType == "" {
+				if len(found) != 0 {
+					t.Errorf("found %d attachments, want none", len(found))
+				}
+				return
+			}
+			a, ok := found["input_0"]
+			if !ok || a.ContentType != tt.wantType || string(a.Data) != "hello" {
+				t.Errorf("input_0 = %+v, want %s \"hello\"", a, tt.wantType)
+			}
+		})
+	}
+}
+
+func TestParseDataURL(t *testing.T) {
+	tests := []struct {
+		in       string
+		mimeType string
+		data     string
+		ok       bool
+	}{
+		{"data:image/png;base64,AAAA", "image/png", "AAAA", true},
+		{"data:audio/wav;codecs=1;base64,AAAA", "audio/wav", "AAAA", true},
+		{"data:;base64,AAAA", "application/octet-stream", "AAAA", true},
+		{"data:text/plain,hello", "", "", false},
+		{"https://example.com/a.png", "", "", false},
+	}
+	for _, tt := range tests {
+		mimeType, data, ok := parseDataURL(tt.in)
+		if mimeType != tt.mimeType || data != tt.data || ok != tt.ok {
+			t.Errorf("parseDataURL(%q) = (%q, %q, %v), want (%q, %q, %v)", tt.in, mimeType, data, ok, tt.mimeType, tt.data, tt.ok)
+		}
+	}
+}
+
+func TestMiddleware_AttachmentsHandedToHandler(t *testing.T) {
+	var gotSpan trace.SpanContext
+	var got map[string]Attachment
+	cfg := Config{Attachments: func(_ context.Context, span trace.SpanContext, a map[string]Attachment) {
+		gotSpan, got = span, a
+	}}
+	completion := `{"messages":[{"role":"assistant","content":[{"type":"image_url","image_url":{"url":"data:image/png;base64,b3V0"}}]}]}`
+	respBody, _ := json.Marshal(map[string]any{"text": completion})
+	client, exporter := newTestClient(t, &staticTransport{status: 200, body: string(respBody)}, cfg)
+
+	prompt := `{"messages":[{"role":"user","content":[{"type":"image_url","image_url":{"url":"data:image/jpeg;base64,aW4="}}]}]}`
+	reqBody, _ := json.Marshal(map[string]any{"prompt": prompt})
+	if err := doRequest(t, client, context.Background(), "https://api.example.com/v1/fake", string(reqBody)); err != nil {
+		t.Fatal(err)
+	}
+
+	span := onlySpan(t, exporter)
+	if gotSpan.SpanID() != span.SpanContext.SpanID() {
+		t.Errorf("handler span = %v, want %v", gotSpan.SpanID(), span.SpanContext.SpanID())
+	}
+	if a := got["input_0"]; a.ContentType != "image/jpeg" || string(a.Data) != "in" {
+		t.Errorf("input_0 = %+v", a)
+	}
+	if a := got["output_0"]; a.ContentType != "image/png" || string(a.Data) != "out" {
+		t.Errorf("output_0 = %+v", a)
+	}
+	if v, _ := attr(span, "gen_ai.prompt"); !strings.Contains(v.AsString(), `"url":"attachment:input_0"`) {
+		t.Errorf("gen_ai.prompt = %s", v.AsString())
+	}
+	if v, _ := attr(span, "gen_ai.completion"); !strings.Contains(v.AsString(), `"url":"attachment:output_0"`) {
+		t.Errorf("gen_ai.completion = %s", v.AsString())
+	}
+}
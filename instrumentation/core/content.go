@@ -0,0 +1,103 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/langchain-ai/langsmith-go/internal/genaiattr"
+	"github.com/langchain-ai/langsmith-go/internal/traceutil"
+)
+
+// Redactor rewrites recorded content before it is set on a span. key is
+// gen_ai.prompt or gen_ai.completion and content is the JSON-serialized
+// value; returning "" drops the attribute.
+type Redactor func(key attribute.Key, content string) string
+
+// Content applies the configured capture controls to a prompt or
+// completion about to be recorded under key: hiding (Config.HideInputs,
+// Config.HideOutputs or LANGSMITH_HIDE_INPUTS / LANGSMITH_HIDE_OUTPUTS),
+// then Config.Redact, then Config.MaxContentLength. It returns "" when the
+// content should not be recorded.
+func (c Config) Content(key attribute.Key, content string) string {
+	if content == "" {
+		return ""
+	}
+	switch key {
+	case genaiattr.PromptKey:
+		if c.HideInputs || traceutil.EnvTruthy("LANGSMITH_HIDE_INPUTS") {
+			return ""
+		}
+	case genaiattr.CompletionKey:
+		if c.HideOutputs || traceutil.EnvTruthy("LANGSMITH_HIDE_OUTPUTS") {
+			return ""
+		}
+	}
+	if c.Redact != nil {
+		content = c.Redact(key, content)
+	}
+	if c.MaxContentLength > 0 {
+		content = truncateContent(content, c.MaxContentLength)
+	}
+	return content
+}
+
+// truncateContent shortens every string value in a JSON document to max
+// bytes, so the result stays valid JSON with its message structure intact.
+// Content that is not JSON is truncated as a whole.
+func truncateContent(content string, max int) string {
+	dec := json.NewDecoder(strings.NewReader(content))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return truncateString(content, max)
+	}
+	changed := false
+	v = truncateStrings(v, max, &changed)
+	if !changed {
+		return content
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return truncateString(content, max)
+	}
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+func truncateStrings(v any, max int, changed *bool) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, child := range v {
+			v[k] = truncateStrings(child, max, changed)
+		}
+	case []any:
+		for i, child := range v {
+			v[i] = truncateStrings(child, max, changed)
+		}
+	case string:
+		if len(v) > max {
+			*changed = true
+			return truncateString(v, max)
+		}
+	}
+	return v
+}
+
+// truncateString cuts s to at most max bytes on a rune boundary and notes
+// how much was removed.
+func truncateString(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	cut := max
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return fmt.Sprintf("%s…[truncated %d bytes]", s[:cut], len(s)-cut)
+}
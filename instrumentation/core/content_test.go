@@ -0,0 +1,83 @@
+package core
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/langchain-ai/langsmith-go/internal/genaiattr"
+)
+
+func TestConfigContent(t *testing.T) {
+	const prompt = `{"messages":[{"content":"my SSN is 123-45-6789","role":"user"}]}`
+	tests := []struct {
+		name string
+		cfg  Config
+		key  attribute.Key
+		want string
+	}{
+		{"default records", Config{}, genaiattr.PromptKey, prompt},
+		{"hide inputs", Config{HideInputs: true}, genaiattr.PromptKey, ""},
+		{"hide inputs keeps outputs", Config{HideInputs: true}, genaiattr.CompletionKey, prompt},
+		{"hide outputs", Config{HideOutputs: true}, genaiattr.CompletionKey, ""},
+		{
+			"redact",
+			Config{Redact: func(_ attribute.Key, s string) string { return strings.ReplaceAll(s, "123-45-6789", "***") }},
+			genaiattr.PromptKey,
+			`{"messages":[{"content":"my SSN is ***","role":"user"}]}`,
+		},
+		{
+			"truncate each string",
+			Config{MaxContentLength: 9},
+			genaiattr.PromptKey,
+			`{"messages":[{"content":"my SSN is…[truncated 12 bytes]","role":"user"}]}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.cfg.Content(tt.key, prompt); got != tt.want {
+				t.Errorf("got  %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfigContent_EnvHides(t *testing.T) {
+	t.Setenv("LANGSMITH_HIDE_INPUTS", "true")
+	t.Setenv("LANGSMITH_HIDE_OUTPUTS", "false")
+	var cfg Config
+	if got := cfg.Content(genaiattr.PromptKey, `{"a":1}`); got != "" {
+		t.Errorf("prompt = %q, want hidden", got)
+	}
+	if got := cfg.Content(genaiattr.CompletionKey, `{"a":1}`); got != `{"a":1}` {
+		t.Errorf("completion = %q, want recorded", got)
+	}
+}
+
+func TestTruncateString_RuneBoundary(t *testing.T) {
+	if got := truncateString("héllo", 2); got != "h…[truncated 5 bytes]" {
+		t.Errorf("got %q", got)
+	}
+	if got := truncateContent("not json at all", 3); got != "not…[truncated 12 bytes]" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestMiddleware_HideOutputs(t *testing.T) {
+	client, exporter := newTestClient(t, &staticTransport{status: 200, body: `{"text":"secret","in":3,"out":2}`}, Config{HideOutputs: true})
+	if err := doRequest(t, client, context.Background(), "https://api.example.com/v1/fake", `{"prompt":"p"}`); err != nil {
+		t.Fatal(err)
+	}
+	span := onlySpan(t, exporter)
+	if _, ok := attr(span, "gen_ai.completion"); ok {
+		t.Error("gen_ai.completion recorded despite HideOutputs")
+	}
+	if v, _ := attr(span, "gen_ai.prompt"); v.AsString() != "p" {
+		t.Errorf("gen_ai.prompt = %q", v.AsString())
+	}
+	if v, _ := attr(span, "gen_ai.usage.output_tokens"); v.AsInt64() != 2 {
+		t.Errorf("usage should still be recorded, output_tokens = %d", v.AsInt64())
+	}
+}
@@ -0,0 +1,527 @@
+// Package core is the shared tracing engine behind the LangSmith LLM
+// instrumentation packages (traceopenai, traceanthropic, tracegemini, ...).
+//
+// Each instrumentation package implements a Provider that parses its API's
+// requests, responses and stream events; core supplies everything else so
+// that behaviour is identical across providers:
+//
+//   - span (run) naming via WithRunNameContext and Config.RunName
+//   - W3C trace-context propagation into the outgoing request
+//   - thread metadata (session_id, thread_id, conversation_id) from baggage
+//   - API-key redaction in the recorded http.url
+//   - prompt and completion capture controls: hiding, redaction, truncation
+//   - error tagging for transport errors, HTTP errors and cancelled streams
+//   - first-token (new_token) events for streaming responses
+//   - base64 images, audio and files moved out of prompts and completions
+//     into attachments
+//   - span links from a model request to the tool runs (RegisterToolCall)
+//     whose results it carries
+//   - SDK retries grouped into one run with a child span per HTTP attempt,
+//     and fallback candidates tagged via WithFallbackContext
+//   - usage_metadata and flat gen_ai.usage.* attributes, including
+//     propagation of token totals to the parent span
+//   - optional client-side costs from a pricing.Table (Config.Pricing)
+//
+// A new provider is wired up with:
+//
+//	client := core.WrapClient(nil, myProvider{}, core.Config{TracerProvider: tp})
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/langchain-ai/langsmith-go/instrumentation/pricing"
+	"github.com/langchain-ai/langsmith-go/internal/genaiattr"
+	"github.com/langchain-ai/langsmith-go/internal/thread"
+	"github.com/langchain-ai/langsmith-go/internal/traceutil"
+)
+
+// genAISystemKey is the pre-v1.37 semconv name of gen_ai.provider.name,
+// still emitted by the OpenAI and Anthropic instrumentations.
+const genAISystemKey = attribute.Key("gen_ai.system")
+
+// maxErrorBodyLen caps how much of an HTTP error body is copied into the
+// span status and error event.
+const maxErrorBodyLen = 500
+
+type contextKey struct{ name string }
+
+var ctxKeyRunName = contextKey{"run_name"}
+
+// WithRunNameContext sets the span (run) name for the next traced request made with ctx.
+// The run name in LangSmith is the OTLP span name; there is no separate field.
+func WithRunNameContext(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, ctxKeyRunName, name)
+}
+
+// RunNameFromContext returns the run name set with WithRunNameContext, or ""
+// when none is set.
+func RunNameFromContext(ctx context.Context) string {
+	s, _ := ctx.Value(ctxKeyRunName).(string)
+	return s
+}
+
+// Config configures a traced client or middleware.
+type Config struct {
+	// TracerProvider is used to create spans. If nil, the global tracer
+	// provider is used.
+	TracerProvider trace.TracerProvider
+
+	// RunName overrides the provider's default span name when non-empty. A
+	// run name set on the request context takes precedence.
+	RunName string
+
+	// ProviderName overrides the gen_ai.provider.name (and legacy
+	// gen_ai.system) recorded on spans when non-empty. Set it when an
+	// API-compatible server (vLLM, llama.cpp, a gateway) hosts models that
+	// are not the wire format's vendor's, so LangSmith attributes the run and
+	// matches model prices against the right provider.
+	ProviderName string
+
+	// Attachments receives the images, audio and files sent or returned as
+	// base64 content parts, which are replaced in gen_ai.prompt and
+	// gen_ai.completion by "attachment:<name>" references. When nil, the
+	// parts are replaced by a placeholder and dropped, so base64 data never
+	// reaches span attributes.
+	Attachments AttachmentHandler
+
+	// MaxAttachmentSize is the largest decoded content part, in bytes,
+	// handed to Attachments; larger parts are dropped. Zero means
+	// DefaultMaxAttachmentSize.
+	MaxAttachmentSize int
+
+	// HideInputs and HideOutputs omit gen_ai.prompt and gen_ai.completion
+	// from spans. Setting LANGSMITH_HIDE_INPUTS or LANGSMITH_HIDE_OUTPUTS to
+	// "true" has the same effect for every client.
+	HideInputs  bool
+	HideOutputs bool
+
+	// Redact, when set, rewrites gen_ai.prompt and gen_ai.completion before
+	// they are recorded.
+	Redact Redactor
+
+	// MaxContentLength, when positive, truncates each string in
+	// gen_ai.prompt and gen_ai.completion (message contents, tool
+	// arguments) to this many bytes.
+	MaxContentLength int
+
+	// Pricing, when set, prices each call's token usage on the client and
+	// adds input_cost, output_cost and total_cost to its usage_metadata, for
+	// LangSmith deployments that have no price for the model.
+	Pricing *pricing.Table
+}
+
+// Next passes an HTTP request to the next stage in a middleware chain.
+type Next func(*http.Request) (*http.Response, error)
+
+// WrapClient wraps an existing http.Client so requests matched by p are traced.
+// If client is nil, a new client with the default transport is created.
+func WrapClient(client *http.Client, p Provider, cfg Config) *http.Client {
+	if client == nil {
+		client = &http.Client{}
+	}
+	client.Transport = NewRoundTripper(client.Transport, p, cfg)
+	return client
+}
+
+// NewRoundTripper returns an http.RoundTripper that traces requests matched
+// by p and forwards everything to base. If base is nil, http.DefaultTransport
+// is used.
+func NewRoundTripper(base http.RoundTripper, p Provider, cfg Config) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &roundTripper{base: base, provider: p, cfg: cfg}
+}
+
+type roundTripper struct {
+	base     http.RoundTripper
+	provider Provider
+	cfg      Config
+}
+
+// RoundTrip intercepts requests/responses to add OpenTelemetry tracing.
+func (rt *roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	return Middleware(req, rt.base.RoundTrip, rt.provider, rt.cfg)
+}
+
+// Middleware traces req when p matches it and then calls next to make the
+// actual HTTP request. Unmatched requests are passed to next unchanged.
+//
+// The returned response body streams through to the caller; the span ends
+// when the body reaches EOF, is closed, or a read fails.
+func Middleware(req *http.Request, next Next, p Provider, cfg Config) (*http.Response, error) {
+	if req == nil || req.URL == nil || !p.Match(req) {
+		return next(req)
+	}
+
+	ctx := req.Context()
+	var tracer trace.Tracer
+	if cfg.TracerProvider != nil {
+		tracer = cfg.TracerProvider.Tracer(p.TracerName())
+	} else {
+		tracer = otel.Tracer(p.TracerName())
+	}
+
+	// Extract span context from request headers
+	ctx = otel.GetTextMapPropagator().Extract(ctx, propagation.HeaderCarrier(req.Header))
+
+	// Capture parent span before creating child span (for token propagation)
+	parentSpan := trace.SpanFromContext(ctx)
+
+	// Read request body if present
+	var requestBody []byte
+	var bodyErr error
+	if req.Body != nil {
+		requestBody, bodyErr = io.ReadAll(req.Body)
+		if bodyErr == nil {
+			req.Body = io.NopCloser(bytes.NewBuffer(requestBody))
+		}
+	}
+
+	call := p.ParseRequest(req, requestBody)
+
+	spanName := call.SpanName
+	if s := RunNameFromContext(ctx); s != "" {
+		spanName = s
+	} else if cfg.RunName != "" {
+		spanName = cfg.RunName
+	}
+
+	attrs := append([]attribute.KeyValue{}, call.Attributes...)
+	if cfg.ProviderName != "" {
+		attrs = overrideProviderName(attrs, cfg.ProviderName)
+	}
+	attrs = append(attrs,
+		genaiattr.HTTPMethodKey.String(req.Method),
+		genaiattr.HTTPURLKey.String(RedactURL(req.URL)),
+	)
+	attrs = append(attrs, thread.Attributes(ctx)...)
+	attrs = append(attrs, fallbackAttributes(ctx)...)
+
+	// A retry of an attempt that failed continues that attempt's run.
+	var run *retryState
+	key := newRetryKey(tracer, parentSpan.SpanContext(), req, requestBody)
+	if bodyErr == nil {
+		run = claimRetry(key, req.Header)
+	}
+	var span trace.Span
+	if run != nil {
+		ctx, span, parentSpan = run.ctx, run.span, run.parent
+	} else {
+		// Start span (child span), linked to the tool runs whose results the
+		// prompt returns to the model.
+		ctx, span = tracer.Start(ctx, spanName,
+			trace.WithAttributes(attrs...),
+			trace.WithLinks(toolResultLinks(call.Prompt)...),
+		)
+
+		if bodyErr != nil {
+			span.RecordError(bodyErr)
+			span.SetStatus(codes.Error, fmt.Sprintf("failed to read request body: %v", bodyErr))
+			span.End()
+			return next(req)
+		}
+
+		prompt, attachments := extractAttachments(call.Prompt, "input", cfg)
+		if prompt = cfg.Content(genaiattr.PromptKey, prompt); prompt != "" {
+			span.SetAttributes(genaiattr.PromptKey.String(prompt))
+		}
+		run = &retryState{
+			key:         key,
+			tracer:      tracer,
+			ctx:         ctx,
+			span:        span,
+			parent:      parentSpan,
+			attachments: attachments,
+			onEnd:       cfg.Attachments,
+			attempts:    1,
+			start:       time.Now(),
+		}
+	}
+
+	// Inject span context into request headers and update request context
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+	req = req.WithContext(ctx)
+
+	resp, err := next(req)
+	if err != nil {
+		if retryable(nil, err) && willRetry(req.Header) {
+			run.fail(nil, err)
+			return resp, err
+		}
+		run.done(nil, err)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		run.end()
+		return resp, err
+	}
+
+	span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+	if hp, ok := p.(ResponseHeaderParser); ok {
+		hp.ParseResponseHeader(call, resp.Header)
+	}
+
+	var stream Stream
+	if call.Streaming {
+		stream = p.NewStream(call)
+	}
+
+	br := traceutil.NewBufferedReader(resp.Body, func(r io.Reader, readErr error) {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			run.done(resp, err)
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			run.end()
+			return
+		}
+
+		var httpErr error
+		if resp.StatusCode >= 400 {
+			httpErr = httpError(resp.StatusCode, data)
+			if retryable(resp, nil) && willRetry(req.Header) {
+				run.fail(resp, httpErr)
+				return
+			}
+		}
+		run.done(resp, httpErr)
+
+		if len(data) == 0 {
+			if httpErr != nil {
+				recordError(span, httpErr)
+			}
+			// readErr is the error that ended the read (e.g. context.Canceled); record it so run has real error
+			if readErr != nil && readErr != io.EOF {
+				recordError(span, readErr)
+			}
+			run.end()
+			return
+		}
+
+		if httpErr != nil {
+			// Record an error so LangSmith shows the run as failed and populates run.error
+			recordError(span, httpErr)
+		}
+
+		var out *Response
+		var incompleteStream bool
+		if stream != nil {
+			var complete bool
+			out, complete = ParseStream(stream, data, readErr)
+			// Early stream termination: use real error from read (e.g.
+			// context.Canceled) or a synthetic "Cancelled".
+			if resp.StatusCode < 400 && !complete {
+				incompleteStream = true
+				endErr := readErr
+				if endErr == nil || endErr == io.EOF {
+					endErr = fmt.Errorf("Cancelled")
+				}
+				recordError(span, endErr)
+			}
+		} else {
+			out = p.ParseResponse(call, data)
+		}
+
+		var inBandErr bool
+		if out != nil && out.Err != nil {
+			inBandErr = true
+			recordError(span, out.Err)
+		}
+
+		if out != nil {
+			var outAttachments map[string]Attachment
+			out.Completion, outAttachments = extractAttachments(out.Completion, "output", cfg)
+			out.Completion = cfg.Content(genaiattr.CompletionKey, out.Completion)
+			for name, a := range outAttachments {
+				if run.attachments == nil {
+					run.attachments = make(map[string]Attachment)
+				}
+				run.attachments[name] = a
+			}
+		}
+
+		if out != nil && cfg.Pricing != nil {
+			recordCost(span, out, call, cfg.Pricing)
+		}
+		RecordResponse(span, out, parentSpan)
+		if resp.StatusCode < 400 && !incompleteStream && !inBandErr {
+			span.SetStatus(codes.Ok, "")
+		}
+		run.end()
+	})
+	// LangSmith ingest reads new_token to derive first_token_time; skip on
+	// HTTP errors so an error body doesn't inflate it.
+	if stream != nil && resp.StatusCode < 400 {
+		newToken := func() { span.AddEvent("new_token") }
+		if d, ok := stream.(Decoder); ok {
+			newScanner := func(onEvent func(map[string]any)) traceutil.Scanner { return d.NewScanner(onEvent) }
+			traceutil.OnFirstMatch(br, newScanner, stream.IsFirstToken, newToken)
+		} else {
+			traceutil.OnFirstSSEMatch(br, stream.IsFirstToken, newToken)
+		}
+	}
+	resp.Body = br
+
+	return resp, nil
+}
+
+// ParseStream decodes a buffered stream body (SSE unless s implements
+// Decoder), folds each event into s and returns the accumulated response.
+// The response is nil when the body holds no events or is malformed;
+// complete is reported regardless.
+func ParseStream(s Stream, data []byte, readErr error) (resp *Response, complete bool) {
+	var events int
+	var err error
+	if d, ok := s.(Decoder); ok {
+		d.NewScanner(func(event map[string]any) {
+			events++
+			s.Chunk(event)
+		}).Feed(data)
+	} else {
+		var chunks []map[string]any
+		chunks, err = traceutil.ParseSSEChunks(bytes.NewReader(data))
+		if err == nil {
+			for _, chunk := range chunks {
+				s.Chunk(chunk)
+			}
+		}
+		events = len(chunks)
+	}
+	resp, complete = s.Finish(data, readErr)
+	if err != nil || events == 0 {
+		resp = nil
+	}
+	return resp, complete
+}
+
+// RecordResponse sets response model, id, completion, stop reason and usage
+// attributes on span. A nil resp is a no-op.
+func RecordResponse(span trace.Span, resp *Response, parentSpan trace.Span) {
+	if resp == nil {
+		return
+	}
+	if resp.Model != "" {
+		span.SetAttributes(attribute.String("gen_ai.response.model", resp.Model))
+	}
+	if resp.ID != "" {
+		span.SetAttributes(attribute.String("gen_ai.response.id", resp.ID))
+	}
+	if resp.Completion != "" {
+		span.SetAttributes(genaiattr.CompletionKey.String(resp.Completion))
+	}
+	if resp.StopReason != "" {
+		span.SetAttributes(genaiattr.StopReasonKey.String(resp.StopReason))
+	}
+	if len(resp.Attributes) > 0 {
+		span.SetAttributes(resp.Attributes...)
+	}
+	if resp.Usage != nil {
+		RecordUsage(span, resp.Usage, parentSpan)
+	}
+}
+
+// RecordUsage records token usage on span. It emits a single
+// langsmith.usage_metadata JSON attribute (the converter's preferred,
+// cost-driving path) plus the flat gen_ai.usage.* attributes that
+// Thread-list aggregation reads from root spans.
+func RecordUsage(span trace.Span, usage *Usage, parentSpan trace.Span) {
+	if len(usage.Metadata) > 0 {
+		if out, err := json.Marshal(usage.Metadata); err == nil {
+			span.SetAttributes(genaiattr.UsageMetadataKey.String(string(out)))
+		}
+	}
+
+	// Flat gen_ai.usage.* attributes. Propagating input/output to the parent
+	// is load-bearing: the root span has no usage_metadata of its own, so the
+	// converter's fallback path turns these into the root run's token totals,
+	// which Thread-list stats aggregate.
+	setSelfAndParent := func(kv attribute.KeyValue) {
+		span.SetAttributes(kv)
+		if parentSpan != nil && parentSpan.SpanContext().IsValid() && parentSpan.IsRecording() {
+			parentSpan.SetAttributes(kv)
+		}
+	}
+	if usage.InputTokens > 0 {
+		setSelfAndParent(genaiattr.UsageInputTokensKey.Int64(usage.InputTokens))
+	}
+	if usage.OutputTokens > 0 {
+		setSelfAndParent(genaiattr.UsageOutputTokensKey.Int64(usage.OutputTokens))
+	}
+	if usage.TotalTokens > 0 {
+		span.SetAttributes(genaiattr.UsageTotalTokensKey.Int64(usage.TotalTokens))
+	}
+	if len(usage.Attributes) > 0 {
+		span.SetAttributes(usage.Attributes...)
+	}
+}
+
+// httpError describes an HTTP error response, quoting the start of its body.
+func httpError(status int, body []byte) error {
+	if len(body) == 0 {
+		return fmt.Errorf("HTTP %d", status)
+	}
+	msg := string(body)
+	if len(msg) > maxErrorBodyLen {
+		msg = msg[:maxErrorBodyLen] + "..."
+	}
+	return fmt.Errorf("HTTP %d: %s", status, msg)
+}
+
+func recordError(span trace.Span, err error) {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// overrideProviderName replaces the provider-name attributes in attrs with
+// name, adding gen_ai.provider.name when the provider recorded neither key.
+func overrideProviderName(attrs []attribute.KeyValue, name string) []attribute.KeyValue {
+	var found bool
+	for i, kv := range attrs {
+		if kv.Key == semconv.GenAIProviderNameKey || kv.Key == genAISystemKey {
+			attrs[i] = kv.Key.String(name)
+			found = true
+		}
+	}
+	if !found {
+		attrs = append(attrs, semconv.GenAIProviderNameKey.String(name))
+	}
+	return attrs
+}
+
+// redactedQueryParams are query parameters that carry credentials. Gemini
+// accepts its API key as ?key=, Azure OpenAI as ?api-key= on some routes.
+var redactedQueryParams = []string{"key", "api-key", "api_key", "access_token"}
+
+// RedactURL returns u as a string with credential query parameters replaced
+// by REDACTED.
+func RedactURL(u *url.URL) string {
+	q := u.Query()
+	var redact bool
+	for _, k := range redactedQueryParams {
+		if q.Has(k) {
+			q.Set(k, "REDACTED")
+			redact = true
+		}
+	}
+	if !redact {
+		return u.String()
+	}
+	redacted := *u
+	redacted.RawQuery = q.Encode()
+	return redacted.String()
+}
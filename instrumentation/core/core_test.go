@@ -0,0 +1,421 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// fakeProvider traces any /v1/fake path. Requests are {"stream":bool,"prompt":string};
+// responses are {"text":string,"in":n,"out":n,"fail":string}; stream events are
+// {"delta":string} terminated by {"done":true}.
+type fakeProvider struct{}
+
+func (fakeProvider) TracerName() string { return "fake" }
+
+func (fakeProvider) Match(req *http.Request) bool { return strings.HasPrefix(req.URL.Path, "/v1/fake") }
+
+func (fakeProvider) ParseRequest(req *http.Request, body []byte) *Request {
+	call := &Request{
+		SpanName:   "fake.call",
+		Attributes: []attribute.KeyValue{attribute.String("gen_ai.system", "fake")},
+	}
+	var in struct {
+		Stream bool   `json:"stream"`
+		Prompt string `json:"prompt"`
+	}
+	if json.Unmarshal(body, &in) == nil {
+		call.Streaming = in.Stream
+		call.Prompt = in.Prompt
+	}
+	return call
+}
+
+func (fakeProvider) ParseResponse(_ *Request, body []byte) *Response {
+	var out map[string]any
+	if err := json.Unmarshal(body, &out); err != nil {
+		return nil
+	}
+	text, _ := out["text"].(string)
+	model, _ := out["model"].(string)
+	resp := &Response{Model: model, Completion: text, Usage: fakeUsage(out)}
+	if msg, ok := out["fail"].(string); ok {
+		resp.Err = errors.New(msg)
+	}
+	return resp
+}
+
+func (fakeProvider) NewStream(*Request) Stream { return &fakeStream{} }
+
+func fakeUsage(m map[string]any) *Usage {
+	in, out := Int(m, "in"), Int(m, "out")
+	if in == 0 && out == 0 {
+		return nil
+	}
+	return &Usage{
+		InputTokens:  in,
+		OutputTokens: out,
+		TotalTokens:  in + out,
+		Metadata:     UsageMetadata(in, out, in+out, nil, nil),
+	}
+}
+
+type fakeStream struct {
+	text strings.Builder
+	done bool
+}
+
+func (s *fakeStream) IsFirstToken(chunk map[string]any) bool {
+	d, _ := chunk["delta"].(string)
+	return d != ""
+}
+
+func (s *fakeStream) Chunk(chunk map[string]any) {
+	if d, ok := chunk["delta"].(string); ok {
+		s.text.WriteString(d)
+	}
+	if done, _ := chunk["done"].(bool); done {
+		s.done = true
+	}
+}
+
+func (s *fakeStream) Finish([]byte, error) (*Response, bool) {
+	return &Response{Completion: s.text.String()}, s.done
+}
+
+type staticTransport struct {
+	status int
+	body   string
+	err    error
+	seen   *http.Request
+}
+
+func (t *staticTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.seen = req
+	if t.err != nil {
+		return nil, t.err
+	}
+	return &http.Response{
+		StatusCode: t.status,
+		Body:       io.NopCloser(strings.NewReader(t.body)),
+		Header:     make(http.Header),
+		Request:    req,
+	}, nil
+}
+
+func newTestClient(t *testing.T, base http.RoundTripper, cfg Config) (*http.Client, *tracetest.InMemoryExporter) {
+	t.Helper()
+	exporter := tracetest.NewInMemoryExporter()
+	cfg.TracerProvider = sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	return WrapClient(&http.Client{Transport: base}, fakeProvider{}, cfg), exporter
+}
+
+func doRequest(t *testing.T, client *http.Client, ctx context.Context, target, body string) error {
+	t.Helper()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, err = io.ReadAll(resp.Body)
+	return err
+}
+
+func attr(s tracetest.SpanStub, key string) (attribute.Value, bool) {
+	for _, kv := range s.Attributes {
+		if string(kv.Key) == key {
+			return kv.Value, true
+		}
+	}
+	return attribute.Value{}, false
+}
+
+func onlySpan(t *testing.T, exporter *tracetest.InMemoryExporter) tracetest.SpanStub {
+	t.Helper()
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	return spans[0]
+}
+
+func TestMiddleware_NonStreaming(t *testing.T) {
+	client, exporter := newTestClient(t, &staticTransport{status: 200, body: `{"text":"hi","in":3,"out":2}`}, Config{})
+	if err := doRequest(t, client, context.Background(), "https://api.example.com/v1/fake", `{"prompt":"p"}`); err != nil {
+		t.Fatal(err)
+	}
+	span := onlySpan(t, exporter)
+	if span.Name != "fake.call" {
+		t.Errorf("span name = %q, want fake.call", span.Name)
+	}
+	if span.Status.Code != codes.Ok {
+		t.Errorf("status = %v, want Ok", span.Status.Code)
+	}
+	if v, _ := attr(span, "gen_ai.prompt"); v.AsString() != "p" {
+		t.Errorf("gen_ai.prompt = %q", v.AsString())
+	}
+	if v, _ := attr(span, "gen_ai.completion"); v.AsString() != "hi" {
+		t.Errorf("gen_ai.completion = %q", v.AsString())
+	}
+	if v, _ := attr(span, "gen_ai.usage.total_tokens"); v.AsInt64() != 5 {
+		t.Errorf("gen_ai.usage.total_tokens = %d, want 5", v.AsInt64())
+	}
+	if v, _ := attr(span, "langsmith.usage_metadata"); v.AsString() != `{"input_tokens":3,"output_tokens":2,"total_tokens":5}` {
+		t.Errorf("langsmith.usage_metadata = %s", v.AsString())
+	}
+}
+
+func TestMiddleware_UnmatchedPassesThrough(t *testing.T) {
+	base := &staticTransport{status: 200, body: `{}`}
+	client, exporter := newTestClient(t, base, Config{})
+	if err := doRequest(t, client, context.Background(), "https://api.example.com/health", ``); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(exporter.GetSpans()); n != 0 {
+		t.Fatalf("got %d spans, want 0", n)
+	}
+	if base.seen == nil {
+		t.Fatal("unmatched request was not forwarded")
+	}
+}
+
+func TestMiddleware_RunNamePrecedence(t *testing.T) {
+	client, exporter := newTestClient(t, &staticTransport{status: 200, body: `{}`}, Config{RunName: "from_config"})
+
+	if err := doRequest(t, client, context.Background(), "https://x/v1/fake", `{}`); err != nil {
+		t.Fatal(err)
+	}
+	ctx := WithRunNameContext(context.Background(), "from_context")
+	if err := doRequest(t, client, ctx, "https://x/v1/fake", `{}`); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(spans))
+	}
+	if spans[0].Name != "from_config" {
+		t.Errorf("span 0 name = %q, want from_config", spans[0].Name)
+	}
+	if spans[1].Name != "from_context" {
+		t.Errorf("span 1 name = %q, want from_context", spans[1].Name)
+	}
+}
+
+func TestMiddleware_ProviderNameOverride(t *testing.T) {
+	client, exporter := newTestClient(t, &staticTransport{status: 200, body: `{}`}, Config{ProviderName: "vllm"})
+	if err := doRequest(t, client, context.Background(), "https://x/v1/fake", `{}`); err != nil {
+		t.Fatal(err)
+	}
+	span := onlySpan(t, exporter)
+	if v, _ := attr(span, "gen_ai.system"); v.AsString() != "vllm" {
+		t.Errorf("gen_ai.system = %q, want vllm", v.AsString())
+	}
+	if _, ok := attr(span, "gen_ai.provider.name"); ok {
+		t.Error("gen_ai.provider.name should only be added when the provider records no name")
+	}
+}
+
+func TestOverrideProviderName_AddsWhenMissing(t *testing.T) {
+	attrs := overrideProviderName([]attribute.KeyValue{attribute.String("gen_ai.request.model", "m")}, "llamacpp")
+	if len(attrs) != 2 || attrs[1] != attribute.String("gen_ai.provider.name", "llamacpp") {
+		t.Errorf("attrs = %v", attrs)
+	}
+}
+
+func TestMiddleware_RedactsCredentialQueryParams(t *testing.T) {
+	client, exporter := newTestClient(t, &staticTransport{status: 200, body: `{}`}, Config{})
+	if err := doRequest(t, client, context.Background(), "https://x/v1/fake?key=secret&api-key=secret2&alt=sse", `{}`); err != nil {
+		t.Fatal(err)
+	}
+	v, _ := attr(onlySpan(t, exporter), "http.url")
+	if strings.Contains(v.AsString(), "secret") {
+		t.Errorf("http.url leaks credential: %q", v.AsString())
+	}
+	if !strings.Contains(v.AsString(), "alt=sse") {
+		t.Errorf("http.url dropped non-credential params: %q", v.AsString())
+	}
+}
+
+func TestMiddleware_ThreadBaggage(t *testing.T) {
+	client, exporter := newTestClient(t, &staticTransport{status: 200, body: `{}`}, Config{})
+	m, _ := baggage.NewMember("thread_id", "t-1")
+	bag, _ := baggage.New(m)
+	ctx := baggage.ContextWithBaggage(context.Background(), bag)
+	if err := doRequest(t, client, ctx, "https://x/v1/fake", `{}`); err != nil {
+		t.Fatal(err)
+	}
+	span := onlySpan(t, exporter)
+	for _, key := range []string{"thread_id", "langsmith.metadata.thread_id", "thread.id"} {
+		if v, ok := attr(span, key); !ok || v.AsString() != "t-1" {
+			t.Errorf("%s = %q, want t-1", key, v.AsString())
+		}
+	}
+}
+
+func TestMiddleware_HTTPErrorTruncatesBody(t *testing.T) {
+	body := `{"error":"` + strings.Repeat("x", 1000) + `"}`
+	client, exporter := newTestClient(t, &staticTransport{status: 429, body: body}, Config{})
+	if err := doRequest(t, client, context.Background(), "https://x/v1/fake", `{}`); err != nil {
+		t.Fatal(err)
+	}
+	span := onlySpan(t, exporter)
+	if span.Status.Code != codes.Error {
+		t.Fatalf("status = %v, want Error", span.Status.Code)
+	}
+	if !strings.HasPrefix(span.Status.Description, "HTTP 429: ") || !strings.HasSuffix(span.Status.Description, "...") {
+		t.Errorf("status description = %q", span.Status.Description)
+	}
+	if len(span.Status.Description) > maxErrorBodyLen+20 {
+		t.Errorf("status description not truncated: %d bytes", len(span.Status.Description))
+	}
+}
+
+func TestMiddleware_InBandErrorMarksSpanFailed(t *testing.T) {
+	client, exporter := newTestClient(t, &staticTransport{status: 200, body: `{"text":"partial","fail":"model error"}`}, Config{})
+	if err := doRequest(t, client, context.Background(), "https://x/v1/fake", `{}`); err != nil {
+		t.Fatal(err)
+	}
+	span := onlySpan(t, exporter)
+	if span.Status.Code != codes.Error || span.Status.Description != "model error" {
+		t.Errorf("status = %v, want Error(model error)", span.Status)
+	}
+	if v, _ := attr(span, "gen_ai.completion"); v.AsString() != "partial" {
+		t.Errorf("gen_ai.completion = %q, want partial output kept", v.AsString())
+	}
+}
+
+func TestMiddleware_TransportError(t *testing.T) {
+	client, exporter := newTestClient(t, &staticTransport{err: errors.New("dial failed")}, Config{})
+	if err := doRequest(t, client, context.Background(), "https://x/v1/fake", `{}`); err == nil {
+		t.Fatal("expected transport error")
+	}
+	span := onlySpan(t, exporter)
+	if span.Status.Code != codes.Error || !strings.Contains(span.Status.Description, "dial failed") {
+		t.Errorf("status = %v %q", span.Status.Code, span.Status.Description)
+	}
+}
+
+func TestMiddleware_StreamingFirstTokenAndCompletion(t *testing.T) {
+	sse := "data: {\"meta\":1}\n\ndata: {\"delta\":\"he\"}\n\ndata: {\"delta\":\"llo\"}\n\ndata: {\"done\":true}\n\n"
+	client, exporter := newTestClient(t, &staticTransport{status: 200, body: sse}, Config{})
+	if err := doRequest(t, client, context.Background(), "https://x/v1/fake", `{"stream":true}`); err != nil {
+		t.Fatal(err)
+	}
+	span := onlySpan(t, exporter)
+	if span.Status.Code != codes.Ok {
+		t.Errorf("status = %v %q, want Ok", span.Status.Code, span.Status.Description)
+	}
+	if v, _ := attr(span, "gen_ai.completion"); v.AsString() != "hello" {
+		t.Errorf("gen_ai.completion = %q, want hello", v.AsString())
+	}
+	var newTokens int
+	for _, e := range span.Events {
+		if e.Name == "new_token" {
+			newTokens++
+		}
+	}
+	if newTokens != 1 {
+		t.Errorf("got %d new_token events, want 1", newTokens)
+	}
+}
+
+func TestMiddleware_StreamClosedEarlyIsCancelled(t *testing.T) {
+	sse := "data: {\"delta\":\"he\"}\n\ndata: {\"delta\":\"llo\"}\n\n"
+	client, exporter := newTestClient(t, &staticTransport{status: 200, body: sse}, Config{})
+	if err := doRequest(t, client, context.Background(), "https://x/v1/fake", `{"stream":true}`); err != nil {
+		t.Fatal(err)
+	}
+	span := onlySpan(t, exporter)
+	if span.Status.Code != codes.Error || span.Status.Description != "Cancelled" {
+		t.Errorf("status = %v %q, want Error Cancelled", span.Status.Code, span.Status.Description)
+	}
+	// Partial output is still recorded.
+	if v, _ := attr(span, "gen_ai.completion"); v.AsString() != "hello" {
+		t.Errorf("gen_ai.completion = %q, want hello", v.AsString())
+	}
+}
+
+func TestMiddleware_PropagatesUsageToParent(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	client := WrapClient(&http.Client{Transport: &staticTransport{status: 200, body: `{"in":7,"out":4}`}}, fakeProvider{}, Config{TracerProvider: tp})
+
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "parent")
+	if err := doRequest(t, client, ctx, "https://x/v1/fake", `{}`); err != nil {
+		t.Fatal(err)
+	}
+	parent.End()
+
+	var found bool
+	for _, s := range exporter.GetSpans() {
+		if s.Name != "parent" {
+			continue
+		}
+		found = true
+		if v, _ := attr(s, "gen_ai.usage.input_tokens"); v.AsInt64() != 7 {
+			t.Errorf("parent input_tokens = %d, want 7", v.AsInt64())
+		}
+		if v, _ := attr(s, "gen_ai.usage.output_tokens"); v.AsInt64() != 4 {
+			t.Errorf("parent output_tokens = %d, want 4", v.AsInt64())
+		}
+	}
+	if !found {
+		t.Fatal("parent span not exported")
+	}
+}
+
+func TestMiddleware_RequestBodyRestored(t *testing.T) {
+	base := &staticTransport{status: 200, body: `{}`}
+	client, _ := newTestClient(t, base, Config{})
+	if err := doRequest(t, client, context.Background(), "https://x/v1/fake", `{"prompt":"keep me"}`); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := io.ReadAll(base.seen.Body)
+	if !bytes.Equal(got, []byte(`{"prompt":"keep me"}`)) {
+		t.Errorf("upstream body = %q", got)
+	}
+}
+
+func TestRedactURL(t *testing.T) {
+	u, _ := url.Parse("https://generativelanguage.googleapis.com/v1beta/models/m:generateContent?key=abc")
+	if got := RedactURL(u); strings.Contains(got, "abc") || !strings.Contains(got, "key=REDACTED") {
+		t.Errorf("RedactURL = %q", got)
+	}
+	u, _ = url.Parse("https://api.openai.com/v1/chat/completions")
+	if got := RedactURL(u); got != u.String() {
+		t.Errorf("RedactURL changed URL without credentials: %q", got)
+	}
+}
+
+func TestUsageMetadata_OmitsZeroes(t *testing.T) {
+	if um := UsageMetadata(0, 0, 0, map[string]any{}, nil); len(um) != 0 {
+		t.Errorf("expected empty usage_metadata, got %v", um)
+	}
+	um := UsageMetadata(10, 0, 10, map[string]any{"cache_read": int64(4)}, nil)
+	if um["input_tokens"] != int64(10) || um["total_tokens"] != int64(10) {
+		t.Errorf("totals = %v", um)
+	}
+	if _, ok := um["output_tokens"]; ok {
+		t.Error("zero output_tokens should be omitted")
+	}
+	if _, ok := um["input_token_details"]; !ok {
+		t.Error("expected input_token_details")
+	}
+}
@@ -0,0 +1,55 @@
+package core
+
+import (
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/langchain-ai/langsmith-go/instrumentation/pricing"
+	"github.com/langchain-ai/langsmith-go/internal/genaiattr"
+)
+
+// recordCost prices resp's usage with prices and adds the costs to its
+// usage_metadata, which LangSmith reads as the run's prompt_cost,
+// completion_cost and total_cost. The response model is priced, falling back
+// to the request model; calls whose model is not in the table are left
+// unpriced.
+func recordCost(span trace.Span, resp *Response, call *Request, prices *pricing.Table) {
+	if resp.Usage == nil {
+		return
+	}
+	model := resp.Model
+	if model == "" {
+		model = requestModel(call.Attributes)
+	}
+	usage := resp.Usage
+	if len(usage.Metadata) == 0 {
+		usage.Metadata = UsageMetadata(usage.InputTokens, usage.OutputTokens, usage.TotalTokens, nil, nil)
+	}
+	cost, ok := prices.Cost(model, usage.Metadata)
+	if !ok {
+		return
+	}
+	usage.Metadata["input_cost"] = cost.Input
+	usage.Metadata["output_cost"] = cost.Output
+	usage.Metadata["total_cost"] = cost.Total()
+	if len(cost.InputDetails) > 0 {
+		usage.Metadata["input_cost_details"] = cost.InputDetails
+	}
+	if len(cost.OutputDetails) > 0 {
+		usage.Metadata["output_cost_details"] = cost.OutputDetails
+	}
+	if prices.Version != "" {
+		span.SetAttributes(genaiattr.PriceTableVersionKey.String(prices.Version))
+	}
+}
+
+// requestModel returns the gen_ai.request.model value in attrs.
+func requestModel(attrs []attribute.KeyValue) string {
+	for _, kv := range attrs {
+		if kv.Key == semconv.GenAIRequestModelKey {
+			return kv.Value.AsString()
+		}
+	}
+	return ""
+}
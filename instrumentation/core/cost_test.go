@@ -0,0 +1,53 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/langchain-ai/langsmith-go/instrumentation/pricing"
+)
+
+func TestMiddleware_PricingAddsCosts(t *testing.T) {
+	prices := &pricing.Table{Version: "test-1", Models: map[string]pricing.Price{
+		"fake-model": {Input: 1, Output: 2},
+	}}
+	client, exporter := newTestClient(t,
+		&staticTransport{status: 200, body: `{"text":"hi","model":"fake-model-001","in":1000000,"out":500000}`},
+		Config{Pricing: prices})
+	if err := doRequest(t, client, context.Background(), "https://api.example.com/v1/fake", `{"prompt":"p"}`); err != nil {
+		t.Fatal(err)
+	}
+	span := onlySpan(t, exporter)
+
+	v, _ := attr(span, "langsmith.usage_metadata")
+	var um map[string]float64
+	if err := json.Unmarshal([]byte(v.AsString()), &um); err != nil {
+		t.Fatalf("usage_metadata %q: %v", v.AsString(), err)
+	}
+	for key, want := range map[string]float64{"input_cost": 1, "output_cost": 1, "total_cost": 2} {
+		if math.Abs(um[key]-want) > 1e-9 {
+			t.Errorf("%s = %v, want %v", key, um[key], want)
+		}
+	}
+	if v, _ := attr(span, "langsmith.metadata.price_table_version"); v.AsString() != "test-1" {
+		t.Errorf("price_table_version = %q, want test-1", v.AsString())
+	}
+}
+
+func TestMiddleware_PricingSkipsUnknownModel(t *testing.T) {
+	client, exporter := newTestClient(t,
+		&staticTransport{status: 200, body: `{"text":"hi","model":"other","in":3,"out":2}`},
+		Config{Pricing: pricing.Default()})
+	if err := doRequest(t, client, context.Background(), "https://api.example.com/v1/fake", `{"prompt":"p"}`); err != nil {
+		t.Fatal(err)
+	}
+	span := onlySpan(t, exporter)
+	if v, _ := attr(span, "langsmith.usage_metadata"); v.AsString() != `{"input_tokens":3,"output_tokens":2,"total_tokens":5}` {
+		t.Errorf("langsmith.usage_metadata = %s", v.AsString())
+	}
+	if _, ok := attr(span, "langsmith.metadata.price_table_version"); ok {
+		t.Error("price_table_version recorded for an unpriced model")
+	}
+}
@@ -0,0 +1,38 @@
+package core
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/langchain-ai/langsmith-go/internal/genaiattr"
+)
+
+var ctxKeyFallback = contextKey{"fallback"}
+
+type fallback struct {
+	index int
+	from  string
+}
+
+// WithFallbackContext marks model calls made with ctx as made by the
+// candidate at index in a fallback chain, after the candidate named from
+// failed. Traced calls record both as run metadata, so fallback traffic can
+// be told apart from primary traffic.
+func WithFallbackContext(ctx context.Context, index int, from string) context.Context {
+	return context.WithValue(ctx, ctxKeyFallback, fallback{index: index, from: from})
+}
+
+// fallbackAttributes returns the fallback metadata set on ctx by
+// WithFallbackContext.
+func fallbackAttributes(ctx context.Context) []attribute.KeyValue {
+	fb, ok := ctx.Value(ctxKeyFallback).(fallback)
+	if !ok {
+		return nil
+	}
+	attrs := []attribute.KeyValue{genaiattr.FallbackIndexKey.Int(fb.index)}
+	if fb.from != "" {
+		attrs = append(attrs, genaiattr.FallbackFromKey.String(fb.from))
+	}
+	return attrs
+}
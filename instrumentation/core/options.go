@@ -0,0 +1,63 @@
+package core
+
+import "github.com/langchain-ai/langsmith-go/instrumentation/pricing"
+
+// Option sets a field of a Config. The provider packages re-export these
+// options as their own Option type.
+type Option func(*Config)
+
+// WithCaptureContent controls whether prompts and completions are recorded
+// on spans. Pass false for workloads whose model inputs and outputs must not
+// leave the process; token usage, model and timing are still recorded. The
+// LANGSMITH_HIDE_INPUTS and LANGSMITH_HIDE_OUTPUTS environment variables
+// hide each side regardless of this option.
+func WithCaptureContent(capture bool) Option {
+	return func(cfg *Config) {
+		cfg.HideInputs = !capture
+		cfg.HideOutputs = !capture
+	}
+}
+
+// WithRedactor sets a function that rewrites each prompt and completion
+// before it is recorded, e.g. to mask personal data.
+func WithRedactor(r Redactor) Option {
+	return func(cfg *Config) {
+		cfg.Redact = r
+	}
+}
+
+// WithMaxContentLength truncates each string in recorded prompts and
+// completions (message contents, tool arguments) to n bytes.
+func WithMaxContentLength(n int) Option {
+	return func(cfg *Config) {
+		cfg.MaxContentLength = n
+	}
+}
+
+// WithAttachments sets the handler that receives images, audio and files
+// sent or returned as base64 content parts. They are replaced in the
+// recorded prompt and completion by "attachment:<name>" references. Without
+// a handler the parts are omitted from the span.
+func WithAttachments(h AttachmentHandler) Option {
+	return func(cfg *Config) {
+		cfg.Attachments = h
+	}
+}
+
+// WithMaxAttachmentSize sets the largest content part, in bytes, passed to
+// the attachment handler; larger parts are omitted. The default is
+// DefaultMaxAttachmentSize.
+func WithMaxAttachmentSize(n int) Option {
+	return func(cfg *Config) {
+		cfg.MaxAttachmentSize = n
+	}
+}
+
+// WithPricing prices each call's token usage with prices and records
+// input_cost, output_cost and total_cost in its usage_metadata. Use it when
+// LangSmith has no price for the model, e.g. on self-hosted deployments.
+func WithPricing(prices *pricing.Table) Option {
+	return func(cfg *Config) {
+		cfg.Pricing = prices
+	}
+}
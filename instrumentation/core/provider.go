@@ -0,0 +1,185 @@
+package core
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Provider adapts one LLM API to the shared tracing round tripper. The round
+// tripper owns the span lifecycle (run naming, context propagation, URL
+// redaction, error tagging, first-token events); a Provider only translates
+// the wire format into Request and Response values.
+type Provider interface {
+	// TracerName is the instrumentation scope name, conventionally the import
+	// path of the SDK whose traffic is traced.
+	TracerName() string
+
+	// Match reports whether req targets an endpoint the provider understands.
+	// Requests that don't match pass through untraced.
+	Match(req *http.Request) bool
+
+	// ParseRequest extracts span name, attributes and prompt from an outgoing
+	// request. body is nil when the request body could not be read, in which
+	// case only URL-derived fields should be populated. It must not return nil.
+	ParseRequest(req *http.Request, body []byte) *Request
+
+	// ParseResponse extracts completion and usage from a non-streaming
+	// response body. It may return nil when the body is not understood.
+	ParseResponse(req *Request, body []byte) *Response
+
+	// NewStream returns an accumulator for a streaming response to req.
+	NewStream(req *Request) Stream
+}
+
+// Stream accumulates a streaming response one decoded event at a time.
+type Stream interface {
+	// IsFirstToken reports whether chunk carries generated content. The round
+	// tripper records a new_token event on the first matching chunk, which
+	// LangSmith uses to derive first_token_time.
+	IsFirstToken(chunk map[string]any) bool
+
+	// Chunk folds one decoded stream event into the accumulated response.
+	Chunk(chunk map[string]any)
+
+	// Finish returns the accumulated response and whether the stream reached
+	// its terminal event. data is the full buffered body and readErr the
+	// error that ended the read (io.EOF at end of stream, nil when the
+	// consumer closed the body early).
+	Finish(data []byte, readErr error) (resp *Response, complete bool)
+}
+
+// Decoder is implemented by a Stream whose wire format is not SSE (e.g. AWS
+// event-stream or newline-delimited JSON).
+type Decoder interface {
+	// NewScanner returns an incremental decoder that calls onEvent for each
+	// complete event in the bytes fed to it.
+	NewScanner(onEvent func(map[string]any)) Scanner
+}
+
+// Scanner incrementally decodes a stream body fed to it in arbitrary pieces.
+type Scanner interface {
+	Feed(p []byte)
+}
+
+// ResponseHeaderParser is implemented by a Provider that reads response
+// metadata, such as token counts, from HTTP headers. The round tripper calls
+// it before the body is read; the provider records what it needs in
+// Request.State for ParseResponse or Stream.Finish to use.
+type ResponseHeaderParser interface {
+	ParseResponseHeader(req *Request, header http.Header)
+}
+
+// Request is a provider's view of an outgoing API call.
+type Request struct {
+	// SpanName is the default span (run) name. A name set with
+	// WithRunNameContext or Config.RunName takes precedence.
+	SpanName string
+
+	// Streaming reports whether the response body is a stream that should be
+	// accumulated by the provider's Stream.
+	Streaming bool
+
+	// Attributes are recorded when the span starts, e.g. provider name,
+	// operation name and request model.
+	Attributes []attribute.KeyValue
+
+	// Prompt is the JSON-serialized {"messages":[...]} input recorded as
+	// gen_ai.prompt. Empty when there is nothing to record.
+	Prompt string
+
+	// State carries provider-specific parse state (e.g. the API surface the
+	// request targets) through to ParseResponse and NewStream.
+	State any
+}
+
+// Response is a provider's view of a completed API call.
+type Response struct {
+	// Model is the model that served the request (gen_ai.response.model).
+	Model string
+
+	// ID is the provider's response identifier (gen_ai.response.id).
+	ID string
+
+	// Completion is the JSON-serialized {"messages":[...]} output recorded as
+	// gen_ai.completion.
+	Completion string
+
+	// StopReason is the model's stop/finish reason, recorded as run metadata.
+	StopReason string
+
+	// Usage is the token usage reported by the provider, or nil when the
+	// response carried none.
+	Usage *Usage
+
+	// Attributes are any additional provider-specific span attributes.
+	Attributes []attribute.KeyValue
+
+	// Err is an error the provider reported in-band with a successful HTTP
+	// status, such as an exception event that ends a stream. The span is
+	// marked failed with it.
+	Err error
+}
+
+// Usage is token usage normalized to the LangSmith usage_metadata schema.
+type Usage struct {
+	// InputTokens is the inclusive input total (cached tokens included).
+	InputTokens int64
+
+	// OutputTokens is the inclusive output total (reasoning tokens included).
+	OutputTokens int64
+
+	// TotalTokens is the provider-reported grand total, or input+output when
+	// the provider does not report one.
+	TotalTokens int64
+
+	// Metadata is the LangSmith usage_metadata object, including the
+	// per-dimension token-detail breakdown that drives cost. See UsageMetadata.
+	Metadata map[string]any
+
+	// Attributes are non-token usage fields (service tier, server tool
+	// request counts). They are price modifiers rather than token counts, so
+	// they are recorded as run metadata instead of usage_metadata.
+	Attributes []attribute.KeyValue
+}
+
+// UsageMetadata assembles a LangSmith usage_metadata object, omitting zero
+// totals and empty detail maps. Detail maps hold subsets of the matching
+// total keyed by pricing dimension (cache_read, reasoning, audio, ...).
+func UsageMetadata(input, output, total int64, inputDetails, outputDetails map[string]any) map[string]any {
+	um := map[string]any{}
+	if input > 0 {
+		um["input_tokens"] = input
+	}
+	if output > 0 {
+		um["output_tokens"] = output
+	}
+	if input > 0 || output > 0 {
+		um["total_tokens"] = total
+	}
+	if len(inputDetails) > 0 {
+		um["input_token_details"] = inputDetails
+	}
+	if len(outputDetails) > 0 {
+		um["output_token_details"] = outputDetails
+	}
+	return um
+}
+
+// Int reads a JSON number from m as int64, returning 0 when the key is
+// absent. Providers omit zero-valued usage fields and encoding/json decodes
+// numbers as float64, so a missing field and 0 are equivalent.
+func Int(m map[string]any, key string) int64 {
+	if v, ok := m[key].(float64); ok {
+		return int64(v)
+	}
+	return 0
+}
+
+// Map reads a nested JSON object from m, returning nil when absent.
+func Map(m map[string]any, key string) map[string]any {
+	if v, ok := m[key].(map[string]any); ok {
+		return v
+	}
+	return nil
+}
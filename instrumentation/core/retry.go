@@ -0,0 +1,297 @@
+package core
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/langchain-ai/langsmith-go/internal/genaiattr"
+)
+
+// retryWindow is how long a run whose attempt failed with a retryable error
+// stays open waiting for the SDK's retry, on top of the response's
+// Retry-After delay. A run that is not retried within it is ended as failed,
+// timestamped at the end of its last attempt. Only requests from SDKs that
+// number their retries are kept open; see willRetry.
+var retryWindow = 10 * time.Second
+
+// maxRetryAfter caps the Retry-After delay added to retryWindow. SDKs don't
+// wait longer than this before retrying.
+const maxRetryAfter = time.Minute
+
+// attemptSpanName names the child span recorded for each HTTP attempt of a
+// retried call.
+const attemptSpanName = "attempt"
+
+// retryState tracks the attempts of one logical model call. SDKs retry 429s,
+// 5xx and connection errors by re-sending the same request; rather than a
+// separate run per attempt, a failed attempt's run is parked and continued by
+// the retry, with one attempt child span per HTTP attempt. The run's
+// duration and final status are then those of the call as the caller saw it.
+type retryState struct {
+	key    retryKey
+	tracer trace.Tracer
+	ctx    context.Context // holds span
+	span   trace.Span
+	parent trace.Span // span the token totals propagate to
+
+	// attachments are the call's content parts, handed to onEnd when the
+	// run ends.
+	attachments map[string]Attachment
+	onEnd       AttachmentHandler
+
+	attempts int       // attempts made, including the current one
+	start    time.Time // start of the current attempt
+	lastErr  error
+	lastEnd  time.Time
+	timer    *time.Timer
+}
+
+var pendingRetries = struct {
+	sync.Mutex
+	byKey map[retryKey]*retryState
+}{byKey: make(map[retryKey]*retryState)}
+
+// retryKey identifies the requests that are retries of one another: the
+// same method, URL and body, traced by the same tracer under the same parent
+// span.
+type retryKey struct {
+	tracer  trace.Tracer
+	traceID trace.TraceID
+	spanID  trace.SpanID
+	request string
+}
+
+func newRetryKey(tracer trace.Tracer, parent trace.SpanContext, req *http.Request, body []byte) retryKey {
+	h := sha256.New()
+	h.Write([]byte(req.Method))
+	h.Write([]byte{0})
+	h.Write([]byte(req.URL.String()))
+	h.Write([]byte{0})
+	h.Write(body)
+	return retryKey{
+		tracer:  tracer,
+		traceID: parent.TraceID(),
+		spanID:  parent.SpanID(),
+		request: hex.EncodeToString(h.Sum(nil)),
+	}
+}
+
+// claimRetry returns the parked run that req retries, or nil when req starts
+// a new call. Only requests the SDK numbers as retries continue a run; a
+// first attempt ends any parked run with the same key instead.
+func claimRetry(key retryKey, header http.Header) *retryState {
+	n, ok := retryCount(header)
+	if !ok {
+		return nil
+	}
+	pendingRetries.Lock()
+	s := pendingRetries.byKey[key]
+	delete(pendingRetries.byKey, key)
+	pendingRetries.Unlock()
+	if s == nil {
+		return nil
+	}
+	s.timer.Stop()
+	if n == 0 {
+		s.finish()
+		return nil
+	}
+	s.attempts++
+	s.start = time.Now()
+	return s
+}
+
+// retryCount returns the SDK's retry number for a request: the
+// X-Stainless-Retry-Count header sent by the OpenAI and Anthropic SDKs, or
+// the attempt in the AWS SDK's amz-sdk-request header ("attempt=2; max=3").
+func retryCount(header http.Header) (int, bool) {
+	if v := header.Get("X-Stainless-Retry-Count"); v != "" {
+		n, err := strconv.Atoi(v)
+		return n, err == nil
+	}
+	for _, part := range strings.Split(header.Get("Amz-Sdk-Request"), ";") {
+		if k, v, ok := strings.Cut(strings.TrimSpace(part), "="); ok && k == "attempt" {
+			n, err := strconv.Atoi(v)
+			return n - 1, err == nil && n > 0
+		}
+	}
+	return 0, false
+}
+
+// willRetry reports whether the SDK that sent a request retries it when it
+// fails: the request carries the SDK's retry number, and for the AWS SDK,
+// attempts remain. Other clients' failed requests end their runs at once.
+func willRetry(header http.Header) bool {
+	if header.Get("X-Stainless-Retry-Count") != "" {
+		_, ok := retryCount(header)
+		return ok
+	}
+	var attempt, maxAttempts int
+	for _, part := range strings.Split(header.Get("Amz-Sdk-Request"), ";") {
+		k, v, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch k {
+		case "attempt":
+			attempt, _ = strconv.Atoi(v)
+		case "max":
+			maxAttempts, _ = strconv.Atoi(v)
+		}
+	}
+	return attempt > 0 && (maxAttempts == 0 || attempt < maxAttempts)
+}
+
+// retryable reports whether an SDK would retry an attempt that ended with
+// resp or err: connection errors (but not cancellation), and 408, 409, 429
+// and 5xx responses unless the server sent x-should-retry: false.
+func retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+	switch resp.Header.Get("X-Should-Retry") {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	switch code := resp.StatusCode; {
+	case code == http.StatusRequestTimeout, code == http.StatusConflict, code == http.StatusTooManyRequests:
+		return true
+	default:
+		return code >= 500
+	}
+}
+
+// retryAfter returns the delay the server asked for before a retry, from
+// retry-after-ms or Retry-After (seconds or an HTTP date).
+func retryAfter(header http.Header) time.Duration {
+	if v := header.Get("Retry-After-Ms"); v != "" {
+		if ms, err := strconv.ParseFloat(v, 64); err == nil && ms > 0 {
+			return time.Duration(ms * float64(time.Millisecond))
+		}
+	}
+	v := header.Get("Retry-After")
+	if v == "" {
+		return 0
+	}
+	if s, err := strconv.ParseFloat(v, 64); err == nil && s > 0 {
+		return time.Duration(s * float64(time.Second))
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// fail ends the current attempt with a retryable failure and parks the run
+// until the SDK retries it or retryWindow passes. resp is nil for connection
+// errors.
+func (s *retryState) fail(resp *http.Response, err error) {
+	now := time.Now()
+	var wait time.Duration
+	if resp != nil {
+		wait = min(retryAfter(resp.Header), maxRetryAfter)
+	}
+	s.recordAttempt(resp, err, wait, now)
+	s.lastErr, s.lastEnd = err, now
+
+	pendingRetries.Lock()
+	prev := pendingRetries.byKey[s.key]
+	pendingRetries.byKey[s.key] = s
+	s.timer = time.AfterFunc(retryWindow+wait, func() {
+		pendingRetries.Lock()
+		owned := pendingRetries.byKey[s.key] == s
+		if owned {
+			delete(pendingRetries.byKey, s.key)
+		}
+		pendingRetries.Unlock()
+		if owned {
+			s.finish()
+		}
+	})
+	pendingRetries.Unlock()
+
+	// An identical request failed concurrently and is still parked; it was
+	// not retried before this one, so it won't be.
+	if prev != nil {
+		prev.timer.Stop()
+		prev.finish()
+	}
+}
+
+// EndPendingRetries ends, as failed, every run still waiting for an SDK to
+// retry its last attempt. SDKs don't say which attempt is their last, so a
+// run whose final attempt failed stays open for up to retryWindow plus the
+// Retry-After delay. Call it before shutting down the tracer provider, so
+// such runs are exported; langsmith.OTelTracer's Shutdown calls it.
+func EndPendingRetries() {
+	pendingRetries.Lock()
+	pending := make([]*retryState, 0, len(pendingRetries.byKey))
+	for key, s := range pendingRetries.byKey {
+		pending = append(pending, s)
+		delete(pendingRetries.byKey, key)
+	}
+	pendingRetries.Unlock()
+	for _, s := range pending {
+		s.timer.Stop()
+		s.finish()
+	}
+}
+
+// finish ends a parked run that was not retried as failed with its last
+// attempt's error.
+func (s *retryState) finish() {
+	recordError(s.span, s.lastErr)
+	s.span.SetAttributes(genaiattr.AttemptsKey.Int(s.attempts))
+	s.end(trace.WithTimestamp(s.lastEnd))
+}
+
+// end ends the run's span and hands any collected content parts to the
+// attachment handler.
+func (s *retryState) end(opts ...trace.SpanEndOption) {
+	s.span.End(opts...)
+	if s.onEnd != nil && len(s.attachments) > 0 {
+		s.onEnd(s.ctx, s.span.SpanContext(), s.attachments)
+	}
+}
+
+// done records the final attempt of a call that was retried. Calls that
+// succeeded or failed on their first attempt get no attempt spans.
+func (s *retryState) done(resp *http.Response, err error) {
+	if s.attempts == 1 {
+		return
+	}
+	s.recordAttempt(resp, err, 0, time.Now())
+	s.span.SetAttributes(genaiattr.AttemptsKey.Int(s.attempts))
+}
+
+// recordAttempt adds a child span covering the current HTTP attempt.
+func (s *retryState) recordAttempt(resp *http.Response, err error, wait time.Duration, end time.Time) {
+	_, span := s.tracer.Start(s.ctx, attemptSpanName,
+		trace.WithTimestamp(s.start),
+		trace.WithAttributes(genaiattr.AttemptKey.Int(s.attempts)),
+	)
+	if resp != nil {
+		span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+	}
+	if wait > 0 {
+		span.SetAttributes(genaiattr.RetryAfterKey.Float64(wait.Seconds()))
+	}
+	if err != nil {
+		recordError(span, err)
+	} else {
+		span.SetStatus(codes.Ok, "")
+	}
+	span.End(trace.WithTimestamp(end))
+}
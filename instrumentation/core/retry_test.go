@@ -0,0 +1,390 @@
+package core
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// shortRetryWindow makes parked runs end quickly when no retry arrives.
+func shortRetryWindow(t *testing.T) {
+	t.Helper()
+	prev := retryWindow
+	retryWindow = 20 * time.Millisecond
+	t.Cleanup(func() { retryWindow = prev })
+}
+
+// waitForSpan returns the exported span named name, waiting for parked runs
+// to end.
+func waitForSpan(t *testing.T, exporter *tracetest.InMemoryExporter, name string) tracetest.SpanStub {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		for _, s := range exporter.GetSpans() {
+			if s.Name == name {
+				return s
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("span %q not exported", name)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func spansNamed(exporter *tracetest.InMemoryExporter, name string) tracetest.SpanStubs {
+	var out tracetest.SpanStubs
+	for _, s := range exporter.GetSpans() {
+		if s.Name == name {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+type seqResponse struct {
+	status int
+	header http.Header
+	body   string
+}
+
+// seqTransport returns its responses in order, one per request.
+type seqTransport struct {
+	mu        sync.Mutex
+	responses []seqResponse
+}
+
+func (t *seqTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.mu.Lock()
+	r := t.responses[0]
+	t.responses = t.responses[1:]
+	t.mu.Unlock()
+	header := r.header
+	if header == nil {
+		header = make(http.Header)
+	}
+	return &http.Response{
+		StatusCode: r.status,
+		Body:       io.NopCloser(strings.NewReader(r.body)),
+		Header:     header,
+		Request:    req,
+	}, nil
+}
+
+// stainless returns the headers of the OpenAI or Anthropic SDK's retry
+// number retry.
+func stainless(retry int) http.Header {
+	return http.Header{"X-Stainless-Retry-Count": {strconv.Itoa(retry)}}
+}
+
+// amz returns the headers of the AWS SDK's attempt of n.
+func amz(attempt, n int) http.Header {
+	return http.Header{"Amz-Sdk-Request": {"attempt=" + strconv.Itoa(attempt) + "; max=" + strconv.Itoa(n)}}
+}
+
+// doAttempt sends body with header under ctx.
+func doAttempt(t *testing.T, client *http.Client, ctx context.Context, body string, header http.Header) {
+	t.Helper()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://x/v1/fake", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.ReadAll(resp.Body)
+	resp.Body.Close()
+}
+
+func TestMiddleware_RetryContinuesRun(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers []http.Header
+	}{
+		{"stainless", []http.Header{stainless(0), stainless(1)}},
+		{"aws", []http.Header{amz(1, 3), amz(2, 3)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport := &seqTransport{responses: []seqResponse{
+				{status: 429, header: http.Header{"Retry-After-Ms": {"1500"}}, body: `{"error":"rate limited"}`},
+				{status: 200, body: `{"text":"hi","in":3,"out":2}`},
+			}}
+			client, exporter := newTestClient(t, transport, Config{})
+			for _, header := range tt.headers {
+				doAttempt(t, client, context.Background(), `{"prompt":"p"}`, header)
+			}
+
+			runs := spansNamed(exporter, "fake.call")
+			if len(runs) != 1 {
+				t.Fatalf("got %d runs, want 1", len(runs))
+			}
+			run := runs[0]
+			if run.Status.Code != codes.Ok {
+				t.Errorf("run status = %v, want Ok", run.Status)
+			}
+			if len(run.Events) != 0 {
+				t.Errorf("run has events %v; the failed attempt's error belongs to its attempt span", run.Events)
+			}
+			if v, _ := attr(run, "langsmith.metadata.attempts"); v.AsInt64() != 2 {
+				t.Errorf("attempts = %d, want 2", v.AsInt64())
+			}
+			if v, _ := attr(run, "gen_ai.completion"); v.AsString() != "hi" {
+				t.Errorf("gen_ai.completion = %q", v.AsString())
+			}
+
+			attempts := spansNamed(exporter, "attempt")
+			if len(attempts) != 2 {
+				t.Fatalf("got %d attempt spans, want 2", len(attempts))
+			}
+			for i, a := range attempts {
+				if a.Parent.SpanID() != run.SpanContext.SpanID() {
+					t.Errorf("attempt %d is not a child of the run", i+1)
+				}
+				if v, _ := attr(a, "langsmith.metadata.attempt"); v.AsInt64() != int64(i+1) {
+					t.Errorf("attempt %d numbered %d", i+1, v.AsInt64())
+				}
+			}
+			first := attempts[0]
+			if v, _ := attr(first, "http.response.status_code"); v.AsInt64() != 429 {
+				t.Errorf("first attempt status code = %d, want 429", v.AsInt64())
+			}
+			if v, _ := attr(first, "langsmith.metadata.retry_after_seconds"); v.AsFloat64() != 1.5 {
+				t.Errorf("retry_after_seconds = %v, want 1.5", v.AsFloat64())
+			}
+			if first.Status.Code != codes.Error || attempts[1].Status.Code != codes.Ok {
+				t.Errorf("attempt statuses = %v, %v", first.Status.Code, attempts[1].Status.Code)
+			}
+			if run.StartTime.After(first.StartTime) || run.EndTime.Before(attempts[1].EndTime) {
+				t.Error("run does not cover both attempts")
+			}
+		})
+	}
+}
+
+func TestMiddleware_RetriesExhausted(t *testing.T) {
+	shortRetryWindow(t)
+	transport := &seqTransport{responses: []seqResponse{
+		{status: 503, body: `{"error":"overloaded"}`},
+		{status: 503, body: `{"error":"still overloaded"}`},
+	}}
+	client, exporter := newTestClient(t, transport, Config{})
+	doAttempt(t, client, context.Background(), `{"prompt":"p"}`, stainless(0))
+	doAttempt(t, client, context.Background(), `{"prompt":"p"}`, stainless(1))
+
+	run := waitForSpan(t, exporter, "fake.call")
+	if run.Status.Code != codes.Error || !strings.Contains(run.Status.Description, "still overloaded") {
+		t.Errorf("run status = %v, want the last attempt's error", run.Status)
+	}
+	if v, _ := attr(run, "langsmith.metadata.attempts"); v.AsInt64() != 2 {
+		t.Errorf("attempts = %d, want 2", v.AsInt64())
+	}
+	attempts := spansNamed(exporter, "attempt")
+	if len(attempts) != 2 {
+		t.Fatalf("got %d attempt spans, want 2", len(attempts))
+	}
+	if !run.EndTime.Equal(attempts[1].EndTime) {
+		t.Errorf("run ended at %v, want the last attempt's end %v", run.EndTime, attempts[1].EndTime)
+	}
+}
+
+func TestMiddleware_FirstAttemptStartsNewRun(t *testing.T) {
+	transport := &seqTransport{responses: []seqResponse{
+		{status: 500, body: `{"error":"boom"}`},
+		{status: 200, body: `{"text":"hi"}`},
+	}}
+	client, exporter := newTestClient(t, transport, Config{})
+	doAttempt(t, client, context.Background(), `{"prompt":"p"}`, stainless(0))
+	doAttempt(t, client, context.Background(), `{"prompt":"p"}`, stainless(0))
+
+	runs := spansNamed(exporter, "fake.call")
+	if len(runs) != 2 {
+		t.Fatalf("got %d runs, want 2", len(runs))
+	}
+	if runs[0].Status.Code != codes.Error || runs[1].Status.Code != codes.Ok {
+		t.Errorf("run statuses = %v, %v; want Error, Ok", runs[0].Status.Code, runs[1].Status.Code)
+	}
+}
+
+func TestMiddleware_NonRetryableErrorEndsRun(t *testing.T) {
+	transport := &seqTransport{responses: []seqResponse{
+		{status: 500, header: http.Header{"X-Should-Retry": {"false"}}, body: `{"error":"boom"}`},
+	}}
+	client, exporter := newTestClient(t, transport, Config{})
+	doAttempt(t, client, context.Background(), `{"prompt":"p"}`, stainless(0))
+
+	span := onlySpan(t, exporter)
+	if span.Name != "fake.call" || span.Status.Code != codes.Error {
+		t.Errorf("span = %s %v, want a failed fake.call run", span.Name, span.Status)
+	}
+}
+
+func TestMiddleware_UnnumberedRequestsAreNotGrouped(t *testing.T) {
+	transport := &seqTransport{responses: []seqResponse{
+		{status: 429, body: `{"error":"rate limited"}`},
+		{status: 200, body: `{"text":"hi"}`},
+	}}
+	client, exporter := newTestClient(t, transport, Config{})
+	// A client that doesn't number its retries: the 429 ends its run at once.
+	doAttempt(t, client, context.Background(), `{"prompt":"p"}`, nil)
+	span := onlySpan(t, exporter)
+	if span.Status.Code != codes.Error || !strings.Contains(span.Status.Description, "HTTP 429") {
+		t.Errorf("run status = %v, want the 429", span.Status)
+	}
+
+	doAttempt(t, client, context.Background(), `{"prompt":"p"}`, nil)
+	if runs := spansNamed(exporter, "fake.call"); len(runs) != 2 || runs[1].Status.Code != codes.Ok {
+		t.Errorf("got runs %v, want a second, successful run", runs)
+	}
+	if attempts := spansNamed(exporter, "attempt"); len(attempts) != 0 {
+		t.Errorf("got %d attempt spans, want none", len(attempts))
+	}
+}
+
+func TestMiddleware_LastAWSAttemptEndsRun(t *testing.T) {
+	transport := &seqTransport{responses: []seqResponse{
+		{status: 429, body: `{"error":"rate limited"}`},
+	}}
+	client, exporter := newTestClient(t, transport, Config{})
+	doAttempt(t, client, context.Background(), `{"prompt":"p"}`, amz(3, 3))
+
+	span := onlySpan(t, exporter)
+	if span.Status.Code != codes.Error {
+		t.Errorf("run status = %v, want Error", span.Status)
+	}
+}
+
+func TestMiddleware_RetryUnderOtherParentStartsNewRun(t *testing.T) {
+	shortRetryWindow(t)
+	transport := &seqTransport{responses: []seqResponse{
+		{status: 429, body: `{"error":"rate limited"}`},
+		{status: 200, body: `{"text":"hi"}`},
+	}}
+	client, exporter := newTestClient(t, transport, Config{})
+	parent := func(b byte) context.Context {
+		return trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID:    trace.TraceID{b},
+			SpanID:     trace.SpanID{b},
+			TraceFlags: trace.FlagsSampled,
+		}))
+	}
+	doAttempt(t, client, parent(1), `{"prompt":"p"}`, stainless(0))
+	doAttempt(t, client, parent(2), `{"prompt":"p"}`, stainless(1))
+
+	waitForSpan(t, exporter, "fake.call")
+	deadline := time.Now().Add(2 * time.Second)
+	for len(spansNamed(exporter, "fake.call")) < 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	runs := spansNamed(exporter, "fake.call")
+	if len(runs) != 2 {
+		t.Fatalf("got %d runs, want 2", len(runs))
+	}
+	for _, run := range runs {
+		want := codes.Error
+		if run.Parent.TraceID() == (trace.TraceID{2}) {
+			want = codes.Ok
+		}
+		if run.Status.Code != want {
+			t.Errorf("run under trace %s has status %v, want %v", run.Parent.TraceID(), run.Status.Code, want)
+		}
+	}
+}
+
+func TestEndPendingRetries(t *testing.T) {
+	transport := &seqTransport{responses: []seqResponse{
+		{status: 429, body: `{"error":"rate limited"}`},
+	}}
+	client, exporter := newTestClient(t, transport, Config{})
+	doAttempt(t, client, context.Background(), `{"prompt":"p"}`, stainless(2))
+	if n := len(exporter.GetSpans()); n != 1 {
+		t.Fatalf("got %d spans before EndPendingRetries, want only the attempt", n)
+	}
+
+	EndPendingRetries()
+	run := waitForSpan(t, exporter, "fake.call")
+	if run.Status.Code != codes.Error || !strings.Contains(run.Status.Description, "rate limited") {
+		t.Errorf("run status = %v, want the 429", run.Status)
+	}
+}
+
+func TestRetryCount(t *testing.T) {
+	tests := []struct {
+		header http.Header
+		want   int
+		ok     bool
+	}{
+		{http.Header{"X-Stainless-Retry-Count": {"2"}}, 2, true},
+		{http.Header{"Amz-Sdk-Request": {"attempt=1; max=3"}}, 0, true},
+		{http.Header{"Amz-Sdk-Request": {"attempt=3; max=3"}}, 2, true},
+		{http.Header{}, 0, false},
+	}
+	for _, tt := range tests {
+		if got, ok := retryCount(tt.header); got != tt.want || ok != tt.ok {
+			t.Errorf("retryCount(%v) = %d, %v; want %d, %v", tt.header, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestWillRetry(t *testing.T) {
+	tests := []struct {
+		header http.Header
+		want   bool
+	}{
+		{stainless(0), true},
+		{stainless(2), true},
+		{amz(1, 3), true},
+		{amz(3, 3), false},
+		{http.Header{"Amz-Sdk-Request": {"attempt=1"}}, true},
+		{http.Header{}, false},
+	}
+	for _, tt := range tests {
+		if got := willRetry(tt.header); got != tt.want {
+			t.Errorf("willRetry(%v) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	for code, want := range map[int]bool{400: false, 404: false, 408: true, 409: true, 429: true, 500: true, 529: true} {
+		if got := retryable(&http.Response{StatusCode: code, Header: http.Header{}}, nil); got != want {
+			t.Errorf("retryable(%d) = %v, want %v", code, got, want)
+		}
+	}
+	if retryable(nil, context.Canceled) {
+		t.Error("cancellation is not retryable")
+	}
+	if !retryable(nil, io.ErrUnexpectedEOF) {
+		t.Error("connection errors are retryable")
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		header http.Header
+		want   time.Duration
+	}{
+		{http.Header{"Retry-After-Ms": {"250"}}, 250 * time.Millisecond},
+		{http.Header{"Retry-After": {"2"}}, 2 * time.Second},
+		{http.Header{"Retry-After": {"soon"}}, 0},
+		{http.Header{}, 0},
+	}
+	for _, tt := range tests {
+		if got := retryAfter(tt.header); got != tt.want {
+			t.Errorf("retryAfter(%v) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
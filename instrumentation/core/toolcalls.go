@@ -0,0 +1,107 @@
+package core
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// toolCallTTL bounds how long a tool span waits for the model request that
+// carries its result. Tool calls whose result is never sent back are
+// forgotten after it.
+const toolCallTTL = 10 * time.Minute
+
+// toolCallIDKeys are the message fields that reference a tool call from its
+// result: tool_call_id in OpenAI-format tool messages (OpenAI, Gemini,
+// Bedrock, Ollama) and tool_use_id in Anthropic tool_result blocks.
+var toolCallIDKeys = []string{"tool_call_id", "tool_use_id"}
+
+type toolSpan struct {
+	span    trace.SpanContext
+	expires time.Time
+}
+
+var toolSpans = struct {
+	sync.Mutex
+	byID map[string]toolSpan
+}{byID: make(map[string]toolSpan)}
+
+// RegisterToolCall records span as the execution of the model tool call id.
+// The next traced model request whose prompt includes the call's result is
+// linked to span, connecting each tool run to the model turn that consumed
+// it.
+func RegisterToolCall(id string, span trace.SpanContext) {
+	if id == "" || !span.IsValid() {
+		return
+	}
+	now := time.Now()
+	toolSpans.Lock()
+	defer toolSpans.Unlock()
+	for k, v := range toolSpans.byID {
+		if now.After(v.expires) {
+			delete(toolSpans.byID, k)
+		}
+	}
+	toolSpans.byID[id] = toolSpan{span: span, expires: now.Add(toolCallTTL)}
+}
+
+// toolResultLinks returns span links to the registered tool spans whose
+// results appear in prompt. Each tool span is linked once: later turns of
+// the conversation resend the same results but did not consume them.
+func toolResultLinks(prompt string) []trace.Link {
+	if !strings.Contains(prompt, "tool_call_id") && !strings.Contains(prompt, "tool_use_id") {
+		return nil
+	}
+	var v any
+	if err := json.Unmarshal([]byte(prompt), &v); err != nil {
+		return nil
+	}
+	ids := map[string]bool{}
+	collectToolCallIDs(v, ids)
+
+	sorted := make([]string, 0, len(ids))
+	for id := range ids {
+		sorted = append(sorted, id)
+	}
+	sort.Strings(sorted)
+
+	toolSpans.Lock()
+	defer toolSpans.Unlock()
+	var links []trace.Link
+	for _, id := range sorted {
+		ts, ok := toolSpans.byID[id]
+		if !ok {
+			continue
+		}
+		delete(toolSpans.byID, id)
+		links = append(links, trace.Link{
+			SpanContext: ts.span,
+			Attributes:  []attribute.KeyValue{semconv.GenAIToolCallID(id)},
+		})
+	}
+	return links
+}
+
+func collectToolCallIDs(v any, ids map[string]bool) {
+	switch v := v.(type) {
+	case map[string]any:
+		for _, k := range toolCallIDKeys {
+			if id, ok := v[k].(string); ok && id != "" {
+				ids[id] = true
+			}
+		}
+		for _, child := range v {
+			collectToolCallIDs(child, ids)
+		}
+	case []any:
+		for _, child := range v {
+			collectToolCallIDs(child, ids)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package core
+
+import (
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+func testSpanContext(b byte) trace.SpanContext {
+	return trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{1},
+		SpanID:  trace.SpanID{b},
+	})
+}
+
+func TestToolResultLinks_AnthropicToolResult(t *testing.T) {
+	RegisterToolCall("toolu_1", testSpanContext(1))
+	RegisterToolCall("toolu_2", testSpanContext(2))
+
+	prompt := `{"messages":[{"role":"user","content":[{"type":"tool_result","tool_use_id":"toolu_1","content":"ok"}]}]}`
+	links := toolResultLinks(prompt)
+	if len(links) != 1 || links[0].SpanContext.SpanID() != (trace.SpanID{1}) {
+		t.Fatalf("links = %+v, want toolu_1's span", links)
+	}
+	if links := toolResultLinks(prompt); len(links) != 0 {
+		t.Errorf("second lookup returned %d links, want 0", len(links))
+	}
+}
+
+func TestToolResultLinks_Unregistered(t *testing.T) {
+	prompt := `{"messages":[{"role":"tool","tool_call_id":"call_unknown","content":"ok"}]}`
+	if links := toolResultLinks(prompt); len(links) != 0 {
+		t.Errorf("links = %+v, want none", links)
+	}
+	if links := toolResultLinks(`{"messages":[{"role":"user","content":"hi"}]}`); links != nil {
+		t.Errorf("links = %+v, want nil", links)
+	}
+}
+
+func TestRegisterToolCall_IgnoresInvalid(t *testing.T) {
+	RegisterToolCall("", testSpanContext(3))
+	RegisterToolCall("call_invalid", trace.SpanContext{})
+	if links := toolResultLinks(`{"messages":[{"role":"tool","tool_call_id":"call_invalid"}]}`); len(links) != 0 {
+		t.Errorf("links = %+v, want none", links)
+	}
+}
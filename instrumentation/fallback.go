@@ -0,0 +1,74 @@
+package instrumentation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/langchain-ai/langsmith-go/instrumentation/core"
+	"github.com/langchain-ai/langsmith-go/internal/genaiattr"
+)
+
+// Fallback is one candidate in a fallback chain.
+type Fallback[T any] struct {
+	// Name identifies the candidate, such as "anthropic/claude-sonnet-4".
+	Name string
+
+	// Call makes the candidate's model call with ctx.
+	Call func(context.Context) (T, error)
+}
+
+// TraceFallbacks calls each candidate in order until one succeeds and
+// returns its result. The calls run inside a chain run named name, so its
+// latency and status are those the caller experienced; a failed candidate is
+// recorded as a "fallback" event rather than failing the run. Model calls
+// traced by the instrumentation packages record the candidate's position and
+// the candidate that failed before it (see core.WithFallbackContext).
+//
+// When every candidate fails, the run fails and the candidates' errors are
+// returned joined. Cancelling ctx stops the chain.
+func TraceFallbacks[T any](ctx context.Context, name string, fallbacks []Fallback[T], opts ...Option) (T, error) {
+	ctx, span := newConfig(opts).start(ctx, name, genaiattr.SpanKindKey.String("chain"))
+	defer span.End()
+
+	var zero T
+	var errs []error
+	var from string
+	for i, fb := range fallbacks {
+		if err := ctx.Err(); err != nil {
+			errs = append(errs, err)
+			break
+		}
+		candidate := fb.Name
+		if candidate == "" {
+			candidate = fmt.Sprintf("fallback %d", i)
+		}
+		result, err := fb.Call(core.WithFallbackContext(ctx, i, from))
+		if err == nil {
+			span.SetAttributes(genaiattr.FallbackIndexKey.Int(i))
+			if from != "" {
+				span.SetAttributes(genaiattr.FallbackFromKey.String(from))
+			}
+			span.SetStatus(codes.Ok, "")
+			return result, nil
+		}
+		span.AddEvent("fallback", trace.WithAttributes(
+			attribute.String("candidate", candidate),
+			attribute.String("error", err.Error()),
+		))
+		errs = append(errs, fmt.Errorf("%s: %w", candidate, err))
+		from = candidate
+	}
+
+	err := errors.Join(errs...)
+	if err == nil {
+		err = errors.New("no fallback candidates")
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+	return zero, err
+}
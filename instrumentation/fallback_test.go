@@ -0,0 +1,113 @@
+package instrumentation
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/codes"
+
+	"github.com/langchain-ai/langsmith-go/instrumentation/traceopenai"
+)
+
+// statusTransport answers every request with status and body.
+type statusTransport struct {
+	status int
+	body   string
+}
+
+func (t *statusTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return &http.Response{
+		StatusCode: t.status,
+		Body:       io.NopCloser(strings.NewReader(t.body)),
+		Header:     make(http.Header),
+		Request:    req,
+	}, nil
+}
+
+func chatCall(client *http.Client) func(context.Context) (string, error) {
+	return func(ctx context.Context) (string, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://api.openai.com/v1/chat/completions",
+			strings.NewReader(`{"model":"gpt-4o","messages":[{"role":"user","content":"hi"}]}`))
+		if err != nil {
+			return "", err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode >= 400 {
+			return "", errors.New(resp.Status)
+		}
+		return string(b), nil
+	}
+}
+
+func TestTraceFallbacks_UsesNextCandidate(t *testing.T) {
+	exporter, tp := newExporter()
+	primary := traceopenai.WrapClient(&http.Client{Transport: &statusTransport{status: 400, body: `{"error":{}}`}},
+		traceopenai.WithTracerProvider(tp))
+	secondary := traceopenai.WrapClient(&http.Client{Transport: &statusTransport{status: 200,
+		body: `{"model":"gpt-4o","choices":[{"message":{"role":"assistant","content":"hello"}}]}`}},
+		traceopenai.WithTracerProvider(tp), traceopenai.WithProviderName("azure.ai.openai"))
+
+	_, err := TraceFallbacks(context.Background(), "chat", []Fallback[string]{
+		{Name: "openai", Call: chatCall(primary)},
+		{Name: "azure", Call: chatCall(secondary)},
+	}, WithTracerProvider(tp))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 3 {
+		t.Fatalf("got %d spans, want 3", len(spans))
+	}
+	chain := spans[2]
+	if chain.Name != "chat" || chain.Status.Code != codes.Ok {
+		t.Errorf("chain run = %s %v, want an Ok chat run", chain.Name, chain.Status)
+	}
+	if getAttr(chain, "langsmith.metadata.fallback_index") != "1" || getAttr(chain, "langsmith.metadata.fallback_from") != "openai" {
+		t.Errorf("chain fallback metadata = %v", chain.Attributes)
+	}
+	if len(chain.Events) != 1 || chain.Events[0].Name != "fallback" {
+		t.Errorf("chain events = %v, want one fallback event", chain.Events)
+	}
+
+	first, second := spans[0], spans[1]
+	for _, s := range []int{0, 1} {
+		if spans[s].Parent.SpanID() != chain.SpanContext.SpanID() {
+			t.Errorf("model run %d is not a child of the chain run", s)
+		}
+	}
+	if getAttr(first, "langsmith.metadata.fallback_index") != "0" || getAttr(first, "langsmith.metadata.fallback_from") != "" {
+		t.Errorf("primary run fallback metadata = %v", first.Attributes)
+	}
+	if getAttr(second, "langsmith.metadata.fallback_index") != "1" || getAttr(second, "langsmith.metadata.fallback_from") != "openai" {
+		t.Errorf("fallback run fallback metadata = %v", second.Attributes)
+	}
+}
+
+func TestTraceFallbacks_AllFail(t *testing.T) {
+	exporter, tp := newExporter()
+	errA, errB := errors.New("a down"), errors.New("b down")
+	_, err := TraceFallbacks(context.Background(), "chat", []Fallback[int]{
+		{Name: "a", Call: func(context.Context) (int, error) { return 0, errA }},
+		{Name: "b", Call: func(context.Context) (int, error) { return 0, errB }},
+	}, WithTracerProvider(tp))
+	if !errors.Is(err, errA) || !errors.Is(err, errB) {
+		t.Fatalf("err = %v, want both candidates' errors", err)
+	}
+	spans := exporter.GetSpans()
+	if len(spans) != 1 || spans[0].Status.Code != codes.Error {
+		t.Fatalf("spans = %v, want one failed chain run", spans)
+	}
+	if len(spans[0].Events) < 2 {
+		t.Errorf("events = %v, want a fallback event per candidate", spans[0].Events)
+	}
+}
@@ -0,0 +1,90 @@
+package pricing
+
+// DefaultVersion identifies the built-in price table. Bump it whenever a
+// price in defaultModels changes.
+const DefaultVersion = "2025-08-01"
+
+// Default returns a copy of the built-in price table, covering current
+// OpenAI, Anthropic and Gemini models at their list prices for standard
+// (non-batch) requests. Override or extend it with Table.With.
+func Default() *Table {
+	return (&Table{Version: DefaultVersion, Models: defaultModels}).With(nil)
+}
+
+// anthropicPrice applies Anthropic's cache multipliers to a base rate:
+// reads at 0.1x, 5-minute writes at 1.25x, 1-hour writes at 2x
+// (https://docs.anthropic.com/en/docs/about-claude/pricing).
+func anthropicPrice(input, output float64) Price {
+	return Price{
+		Input:        input,
+		Output:       output,
+		CacheRead:    input * 0.1,
+		CacheWrite:   input * 1.25,
+		CacheWrite1h: input * 2,
+	}
+}
+
+// defaultModels holds list prices in USD per million tokens.
+//
+//	OpenAI:    https://platform.openai.com/docs/pricing
+//	Anthropic: https://docs.anthropic.com/en/docs/about-claude/pricing
+//	Gemini:    https://ai.google.dev/gemini-api/docs/pricing
+var defaultModels = map[string]Price{
+	// OpenAI. Service-tier buckets ("flex", "priority") come from
+	// traceopenai's usage_metadata when the response reports the tier.
+	"gpt-5": {Input: 1.25, Output: 10, CacheRead: 0.125,
+		InputDetails:  map[string]float64{"flex": 0.625, "flex_cache_read": 0.0625, "priority": 2.5, "priority_cache_read": 0.25},
+		OutputDetails: map[string]float64{"flex": 5, "flex_reasoning": 5, "priority": 20, "priority_reasoning": 20}},
+	"gpt-5-mini": {Input: 0.25, Output: 2, CacheRead: 0.025,
+		InputDetails:  map[string]float64{"flex": 0.125, "flex_cache_read": 0.0125, "priority": 0.45, "priority_cache_read": 0.045},
+		OutputDetails: map[string]float64{"flex": 1, "flex_reasoning": 1, "priority": 3.6, "priority_reasoning": 3.6}},
+	"gpt-5-nano": {Input: 0.05, Output: 0.4, CacheRead: 0.005,
+		InputDetails:  map[string]float64{"flex": 0.025, "flex_cache_read": 0.0025},
+		OutputDetails: map[string]float64{"flex": 0.2, "flex_reasoning": 0.2}},
+	"gpt-4.1":                   {Input: 2, Output: 8, CacheRead: 0.5},
+	"gpt-4.1-mini":              {Input: 0.4, Output: 1.6, CacheRead: 0.1},
+	"gpt-4.1-nano":              {Input: 0.1, Output: 0.4, CacheRead: 0.025},
+	"gpt-4o":                    {Input: 2.5, Output: 10, CacheRead: 1.25},
+	"gpt-4o-2024-05-13":         {Input: 5, Output: 15},
+	"gpt-4o-mini":               {Input: 0.15, Output: 0.6, CacheRead: 0.075},
+	"gpt-4o-audio-preview":      {Input: 2.5, Output: 10, InputAudio: 40, OutputAudio: 80},
+	"gpt-4o-mini-audio-preview": {Input: 0.15, Output: 0.6, InputAudio: 10, OutputAudio: 20},
+	"gpt-4-turbo":               {Input: 10, Output: 30},
+	"gpt-3.5-turbo":             {Input: 0.5, Output: 1.5},
+	"o1":                        {Input: 15, Output: 60, CacheRead: 7.5},
+	"o1-mini":                   {Input: 1.1, Output: 4.4, CacheRead: 0.55},
+	"o3": {Input: 2, Output: 8, CacheRead: 0.5,
+		InputDetails:  map[string]float64{"flex": 1, "flex_cache_read": 0.25},
+		OutputDetails: map[string]float64{"flex": 4, "flex_reasoning": 4}},
+	"o3-mini": {Input: 1.1, Output: 4.4, CacheRead: 0.55},
+	"o4-mini": {Input: 1.1, Output: 4.4, CacheRead: 0.275,
+		InputDetails:  map[string]float64{"flex": 0.55, "flex_cache_read": 0.1375},
+		OutputDetails: map[string]float64{"flex": 2.2, "flex_reasoning": 2.2}},
+	"text-embedding-3-small": {Input: 0.02},
+	"text-embedding-3-large": {Input: 0.13},
+	"text-embedding-ada-002": {Input: 0.1},
+
+	// Anthropic, also matching Bedrock and Vertex AI model IDs.
+	"claude-opus-4-1":   anthropicPrice(15, 75),
+	"claude-opus-4":     anthropicPrice(15, 75),
+	"claude-sonnet-4-5": anthropicPrice(3, 15),
+	"claude-sonnet-4":   anthropicPrice(3, 15),
+	"claude-3-7-sonnet": anthropicPrice(3, 15),
+	"claude-3-5-sonnet": anthropicPrice(3, 15),
+	"claude-haiku-4-5":  anthropicPrice(1, 5),
+	"claude-3-5-haiku":  anthropicPrice(0.8, 4),
+	"claude-3-opus":     anthropicPrice(15, 75),
+	"claude-3-haiku":    anthropicPrice(0.25, 1.25),
+
+	// Gemini. Above 200k prompt tokens tracegemini moves every token into
+	// the over_200k buckets, priced here.
+	"gemini-2.5-pro": {Input: 1.25, Output: 10, CacheRead: 0.31,
+		InputDetails:  map[string]float64{"over_200k": 2.5, "cache_read_over_200k": 0.625},
+		OutputDetails: map[string]float64{"over_200k": 15}},
+	"gemini-2.5-flash":      {Input: 0.3, Output: 2.5, CacheRead: 0.075, InputAudio: 1},
+	"gemini-2.5-flash-lite": {Input: 0.1, Output: 0.4, CacheRead: 0.025, InputAudio: 0.3},
+	"gemini-2.0-flash":      {Input: 0.1, Output: 0.4, CacheRead: 0.025, InputAudio: 0.7},
+	"gemini-2.0-flash-lite": {Input: 0.075, Output: 0.3},
+	"gemini-1.5-pro":        {Input: 1.25, Output: 5},
+	"gemini-1.5-flash":      {Input: 0.075, Output: 0.3},
+}
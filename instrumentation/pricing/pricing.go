@@ -0,0 +1,297 @@
+// Package pricing computes the cost of LLM calls on the client from token
+// usage and a table of model prices.
+//
+// LangSmith prices runs server-side from its model price map. Deployments
+// without one (self-hosted, air-gapped, custom or fine-tuned models) can
+// price runs in the process instead: pass a Table to an instrumentation
+// package's WithPricing option and every traced call records input_cost,
+// output_cost and total_cost in its usage_metadata, which LangSmith shows as
+// the run's prompt_cost, completion_cost and total_cost.
+//
+//	prices, err := pricing.LoadFile("prices.json") // optional overrides
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	client := openai.NewClient(
+//		option.WithHTTPClient(traceopenai.Client(traceopenai.WithPricing(pricing.Default().With(prices)))),
+//	)
+//
+// Overrides use the same JSON shape as the built-in table, with prices in
+// USD per million tokens:
+//
+//	{
+//	  "version": "2025-09-acme",
+//	  "models": {
+//	    "acme-llm": {"input": 0.5, "output": 1.5, "cache_read": 0.05},
+//	    "gpt-5": {"input": 1.0, "output": 8.0}
+//	  }
+//	}
+package pricing
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Price is a model's rates in USD per million tokens. Input and Output are
+// the base rates; the other rates apply to the matching usage_metadata token
+// detail buckets, and fall back to the base rate when zero.
+type Price struct {
+	Input  float64 `json:"input"`
+	Output float64 `json:"output"`
+
+	// CacheRead prices input_token_details.cache_read.
+	CacheRead float64 `json:"cache_read,omitempty"`
+
+	// CacheWrite prices input_token_details.cache_creation and Anthropic's
+	// 5-minute cache writes (ephemeral_5m_input_tokens).
+	CacheWrite float64 `json:"cache_write,omitempty"`
+
+	// CacheWrite1h prices Anthropic's 1-hour cache writes
+	// (ephemeral_1h_input_tokens), falling back to CacheWrite.
+	CacheWrite1h float64 `json:"cache_write_1h,omitempty"`
+
+	// Reasoning prices output_token_details.reasoning. Providers bill
+	// reasoning at the output rate, so it is usually left zero.
+	Reasoning float64 `json:"reasoning,omitempty"`
+
+	// InputAudio and OutputAudio price the audio detail buckets.
+	InputAudio  float64 `json:"input_audio,omitempty"`
+	OutputAudio float64 `json:"output_audio,omitempty"`
+
+	// InputDetails and OutputDetails price any other token detail bucket by
+	// its key, such as OpenAI service tiers ("flex", "flex_cache_read") or
+	// Gemini long-context tokens ("over_200k"). They take precedence over
+	// the named rates above.
+	InputDetails  map[string]float64 `json:"input_details,omitempty"`
+	OutputDetails map[string]float64 `json:"output_details,omitempty"`
+}
+
+// inputRate returns the rate for an input_token_details bucket.
+func (p Price) inputRate(key string) float64 {
+	if r, ok := p.InputDetails[key]; ok {
+		return r
+	}
+	var r float64
+	switch key {
+	case "cache_read":
+		r = p.CacheRead
+	case "cache_creation", "ephemeral_5m_input_tokens":
+		r = p.CacheWrite
+	case "ephemeral_1h_input_tokens":
+		r = p.CacheWrite1h
+		if r == 0 {
+			r = p.CacheWrite
+		}
+	case "audio":
+		r = p.InputAudio
+	}
+	if r == 0 {
+		r = p.Input
+	}
+	return r
+}
+
+// outputRate returns the rate for an output_token_details bucket.
+func (p Price) outputRate(key string) float64 {
+	if r, ok := p.OutputDetails[key]; ok {
+		return r
+	}
+	var r float64
+	switch key {
+	case "reasoning":
+		r = p.Reasoning
+	case "audio":
+		r = p.OutputAudio
+	}
+	if r == 0 {
+		r = p.Output
+	}
+	return r
+}
+
+// Table maps model names to prices. A model matches an entry with the same
+// name, or else the longest entry that is a prefix of it followed by a
+// version separator, so "gpt-4o" prices "gpt-4o-2024-08-06" and
+// "claude-sonnet-4" prices "claude-sonnet-4@20250514".
+type Table struct {
+	// Version identifies the prices, and is recorded on each priced run so
+	// costs can be traced back to the rates that produced them.
+	Version string `json:"version"`
+
+	Models map[string]Price `json:"models"`
+}
+
+// Load reads a Table from JSON.
+func Load(r io.Reader) (*Table, error) {
+	var t Table
+	if err := json.NewDecoder(r).Decode(&t); err != nil {
+		return nil, fmt.Errorf("pricing: decode table: %w", err)
+	}
+	models := make(map[string]Price, len(t.Models))
+	for name, p := range t.Models {
+		models[normalizeModel(name)] = p
+	}
+	t.Models = models
+	return &t, nil
+}
+
+// LoadFile reads a Table from a JSON file.
+func LoadFile(path string) (*Table, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("pricing: %w", err)
+	}
+	defer f.Close()
+	return Load(f)
+}
+
+// With returns a copy of t with the models in overrides added or replaced.
+// The copy takes overrides' version when it has one.
+func (t *Table) With(overrides *Table) *Table {
+	out := &Table{Version: t.Version, Models: make(map[string]Price, len(t.Models))}
+	for name, p := range t.Models {
+		out.Models[name] = p
+	}
+	if overrides == nil {
+		return out
+	}
+	if overrides.Version != "" {
+		out.Version = overrides.Version
+	}
+	for name, p := range overrides.Models {
+		out.Models[normalizeModel(name)] = p
+	}
+	return out
+}
+
+// Lookup returns the price for model.
+func (t *Table) Lookup(model string) (Price, bool) {
+	if t == nil || model == "" {
+		return Price{}, false
+	}
+	model = normalizeModel(model)
+	if p, ok := t.Models[model]; ok {
+		return p, true
+	}
+	var best string
+	for name := range t.Models {
+		if len(name) > len(best) && strings.HasPrefix(model, name) && isVersionSeparator(model[len(name)]) {
+			best = name
+		}
+	}
+	if best == "" {
+		return Price{}, false
+	}
+	return t.Models[best], true
+}
+
+func isVersionSeparator(c byte) bool {
+	return c == '-' || c == '@' || c == ':'
+}
+
+// bedrockRegionPrefixes are the cross-region inference profile prefixes on
+// Bedrock model IDs ("us.anthropic.claude-...").
+var bedrockRegionPrefixes = []string{"us.", "eu.", "apac.", "us-gov.", "global."}
+
+// normalizeModel reduces a provider model ID to its table key: lowercased,
+// without a resource path ("models/gemini-2.5-flash",
+// "publishers/google/models/...") or a Bedrock region and vendor prefix
+// ("us.anthropic.claude-...").
+func normalizeModel(model string) string {
+	model = strings.ToLower(strings.TrimSpace(model))
+	if i := strings.LastIndexByte(model, '/'); i >= 0 {
+		model = model[i+1:]
+	}
+	for _, prefix := range bedrockRegionPrefixes {
+		if strings.HasPrefix(model, prefix) {
+			model = model[len(prefix):]
+			break
+		}
+	}
+	for _, vendor := range []string{"anthropic.", "amazon.", "meta.", "google."} {
+		if strings.HasPrefix(model, vendor) {
+			return model[len(vendor):]
+		}
+	}
+	return model
+}
+
+// Cost is the price of one call in USD.
+type Cost struct {
+	Input  float64
+	Output float64
+
+	// InputDetails and OutputDetails break Input and Output down by token
+	// detail bucket. Tokens outside any bucket are not listed.
+	InputDetails  map[string]float64
+	OutputDetails map[string]float64
+}
+
+// Total returns the input plus output cost.
+func (c Cost) Total() float64 { return c.Input + c.Output }
+
+// Cost prices a LangSmith usage_metadata object (input_tokens,
+// output_tokens and their token detail breakdowns) for model. Detail
+// buckets are charged at their own rate and the remaining tokens at the base
+// rate. ok is false when the model is not in the table.
+func (t *Table) Cost(model string, usageMetadata map[string]any) (cost Cost, ok bool) {
+	p, ok := t.Lookup(model)
+	if !ok {
+		return Cost{}, false
+	}
+	cost.Input, cost.InputDetails = bucketCost(
+		number(usageMetadata["input_tokens"]), details(usageMetadata, "input_token_details"), p.Input, p.inputRate)
+	cost.Output, cost.OutputDetails = bucketCost(
+		number(usageMetadata["output_tokens"]), details(usageMetadata, "output_token_details"), p.Output, p.outputRate)
+	return cost, true
+}
+
+// bucketCost charges each detail bucket at rate(key) and whatever part of
+// total the buckets don't cover at base.
+func bucketCost(total float64, buckets map[string]any, base float64, rate func(string) float64) (float64, map[string]float64) {
+	var cost, covered float64
+	var byKey map[string]float64
+	for key, v := range buckets {
+		n := number(v)
+		if n <= 0 {
+			continue
+		}
+		c := n * rate(key) / 1e6
+		if byKey == nil {
+			byKey = make(map[string]float64)
+		}
+		byKey[key] = c
+		cost += c
+		covered += n
+	}
+	if rest := total - covered; rest > 0 {
+		cost += rest * base / 1e6
+	}
+	return cost, byKey
+}
+
+func details(m map[string]any, key string) map[string]any {
+	d, _ := m[key].(map[string]any)
+	return d
+}
+
+// number reads a token count of any of the numeric types usage_metadata
+// holds before and after a JSON round trip.
+func number(v any) float64 {
+	switch n := v.(type) {
+	case int:
+		return float64(n)
+	case int64:
+		return float64(n)
+	case float64:
+		return n
+	case json.Number:
+		f, _ := n.Float64()
+		return f
+	}
+	return 0
+}
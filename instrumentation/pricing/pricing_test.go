@@ -0,0 +1,130 @@
+package pricing
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+)
+
+func approx(a, b float64) bool { return math.Abs(a-b) < 1e-9 }
+
+func TestLookup(t *testing.T) {
+	table := Default()
+	tests := []struct {
+		model string
+		want  string // the table entry expected to match, "" for none
+	}{
+		{"gpt-4o", "gpt-4o"},
+		{"gpt-4o-2024-08-06", "gpt-4o"},
+		{"gpt-4o-2024-05-13", "gpt-4o-2024-05-13"},
+		{"gpt-4o-mini-2024-07-18", "gpt-4o-mini"},
+		{"claude-sonnet-4-20250514", "claude-sonnet-4"},
+		{"claude-sonnet-4-5-20250929", "claude-sonnet-4-5"},
+		{"claude-sonnet-4@20250514", "claude-sonnet-4"},
+		{"us.anthropic.claude-3-5-haiku-20241022-v1:0", "claude-3-5-haiku"},
+		{"models/gemini-2.5-flash", "gemini-2.5-flash"},
+		{"gemini-2.5-flash-lite-preview-06-17", "gemini-2.5-flash-lite"},
+		{"publishers/google/models/gemini-2.5-pro", "gemini-2.5-pro"},
+		{"GPT-4.1", "gpt-4.1"},
+		{"gpt-4", ""},
+		{"gpt-4oo", ""},
+		{"llama3", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		got, ok := table.Lookup(tt.model)
+		if tt.want == "" {
+			if ok {
+				t.Errorf("Lookup(%q) = %+v, want no match", tt.model, got)
+			}
+			continue
+		}
+		if want := table.Models[tt.want]; !ok || got.Input != want.Input || got.Output != want.Output {
+			t.Errorf("Lookup(%q) = %+v, %v; want %s %+v", tt.model, got, ok, tt.want, want)
+		}
+	}
+}
+
+func TestCost_DetailBuckets(t *testing.T) {
+	table := &Table{Models: map[string]Price{
+		"m": {Input: 10, Output: 20, CacheRead: 1, CacheWrite: 12.5, CacheWrite1h: 20, InputAudio: 40,
+			InputDetails: map[string]float64{"over_200k": 30}},
+	}}
+	// 1M input tokens: 200k cache read, 100k 1h cache write, 100k audio,
+	// 100k over_200k and 500k at the base rate. 1M output tokens, 400k of
+	// them reasoning (billed at the output rate).
+	cost, ok := table.Cost("m", map[string]any{
+		"input_tokens":  1_000_000,
+		"output_tokens": int64(1_000_000),
+		"input_token_details": map[string]any{
+			"cache_read": 200_000, "ephemeral_1h_input_tokens": 100_000, "audio": 100_000, "over_200k": 100_000,
+		},
+		"output_token_details": map[string]any{"reasoning": json.Number("400000")},
+	})
+	if !ok {
+		t.Fatal("model not priced")
+	}
+	wantInput := 0.2*1 + 0.1*20 + 0.1*40 + 0.1*30 + 0.5*10
+	if !approx(cost.Input, wantInput) {
+		t.Errorf("input cost = %v, want %v", cost.Input, wantInput)
+	}
+	if !approx(cost.Output, 20) {
+		t.Errorf("output cost = %v, want 20", cost.Output)
+	}
+	if !approx(cost.Total(), wantInput+20) {
+		t.Errorf("total = %v", cost.Total())
+	}
+	if !approx(cost.InputDetails["cache_read"], 0.2) || !approx(cost.OutputDetails["reasoning"], 8) {
+		t.Errorf("details = %v %v", cost.InputDetails, cost.OutputDetails)
+	}
+}
+
+func TestCost_OpenAIFlexTier(t *testing.T) {
+	// traceopenai's usage_metadata for a flex-tier gpt-5 call.
+	cost, ok := Default().Cost("gpt-5-2025-08-07", map[string]any{
+		"input_tokens":         1_000_000,
+		"output_tokens":        1_000_000,
+		"input_token_details":  map[string]any{"flex_cache_read": 500_000, "flex": 500_000},
+		"output_token_details": map[string]any{"flex_reasoning": 250_000, "flex": 750_000},
+	})
+	if !ok {
+		t.Fatal("gpt-5 not priced")
+	}
+	if !approx(cost.Input, 0.5*0.0625+0.5*0.625) || !approx(cost.Output, 5) {
+		t.Errorf("cost = %+v", cost)
+	}
+}
+
+func TestLoadAndWith(t *testing.T) {
+	overrides, err := Load(strings.NewReader(`{
+		"version": "acme-2",
+		"models": {
+			"Acme-LLM": {"input": 0.5, "output": 1.5, "cache_read": 0.05},
+			"gpt-4o": {"input": 1, "output": 4}
+		}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	table := Default().With(overrides)
+	if table.Version != "acme-2" {
+		t.Errorf("version = %q, want acme-2", table.Version)
+	}
+	if p, ok := table.Lookup("acme-llm-v3"); !ok || p.CacheRead != 0.05 {
+		t.Errorf("acme-llm = %+v, %v", p, ok)
+	}
+	if p, _ := table.Lookup("gpt-4o"); p.Input != 1 {
+		t.Errorf("gpt-4o input = %v, want override 1", p.Input)
+	}
+	if p, _ := Default().Lookup("gpt-4o"); p.Input != 2.5 {
+		t.Errorf("With modified the default table: gpt-4o input = %v", p.Input)
+	}
+
+	if _, err := Load(strings.NewReader(`{"models": [`)); err == nil {
+		t.Error("Load accepted malformed JSON")
+	}
+	if _, err := LoadFile("does-not-exist.json"); err == nil {
+		t.Error("LoadFile succeeded for a missing file")
+	}
+}
@@ -0,0 +1,67 @@
+package instrumentation
+
+import (
+	"context"
+	"encoding/json"
+
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+
+	"github.com/langchain-ai/langsmith-go/instrumentation/core"
+	"github.com/langchain-ai/langsmith-go/internal/genaiattr"
+)
+
+// Document is a retrieved document in the shape LangSmith renders for
+// retriever runs (LangChain's Document).
+type Document struct {
+	// ID identifies the document in its store, when it has one.
+	ID string `json:"id,omitempty"`
+
+	// PageContent is the document text.
+	PageContent string `json:"page_content"`
+
+	// Metadata holds the document's source, score and other fields.
+	Metadata map[string]any `json:"metadata"`
+}
+
+// TraceRetriever runs fn inside a retriever run (a child span of ctx) named
+// "retriever", recording query as the run's inputs and the returned
+// documents as its outputs. An error from fn marks the run failed and is
+// returned unchanged.
+func TraceRetriever(ctx context.Context, query string, fn func(context.Context) ([]Document, error), opts ...Option) ([]Document, error) {
+	ctx, span := newConfig(opts).start(ctx, "retriever",
+		genaiattr.SpanKindKey.String("retriever"),
+		semconv.GenAIOperationNameRetrieval,
+	)
+	defer span.End()
+
+	// A zero core.Config applies the LANGSMITH_HIDE_INPUTS and
+	// LANGSMITH_HIDE_OUTPUTS environment controls.
+	var content core.Config
+	if b, err := json.Marshal(map[string]string{"query": query}); err == nil {
+		if inputs := content.Content(genaiattr.PromptKey, string(b)); inputs != "" {
+			span.SetAttributes(genaiattr.PromptKey.String(inputs))
+		}
+	}
+
+	docs, err := fn(ctx)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return docs, err
+	}
+	out := make([]Document, len(docs))
+	for i, d := range docs {
+		if d.Metadata == nil {
+			d.Metadata = map[string]any{}
+		}
+		out[i] = d
+	}
+	if b, err := json.Marshal(map[string]any{"documents": out}); err == nil {
+		if outputs := content.Content(genaiattr.CompletionKey, string(b)); outputs != "" {
+			span.SetAttributes(genaiattr.CompletionKey.String(outputs))
+		}
+	}
+	span.SetStatus(codes.Ok, "")
+	return docs, nil
+}
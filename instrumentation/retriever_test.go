@@ -0,0 +1,84 @@
+package instrumentation
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+
+	"github.com/langchain-ai/langsmith-go/internal/thread"
+)
+
+func TestTraceRetriever_RecordsDocuments(t *testing.T) {
+	exporter, tp := newExporter()
+	docs, err := TraceRetriever(context.Background(), "rotate keys", func(context.Context) ([]Document, error) {
+		return []Document{
+			{ID: "1", PageContent: "Rotate keys in settings.", Metadata: map[string]any{"source": "faq.md"}},
+			{PageContent: "No metadata."},
+		}, nil
+	}, WithTracerProvider(tp), WithRunName("docs"), WithAttributes(attribute.String("langsmith.metadata.k", "2")))
+	if err != nil || len(docs) != 2 {
+		t.Fatalf("TraceRetriever = (%v, %v)", docs, err)
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	span := spans[0]
+	if span.Name != "docs" {
+		t.Errorf("name = %q, want docs", span.Name)
+	}
+	if got := getAttr(span, "langsmith.span.kind"); got != "retriever" {
+		t.Errorf("span kind = %q, want retriever", got)
+	}
+	if got := getAttr(span, "gen_ai.operation.name"); got != "retrieval" {
+		t.Errorf("operation = %q, want retrieval", got)
+	}
+	if got := getAttr(span, "langsmith.metadata.k"); got != "2" {
+		t.Errorf("custom attribute = %q, want 2", got)
+	}
+	if got := getAttr(span, "gen_ai.prompt"); got != `{"query":"rotate keys"}` {
+		t.Errorf("inputs = %s", got)
+	}
+	want := `{"documents":[{"id":"1","page_content":"Rotate keys in settings.","metadata":{"source":"faq.md"}},{"page_content":"No metadata.","metadata":{}}]}`
+	if got := getAttr(span, "gen_ai.completion"); got != want {
+		t.Errorf("outputs = %s, want %s", got, want)
+	}
+	if span.Status.Code != codes.Ok {
+		t.Errorf("status = %v, want Ok", span.Status.Code)
+	}
+}
+
+func TestTraceRetriever_Error(t *testing.T) {
+	exporter, tp := newExporter()
+	wantErr := errors.New("index unavailable")
+	_, err := TraceRetriever(context.Background(), "q", func(context.Context) ([]Document, error) {
+		return nil, wantErr
+	}, WithTracerProvider(tp))
+	if !errors.Is(err, wantErr) {
+		t.Fatalf("err = %v, want %v", err, wantErr)
+	}
+	span := exporter.GetSpans()[0]
+	if span.Name != "retriever" || span.Status.Code != codes.Error {
+		t.Errorf("span = %q %v, want retriever Error", span.Name, span.Status.Code)
+	}
+	if got := getAttr(span, "gen_ai.completion"); got != "" {
+		t.Errorf("outputs recorded on failure: %s", got)
+	}
+}
+
+func TestTraceRetriever_Thread(t *testing.T) {
+	exporter, tp := newExporter()
+	ctx := thread.With(context.Background(), "thread-1")
+	if _, err := TraceRetriever(ctx, "q", func(context.Context) ([]Document, error) {
+		return nil, nil
+	}, WithTracerProvider(tp)); err != nil {
+		t.Fatal(err)
+	}
+	if got := getAttr(exporter.GetSpans()[0], "langsmith.metadata.thread_id"); got != "thread-1" {
+		t.Errorf("thread_id = %q, want thread-1", got)
+	}
+}
@@ -0,0 +1,190 @@
+// Package instrumentation holds helpers shared by the LangSmith LLM
+// instrumentation packages (traceopenai, traceanthropic, tracegemini, ...)
+// for tracing the application code around model calls.
+//
+// TraceToolCall records the tools an application runs on a model's behalf as
+// tool runs. Wrapping each iteration of an agent loop in a parent span gives
+// the same tree LangChain agents produce, with each model run linked to the
+// tool runs whose results it received:
+//
+//	ctx, span := tracer.Start(ctx, "agent")
+//	defer span.End()
+//	for {
+//		resp, _ := client.Chat.Completions.New(ctx, params)
+//		calls := resp.Choices[0].Message.ToolCalls
+//		if len(calls) == 0 {
+//			break
+//		}
+//		for _, tc := range calls {
+//			call := instrumentation.ToolCall{ID: tc.ID, Name: tc.Function.Name, Arguments: tc.Function.Arguments}
+//			result, err := instrumentation.TraceToolCall(ctx, call, func(ctx context.Context) (string, error) {
+//				return runTool(ctx, tc.Function.Name, tc.Function.Arguments)
+//			})
+//			// append the tool message with tool_call_id tc.ID and result to params
+//		}
+//	}
+//
+// TraceFallbacks groups a chain of fallback model calls (another provider or
+// model when the primary fails) into one run:
+//
+//	resp, err := instrumentation.TraceFallbacks(ctx, "chat", []instrumentation.Fallback[string]{
+//		{Name: "openai/gpt-4o", Call: callOpenAI},
+//		{Name: "anthropic/claude-sonnet-4", Call: callAnthropic},
+//	})
+//
+// TraceRetriever records a document lookup as a retriever run, whose
+// Document outputs LangSmith renders as a document list. The vectorstore
+// package wraps common vector databases with it.
+package instrumentation
+
+import (
+	"context"
+	"encoding/json"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/langchain-ai/langsmith-go/instrumentation/core"
+	"github.com/langchain-ai/langsmith-go/internal/genaiattr"
+	"github.com/langchain-ai/langsmith-go/internal/thread"
+)
+
+const tracerName = "github.com/langchain-ai/langsmith-go/instrumentation"
+
+// ToolCall is a tool invocation requested by a model.
+type ToolCall struct {
+	// ID is the provider's tool-call ID: tool_calls[].id for OpenAI, the
+	// tool_use block id for Anthropic, functionCall.id for Gemini. It
+	// correlates the tool run with the model request that returns its result.
+	ID string
+
+	// Name is the tool (function) name, used as the run name.
+	Name string
+
+	// Arguments is the JSON-encoded arguments the model supplied.
+	Arguments string
+}
+
+// Option configures TraceToolCall, TraceFallbacks and TraceRetriever.
+type Option func(*config)
+
+type config struct {
+	tracerProvider trace.TracerProvider
+	runName        string
+	attributes     []attribute.KeyValue
+}
+
+// WithTracerProvider returns an Option that sets the tracer provider.
+// If not provided, the global tracer provider is used.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(cfg *config) {
+		cfg.tracerProvider = tp
+	}
+}
+
+// WithRunName sets the span (run) name when non-empty, in place of the tool
+// name, the fallback chain name or "retriever".
+func WithRunName(name string) Option {
+	return func(cfg *config) {
+		cfg.runName = name
+	}
+}
+
+// WithAttributes adds attributes to the run, such as
+// langsmith.metadata.<key> entries that LangSmith records as run metadata.
+func WithAttributes(attrs ...attribute.KeyValue) Option {
+	return func(cfg *config) {
+		cfg.attributes = append(cfg.attributes, attrs...)
+	}
+}
+
+func newConfig(opts []Option) config {
+	var cfg config
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.tracerProvider == nil {
+		cfg.tracerProvider = otel.GetTracerProvider()
+	}
+	return cfg
+}
+
+// start starts a run named name, or the WithRunName name, with attrs, the
+// WithAttributes attributes and ctx's thread metadata.
+func (cfg config) start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	if cfg.runName != "" {
+		name = cfg.runName
+	}
+	return cfg.tracerProvider.Tracer(tracerName).Start(ctx, name,
+		trace.WithAttributes(attrs...),
+		trace.WithAttributes(cfg.attributes...),
+		trace.WithAttributes(thread.Attributes(ctx)...),
+	)
+}
+
+// TraceToolCall runs fn inside a tool run (a child span of ctx) named after
+// the tool, recording call's arguments as inputs and fn's result as outputs.
+// An error from fn marks the run failed and is returned unchanged.
+//
+// The run is keyed by call.ID: the next traced model request whose prompt
+// carries that tool call's result is linked to it.
+func TraceToolCall[T any](ctx context.Context, call ToolCall, fn func(context.Context) (T, error), opts ...Option) (T, error) {
+	cfg := newConfig(opts)
+	name := call.Name
+	if name == "" {
+		name = "tool"
+	}
+	ctx, span := cfg.start(ctx, name,
+		genaiattr.SpanKindKey.String("tool"),
+		semconv.GenAIOperationNameExecuteTool,
+		semconv.GenAIToolName(call.Name),
+		semconv.GenAIToolType("function"),
+	)
+	defer span.End()
+	if call.ID != "" {
+		span.SetAttributes(semconv.GenAIToolCallID(call.ID))
+	}
+	core.RegisterToolCall(call.ID, span.SpanContext())
+
+	// A zero core.Config applies the LANGSMITH_HIDE_INPUTS and
+	// LANGSMITH_HIDE_OUTPUTS environment controls.
+	var content core.Config
+	if inputs := content.Content(genaiattr.PromptKey, toolInputs(call.Arguments)); inputs != "" {
+		span.SetAttributes(genaiattr.PromptKey.String(inputs))
+	}
+
+	result, err := fn(ctx)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return result, err
+	}
+	if b, err := json.Marshal(map[string]any{"output": result}); err == nil {
+		if outputs := content.Content(genaiattr.CompletionKey, string(b)); outputs != "" {
+			span.SetAttributes(genaiattr.CompletionKey.String(outputs))
+		}
+	}
+	span.SetStatus(codes.Ok, "")
+	return result, nil
+}
+
+// toolInputs returns the run inputs for a tool call's arguments: the
+// arguments object itself, or {"input": arguments} when they are not a JSON
+// object, matching LangChain tool runs.
+func toolInputs(arguments string) string {
+	if arguments == "" {
+		return ""
+	}
+	var obj map[string]any
+	if json.Unmarshal([]byte(arguments), &obj) == nil {
+		return arguments
+	}
+	b, err := json.Marshal(map[string]any{"input": arguments})
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
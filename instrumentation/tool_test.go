@@ -0,0 +1,140 @@
+package instrumentation
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/langchain-ai/langsmith-go/instrumentation/traceopenai"
+)
+
+type fakeTransport struct {
+	body []byte
+}
+
+func (t *fakeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       io.NopCloser(bytes.NewReader(t.body)),
+		Header:     make(http.Header),
+		Request:    req,
+	}, nil
+}
+
+func getAttr(span tracetest.SpanStub, key string) string {
+	for _, kv := range span.Attributes {
+		if string(kv.Key) == key {
+			return kv.Value.Emit()
+		}
+	}
+	return ""
+}
+
+func newExporter() (*tracetest.InMemoryExporter, *sdktrace.TracerProvider) {
+	exporter := tracetest.NewInMemoryExporter()
+	return exporter, sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+}
+
+func TestTraceToolCall_RecordsToolRun(t *testing.T) {
+	exporter, tp := newExporter()
+	call := ToolCall{ID: "call_1", Name: "get_weather", Arguments: `{"city":"Paris"}`}
+	got, err := TraceToolCall(context.Background(), call, func(context.Context) (string, error) {
+		return "sunny", nil
+	}, WithTracerProvider(tp))
+	if err != nil || got != "sunny" {
+		t.Fatalf("TraceToolCall = (%q, %v)", got, err)
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	span := spans[0]
+	if span.Name != "get_weather" || span.Status.Code != codes.Ok {
+		t.Errorf("span = %q %v", span.Name, span.Status)
+	}
+	want := map[string]string{
+		"langsmith.span.kind":   "tool",
+		"gen_ai.operation.name": "execute_tool",
+		"gen_ai.tool.call.id":   "call_1",
+		"gen_ai.prompt":         `{"city":"Paris"}`,
+		"gen_ai.completion":     `{"output":"sunny"}`,
+	}
+	for k, v := range want {
+		if got := getAttr(span, k); got != v {
+			t.Errorf("%s = %q, want %q", k, got, v)
+		}
+	}
+}
+
+func TestTraceToolCall_Error(t *testing.T) {
+	exporter, tp := newExporter()
+	wantErr := errors.New("city not found")
+	_, err := TraceToolCall(context.Background(), ToolCall{Name: "get_weather", Arguments: "Atlantis"}, func(context.Context) (int, error) {
+		return 0, wantErr
+	}, WithTracerProvider(tp))
+	if err != wantErr {
+		t.Fatalf("err = %v, want %v", err, wantErr)
+	}
+
+	span := exporter.GetSpans()[0]
+	if span.Status.Code != codes.Error || span.Status.Description != "city not found" {
+		t.Errorf("status = %v", span.Status)
+	}
+	if got := getAttr(span, "gen_ai.prompt"); got != `{"input":"Atlantis"}` {
+		t.Errorf("gen_ai.prompt = %s", got)
+	}
+}
+
+func TestTraceToolCall_LinksFollowUpModelRequest(t *testing.T) {
+	exporter, tp := newExporter()
+	ctx, agent := tp.Tracer("test").Start(context.Background(), "agent")
+
+	_, err := TraceToolCall(ctx, ToolCall{ID: "call_link", Name: "lookup"}, func(context.Context) (string, error) {
+		return "42", nil
+	}, WithTracerProvider(tp))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp := `{"model":"gpt-4o","choices":[{"message":{"role":"assistant","content":"It is 42."}}]}`
+	client := traceopenai.WrapClient(&http.Client{Transport: &fakeTransport{body: []byte(resp)}}, traceopenai.WithTracerProvider(tp))
+	body := `{"model":"gpt-4o","messages":[
+		{"role":"user","content":"look it up"},
+		{"role":"assistant","tool_calls":[{"id":"call_link","type":"function","function":{"name":"lookup","arguments":"{}"}}]},
+		{"role":"tool","tool_call_id":"call_link","content":"42"}
+	]}`
+	for i := 0; i < 2; i++ {
+		req, _ := http.NewRequestWithContext(ctx, http.MethodPost, "https://api.openai.com/v1/chat/completions", strings.NewReader(body))
+		r, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.ReadAll(r.Body)
+		r.Body.Close()
+	}
+	agent.End()
+
+	spans := exporter.GetSpans()
+	if len(spans) != 4 {
+		t.Fatalf("got %d spans, want 4", len(spans))
+	}
+	tool, first, second := spans[0], spans[1], spans[2]
+	if tool.Parent.SpanID() != agent.SpanContext().SpanID() || first.Parent.SpanID() != agent.SpanContext().SpanID() {
+		t.Error("tool and model runs should be children of the agent span")
+	}
+	if len(first.Links) != 1 || first.Links[0].SpanContext.SpanID() != tool.SpanContext.SpanID() {
+		t.Fatalf("first model request links = %+v, want the tool span", first.Links)
+	}
+	if len(second.Links) != 0 {
+		t.Errorf("a resent tool result should not be linked again, got %d links", len(second.Links))
+	}
+}
@@ -1,9 +1,20 @@
 // Package traceanthropic provides OpenTelemetry tracing for the
 // Anthropic API client using LangSmith-compatible spans.
 //
+// Messages (streaming and not), count_tokens and Message Batches creation are
+// traced, on the Anthropic API and on Vertex AI. Prompt caching usage, the
+// anthropic-beta header and message and batch IDs are recorded.
+//
 // Usage:
 //
-//	// Configure your Anthropic client to use a traced HTTP client
+//	// Plug into the SDK's middleware chain; the client keeps its own HTTP
+//	// client, retries and, with vertex.WithGoogleAuth, its Vertex AI auth
+//	client := anthropic.NewClient(
+//		option.WithAPIKey(apiKey),
+//		option.WithMiddleware(traceanthropic.Middleware()),
+//	)
+//
+//	// Or configure your Anthropic client to use a traced HTTP client
 //	client := anthropic.NewClient(
 //		anthropic.WithAPIKey(apiKey),
 //		anthropic.WithHTTPClient(traceanthropic.Client()),
@@ -21,28 +32,20 @@
 package traceanthropic
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
-	"fmt"
-	"io"
 	"net/http"
 	"strings"
 
-	"go.opentelemetry.io/otel"
+	"github.com/anthropics/anthropic-sdk-go/option"
 	"go.opentelemetry.io/otel/attribute"
-	"go.opentelemetry.io/otel/codes"
-	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/trace"
 
+	"github.com/langchain-ai/langsmith-go/instrumentation/core"
+	"github.com/langchain-ai/langsmith-go/instrumentation/pricing"
 	"github.com/langchain-ai/langsmith-go/internal/genaiattr"
-	"github.com/langchain-ai/langsmith-go/internal/traceutil"
 )
 
-type contextKey struct{ name string }
-
-var ctxKeyRunName = contextKey{"run_name"}
-
 // WithRunNameContext sets the span (run) name for the next traced request made with ctx.
 // The run name in LangSmith is the OTLP span name; there is no separate field.
 // Use this so one client can emit runs with different names per call, e.g. in tests:
@@ -50,32 +53,51 @@ var ctxKeyRunName = contextKey{"run_name"}
 //	ctx = traceanthropic.WithRunNameContext(ctx, "anthropic_nonstreaming")
 //	client.Messages.New(ctx, ...)
 func WithRunNameContext(ctx context.Context, name string) context.Context {
-	return context.WithValue(ctx, ctxKeyRunName, name)
+	return core.WithRunNameContext(ctx, name)
 }
 
 // Option configures a traced HTTP client.
-type Option func(*clientOptions)
-
-type clientOptions struct {
-	tracerProvider trace.TracerProvider
-	runName        string
-}
+type Option func(*core.Config)
 
 // WithTracerProvider returns an Option that sets the tracer provider.
 // If not provided, the global tracer provider is used.
 func WithTracerProvider(tp trace.TracerProvider) Option {
-	return func(opts *clientOptions) {
-		opts.tracerProvider = tp
+	return func(cfg *core.Config) {
+		cfg.TracerProvider = tp
 	}
 }
 
 // WithRunName sets the span (run) name to the given string when non-empty. Used by integration tests to identify runs in a shared project.
 func WithRunName(name string) Option {
-	return func(opts *clientOptions) {
-		opts.runName = name
+	return func(cfg *core.Config) {
+		cfg.RunName = name
 	}
 }
 
+// WithAttachments is [core.WithAttachments].
+func WithAttachments(h core.AttachmentHandler) Option { return Option(core.WithAttachments(h)) }
+
+// WithMaxAttachmentSize is [core.WithMaxAttachmentSize].
+func WithMaxAttachmentSize(n int) Option { return Option(core.WithMaxAttachmentSize(n)) }
+
+// WithCaptureContent is [core.WithCaptureContent].
+func WithCaptureContent(capture bool) Option { return Option(core.WithCaptureContent(capture)) }
+
+// WithRedactor is [core.WithRedactor].
+func WithRedactor(r core.Redactor) Option { return Option(core.WithRedactor(r)) }
+
+// WithMaxContentLength is [core.WithMaxContentLength].
+func WithMaxContentLength(n int) Option { return Option(core.WithMaxContentLength(n)) }
+
+// WithPricing is [core.WithPricing].
+func WithPricing(prices *pricing.Table) Option { return Option(core.WithPricing(prices)) }
+
+// EndPendingRetries is [core.EndPendingRetries]. A run whose last attempt
+// failed stays open until the SDK's retry window passes; call this before
+// shutting down your tracer provider so it is exported. The LangSmith
+// OTelTracer's Shutdown calls it for you.
+func EndPendingRetries() { core.EndPendingRetries() }
+
 // Client returns a new http.Client configured with tracing middleware.
 // Equivalent to WrapClient(nil, opts...), which wraps the default transport.
 func Client(opts ...Option) *http.Client {
@@ -85,171 +107,95 @@ func Client(opts ...Option) *http.Client {
 // WrapClient wraps an existing http.Client with tracing middleware.
 // If client is nil, a new client with the default transport is created.
 func WrapClient(client *http.Client, opts ...Option) *http.Client {
-	options := &clientOptions{}
+	var cfg core.Config
 	for _, opt := range opts {
-		opt(options)
+		opt(&cfg)
 	}
+	return core.WrapClient(client, provider{}, cfg)
+}
 
-	if client == nil {
-		client = &http.Client{}
+// Middleware returns an anthropic-sdk-go middleware that traces Anthropic API
+// requests. Pass it to option.WithMiddleware, either when constructing the
+// client or on individual calls. Middleware runs in the order given, so
+// placing it after vertex.WithGoogleAuth traces the rewritten Vertex AI
+// request.
+func Middleware(opts ...Option) option.Middleware {
+	var cfg core.Config
+	for _, opt := range opts {
+		opt(&cfg)
 	}
-	transport := client.Transport
-	if transport == nil {
-		transport = http.DefaultTransport
+	return func(req *http.Request, next option.MiddlewareNext) (*http.Response, error) {
+		return core.Middleware(req, next, provider{}, cfg)
 	}
-	client.Transport = newRoundTripper(transport, options.tracerProvider, options.runName)
-	return client
 }
 
-type roundTripper struct {
-	base           http.RoundTripper
-	tracerProvider trace.TracerProvider
-	runName        string
+// Provider returns the core.Provider that parses Anthropic Messages API
+// traffic. It is exported so instrumentations of platforms that serve the
+// Messages API format, such as Bedrock, can share the same parsing.
+func Provider() core.Provider {
+	return provider{}
 }
 
-func newRoundTripper(base http.RoundTripper, tp trace.TracerProvider, runName string) http.RoundTripper {
-	return &roundTripper{
-		base:           base,
-		tracerProvider: tp,
-		runName:        runName,
-	}
-}
+// provider adapts the Anthropic Messages API to the core round tripper.
+type provider struct{}
 
-// RoundTrip intercepts requests/responses to add OpenTelemetry tracing.
-func (rt *roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
-	// Only trace Anthropic API requests
-	if !shouldTrace(req) {
-		return rt.base.RoundTrip(req)
-	}
+func (provider) TracerName() string { return "github.com/anthropics/anthropic-sdk-go" }
 
-	ctx := req.Context()
-	var tracer trace.Tracer
-	if rt.tracerProvider != nil {
-		tracer = rt.tracerProvider.Tracer("github.com/anthropics/anthropic-sdk-go")
-	} else {
-		tracer = otel.Tracer("github.com/anthropics/anthropic-sdk-go")
-	}
-
-	// Extract span context from request headers
-	ctx = otel.GetTextMapPropagator().Extract(ctx, propagation.HeaderCarrier(req.Header))
-
-	// Capture parent span before creating child span (for token propagation)
-	parentSpan := trace.SpanFromContext(ctx)
-
-	spanName := getSpanName(req.URL.Path)
-	if v := ctx.Value(ctxKeyRunName); v != nil {
-		if s, ok := v.(string); ok && s != "" {
-			spanName = s
-		}
-	} else if rt.runName != "" {
-		spanName = rt.runName
-	}
+func (provider) Match(req *http.Request) bool { return shouldTrace(req) }
 
-	// Start span (child span)
-	ctx, span := tracer.Start(ctx, spanName,
-		trace.WithAttributes(
+func (provider) ParseRequest(req *http.Request, body []byte) *core.Request {
+	ep := endpointForPath(req.URL.Path)
+	call := &core.Request{
+		SpanName: getSpanName(req.URL.Path),
+		State:    ep,
+		Attributes: []attribute.KeyValue{
 			attribute.String("gen_ai.system", "anthropic"),
 			attribute.String("gen_ai.operation.name", getOperationName(req.URL.Path)),
-			attribute.String("http.method", req.Method),
-			attribute.String("http.url", req.URL.String()),
-		),
-	)
-
-	// Read request body if present
-	var requestBody []byte
-	if req.Body != nil {
-		var err error
-		requestBody, err = io.ReadAll(req.Body)
-		if err != nil {
-			span.RecordError(err)
-			span.SetStatus(codes.Error, fmt.Sprintf("failed to read request body: %v", err))
-			span.End()
-			return rt.base.RoundTrip(req)
-		}
-		req.Body = io.NopCloser(bytes.NewBuffer(requestBody))
+		},
 	}
-
-	// Extract request attributes and streaming flag
-	var streaming bool
-	if len(requestBody) > 0 {
-		streaming = extractRequestAttributes(span, requestBody)
+	if betas := req.Header.Values("anthropic-beta"); len(betas) > 0 {
+		call.Attributes = append(call.Attributes, betaKey.String(strings.Join(betas, ",")))
 	}
 
-	// Inject span context into request headers and update request context
-	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
-	req = req.WithContext(ctx)
-
-	// Make the actual request
-	resp, err := rt.base.RoundTrip(req)
-	if err != nil {
-		span.RecordError(err)
-		span.SetStatus(codes.Error, err.Error())
-		span.End()
-		return resp, err
-	}
-
-	br := traceutil.NewBufferedReader(resp.Body, func(r io.Reader, readErr error) {
-		data, err := io.ReadAll(r)
-		if err != nil {
-			span.RecordError(err)
-			span.SetStatus(codes.Error, err.Error())
-			span.End()
-			return
-		}
-		if len(data) == 0 {
-			if resp.StatusCode >= 400 {
-				apiErr := fmt.Errorf("HTTP %d", resp.StatusCode)
-				span.RecordError(apiErr)
-				span.SetStatus(codes.Error, apiErr.Error())
-			}
-			if readErr != nil && readErr != io.EOF {
-				span.RecordError(readErr)
-				span.SetStatus(codes.Error, readErr.Error())
-			}
-			span.End()
-			return
-		}
-
-		bodyText := string(data)
-		if resp.StatusCode >= 400 {
-			msg := bodyText
-			if len(msg) > 500 {
-				msg = msg[:500] + "..."
-			}
-			apiErr := fmt.Errorf("HTTP %d: %s", resp.StatusCode, msg)
-			span.RecordError(apiErr)
-			span.SetStatus(codes.Error, apiErr.Error())
-		}
-		incompleteStream := resp.StatusCode < 400 && streaming && !strings.Contains(bodyText, "message_stop")
-		if incompleteStream {
-			endErr := readErr
-			if endErr == nil || endErr == io.EOF {
-				endErr = fmt.Errorf("Cancelled")
-			}
-			span.RecordError(endErr)
-			span.SetStatus(codes.Error, endErr.Error())
+	if ep == endpointBatches {
+		model, prompt := parseBatchRequest(body)
+		if model != "" {
+			call.Attributes = append(call.Attributes, attribute.String("gen_ai.request.model", model))
 		}
+		call.Prompt = prompt
+		return call
+	}
 
-		if streaming {
-			extractStreamingResponseAttributes(span, data, parentSpan)
-		} else {
-			extractResponseAttributes(span, data, parentSpan)
-		}
-		if resp.StatusCode < 400 && !incompleteStream {
-			span.SetStatus(codes.Ok, "")
-		}
-		span.End()
-	})
-	// LangSmith ingest reads new_token to derive first_token_time; skip on
-	// HTTP errors so an error body doesn't inflate it.
-	if streaming && resp.StatusCode < 400 {
-		traceutil.OnFirstSSEMatch(br, isFirstContent, func() { span.AddEvent("new_token") })
+	var fields requestFields
+	if len(body) > 0 {
+		fields = parseRequestBody(body)
+		call.Attributes = append(call.Attributes, fields.attrs...)
+		call.Prompt = fields.prompt
+		call.Streaming = fields.streaming
+	}
+	// Vertex AI takes the model from the URL and drops it from the body.
+	if model := vertexModel(req.URL.Path); fields.model == "" && model != "" {
+		call.Attributes = append(call.Attributes, attribute.String("gen_ai.request.model", model))
 	}
-	resp.Body = br
+	return call
+}
 
-	return resp, nil
+func (provider) ParseResponse(call *core.Request, body []byte) *core.Response {
+	switch ep, _ := call.State.(endpoint); ep {
+	case endpointCountTokens:
+		return parseCountTokensResponse(body)
+	case endpointBatches:
+		return parseBatchResponse(body)
+	}
+	return parseResponse(body)
 }
 
+func (provider) NewStream(*core.Request) core.Stream { return &stream{} }
+
+// betaKey records the anthropic-beta request header, which opts a request
+// into beta features that can change its behaviour and pricing.
+const betaKey = attribute.Key("langsmith.metadata.anthropic_beta")
+
 // Anthropic streams open with message_start and content_block_start before
 // any tokens; content_block_delta is the first real token.
 func isFirstContent(chunk map[string]any) bool {
@@ -261,59 +207,140 @@ func shouldTrace(req *http.Request) bool {
 	if req == nil || req.URL == nil {
 		return false
 	}
-	if strings.Contains(req.URL.Host, "api.anthropic.com") {
+	switch endpointForPath(req.URL.Path) {
+	case endpointMessages, endpointCountTokens:
 		return true
+	case endpointBatches:
+		// Only creation is traced; listing batches is not a model call.
+		return req.Method == http.MethodPost
+	case endpointBatch:
+		// Retrieving, cancelling or deleting a batch, or fetching its
+		// results, is not a model call.
+		return false
 	}
-	return isAnthropicEndpoint(req.URL.Path)
+	return strings.Contains(req.URL.Host, "api.anthropic.com")
 }
 
-// isAnthropicEndpoint matches direct and Vertex Anthropic calls.
-func isAnthropicEndpoint(path string) bool {
-	return strings.Contains(path, "/v1/messages") ||
-		strings.Contains(path, "/publishers/anthropic/")
+// endpoint identifies the API operation a request path targets.
+type endpoint int
+
+const (
+	endpointOther endpoint = iota
+	// endpointMessages is /v1/messages, or a Vertex AI rawPredict or
+	// streamRawPredict call on an Anthropic model.
+	endpointMessages
+	// endpointCountTokens is /v1/messages/count_tokens, or Vertex AI's
+	// count-tokens:rawPredict.
+	endpointCountTokens
+	// endpointBatches is the /v1/messages/batches collection.
+	endpointBatches
+	// endpointBatch is a single batch or its results.
+	endpointBatch
+)
+
+// endpointForPath classifies direct and Vertex Anthropic calls. Paths may
+// carry a prefix (Anthropic-compatible hosts) and a query (?beta=true).
+func endpointForPath(path string) endpoint {
+	if i := strings.IndexByte(path, '?'); i >= 0 {
+		path = path[:i]
+	}
+	path = strings.TrimSuffix(path, "/")
+	switch {
+	case strings.HasSuffix(path, "/v1/messages"):
+		return endpointMessages
+	case strings.HasSuffix(path, "/v1/messages/count_tokens"),
+		strings.HasSuffix(path, "/publishers/anthropic/models/count-tokens:rawPredict"):
+		return endpointCountTokens
+	case strings.HasSuffix(path, "/v1/messages/batches"):
+		return endpointBatches
+	case strings.Contains(path, "/v1/messages/batches/"):
+		return endpointBatch
+	case strings.Contains(path, "/publishers/anthropic/"):
+		return endpointMessages
+	}
+	return endpointOther
+}
+
+// vertexModel returns the model named in a Vertex AI path
+// (…/publishers/anthropic/models/{model}:rawPredict), or "".
+func vertexModel(path string) string {
+	const marker = "/publishers/anthropic/models/"
+	i := strings.Index(path, marker)
+	if i < 0 {
+		return ""
+	}
+	model := path[i+len(marker):]
+	if j := strings.IndexByte(model, ':'); j >= 0 {
+		model = model[:j]
+	}
+	if model == "count-tokens" {
+		return ""
+	}
+	return model
 }
 
 // getSpanName returns an appropriate span name based on the API endpoint.
 func getSpanName(path string) string {
-	if isAnthropicEndpoint(path) {
+	switch endpointForPath(path) {
+	case endpointMessages:
 		return "anthropic.messages"
+	case endpointCountTokens:
+		return "anthropic.messages.count_tokens"
+	case endpointBatches:
+		return "anthropic.messages.batches"
 	}
 	return "anthropic.request"
 }
 
 // getOperationName returns the operation name for Gen AI semantic conventions.
 func getOperationName(path string) string {
-	if isAnthropicEndpoint(path) {
+	switch endpointForPath(path) {
+	case endpointMessages:
 		return "chat"
+	case endpointCountTokens:
+		return "count_tokens"
+	case endpointBatches:
+		return "batch"
 	}
 	return "request"
 }
 
-// extractRequestAttributes extracts attributes from the Anthropic request body
-// and returns whether the request is streaming.
-func extractRequestAttributes(span trace.Span, body []byte) (streaming bool) {
+// requestFields holds fields extracted from the request body.
+type requestFields struct {
+	attrs     []attribute.KeyValue
+	model     string
+	prompt    string
+	streaming bool
+}
+
+// parseRequestBody extracts model, sampling parameters, input messages and
+// the streaming flag from an Anthropic request body.
+func parseRequestBody(body []byte) requestFields {
 	var req map[string]any
 	if err := json.Unmarshal(body, &req); err != nil {
-		return false
+		return requestFields{}
 	}
 
+	var fields requestFields
+
 	// Model
 	if model, ok := req["model"].(string); ok {
-		span.SetAttributes(attribute.String("gen_ai.request.model", model))
+		fields.model = model
+		fields.attrs = append(fields.attrs, attribute.String("gen_ai.request.model", model))
 	}
 
 	// Max tokens
 	if maxTokens, ok := req["max_tokens"].(float64); ok {
-		span.SetAttributes(attribute.Int64("gen_ai.request.max_tokens", int64(maxTokens)))
+		fields.attrs = append(fields.attrs, attribute.Int64("gen_ai.request.max_tokens", int64(maxTokens)))
 	}
 
 	// Temperature
 	if temp, ok := req["temperature"].(float64); ok {
-		span.SetAttributes(attribute.Float64("gen_ai.request.temperature", temp))
+		fields.attrs = append(fields.attrs, attribute.Float64("gen_ai.request.temperature", temp))
 	}
 
 	// Streaming flag
-	streaming, _ = req["stream"].(bool)
+	fields.streaming, _ = req["stream"].(bool)
 
 	// Build input messages — system prepended, all roles preserved
 	var messages []any
@@ -329,16 +356,15 @@ func extractRequestAttributes(span trace.Span, body []byte) (streaming bool) {
 
 	if len(messages) > 0 {
 		if out, err := json.Marshal(map[string]any{"messages": messages}); err == nil {
-			span.SetAttributes(attribute.String("gen_ai.prompt", string(out)))
+			fields.prompt = string(out)
 		}
 	}
 
-	return streaming
+	return fields
 }
 
-// extractStreamingResponseAttributes parses an SSE response body and sets
-// the same span attributes as the non-streaming path: gen_ai.completion,
-// usage tokens, and cache breakdown.
+// stream accumulates an Anthropic SSE response into the same response shape
+// as the non-streaming path: completion, usage and stop reason.
 //
 // Anthropic SSE events of interest:
 //   - message_start       — contains message.usage (input_tokens, cache tokens,
@@ -349,105 +375,120 @@ func extractRequestAttributes(span trace.Span, body []byte) (streaming bool) {
 //     server_tool_use) and stop_reason
 //
 // Usage fields from message_start and message_delta are merged into a single
-// map and handed to setUsageAttributes, which captures every token type.
-func extractStreamingResponseAttributes(span trace.Span, data []byte, parentSpan trace.Span) {
-	chunks, err := traceutil.ParseSSEChunks(bytes.NewReader(data))
-	if err != nil || len(chunks) == 0 {
-		return
-	}
-
-	usage := make(map[string]interface{})
+// map and handed to buildUsage, which captures every token type.
+type stream struct {
+	id         string
+	model      string
+	stopReason string
+	usage      map[string]any
+	// blocks tracks content blocks by index for multi-block reconstruction.
+	blocks []*contentBlock
+}
 
-	// Track content blocks by index for proper multi-block reconstruction
-	type contentBlock struct {
-		blockType string // "text" or "tool_use"
-		id        string // tool_use id
-		name      string // tool_use function name
-		buf       strings.Builder
-	}
-	var blocks []*contentBlock
+type contentBlock struct {
+	blockType string // "text" or "tool_use"
+	id        string // tool_use id
+	name      string // tool_use function name
+	buf       strings.Builder
+}
 
-	for _, chunk := range chunks {
-		eventType, _ := chunk["type"].(string)
+func (s *stream) IsFirstToken(chunk map[string]any) bool { return isFirstContent(chunk) }
 
-		switch eventType {
-		case "message_start":
-			if message, ok := chunk["message"].(map[string]any); ok {
-				if curUsage, ok := message["usage"].(map[string]any); ok {
-					for k, v := range curUsage {
-						usage[k] = v
-					}
-				}
-				if model, ok := message["model"].(string); ok {
-					span.SetAttributes(attribute.String("gen_ai.response.model", model))
-				}
-			}
-
-		case "content_block_start":
-			idxF, ok := chunk["index"].(float64)
-			if !ok {
-				continue
+func (s *stream) Chunk(chunk map[string]any) {
+	switch eventType, _ := chunk["type"].(string); eventType {
+	case "message_start":
+		if message, ok := chunk["message"].(map[string]any); ok {
+			if curUsage, ok := message["usage"].(map[string]any); ok {
+				s.mergeUsage(curUsage)
 			}
-			idx := int(idxF)
-			for len(blocks) <= idx {
-				blocks = append(blocks, nil)
+			if model, ok := message["model"].(string); ok {
+				s.model = model
 			}
-			block := &contentBlock{}
-			if cb, ok := chunk["content_block"].(map[string]any); ok {
-				block.blockType, _ = cb["type"].(string)
-				block.id, _ = cb["id"].(string)
-				block.name, _ = cb["name"].(string)
-			}
-			blocks[idx] = block
+			s.id, _ = message["id"].(string)
+		}
 
-		case "content_block_delta":
-			idxF, ok := chunk["index"].(float64)
-			if !ok {
-				continue
-			}
-			idx := int(idxF)
-			for len(blocks) <= idx {
-				blocks = append(blocks, nil)
-			}
-			if blocks[idx] == nil {
-				blocks[idx] = &contentBlock{}
-			}
-			if delta, ok := chunk["delta"].(map[string]any); ok {
-				switch deltaType, _ := delta["type"].(string); deltaType {
-				case "text_delta":
-					if text, ok := delta["text"].(string); ok {
-						blocks[idx].buf.WriteString(text)
-						if blocks[idx].blockType == "" {
-							blocks[idx].blockType = "text"
-						}
+	case "content_block_start":
+		block := s.block(chunk)
+		if block == nil {
+			return
+		}
+		if cb, ok := chunk["content_block"].(map[string]any); ok {
+			block.blockType, _ = cb["type"].(string)
+			block.id, _ = cb["id"].(string)
+			block.name, _ = cb["name"].(string)
+		}
+
+	case "content_block_delta":
+		block := s.block(chunk)
+		if block == nil {
+			return
+		}
+		if delta, ok := chunk["delta"].(map[string]any); ok {
+			switch deltaType, _ := delta["type"].(string); deltaType {
+			case "text_delta":
+				if text, ok := delta["text"].(string); ok {
+					block.buf.WriteString(text)
+					if block.blockType == "" {
+						block.blockType = "text"
 					}
-				case "input_json_delta":
-					if partialJSON, ok := delta["partial_json"].(string); ok {
-						blocks[idx].buf.WriteString(partialJSON)
-						if blocks[idx].blockType == "" {
-							blocks[idx].blockType = "tool_use"
-						}
+				}
+			case "input_json_delta":
+				if partialJSON, ok := delta["partial_json"].(string); ok {
+					block.buf.WriteString(partialJSON)
+					if block.blockType == "" {
+						block.blockType = "tool_use"
 					}
 				}
 			}
+		}
 
-		case "message_delta":
-			if curUsage, ok := chunk["usage"].(map[string]any); ok {
-				for k, v := range curUsage {
-					usage[k] = v
-				}
-			}
-			if delta, ok := chunk["delta"].(map[string]any); ok {
-				if stopReason, ok := delta["stop_reason"].(string); ok && stopReason != "" {
-					span.SetAttributes(attribute.String("langsmith.metadata.stop_reason", stopReason))
-				}
+	case "message_delta":
+		if curUsage, ok := chunk["usage"].(map[string]any); ok {
+			s.mergeUsage(curUsage)
+		}
+		if delta, ok := chunk["delta"].(map[string]any); ok {
+			if stopReason, ok := delta["stop_reason"].(string); ok && stopReason != "" {
+				s.stopReason = stopReason
 			}
 		}
 	}
+}
+
+// block returns the content block addressed by chunk's index, allocating it
+// on first use. A content_block_start resets the block. Returns nil when the
+// chunk has no index.
+func (s *stream) block(chunk map[string]any) *contentBlock {
+	idxF, ok := chunk["index"].(float64)
+	if !ok {
+		return nil
+	}
+	idx := int(idxF)
+	for len(s.blocks) <= idx {
+		s.blocks = append(s.blocks, nil)
+	}
+	if t, _ := chunk["type"].(string); t == "content_block_start" || s.blocks[idx] == nil {
+		s.blocks[idx] = &contentBlock{}
+	}
+	return s.blocks[idx]
+}
+
+func (s *stream) mergeUsage(u map[string]any) {
+	if s.usage == nil {
+		s.usage = make(map[string]any)
+	}
+	for k, v := range u {
+		s.usage[k] = v
+	}
+}
+
+// Finish reconstructs content blocks into an assistant message. A stream is
+// complete once message_stop has been received.
+func (s *stream) Finish(data []byte, _ error) (*core.Response, bool) {
+	complete := strings.Contains(string(data), "message_stop")
+	resp := &core.Response{Model: s.model, ID: s.id, StopReason: s.stopReason}
 
-	// Reconstruct content blocks into an assistant message
 	var contentBlocks []map[string]any
-	for _, b := range blocks {
+	for _, b := range s.blocks {
 		if b == nil {
 			continue
 		}
@@ -478,35 +519,31 @@ func extractStreamingResponseAttributes(span trace.Span, data []byte, parentSpan
 			map[string]any{"role": "assistant", "content": contentBlocks},
 		}}
 		if out, err := json.Marshal(msg); err == nil {
-			span.SetAttributes(attribute.String("gen_ai.completion", string(out)))
+			resp.Completion = string(out)
 		}
 	}
 
-	if len(usage) > 0 {
-		setUsageAttributes(span, usage, parentSpan)
+	if len(s.usage) > 0 {
+		resp.Usage = buildUsage(s.usage)
 	}
+	return resp, complete
 }
 
-// extractResponseAttributes extracts attributes from Anthropic response body.
-func extractResponseAttributes(span trace.Span, body []byte, parentSpan trace.Span) {
-	var resp map[string]interface{}
+// parseResponse extracts model, usage, stop reason and output from an
+// Anthropic response body. Returns nil when the body is not JSON.
+func parseResponse(body []byte) *core.Response {
+	var resp map[string]any
 	if err := json.Unmarshal(body, &resp); err != nil {
-		return
-	}
-
-	// Extract model
-	if model, ok := resp["model"].(string); ok {
-		span.SetAttributes(attribute.String("gen_ai.response.model", model))
+		return nil
 	}
 
-	// Extract usage
-	if usage, ok := resp["usage"].(map[string]interface{}); ok {
-		setUsageAttributes(span, usage, parentSpan)
-	}
+	out := &core.Response{}
+	out.Model, _ = resp["model"].(string)
+	out.ID, _ = resp["id"].(string)
+	out.StopReason, _ = resp["stop_reason"].(string)
 
-	// Extract stop_reason
-	if stopReason, ok := resp["stop_reason"].(string); ok && stopReason != "" {
-		span.SetAttributes(attribute.String("langsmith.metadata.stop_reason", stopReason))
+	if usage, ok := resp["usage"].(map[string]any); ok {
+		out.Usage = buildUsage(usage)
 	}
 
 	// Extract output — wrap full content array in an assistant message
@@ -514,10 +551,11 @@ func extractResponseAttributes(span trace.Span, body []byte, parentSpan trace.Sp
 		msg := map[string]any{"messages": []any{
 			map[string]any{"role": "assistant", "content": content},
 		}}
-		if out, err := json.Marshal(msg); err == nil {
-			span.SetAttributes(attribute.String("gen_ai.completion", string(out)))
+		if b, err := json.Marshal(msg); err == nil {
+			out.Completion = string(b)
 		}
 	}
+	return out
 }
 
 // buildUsageMetadata maps an Anthropic Messages API usage object onto the
@@ -544,28 +582,11 @@ func extractResponseAttributes(span trace.Span, body []byte, parentSpan trace.Sp
 //
 // Only token-count fields map into usage_metadata; non-token fields
 // (server_tool_use, service_tier, inference_geo, speed) are handled by the caller.
-func buildUsageMetadata(usage map[string]interface{}) (usageMetadata map[string]any, totalInput, outputTokens int64) {
-	// getInt reads a token count, defaulting to 0 when the field is absent.
-	// Anthropic omits zero-valued usage fields, and both the streaming and
-	// non-streaming paths decode JSON numbers as float64, so a missing/0 field
-	// are equivalent here — every emission below is guarded by a > 0 check.
-	getInt := func(m map[string]interface{}, key string) int64 {
-		if v, ok := m[key].(float64); ok {
-			return int64(v)
-		}
-		return 0
-	}
-	getNested := func(key string) map[string]interface{} {
-		if m, ok := usage[key].(map[string]interface{}); ok {
-			return m
-		}
-		return nil
-	}
-
-	inputTokens := getInt(usage, "input_tokens")
-	outputTokens = getInt(usage, "output_tokens")
-	cacheCreate := getInt(usage, "cache_creation_input_tokens")
-	cacheRead := getInt(usage, "cache_read_input_tokens")
+func buildUsageMetadata(usage map[string]any) (usageMetadata map[string]any, totalInput, outputTokens int64) {
+	inputTokens := core.Int(usage, "input_tokens")
+	outputTokens = core.Int(usage, "output_tokens")
+	cacheCreate := core.Int(usage, "cache_creation_input_tokens")
+	cacheRead := core.Int(usage, "cache_read_input_tokens")
 
 	// LangChain/LangSmith convention: input_tokens is the inclusive total
 	// (uncached + cache-write + cache-read). The cache portions are surfaced
@@ -582,9 +603,9 @@ func buildUsageMetadata(usage map[string]interface{}) (usageMetadata map[string]
 	// mirrors the langsmith Python client (wrappers/_anthropic._create_usage_metadata),
 	// keeping cost identical across the Go and Python ingest stacks.
 	var ephemeral5m, ephemeral1h int64
-	if cc := getNested("cache_creation"); cc != nil {
-		ephemeral5m = getInt(cc, "ephemeral_5m_input_tokens")
-		ephemeral1h = getInt(cc, "ephemeral_1h_input_tokens")
+	if cc := core.Map(usage, "cache_creation"); cc != nil {
+		ephemeral5m = core.Int(cc, "ephemeral_5m_input_tokens")
+		ephemeral1h = core.Int(cc, "ephemeral_1h_input_tokens")
 	}
 	switch {
 	case ephemeral5m > 0 || ephemeral1h > 0:
@@ -600,83 +621,116 @@ func buildUsageMetadata(usage map[string]interface{}) (usageMetadata map[string]
 
 	outputTokenDetails := map[string]any{}
 	// Extended-thinking (reasoning) tokens are a subset of output_tokens.
-	if otd := getNested("output_tokens_details"); otd != nil {
-		if v := getInt(otd, "thinking_tokens"); v > 0 {
+	if otd := core.Map(usage, "output_tokens_details"); otd != nil {
+		if v := core.Int(otd, "thinking_tokens"); v > 0 {
 			outputTokenDetails["reasoning"] = v
 		}
 	}
 
-	usageMetadata = map[string]any{}
-	if totalInput > 0 {
-		usageMetadata["input_tokens"] = totalInput
-	}
-	if outputTokens > 0 {
-		usageMetadata["output_tokens"] = outputTokens
-	}
-	if totalInput > 0 || outputTokens > 0 {
-		usageMetadata["total_tokens"] = totalInput + outputTokens
-	}
-	if len(inputTokenDetails) > 0 {
-		usageMetadata["input_token_details"] = inputTokenDetails
-	}
-	if len(outputTokenDetails) > 0 {
-		usageMetadata["output_token_details"] = outputTokenDetails
-	}
+	usageMetadata = core.UsageMetadata(totalInput, outputTokens, totalInput+outputTokens, inputTokenDetails, outputTokenDetails)
 	return usageMetadata, totalInput, outputTokens
 }
 
-// setUsageAttributes records token usage on the span. It emits a single
-// langsmith.usage_metadata JSON attribute (the converter's preferred,
-// cost-driving path) plus the flat gen_ai.usage.* attributes that
-// Thread-list aggregation reads from root spans. Non-token usage fields are
-// recorded as run metadata.
-func setUsageAttributes(span trace.Span, usage map[string]interface{}, parentSpan trace.Span) {
+// buildUsage maps an Anthropic usage object onto core.Usage: the
+// cost-driving usage_metadata plus token totals. Non-token usage fields are
+// carried as metadata attributes.
+func buildUsage(usage map[string]any) *core.Usage {
 	usageMetadata, totalInput, outputTokens := buildUsageMetadata(usage)
-	totalTokens := totalInput + outputTokens
-
-	if len(usageMetadata) > 0 {
-		if out, err := json.Marshal(usageMetadata); err == nil {
-			span.SetAttributes(genaiattr.UsageMetadataKey.String(string(out)))
-		}
-	}
-
-	// Flat gen_ai.usage.* attributes drive Thread-list aggregation, which
-	// reads token counts from root spans rather than usage_metadata. Input and
-	// output are propagated to the parent so root-span totals are populated.
-	setSelfAndParent := func(kv attribute.KeyValue) {
-		span.SetAttributes(kv)
-		if parentSpan.SpanContext().IsValid() && parentSpan.IsRecording() {
-			parentSpan.SetAttributes(kv)
-		}
-	}
-	if totalInput > 0 {
-		setSelfAndParent(genaiattr.UsageInputTokensKey.Int64(totalInput))
-	}
-	if outputTokens > 0 {
-		setSelfAndParent(genaiattr.UsageOutputTokensKey.Int64(outputTokens))
-	}
-	if totalTokens > 0 {
-		span.SetAttributes(genaiattr.UsageTotalTokensKey.Int64(totalTokens))
+	u := &core.Usage{
+		InputTokens:  totalInput,
+		OutputTokens: outputTokens,
+		TotalTokens:  totalInput + outputTokens,
+		Metadata:     usageMetadata,
 	}
 
 	// These are request counts (server_tool_use) and price modifiers
 	// (service_tier, inference_geo, speed), not token counts, so they go in
 	// metadata. Putting them in usage_metadata token details would corrupt cost math.
-	if stu, ok := usage["server_tool_use"].(map[string]interface{}); ok {
+	if stu, ok := usage["server_tool_use"].(map[string]any); ok {
 		for k, raw := range stu {
 			if v, ok := raw.(float64); ok && v > 0 {
-				span.SetAttributes(attribute.Int64(genaiattr.ServerToolUseMetadataKeyPrefix+k, int64(v)))
+				u.Attributes = append(u.Attributes, attribute.Int64(genaiattr.ServerToolUseMetadataKeyPrefix+k, int64(v)))
 			}
 		}
 	}
 	if st, ok := usage["service_tier"].(string); ok && st != "" {
-		span.SetAttributes(genaiattr.ServiceTierKey.String(st))
+		u.Attributes = append(u.Attributes, genaiattr.ServiceTierKey.String(st))
 	}
 	if geo, ok := usage["inference_geo"].(string); ok && geo != "" {
-		span.SetAttributes(genaiattr.InferenceGeoKey.String(geo))
+		u.Attributes = append(u.Attributes, genaiattr.InferenceGeoKey.String(geo))
 	}
 	// speed is the latency tier (standard/fast) on the beta usage object.
 	if speed, ok := usage["speed"].(string); ok && speed != "" {
-		span.SetAttributes(genaiattr.SpeedKey.String(speed))
+		u.Attributes = append(u.Attributes, genaiattr.SpeedKey.String(speed))
+	}
+	return u
+}
+
+// parseCountTokensResponse records the counted input tokens as the run
+// output. Counting is free, so it is not reported as usage.
+//
+//	{"input_tokens": 2095}
+func parseCountTokensResponse(body []byte) *core.Response {
+	var resp map[string]any
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil
+	}
+	b, err := json.Marshal(map[string]any{"input_tokens": core.Int(resp, "input_tokens")})
+	if err != nil {
+		return nil
+	}
+	return &core.Response{Completion: string(b)}
+}
+
+// parseBatchRequest summarizes a Message Batches create request as the
+// request count and custom IDs. The requests themselves are not recorded;
+// each is billed and returned when the batch results are fetched. model is
+// the first request's model.
+//
+//	{"requests": [{"custom_id": "a", "params": {"model": ..., "messages": [...]}}, ...]}
+func parseBatchRequest(body []byte) (model, prompt string) {
+	var req map[string]any
+	if err := json.Unmarshal(body, &req); err != nil {
+		return "", ""
+	}
+	reqs, _ := req["requests"].([]any)
+	customIDs := make([]any, 0, len(reqs))
+	for _, raw := range reqs {
+		r, _ := raw.(map[string]any)
+		if id, ok := r["custom_id"].(string); ok {
+			customIDs = append(customIDs, id)
+		}
+		if model == "" {
+			model, _ = core.Map(r, "params")["model"].(string)
+		}
+	}
+	b, err := json.Marshal(map[string]any{"requests": len(reqs), "custom_ids": customIDs})
+	if err != nil {
+		return model, ""
+	}
+	return model, string(b)
+}
+
+// parseBatchResponse records the created batch's ID, processing status and
+// request counts.
+//
+//	{"id": "msgbatch_…", "type": "message_batch", "processing_status": "in_progress", "request_counts": {...}}
+func parseBatchResponse(body []byte) *core.Response {
+	var resp map[string]any
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil
+	}
+	id, _ := resp["id"].(string)
+	out := map[string]any{"id": id}
+	if status, ok := resp["processing_status"].(string); ok {
+		out["processing_status"] = status
+	}
+	if counts := core.Map(resp, "request_counts"); counts != nil {
+		out["request_counts"] = counts
+	}
+	b, err := json.Marshal(out)
+	if err != nil {
+		return nil
 	}
+	return &core.Response{ID: id, Completion: string(b)}
 }
@@ -6,13 +6,19 @@ import (
 	"encoding/json"
 	"io"
 	"net/http"
+	"net/http/httptest"
 	"reflect"
 	"strings"
 	"testing"
 
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/anthropics/anthropic-sdk-go/option"
 	"go.opentelemetry.io/otel/attribute"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/langchain-ai/langsmith-go/instrumentation/core"
 )
 
 // fakeTransport returns a canned response, capturing the request for inspection.
@@ -272,6 +278,10 @@ func TestGetSpanName(t *testing.T) {
 	}{
 		{"/v1/messages", "anthropic.messages"},
 		{"/v1/messages?beta=true", "anthropic.messages"},
+		{"/v1/messages/count_tokens", "anthropic.messages.count_tokens"},
+		{"/v1/messages/batches", "anthropic.messages.batches"},
+		{"/v1/projects/p/locations/us-east5/publishers/anthropic/models/claude-sonnet-4@20250514:rawPredict", "anthropic.messages"},
+		{"/v1/projects/p/locations/us-east5/publishers/anthropic/models/count-tokens:rawPredict", "anthropic.messages.count_tokens"},
 		{"/v1/complete", "anthropic.request"},
 		{"", "anthropic.request"},
 	}
@@ -288,6 +298,8 @@ func TestGetOperationName(t *testing.T) {
 		want string
 	}{
 		{"/v1/messages", "chat"},
+		{"/v1/messages/count_tokens", "count_tokens"},
+		{"/v1/messages/batches?beta=true", "batch"},
 		{"/v1/complete", "request"},
 	}
 	for _, tt := range tests {
@@ -318,6 +330,30 @@ func getAttr(span sdktrace.ReadWriteSpan, key string) (attribute.Value, bool) {
 	return attribute.Value{}, false
 }
 
+// extractRequestAttributes records the request-body attributes the round
+// tripper would set and returns whether the request is streaming.
+func extractRequestAttributes(span trace.Span, body []byte) bool {
+	fields := parseRequestBody(body)
+	span.SetAttributes(fields.attrs...)
+	if fields.prompt != "" {
+		span.SetAttributes(attribute.String("gen_ai.prompt", fields.prompt))
+	}
+	return fields.streaming
+}
+
+func extractResponseAttributes(span trace.Span, body []byte, parentSpan trace.Span) {
+	core.RecordResponse(span, parseResponse(body), parentSpan)
+}
+
+func extractStreamingResponseAttributes(span trace.Span, data []byte, parentSpan trace.Span) {
+	resp, _ := core.ParseStream(&stream{}, data, io.EOF)
+	core.RecordResponse(span, resp, parentSpan)
+}
+
+func setUsageAttributes(span trace.Span, usage map[string]any, parentSpan trace.Span) {
+	core.RecordUsage(span, buildUsage(usage), parentSpan)
+}
+
 func TestExtractRequestAttributes_BasicMessage(t *testing.T) {
 	span, _ := startTestSpan(t)
 	body := `{"model":"claude-sonnet-4-20250514","messages":[{"role":"user","content":"hi"}],"max_tokens":1024,"temperature":0.7,"stream":false}`
@@ -761,3 +797,219 @@ func TestBuildUsageMetadata(t *testing.T) {
 		})
 	}
 }
+
+func TestShouldTrace_BatchEndpoints(t *testing.T) {
+	tests := []struct {
+		method string
+		url    string
+		want   bool
+	}{
+		{http.MethodPost, "https://api.anthropic.com/v1/messages/batches", true},
+		{http.MethodGet, "https://api.anthropic.com/v1/messages/batches", false},
+		{http.MethodGet, "https://api.anthropic.com/v1/messages/batches/msgbatch_1", false},
+		{http.MethodGet, "https://api.anthropic.com/v1/messages/batches/msgbatch_1/results", false},
+		{http.MethodPost, "https://api.anthropic.com/v1/messages/batches/msgbatch_1/cancel", false},
+		{http.MethodPost, "https://proxy.example.com/v1/messages/count_tokens", true},
+	}
+	for _, tt := range tests {
+		req, err := http.NewRequest(tt.method, tt.url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := shouldTrace(req); got != tt.want {
+			t.Errorf("shouldTrace(%s %s) = %v, want %v", tt.method, tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestVertexModel(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/v1/projects/p/locations/us-east5/publishers/anthropic/models/claude-sonnet-4@20250514:streamRawPredict", "claude-sonnet-4@20250514"},
+		{"/v1/projects/p/locations/us-east5/publishers/anthropic/models/count-tokens:rawPredict", ""},
+		{"/v1/messages", ""},
+	}
+	for _, tt := range tests {
+		if got := vertexModel(tt.path); got != tt.want {
+			t.Errorf("vertexModel(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+const messageBody = `{
+	"id": "msg_01",
+	"type": "message",
+	"role": "assistant",
+	"model": "claude-sonnet-4-20250514",
+	"content": [{"type": "text", "text": "Hello"}],
+	"stop_reason": "end_turn",
+	"usage": {"input_tokens": 10, "output_tokens": 2, "cache_read_input_tokens": 90}
+}`
+
+const batchBody = `{
+	"id": "msgbatch_01",
+	"type": "message_batch",
+	"processing_status": "in_progress",
+	"request_counts": {"processing": 2, "succeeded": 0, "errored": 0, "canceled": 0, "expired": 0},
+	"created_at": "2025-01-01T00:00:00Z",
+	"expires_at": "2025-01-02T00:00:00Z"
+}`
+
+// newSDKClient returns an anthropic-sdk-go client traced through Middleware
+// and talking to a server that serves canned responses keyed by path.
+func newSDKClient(t *testing.T) (anthropic.Client, *tracetest.InMemoryExporter) {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/v1/messages":
+			io.WriteString(w, messageBody)
+		case "/v1/messages/count_tokens":
+			io.WriteString(w, `{"input_tokens": 14}`)
+		case "/v1/messages/batches":
+			io.WriteString(w, batchBody)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	client := anthropic.NewClient(
+		option.WithBaseURL(srv.URL),
+		option.WithAPIKey("test"),
+		option.WithMaxRetries(0),
+		option.WithMiddleware(Middleware(WithTracerProvider(tp))),
+	)
+	return client, exporter
+}
+
+func stubAttr(span tracetest.SpanStub, key string) string {
+	for _, kv := range span.Attributes {
+		if string(kv.Key) == key {
+			return kv.Value.Emit()
+		}
+	}
+	return ""
+}
+
+func TestMiddleware_Messages(t *testing.T) {
+	client, exporter := newSDKClient(t)
+	_, err := client.Messages.New(context.Background(), anthropic.MessageNewParams{
+		Model:     "claude-sonnet-4-20250514",
+		MaxTokens: 16,
+		Messages:  []anthropic.MessageParam{anthropic.NewUserMessage(anthropic.NewTextBlock("hi"))},
+	}, option.WithHeader("anthropic-beta", "context-1m-2025-08-07"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	want := map[string]string{
+		"gen_ai.response.id":                "msg_01",
+		"gen_ai.request.model":              "claude-sonnet-4-20250514",
+		"gen_ai.usage.input_tokens":         "100",
+		"langsmith.metadata.anthropic_beta": "context-1m-2025-08-07",
+	}
+	for k, v := range want {
+		if got := stubAttr(spans[0], k); got != v {
+			t.Errorf("%s = %q, want %q", k, got, v)
+		}
+	}
+	if got := stubAttr(spans[0], "langsmith.usage_metadata"); !strings.Contains(got, `"cache_read":90`) {
+		t.Errorf("langsmith.usage_metadata = %s, want cache_read detail", got)
+	}
+}
+
+func TestMiddleware_CountTokens(t *testing.T) {
+	client, exporter := newSDKClient(t)
+	_, err := client.Messages.CountTokens(context.Background(), anthropic.MessageCountTokensParams{
+		Model:    "claude-sonnet-4-20250514",
+		Messages: []anthropic.MessageParam{anthropic.NewUserMessage(anthropic.NewTextBlock("hi"))},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	if spans[0].Name != "anthropic.messages.count_tokens" {
+		t.Errorf("span name = %q", spans[0].Name)
+	}
+	if got := stubAttr(spans[0], "gen_ai.completion"); got != `{"input_tokens":14}` {
+		t.Errorf("gen_ai.completion = %s", got)
+	}
+	if got := stubAttr(spans[0], "gen_ai.usage.input_tokens"); got != "" {
+		t.Errorf("count_tokens should not report usage, got input_tokens = %s", got)
+	}
+}
+
+func TestMiddleware_BatchCreate(t *testing.T) {
+	client, exporter := newSDKClient(t)
+	params := anthropic.MessageBatchNewParamsRequestParams{
+		Model:     "claude-sonnet-4-20250514",
+		MaxTokens: 16,
+		Messages:  []anthropic.MessageParam{anthropic.NewUserMessage(anthropic.NewTextBlock("hi"))},
+	}
+	_, err := client.Messages.Batches.New(context.Background(), anthropic.MessageBatchNewParams{
+		Requests: []anthropic.MessageBatchNewParamsRequest{
+			{CustomID: "req-a", Params: params},
+			{CustomID: "req-b", Params: params},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	want := map[string]string{
+		"gen_ai.operation.name": "batch",
+		"gen_ai.request.model":  "claude-sonnet-4-20250514",
+		"gen_ai.response.id":    "msgbatch_01",
+		"gen_ai.prompt":         `{"custom_ids":["req-a","req-b"],"requests":2}`,
+	}
+	for k, v := range want {
+		if got := stubAttr(spans[0], k); got != v {
+			t.Errorf("%s = %q, want %q", k, got, v)
+		}
+	}
+	if got := stubAttr(spans[0], "gen_ai.completion"); !strings.Contains(got, `"processing_status":"in_progress"`) {
+		t.Errorf("gen_ai.completion = %s", got)
+	}
+}
+
+func TestRoundTrip_VertexModelFromPath(t *testing.T) {
+	client, exporter := newTracedClient(t, []byte(messageBody))
+	body := `{"anthropic_version":"vertex-2023-10-16","messages":[{"role":"user","content":"hi"}],"max_tokens":16}`
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost,
+		"https://us-east5-aiplatform.googleapis.com/v1/projects/p/locations/us-east5/publishers/anthropic/models/claude-sonnet-4@20250514:rawPredict",
+		strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	if got := stubAttr(spans[0], "gen_ai.request.model"); got != "claude-sonnet-4@20250514" {
+		t.Errorf("gen_ai.request.model = %q", got)
+	}
+}
@@ -0,0 +1,160 @@
+package tracebedrock
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"hash/crc32"
+)
+
+// AWS event-stream framing (application/vnd.amazon.eventstream), used by
+// ConverseStream and InvokeModelWithResponseStream:
+//
+//	[total length uint32][headers length uint32][prelude CRC32 uint32]
+//	[headers ...][payload ...][message CRC32 uint32]
+//
+// Each header is [name length uint8][name][value type uint8][value]. Only the
+// string-typed :message-type, :event-type and :exception-type headers are
+// needed to route a message; other header types are skipped by size.
+const (
+	preludeLen  = 12
+	checksumLen = 4
+
+	// maxMessageLen bounds a single frame, matching the AWS SDK decoder, so a
+	// corrupt length prefix can't make the scanner buffer without limit.
+	maxMessageLen = 16 * 1024 * 1024
+)
+
+// eventScanner incrementally decodes an AWS event-stream body into events.
+// Each message becomes a single-key map from its event type to its JSON
+// payload, mirroring the union shape of the Bedrock stream APIs:
+//
+//	{"contentBlockDelta": {"contentBlockIndex": 0, "delta": {"text": "Hi"}}}
+//
+// InvokeModelWithResponseStream chunks carry the model-native event
+// base64-encoded in {"bytes": ...}; it is decoded into the "chunk" value.
+// Exception messages are keyed by their :exception-type. Decoding stops at
+// the first malformed frame.
+type eventScanner struct {
+	buf     []byte
+	failed  bool
+	onEvent func(map[string]any)
+}
+
+func newEventScanner(onEvent func(map[string]any)) *eventScanner {
+	return &eventScanner{onEvent: onEvent}
+}
+
+// Feed appends p to the pending bytes and emits every complete message.
+func (s *eventScanner) Feed(p []byte) {
+	if s.failed {
+		return
+	}
+	s.buf = append(s.buf, p...)
+	for len(s.buf) >= preludeLen {
+		total := binary.BigEndian.Uint32(s.buf[0:4])
+		headersLen := binary.BigEndian.Uint32(s.buf[4:8])
+		if total > maxMessageLen || total < preludeLen+checksumLen || headersLen > total-preludeLen-checksumLen ||
+			crc32.ChecksumIEEE(s.buf[:8]) != binary.BigEndian.Uint32(s.buf[8:12]) {
+			s.fail()
+			return
+		}
+		if uint32(len(s.buf)) < total {
+			return
+		}
+		msg := s.buf[:total]
+		if crc32.ChecksumIEEE(msg[:total-checksumLen]) != binary.BigEndian.Uint32(msg[total-checksumLen:]) {
+			s.fail()
+			return
+		}
+		headers, ok := parseHeaders(msg[preludeLen : preludeLen+headersLen])
+		if !ok {
+			s.fail()
+			return
+		}
+		if event := decodeEvent(headers, msg[preludeLen+headersLen:total-checksumLen]); event != nil {
+			s.onEvent(event)
+		}
+		s.buf = s.buf[total:]
+	}
+}
+
+func (s *eventScanner) fail() {
+	s.failed = true
+	s.buf = nil
+}
+
+// parseHeaders returns the string-valued headers of a message.
+func parseHeaders(b []byte) (map[string]string, bool) {
+	headers := map[string]string{}
+	for len(b) > 0 {
+		nameLen := int(b[0])
+		if len(b) < 1+nameLen+1 {
+			return nil, false
+		}
+		name := string(b[1 : 1+nameLen])
+		valueType := b[1+nameLen]
+		b = b[2+nameLen:]
+
+		var size int
+		switch valueType {
+		case 0, 1: // bool true, bool false
+			size = 0
+		case 2: // byte
+			size = 1
+		case 3: // int16
+			size = 2
+		case 4: // int32
+			size = 4
+		case 5, 8: // int64, timestamp
+			size = 8
+		case 9: // uuid
+			size = 16
+		case 6, 7: // byte array, string
+			if len(b) < 2 {
+				return nil, false
+			}
+			size = 2 + int(binary.BigEndian.Uint16(b[:2]))
+		default:
+			return nil, false
+		}
+		if len(b) < size {
+			return nil, false
+		}
+		if valueType == 7 {
+			headers[name] = string(b[2:size])
+		}
+		b = b[size:]
+	}
+	return headers, true
+}
+
+// decodeEvent maps a message to its event, or nil when the payload is not
+// a JSON object.
+func decodeEvent(headers map[string]string, payload []byte) map[string]any {
+	eventType := headers[":event-type"]
+	if headers[":message-type"] == "exception" {
+		eventType = headers[":exception-type"]
+	}
+	if eventType == "" {
+		return nil
+	}
+	var body map[string]any
+	if err := json.Unmarshal(payload, &body); err != nil {
+		return nil
+	}
+	if eventType == "chunk" {
+		if b64, ok := body["bytes"].(string); ok {
+			raw, err := base64.StdEncoding.DecodeString(b64)
+			if err != nil {
+				return nil
+			}
+			var inner map[string]any
+			if err := json.Unmarshal(raw, &inner); err != nil {
+				return nil
+			}
+			body = inner
+		}
+	}
+	return map[string]any{eventType: body}
+}
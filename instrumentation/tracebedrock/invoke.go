@@ -0,0 +1,307 @@
+package tracebedrock
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+
+	"github.com/langchain-ai/langsmith-go/instrumentation/core"
+	"github.com/langchain-ai/langsmith-go/instrumentation/traceanthropic"
+)
+
+// Model families whose InvokeModel bodies are understood. The family is the
+// provider prefix of the model ID, e.g. "anthropic" in
+// "anthropic.claude-3-5-sonnet-20240620-v1:0".
+const (
+	familyAnthropic = "anthropic"
+	familyMeta      = "meta"
+	familyMistral   = "mistral"
+)
+
+// invocationMetricsKey is added by Bedrock to the final chunk of every
+// InvokeModelWithResponseStream response.
+const invocationMetricsKey = "amazon-bedrock-invocationMetrics"
+
+// modelFamily returns the provider prefix of a model ID. Cross-region
+// inference profile IDs ("us.anthropic.claude-...") and foundation-model ARNs
+// are resolved to the underlying model; unknown IDs (e.g. application
+// inference profile ARNs) return "".
+func modelFamily(modelID string) string {
+	if i := strings.LastIndex(modelID, "/"); i >= 0 {
+		modelID = modelID[i+1:]
+	}
+	segments := strings.SplitN(modelID, ".", 3)
+	for i := 0; i < len(segments)-1 && i < 2; i++ {
+		switch segments[i] {
+		case familyAnthropic, familyMeta, familyMistral:
+			return segments[i]
+		}
+	}
+	return ""
+}
+
+// parseInvokeRequest extracts sampling attributes and the prompt from a
+// model-native InvokeModel body. chat reports whether the body is a chat
+// (messages) request rather than a raw text completion.
+func parseInvokeRequest(st *callState, req *http.Request, body []byte) (attrs []attribute.KeyValue, prompt string, chat bool) {
+	if st.family == familyAnthropic {
+		// Bedrock serves the Anthropic Messages API body unchanged apart from
+		// the model and stream fields, which live in the URL.
+		st.anthropic = traceanthropic.Provider().ParseRequest(req, body)
+		for _, kv := range st.anthropic.Attributes {
+			if kv.Key == semconv.GenAIRequestMaxTokensKey || kv.Key == semconv.GenAIRequestTemperatureKey {
+				attrs = append(attrs, kv)
+			}
+		}
+		return attrs, st.anthropic.Prompt, true
+	}
+
+	var m map[string]any
+	if err := json.Unmarshal(body, &m); err != nil {
+		return nil, "", false
+	}
+
+	maxTokensField := "max_tokens"
+	if st.family == familyMeta {
+		maxTokensField = "max_gen_len"
+	}
+	if v, ok := m[maxTokensField].(float64); ok {
+		attrs = append(attrs, semconv.GenAIRequestMaxTokens(int(v)))
+	}
+	if v, ok := m["temperature"].(float64); ok {
+		attrs = append(attrs, semconv.GenAIRequestTemperature(v))
+	}
+	if v, ok := m["top_p"].(float64); ok {
+		attrs = append(attrs, semconv.GenAIRequestTopP(v))
+	}
+	if v, ok := m["top_k"].(float64); ok {
+		attrs = append(attrs, semconv.GenAIRequestTopK(v))
+	}
+
+	// Mistral Large accepts OpenAI-style chat messages; other Mistral and
+	// Llama models take a single formatted prompt string.
+	var messages []any
+	if msgs, ok := m["messages"].([]any); ok {
+		messages, chat = msgs, true
+	} else if p, ok := m["prompt"].(string); ok && p != "" {
+		messages = []any{map[string]any{"role": "user", "content": p}}
+	}
+	if len(messages) > 0 {
+		if out, err := json.Marshal(map[string]any{"messages": messages}); err == nil {
+			prompt = string(out)
+		}
+	}
+	return attrs, prompt, chat
+}
+
+// parseInvokeResponse extracts completion, stop reason and usage from a
+// model-native InvokeModel response body. Returns nil when the body is not
+// JSON.
+func parseInvokeResponse(st *callState, body []byte) *core.Response {
+	if st.family == familyAnthropic && st.anthropic != nil {
+		return traceanthropic.Provider().ParseResponse(st.anthropic, body)
+	}
+
+	var m map[string]any
+	if err := json.Unmarshal(body, &m); err != nil {
+		return nil
+	}
+	out := &core.Response{}
+	switch st.family {
+	case familyMeta:
+		text, _ := m["generation"].(string)
+		out.Completion = textCompletion(text)
+		out.StopReason, _ = m["stop_reason"].(string)
+		out.Usage = tokenUsage(core.Int(m, "prompt_token_count"), core.Int(m, "generation_token_count"))
+	case familyMistral:
+		var acc mistralAccumulator
+		acc.add(m)
+		out.Completion, out.StopReason = acc.result()
+	}
+	return out
+}
+
+// textCompletion wraps generated text in a single assistant message, or
+// returns "" when text is empty.
+func textCompletion(text string) string {
+	if text == "" {
+		return ""
+	}
+	b, err := json.Marshal(map[string]any{"messages": []any{
+		map[string]any{"role": "assistant", "content": text},
+	}})
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+// mistralAccumulator folds Mistral response bodies or stream chunks, in
+// either the text-completion shape ({"outputs":[{"text"}]}) or the chat shape
+// ({"choices":[{"message":{"content"}}]}), into one assistant message.
+type mistralAccumulator struct {
+	text       strings.Builder
+	toolCalls  []any
+	stopReason string
+}
+
+func (a *mistralAccumulator) add(m map[string]any) {
+	if outputs, ok := m["outputs"].([]any); ok && len(outputs) > 0 {
+		if o, ok := outputs[0].(map[string]any); ok {
+			text, _ := o["text"].(string)
+			a.text.WriteString(text)
+			a.setStopReason(o)
+		}
+	}
+	if choices, ok := m["choices"].([]any); ok && len(choices) > 0 {
+		if c, ok := choices[0].(map[string]any); ok {
+			// Bedrock uses "message" for both responses and stream
+			// increments; "delta" is accepted for OpenAI-style chunks.
+			msg := core.Map(c, "message")
+			if msg == nil {
+				msg = core.Map(c, "delta")
+			}
+			if content, ok := msg["content"].(string); ok {
+				a.text.WriteString(content)
+			}
+			if calls, ok := msg["tool_calls"].([]any); ok {
+				a.toolCalls = append(a.toolCalls, calls...)
+			}
+			a.setStopReason(c)
+		}
+	}
+}
+
+func (a *mistralAccumulator) setStopReason(m map[string]any) {
+	if s, ok := m["stop_reason"].(string); ok && s != "" {
+		a.stopReason = s
+	} else if s, ok := m["finish_reason"].(string); ok && s != "" {
+		a.stopReason = s
+	}
+}
+
+func (a *mistralAccumulator) result() (completion, stopReason string) {
+	if len(a.toolCalls) == 0 {
+		return textCompletion(a.text.String()), a.stopReason
+	}
+	msg := map[string]any{"role": "assistant", "content": nil, "tool_calls": a.toolCalls}
+	if a.text.Len() > 0 {
+		msg["content"] = a.text.String()
+	}
+	b, err := json.Marshal(map[string]any{"messages": []any{msg}})
+	if err != nil {
+		return "", a.stopReason
+	}
+	return string(b), a.stopReason
+}
+
+// invokeStream accumulates an InvokeModelWithResponseStream response. Each
+// event's "chunk" holds a model-native stream event: Anthropic chunks are
+// delegated to the traceanthropic accumulator, Llama and Mistral chunks are
+// folded here. Bedrock appends invocation metrics (token counts) to the final
+// chunk, which also marks the stream complete.
+type invokeStream struct {
+	st        *callState
+	anthropic core.Stream
+	mistral   mistralAccumulator
+
+	text       strings.Builder
+	stopReason string
+	input      int64
+	output     int64
+	metrics    map[string]any
+	err        error
+}
+
+func newInvokeStream(st *callState) *invokeStream {
+	s := &invokeStream{st: st}
+	if st.family == familyAnthropic && st.anthropic != nil {
+		s.anthropic = traceanthropic.Provider().NewStream(st.anthropic)
+	}
+	return s
+}
+
+func (s *invokeStream) NewScanner(onEvent func(map[string]any)) core.Scanner {
+	return newEventScanner(onEvent)
+}
+
+func (s *invokeStream) IsFirstToken(event map[string]any) bool {
+	chunk := core.Map(event, "chunk")
+	if chunk == nil {
+		return false
+	}
+	switch {
+	case s.anthropic != nil:
+		return s.anthropic.IsFirstToken(chunk)
+	case s.st.family == familyMeta:
+		text, _ := chunk["generation"].(string)
+		return text != ""
+	case s.st.family == familyMistral:
+		var acc mistralAccumulator
+		acc.add(chunk)
+		return acc.text.Len() > 0 || len(acc.toolCalls) > 0
+	}
+	return false
+}
+
+func (s *invokeStream) Chunk(event map[string]any) {
+	for eventType, raw := range event {
+		chunk, _ := raw.(map[string]any)
+		if eventType != "chunk" {
+			if strings.HasSuffix(eventType, "Exception") {
+				msg, _ := chunk["message"].(string)
+				s.err = fmt.Errorf("%s: %s", eventType, msg)
+			}
+			continue
+		}
+		if metrics := core.Map(chunk, invocationMetricsKey); metrics != nil {
+			s.metrics = metrics
+		}
+		switch {
+		case s.anthropic != nil:
+			s.anthropic.Chunk(chunk)
+		case s.st.family == familyMeta:
+			text, _ := chunk["generation"].(string)
+			s.text.WriteString(text)
+			if r, ok := chunk["stop_reason"].(string); ok && r != "" {
+				s.stopReason = r
+			}
+			if v := core.Int(chunk, "prompt_token_count"); v > 0 {
+				s.input = v
+			}
+			if v := core.Int(chunk, "generation_token_count"); v > 0 {
+				s.output = v
+			}
+		case s.st.family == familyMistral:
+			s.mistral.add(chunk)
+		}
+	}
+}
+
+func (s *invokeStream) Finish(data []byte, readErr error) (*core.Response, bool) {
+	resp := &core.Response{}
+	switch {
+	case s.anthropic != nil:
+		if r, _ := s.anthropic.Finish(data, readErr); r != nil {
+			resp = r
+		}
+	case s.st.family == familyMeta:
+		resp.Completion = textCompletion(s.text.String())
+		resp.StopReason = s.stopReason
+		resp.Usage = tokenUsage(s.input, s.output)
+	case s.st.family == familyMistral:
+		resp.Completion, resp.StopReason = s.mistral.result()
+	}
+	if resp.Model == "" {
+		resp.Model = s.st.modelID
+	}
+	if resp.Usage == nil && s.metrics != nil {
+		resp.Usage = tokenUsage(core.Int(s.metrics, "inputTokenCount"), core.Int(s.metrics, "outputTokenCount"))
+	}
+	resp.Err = s.err
+	return resp, s.metrics != nil || s.err != nil
+}
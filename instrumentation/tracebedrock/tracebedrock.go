@@ -0,0 +1,629 @@
+// Package tracebedrock provides OpenTelemetry tracing for the AWS Bedrock
+// Runtime API using LangSmith-compatible spans.
+//
+// Converse, ConverseStream, InvokeModel and InvokeModelWithResponseStream are
+// traced. Converse messages, tool use and token usage map onto the same
+// gen_ai attributes as the other LLM instrumentations; InvokeModel bodies are
+// understood for Anthropic, Meta Llama and Mistral models.
+//
+// Usage:
+//
+//	// Configure your Bedrock Runtime client to use a traced HTTP client
+//	cfg, _ := config.LoadDefaultConfig(ctx)
+//	client := bedrockruntime.NewFromConfig(cfg, func(o *bedrockruntime.Options) {
+//		o.HTTPClient = tracebedrock.Client()
+//	})
+//
+//	// Or use a custom tracer provider:
+//	tp := sdktrace.NewTracerProvider(...)
+//	client := bedrockruntime.NewFromConfig(cfg, func(o *bedrockruntime.Options) {
+//		o.HTTPClient = tracebedrock.Client(tracebedrock.WithTracerProvider(tp))
+//	})
+//
+//	// Your Bedrock API calls will now be automatically traced with LangSmith attrs
+//	// resp, err := client.Converse(ctx, &bedrockruntime.ConverseInput{...})
+package tracebedrock
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/langchain-ai/langsmith-go/instrumentation/core"
+	"github.com/langchain-ai/langsmith-go/instrumentation/pricing"
+)
+
+// WithRunNameContext sets the span (run) name for the next traced request made with ctx.
+// The run name in LangSmith is the OTLP span name; there is no separate field.
+// Use this so one client can emit runs with different names per call, e.g. in tests:
+//
+//	ctx = tracebedrock.WithRunNameContext(ctx, "bedrock_converse")
+//	client.Converse(ctx, ...)
+func WithRunNameContext(ctx context.Context, name string) context.Context {
+	return core.WithRunNameContext(ctx, name)
+}
+
+// Option configures a traced HTTP client.
+type Option func(*core.Config)
+
+// WithTracerProvider returns an Option that sets the tracer provider.
+// If not provided, the global tracer provider is used.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(cfg *core.Config) {
+		cfg.TracerProvider = tp
+	}
+}
+
+// WithRunName sets the span (run) name to the given string when non-empty.
+func WithRunName(name string) Option {
+	return func(cfg *core.Config) {
+		cfg.RunName = name
+	}
+}
+
+// WithAttachments is [core.WithAttachments].
+func WithAttachments(h core.AttachmentHandler) Option { return Option(core.WithAttachments(h)) }
+
+// WithMaxAttachmentSize is [core.WithMaxAttachmentSize].
+func WithMaxAttachmentSize(n int) Option { return Option(core.WithMaxAttachmentSize(n)) }
+
+// WithCaptureContent is [core.WithCaptureContent].
+func WithCaptureContent(capture bool) Option { return Option(core.WithCaptureContent(capture)) }
+
+// WithRedactor is [core.WithRedactor].
+func WithRedactor(r core.Redactor) Option { return Option(core.WithRedactor(r)) }
+
+// WithMaxContentLength is [core.WithMaxContentLength].
+func WithMaxContentLength(n int) Option { return Option(core.WithMaxContentLength(n)) }
+
+// WithPricing is [core.WithPricing].
+func WithPricing(prices *pricing.Table) Option { return Option(core.WithPricing(prices)) }
+
+// EndPendingRetries is [core.EndPendingRetries]. A run whose last attempt
+// failed stays open until the SDK's retry window passes; call this before
+// shutting down your tracer provider so it is exported. The LangSmith
+// OTelTracer's Shutdown calls it for you.
+func EndPendingRetries() { core.EndPendingRetries() }
+
+// Client returns a new http.Client configured with tracing middleware.
+// Equivalent to WrapClient(nil, opts...), which wraps the default transport.
+func Client(opts ...Option) *http.Client {
+	return WrapClient(nil, opts...)
+}
+
+// WrapClient wraps an existing http.Client with tracing middleware.
+// If client is nil, a new client with the default transport is created.
+func WrapClient(client *http.Client, opts ...Option) *http.Client {
+	var cfg core.Config
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return core.WrapClient(client, provider{}, cfg)
+}
+
+// Bedrock Runtime actions, the last path segment of /model/{modelId}/{action}.
+const (
+	actionConverse       = "converse"
+	actionConverseStream = "converse-stream"
+	actionInvoke         = "invoke"
+	actionInvokeStream   = "invoke-with-response-stream"
+)
+
+// Bedrock reports InvokeModel token counts in response headers because the
+// body is in the model's native format, which may carry none.
+const (
+	inputTokenCountHeader  = "X-Amzn-Bedrock-Input-Token-Count"
+	outputTokenCountHeader = "X-Amzn-Bedrock-Output-Token-Count"
+)
+
+// callState is the per-request parse state carried in core.Request.State.
+type callState struct {
+	modelID string
+	action  string
+	family  string
+
+	// anthropic is the Messages API view of an Anthropic InvokeModel body,
+	// used to delegate response parsing to traceanthropic.
+	anthropic *core.Request
+
+	// headerUsage is token usage read from the response headers.
+	headerUsage *core.Usage
+}
+
+func (st *callState) isConverse() bool {
+	return st.action == actionConverse || st.action == actionConverseStream
+}
+
+// provider adapts the Bedrock Runtime API to the core round tripper.
+type provider struct{}
+
+func (provider) TracerName() string { return "github.com/aws/aws-sdk-go-v2/service/bedrockruntime" }
+
+func (provider) Match(req *http.Request) bool {
+	_, action := parseModelAction(req.URL)
+	return action != ""
+}
+
+func (provider) ParseRequest(req *http.Request, body []byte) *core.Request {
+	modelID, action := parseModelAction(req.URL)
+	st := &callState{modelID: modelID, action: action, family: modelFamily(modelID)}
+
+	call := &core.Request{
+		SpanName:  spanName(action),
+		Streaming: action == actionConverseStream || action == actionInvokeStream,
+		State:     st,
+		Attributes: []attribute.KeyValue{
+			semconv.GenAIProviderNameAWSBedrock,
+		},
+	}
+	if modelID != "" {
+		call.Attributes = append(call.Attributes, semconv.GenAIRequestModel(modelID))
+	}
+
+	opName := semconv.GenAIOperationNameChat
+	switch {
+	case st.isConverse():
+		if len(body) > 0 {
+			attrs, prompt := parseConverseRequest(body)
+			call.Attributes = append(call.Attributes, attrs...)
+			call.Prompt = prompt
+		}
+	case len(body) > 0:
+		var chat bool
+		var attrs []attribute.KeyValue
+		attrs, call.Prompt, chat = parseInvokeRequest(st, req, body)
+		call.Attributes = append(call.Attributes, attrs...)
+		if !chat {
+			opName = semconv.GenAIOperationNameTextCompletion
+		}
+	}
+	call.Attributes = append(call.Attributes, opName)
+	return call
+}
+
+func (provider) ParseResponseHeader(req *core.Request, header http.Header) {
+	st := req.State.(*callState)
+	in, _ := strconv.ParseInt(header.Get(inputTokenCountHeader), 10, 64)
+	out, _ := strconv.ParseInt(header.Get(outputTokenCountHeader), 10, 64)
+	st.headerUsage = tokenUsage(in, out)
+}
+
+func (provider) ParseResponse(req *core.Request, body []byte) *core.Response {
+	st := req.State.(*callState)
+	var out *core.Response
+	if st.isConverse() {
+		out = parseConverseResponse(body)
+	} else {
+		out = parseInvokeResponse(st, body)
+	}
+	if out == nil {
+		return nil
+	}
+	if out.Model == "" {
+		out.Model = st.modelID
+	}
+	if out.Usage == nil {
+		out.Usage = st.headerUsage
+	}
+	return out
+}
+
+func (provider) NewStream(req *core.Request) core.Stream {
+	st := req.State.(*callState)
+	if st.isConverse() {
+		return &converseStream{modelID: st.modelID}
+	}
+	return newInvokeStream(st)
+}
+
+// parseModelAction returns the model ID and action of a Bedrock Runtime
+// request path (/model/{modelId}/{action}), or empty strings when the path
+// is not a traced endpoint. The escaped path is split so model ARNs, whose
+// slashes the SDK percent-encodes, stay in one segment.
+func parseModelAction(u *url.URL) (modelID, action string) {
+	if u == nil {
+		return "", ""
+	}
+	segments := strings.Split(strings.Trim(u.EscapedPath(), "/"), "/")
+	if len(segments) != 3 || segments[0] != "model" {
+		return "", ""
+	}
+	switch segments[2] {
+	case actionConverse, actionConverseStream, actionInvoke, actionInvokeStream:
+	default:
+		return "", ""
+	}
+	id, err := url.PathUnescape(segments[1])
+	if err != nil || id == "" {
+		return "", ""
+	}
+	return id, segments[2]
+}
+
+// spanName returns the default span name for a Bedrock Runtime action.
+func spanName(action string) string {
+	switch action {
+	case actionConverseStream:
+		return "bedrock.converse_stream"
+	case actionInvoke:
+		return "bedrock.invoke_model"
+	case actionInvokeStream:
+		return "bedrock.invoke_model_with_response_stream"
+	default:
+		return "bedrock.converse"
+	}
+}
+
+// tokenUsage builds a core.Usage from plain input and output counts, or nil
+// when both are zero.
+func tokenUsage(input, output int64) *core.Usage {
+	if input <= 0 && output <= 0 {
+		return nil
+	}
+	return &core.Usage{
+		InputTokens:  input,
+		OutputTokens: output,
+		TotalTokens:  input + output,
+		Metadata:     core.UsageMetadata(input, output, input+output, nil, nil),
+	}
+}
+
+// parseConverseRequest extracts inference parameters and input messages from
+// a Converse request body. Messages are converted to the OpenAI-compatible
+// shape used across LangSmith instrumentations.
+func parseConverseRequest(body []byte) (attrs []attribute.KeyValue, prompt string) {
+	var req map[string]any
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, ""
+	}
+
+	if cfg := core.Map(req, "inferenceConfig"); cfg != nil {
+		if v, ok := cfg["maxTokens"].(float64); ok {
+			attrs = append(attrs, semconv.GenAIRequestMaxTokens(int(v)))
+		}
+		if v, ok := cfg["temperature"].(float64); ok {
+			attrs = append(attrs, semconv.GenAIRequestTemperature(v))
+		}
+		if v, ok := cfg["topP"].(float64); ok {
+			attrs = append(attrs, semconv.GenAIRequestTopP(v))
+		}
+		if seqs, ok := cfg["stopSequences"].([]any); ok && len(seqs) > 0 {
+			var stops []string
+			for _, s := range seqs {
+				if str, ok := s.(string); ok {
+					stops = append(stops, str)
+				}
+			}
+			attrs = append(attrs, semconv.GenAIRequestStopSequences(stops...))
+		}
+	}
+
+	var messages []any
+	if sys, ok := req["system"].([]any); ok {
+		if texts := blockTexts(sys); len(texts) > 0 {
+			messages = append(messages, map[string]any{
+				"role":    "system",
+				"content": strings.Join(texts, "\n"),
+			})
+		}
+	}
+	if msgs, ok := req["messages"].([]any); ok {
+		for _, raw := range msgs {
+			msg, ok := raw.(map[string]any)
+			if !ok {
+				continue
+			}
+			role, _ := msg["role"].(string)
+			blocks, _ := msg["content"].([]any)
+			messages = append(messages, converseToMessages(role, blocks)...)
+		}
+	}
+
+	if len(messages) > 0 {
+		if out, err := json.Marshal(map[string]any{"messages": messages}); err == nil {
+			prompt = string(out)
+		}
+	}
+	return attrs, prompt
+}
+
+// blockTexts returns the text of every {"text": ...} content block.
+func blockTexts(blocks []any) []string {
+	var texts []string
+	for _, raw := range blocks {
+		if block, ok := raw.(map[string]any); ok {
+			if text, ok := block["text"].(string); ok && text != "" {
+				texts = append(texts, text)
+			}
+		}
+	}
+	return texts
+}
+
+// converseToMessages converts one Converse message to one or more
+// OpenAI-compatible messages:
+//   - text-only → one message with string content
+//   - toolUse blocks → one assistant message with a tool_calls array
+//   - toolResult blocks → one "tool" message per result
+func converseToMessages(role string, blocks []any) []any {
+	texts := blockTexts(blocks)
+	var toolCalls, toolResults []any
+	for _, raw := range blocks {
+		block, ok := raw.(map[string]any)
+		if !ok {
+			continue
+		}
+		if tu := core.Map(block, "toolUse"); tu != nil {
+			id, _ := tu["toolUseId"].(string)
+			name, _ := tu["name"].(string)
+			argsJSON := "{}"
+			switch input := tu["input"].(type) {
+			case string:
+				argsJSON = input
+			case nil:
+			default:
+				if b, err := json.Marshal(input); err == nil {
+					argsJSON = string(b)
+				}
+			}
+			toolCalls = append(toolCalls, map[string]any{
+				"id":    id,
+				"type":  "function",
+				"index": len(toolCalls),
+				"function": map[string]any{
+					"name":      name,
+					"arguments": argsJSON,
+				},
+			})
+		}
+		if tr := core.Map(block, "toolResult"); tr != nil {
+			id, _ := tr["toolUseId"].(string)
+			toolResults = append(toolResults, map[string]any{
+				"role":         "tool",
+				"tool_call_id": id,
+				"content":      toolResultContent(tr),
+			})
+		}
+	}
+
+	if len(toolCalls) > 0 {
+		msg := map[string]any{"role": "assistant", "content": nil}
+		if len(texts) > 0 {
+			msg["content"] = strings.Join(texts, "\n")
+		}
+		msg["tool_calls"] = toolCalls
+		return append([]any{msg}, toolResults...)
+	}
+	if len(toolResults) > 0 {
+		return toolResults
+	}
+
+	if role == "" {
+		role = "user"
+	}
+	msg := map[string]any{"role": role}
+	if len(texts) > 0 {
+		msg["content"] = strings.Join(texts, "\n")
+	}
+	return []any{msg}
+}
+
+// toolResultContent flattens a toolResult's content blocks to a string:
+// text blocks are joined and json blocks are serialized.
+func toolResultContent(tr map[string]any) string {
+	blocks, _ := tr["content"].([]any)
+	var parts []string
+	for _, raw := range blocks {
+		block, ok := raw.(map[string]any)
+		if !ok {
+			continue
+		}
+		if text, ok := block["text"].(string); ok {
+			parts = append(parts, text)
+		} else if v, ok := block["json"]; ok {
+			if b, err := json.Marshal(v); err == nil {
+				parts = append(parts, string(b))
+			}
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// parseConverseResponse extracts the output message, stop reason and usage
+// from a Converse response body. Returns nil when the body is not JSON.
+func parseConverseResponse(body []byte) *core.Response {
+	var resp map[string]any
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil
+	}
+
+	out := &core.Response{}
+	out.StopReason, _ = resp["stopReason"].(string)
+	if msg := core.Map(core.Map(resp, "output"), "message"); msg != nil {
+		blocks, _ := msg["content"].([]any)
+		out.Completion = converseCompletion(blocks)
+	}
+	if usage := core.Map(resp, "usage"); usage != nil {
+		out.Usage = buildConverseUsage(usage)
+	}
+	return out
+}
+
+// converseCompletion builds a {"messages":[...]} JSON string from the
+// assistant's content blocks, or "" when there is no content.
+func converseCompletion(blocks []any) string {
+	if len(blocks) == 0 {
+		return ""
+	}
+	b, err := json.Marshal(map[string]any{"messages": converseToMessages("assistant", blocks)})
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+// buildConverseUsage maps a Converse usage object onto core.Usage. Bedrock
+// reports inputTokens excluding prompt-cache reads and writes:
+//
+//	"usage": {
+//	  "inputTokens": 30,
+//	  "outputTokens": 12,
+//	  "totalTokens": 1542,
+//	  "cacheReadInputTokens": 1200,
+//	  "cacheWriteInputTokens": 300
+//	}
+//
+// Following the LangSmith convention, input_tokens is the inclusive total
+// and the cache portions are reported as input_token_details subsets.
+func buildConverseUsage(usage map[string]any) *core.Usage {
+	cacheRead := core.Int(usage, "cacheReadInputTokens")
+	cacheWrite := core.Int(usage, "cacheWriteInputTokens")
+	input := core.Int(usage, "inputTokens") + cacheRead + cacheWrite
+	output := core.Int(usage, "outputTokens")
+	total := core.Int(usage, "totalTokens")
+	if total == 0 {
+		total = input + output
+	}
+
+	inputDetails := map[string]any{}
+	if cacheRead > 0 {
+		inputDetails["cache_read"] = cacheRead
+	}
+	if cacheWrite > 0 {
+		inputDetails["cache_creation"] = cacheWrite
+	}
+	return &core.Usage{
+		InputTokens:  input,
+		OutputTokens: output,
+		TotalTokens:  total,
+		Metadata:     core.UsageMetadata(input, output, total, inputDetails, nil),
+	}
+}
+
+// converseStream accumulates a ConverseStream response. Events of interest:
+//   - contentBlockStart — opens a toolUse block with its id and name
+//   - contentBlockDelta — carries text or partial toolUse input JSON
+//   - messageStop       — stop reason; the stream's terminal content event
+//   - metadata          — usage, sent after messageStop
+//   - *Exception        — an error that ends the stream
+type converseStream struct {
+	modelID    string
+	stopReason string
+	usage      map[string]any
+	blocks     []*streamBlock
+	complete   bool
+	err        error
+}
+
+type streamBlock struct {
+	toolUseID string
+	name      string
+	isToolUse bool
+	buf       strings.Builder
+}
+
+func (s *converseStream) NewScanner(onEvent func(map[string]any)) core.Scanner {
+	return newEventScanner(onEvent)
+}
+
+func (s *converseStream) IsFirstToken(chunk map[string]any) bool {
+	_, ok := chunk["contentBlockDelta"]
+	return ok
+}
+
+func (s *converseStream) Chunk(chunk map[string]any) {
+	for eventType, raw := range chunk {
+		event, _ := raw.(map[string]any)
+		switch eventType {
+		case "contentBlockStart":
+			block := s.block(event, true)
+			if tu := core.Map(core.Map(event, "start"), "toolUse"); block != nil && tu != nil {
+				block.isToolUse = true
+				block.toolUseID, _ = tu["toolUseId"].(string)
+				block.name, _ = tu["name"].(string)
+			}
+		case "contentBlockDelta":
+			block := s.block(event, false)
+			delta := core.Map(event, "delta")
+			if block == nil || delta == nil {
+				continue
+			}
+			if text, ok := delta["text"].(string); ok {
+				block.buf.WriteString(text)
+			} else if tu := core.Map(delta, "toolUse"); tu != nil {
+				block.isToolUse = true
+				input, _ := tu["input"].(string)
+				block.buf.WriteString(input)
+			}
+		case "messageStop":
+			s.stopReason, _ = event["stopReason"].(string)
+			s.complete = true
+		case "metadata":
+			s.usage = core.Map(event, "usage")
+		default:
+			if strings.HasSuffix(eventType, "Exception") {
+				msg, _ := event["message"].(string)
+				s.err = fmt.Errorf("%s: %s", eventType, msg)
+			}
+		}
+	}
+}
+
+// block returns the content block addressed by event's contentBlockIndex,
+// allocating it on first use (or resetting it when reset is set).
+func (s *converseStream) block(event map[string]any, reset bool) *streamBlock {
+	idxF, ok := event["contentBlockIndex"].(float64)
+	if !ok {
+		return nil
+	}
+	idx := int(idxF)
+	for len(s.blocks) <= idx {
+		s.blocks = append(s.blocks, nil)
+	}
+	if reset || s.blocks[idx] == nil {
+		s.blocks[idx] = &streamBlock{}
+	}
+	return s.blocks[idx]
+}
+
+// Finish rebuilds the streamed blocks as Converse content blocks so the
+// completion has the same shape as the non-streaming path. The stream is
+// complete once messageStop or an exception has been received.
+func (s *converseStream) Finish([]byte, error) (*core.Response, bool) {
+	resp := &core.Response{Model: s.modelID, StopReason: s.stopReason, Err: s.err}
+
+	var blocks []any
+	for _, b := range s.blocks {
+		if b == nil {
+			continue
+		}
+		if b.isToolUse {
+			var input any = b.buf.String()
+			var parsed any
+			if err := json.Unmarshal([]byte(b.buf.String()), &parsed); err == nil {
+				input = parsed
+			}
+			blocks = append(blocks, map[string]any{"toolUse": map[string]any{
+				"toolUseId": b.toolUseID,
+				"name":      b.name,
+				"input":     input,
+			}})
+		} else if b.buf.Len() > 0 {
+			blocks = append(blocks, map[string]any{"text": b.buf.String()})
+		}
+	}
+	resp.Completion = converseCompletion(blocks)
+
+	if len(s.usage) > 0 {
+		resp.Usage = buildConverseUsage(s.usage)
+	}
+	return resp, s.complete || s.err != nil
+}
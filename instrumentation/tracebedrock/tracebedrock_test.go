@@ -0,0 +1,543 @@
+package tracebedrock
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"hash/crc32"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+type fakeTransport struct {
+	body   []byte
+	header http.Header
+}
+
+func (t *fakeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	header := t.header
+	if header == nil {
+		header = make(http.Header)
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       io.NopCloser(bytes.NewReader(t.body)),
+		Header:     header,
+		Request:    req,
+	}, nil
+}
+
+func newTracedClient(t *testing.T, transport *fakeTransport) (*http.Client, *tracetest.InMemoryExporter) {
+	t.Helper()
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	client := WrapClient(&http.Client{Transport: transport}, WithTracerProvider(tp))
+	return client, exporter
+}
+
+// do posts body to the Bedrock Runtime action for modelID and drains the
+// response.
+func do(t *testing.T, client *http.Client, modelID, action, body string) {
+	t.Helper()
+	u := "https://bedrock-runtime.us-east-1.amazonaws.com/model/" + url.PathEscape(modelID) + "/" + action
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, u, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.ReadAll(resp.Body); err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+}
+
+// encodeEvent frames payload as an AWS event-stream message with string
+// headers.
+func encodeEvent(headers map[string]string, payload string) []byte {
+	var hdr bytes.Buffer
+	for name, value := range headers {
+		hdr.WriteByte(byte(len(name)))
+		hdr.WriteString(name)
+		hdr.WriteByte(7)
+		binary.Write(&hdr, binary.BigEndian, uint16(len(value)))
+		hdr.WriteString(value)
+	}
+	total := uint32(preludeLen + hdr.Len() + len(payload) + checksumLen)
+
+	var msg bytes.Buffer
+	binary.Write(&msg, binary.BigEndian, total)
+	binary.Write(&msg, binary.BigEndian, uint32(hdr.Len()))
+	binary.Write(&msg, binary.BigEndian, crc32.ChecksumIEEE(msg.Bytes()))
+	msg.Write(hdr.Bytes())
+	msg.WriteString(payload)
+	binary.Write(&msg, binary.BigEndian, crc32.ChecksumIEEE(msg.Bytes()))
+	return msg.Bytes()
+}
+
+func eventFrame(eventType, payload string) []byte {
+	return encodeEvent(map[string]string{
+		":message-type": "event",
+		":event-type":   eventType,
+		":content-type": "application/json",
+	}, payload)
+}
+
+// chunkFrame wraps a model-native stream event the way
+// InvokeModelWithResponseStream does.
+func chunkFrame(inner string) []byte {
+	payload, _ := json.Marshal(map[string]string{"bytes": base64.StdEncoding.EncodeToString([]byte(inner))})
+	return eventFrame("chunk", string(payload))
+}
+
+func eventStream(frames ...[]byte) []byte {
+	return bytes.Join(frames, nil)
+}
+
+func getSpanAttr(spans tracetest.SpanStubs, key string) (string, bool) {
+	for _, s := range spans {
+		for _, attr := range s.Attributes {
+			if string(attr.Key) == key {
+				return attr.Value.Emit(), true
+			}
+		}
+	}
+	return "", false
+}
+
+func getSpanAttrInt(spans tracetest.SpanStubs, key string) (int64, bool) {
+	for _, s := range spans {
+		for _, attr := range s.Attributes {
+			if string(attr.Key) == key {
+				return attr.Value.AsInt64(), true
+			}
+		}
+	}
+	return 0, false
+}
+
+func hasEvent(spans tracetest.SpanStubs, name string) bool {
+	for _, s := range spans {
+		for _, e := range s.Events {
+			if e.Name == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// --- Endpoint detection ---
+
+func TestParseModelAction(t *testing.T) {
+	tests := []struct {
+		rawURL     string
+		wantModel  string
+		wantAction string
+	}{
+		{"https://bedrock-runtime.us-east-1.amazonaws.com/model/anthropic.claude-3-haiku-20240307-v1:0/converse", "anthropic.claude-3-haiku-20240307-v1:0", "converse"},
+		{"https://bedrock-runtime.us-east-1.amazonaws.com/model/us.meta.llama3-1-8b-instruct-v1%3A0/converse-stream", "us.meta.llama3-1-8b-instruct-v1:0", "converse-stream"},
+		{"https://bedrock-runtime.us-east-1.amazonaws.com/model/arn%3Aaws%3Abedrock%3Aus-east-1%3A123%3Ainference-profile%2Fus.anthropic.claude/invoke", "arn:aws:bedrock:us-east-1:123:inference-profile/us.anthropic.claude", "invoke"},
+		{"https://vpce-1.bedrock-runtime.us-east-1.vpce.amazonaws.com/model/mistral.mistral-large-2407-v1:0/invoke-with-response-stream", "mistral.mistral-large-2407-v1:0", "invoke-with-response-stream"},
+		{"https://bedrock.us-east-1.amazonaws.com/foundation-models", "", ""},
+		{"https://bedrock-runtime.us-east-1.amazonaws.com/model/x/apply-guardrail", "", ""},
+		{"https://api.openai.com/v1/chat/completions", "", ""},
+	}
+	for _, tt := range tests {
+		u, err := url.Parse(tt.rawURL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		model, action := parseModelAction(u)
+		if model != tt.wantModel || action != tt.wantAction {
+			t.Errorf("parseModelAction(%q) = (%q, %q), want (%q, %q)", tt.rawURL, model, action, tt.wantModel, tt.wantAction)
+		}
+	}
+}
+
+func TestModelFamily(t *testing.T) {
+	tests := map[string]string{
+		"anthropic.claude-3-5-sonnet-20240620-v1:0":                        familyAnthropic,
+		"us.anthropic.claude-3-5-sonnet-20240620-v1:0":                     familyAnthropic,
+		"arn:aws:bedrock:us-east-1::foundation-model/meta.llama3-70b-v1:0": familyMeta,
+		"mistral.mistral-7b-instruct-v0:2":                                 familyMistral,
+		"amazon.titan-text-express-v1":                                     "",
+		"arn:aws:bedrock:us-east-1:123:application-inference-profile/abc":  "",
+	}
+	for id, want := range tests {
+		if got := modelFamily(id); got != want {
+			t.Errorf("modelFamily(%q) = %q, want %q", id, got, want)
+		}
+	}
+}
+
+// --- Event-stream decoding ---
+
+func TestEventScanner_SplitFeeds(t *testing.T) {
+	data := eventStream(
+		eventFrame("messageStart", `{"role":"assistant"}`),
+		eventFrame("contentBlockDelta", `{"contentBlockIndex":0,"delta":{"text":"Hi"}}`),
+	)
+	var events []map[string]any
+	s := newEventScanner(func(e map[string]any) { events = append(events, e) })
+	for i := range data {
+		s.Feed(data[i : i+1])
+	}
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2", len(events))
+	}
+	if _, ok := events[1]["contentBlockDelta"]; !ok {
+		t.Errorf("second event = %v, want contentBlockDelta", events[1])
+	}
+}
+
+func TestEventScanner_StopsOnBadChecksum(t *testing.T) {
+	bad := eventFrame("messageStart", `{"role":"assistant"}`)
+	bad[len(bad)-1] ^= 0xff
+	var n int
+	s := newEventScanner(func(map[string]any) { n++ })
+	s.Feed(eventStream(bad, eventFrame("messageStop", `{"stopReason":"end_turn"}`)))
+	if n != 0 {
+		t.Errorf("got %d events after corrupt frame, want 0", n)
+	}
+}
+
+func TestEventScanner_Exception(t *testing.T) {
+	frame := encodeEvent(map[string]string{
+		":message-type":   "exception",
+		":exception-type": "throttlingException",
+	}, `{"message":"slow down"}`)
+	var got map[string]any
+	newEventScanner(func(e map[string]any) { got = e }).Feed(frame)
+	if _, ok := got["throttlingException"]; !ok {
+		t.Errorf("event = %v, want throttlingException", got)
+	}
+}
+
+// --- Converse ---
+
+func TestRoundTrip_Converse(t *testing.T) {
+	respBody := `{
+		"output": {"message": {"role": "assistant", "content": [
+			{"text": "Checking the weather."},
+			{"toolUse": {"toolUseId": "tool-1", "name": "get_weather", "input": {"city": "Paris"}}}
+		]}},
+		"stopReason": "tool_use",
+		"usage": {"inputTokens": 20, "outputTokens": 8, "totalTokens": 128, "cacheReadInputTokens": 100},
+		"metrics": {"latencyMs": 420}
+	}`
+	client, exporter := newTracedClient(t, &fakeTransport{body: []byte(respBody)})
+	do(t, client, "anthropic.claude-3-haiku-20240307-v1:0", "converse", `{
+		"system": [{"text": "Be brief."}],
+		"messages": [
+			{"role": "user", "content": [{"text": "Weather in Paris?"}]},
+			{"role": "assistant", "content": [{"toolUse": {"toolUseId": "tool-0", "name": "noop", "input": {}}}]},
+			{"role": "user", "content": [{"toolResult": {"toolUseId": "tool-0", "content": [{"json": {"ok": true}}]}}]}
+		],
+		"inferenceConfig": {"maxTokens": 256, "temperature": 0.2}
+	}`)
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	if spans[0].Name != "bedrock.converse" {
+		t.Errorf("span name = %q", spans[0].Name)
+	}
+	if v, _ := getSpanAttr(spans, "gen_ai.provider.name"); v != "aws.bedrock" {
+		t.Errorf("gen_ai.provider.name = %q", v)
+	}
+	if v, _ := getSpanAttr(spans, "gen_ai.operation.name"); v != "chat" {
+		t.Errorf("gen_ai.operation.name = %q", v)
+	}
+	if v, _ := getSpanAttr(spans, "gen_ai.request.model"); v != "anthropic.claude-3-haiku-20240307-v1:0" {
+		t.Errorf("gen_ai.request.model = %q", v)
+	}
+	if v, _ := getSpanAttrInt(spans, "gen_ai.request.max_tokens"); v != 256 {
+		t.Errorf("gen_ai.request.max_tokens = %d", v)
+	}
+
+	prompt, _ := getSpanAttr(spans, "gen_ai.prompt")
+	for _, want := range []string{`{"content":"Be brief.","role":"system"}`, `"content":"Weather in Paris?"`, `"tool_call_id":"tool-0"`, `"content":"{\"ok\":true}"`} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("gen_ai.prompt missing %s: %s", want, prompt)
+		}
+	}
+	completion, _ := getSpanAttr(spans, "gen_ai.completion")
+	for _, want := range []string{`"content":"Checking the weather."`, `"name":"get_weather"`, `"arguments":"{\"city\":\"Paris\"}"`, `"id":"tool-1"`} {
+		if !strings.Contains(completion, want) {
+			t.Errorf("gen_ai.completion missing %s: %s", want, completion)
+		}
+	}
+	if v, _ := getSpanAttr(spans, "langsmith.metadata.stop_reason"); v != "tool_use" {
+		t.Errorf("stop_reason = %q", v)
+	}
+	if v, _ := getSpanAttrInt(spans, "gen_ai.usage.input_tokens"); v != 120 {
+		t.Errorf("input_tokens = %d, want 120 (inclusive of cache reads)", v)
+	}
+	if v, _ := getSpanAttrInt(spans, "gen_ai.usage.total_tokens"); v != 128 {
+		t.Errorf("total_tokens = %d, want 128", v)
+	}
+	um, _ := getSpanAttr(spans, "langsmith.usage_metadata")
+	if !strings.Contains(um, `"cache_read":100`) {
+		t.Errorf("usage_metadata missing cache_read: %s", um)
+	}
+}
+
+func TestRoundTrip_ConverseStream(t *testing.T) {
+	body := eventStream(
+		eventFrame("messageStart", `{"role":"assistant"}`),
+		eventFrame("contentBlockDelta", `{"contentBlockIndex":0,"delta":{"text":"Let me "}}`),
+		eventFrame("contentBlockDelta", `{"contentBlockIndex":0,"delta":{"text":"check."}}`),
+		eventFrame("contentBlockStop", `{"contentBlockIndex":0}`),
+		eventFrame("contentBlockStart", `{"contentBlockIndex":1,"start":{"toolUse":{"toolUseId":"tool-1","name":"get_weather"}}}`),
+		eventFrame("contentBlockDelta", `{"contentBlockIndex":1,"delta":{"toolUse":{"input":"{\"city\":"}}}`),
+		eventFrame("contentBlockDelta", `{"contentBlockIndex":1,"delta":{"toolUse":{"input":"\"Paris\"}"}}}`),
+		eventFrame("contentBlockStop", `{"contentBlockIndex":1}`),
+		eventFrame("messageStop", `{"stopReason":"tool_use"}`),
+		eventFrame("metadata", `{"usage":{"inputTokens":12,"outputTokens":9,"totalTokens":21},"metrics":{"latencyMs":300}}`),
+	)
+	client, exporter := newTracedClient(t, &fakeTransport{body: body})
+	do(t, client, "meta.llama3-1-70b-instruct-v1:0", "converse-stream", `{"messages":[{"role":"user","content":[{"text":"hi"}]}]}`)
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	if spans[0].Name != "bedrock.converse_stream" {
+		t.Errorf("span name = %q", spans[0].Name)
+	}
+	if spans[0].Status.Code != codes.Ok {
+		t.Errorf("status = %v, want Ok", spans[0].Status)
+	}
+	if !hasEvent(spans, "new_token") {
+		t.Error("expected new_token event")
+	}
+	completion, _ := getSpanAttr(spans, "gen_ai.completion")
+	for _, want := range []string{`"content":"Let me check."`, `"arguments":"{\"city\":\"Paris\"}"`} {
+		if !strings.Contains(completion, want) {
+			t.Errorf("gen_ai.completion missing %s: %s", want, completion)
+		}
+	}
+	if v, _ := getSpanAttrInt(spans, "gen_ai.usage.output_tokens"); v != 9 {
+		t.Errorf("output_tokens = %d, want 9", v)
+	}
+}
+
+func TestRoundTrip_ConverseStreamException(t *testing.T) {
+	body := eventStream(
+		eventFrame("messageStart", `{"role":"assistant"}`),
+		encodeEvent(map[string]string{
+			":message-type":   "exception",
+			":exception-type": "modelStreamErrorException",
+		}, `{"message":"model failed"}`),
+	)
+	client, exporter := newTracedClient(t, &fakeTransport{body: body})
+	do(t, client, "anthropic.claude-3-haiku-20240307-v1:0", "converse-stream", `{"messages":[]}`)
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	if spans[0].Status.Code != codes.Error || !strings.Contains(spans[0].Status.Description, "model failed") {
+		t.Errorf("status = %v, want error with exception message", spans[0].Status)
+	}
+	if hasEvent(spans, "new_token") {
+		t.Error("unexpected new_token event")
+	}
+}
+
+// --- InvokeModel ---
+
+func TestRoundTrip_InvokeAnthropic(t *testing.T) {
+	respBody := `{
+		"id": "msg_01", "type": "message", "role": "assistant",
+		"model": "claude-3-haiku-20240307",
+		"content": [{"type": "text", "text": "Hello!"}],
+		"stop_reason": "end_turn",
+		"usage": {"input_tokens": 10, "output_tokens": 3}
+	}`
+	client, exporter := newTracedClient(t, &fakeTransport{body: []byte(respBody)})
+	do(t, client, "anthropic.claude-3-haiku-20240307-v1:0", "invoke",
+		`{"anthropic_version":"bedrock-2023-05-31","max_tokens":100,"messages":[{"role":"user","content":"hi"}]}`)
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	if spans[0].Name != "bedrock.invoke_model" {
+		t.Errorf("span name = %q", spans[0].Name)
+	}
+	if v, _ := getSpanAttr(spans, "gen_ai.operation.name"); v != "chat" {
+		t.Errorf("gen_ai.operation.name = %q", v)
+	}
+	if _, ok := getSpanAttr(spans, "gen_ai.system"); ok {
+		t.Error("gen_ai.system should not be copied from the Anthropic parser")
+	}
+	if v, _ := getSpanAttrInt(spans, "gen_ai.request.max_tokens"); v != 100 {
+		t.Errorf("gen_ai.request.max_tokens = %d", v)
+	}
+	if v, _ := getSpanAttr(spans, "gen_ai.prompt"); !strings.Contains(v, `"content":"hi"`) {
+		t.Errorf("gen_ai.prompt = %s", v)
+	}
+	if v, _ := getSpanAttr(spans, "gen_ai.completion"); !strings.Contains(v, "Hello!") {
+		t.Errorf("gen_ai.completion = %s", v)
+	}
+	if v, _ := getSpanAttr(spans, "gen_ai.response.model"); v != "claude-3-haiku-20240307" {
+		t.Errorf("gen_ai.response.model = %q", v)
+	}
+	if v, _ := getSpanAttrInt(spans, "gen_ai.usage.input_tokens"); v != 10 {
+		t.Errorf("input_tokens = %d, want 10", v)
+	}
+}
+
+func TestRoundTrip_InvokeStreamAnthropic(t *testing.T) {
+	body := eventStream(
+		chunkFrame(`{"type":"message_start","message":{"model":"claude-3-haiku-20240307","usage":{"input_tokens":10,"output_tokens":1}}}`),
+		chunkFrame(`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`),
+		chunkFrame(`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hel"}}`),
+		chunkFrame(`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"lo"}}`),
+		chunkFrame(`{"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":4}}`),
+		chunkFrame(`{"type":"message_stop","amazon-bedrock-invocationMetrics":{"inputTokenCount":10,"outputTokenCount":4}}`),
+	)
+	client, exporter := newTracedClient(t, &fakeTransport{body: body})
+	do(t, client, "us.anthropic.claude-3-haiku-20240307-v1:0", "invoke-with-response-stream",
+		`{"anthropic_version":"bedrock-2023-05-31","max_tokens":100,"messages":[{"role":"user","content":"hi"}]}`)
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	if spans[0].Status.Code != codes.Ok {
+		t.Errorf("status = %v, want Ok", spans[0].Status)
+	}
+	if !hasEvent(spans, "new_token") {
+		t.Error("expected new_token event")
+	}
+	if v, _ := getSpanAttr(spans, "gen_ai.completion"); !strings.Contains(v, `"text":"Hello"`) {
+		t.Errorf("gen_ai.completion = %s", v)
+	}
+	if v, _ := getSpanAttr(spans, "langsmith.metadata.stop_reason"); v != "end_turn" {
+		t.Errorf("stop_reason = %q", v)
+	}
+	if v, _ := getSpanAttrInt(spans, "gen_ai.usage.output_tokens"); v != 4 {
+		t.Errorf("output_tokens = %d, want 4", v)
+	}
+}
+
+func TestRoundTrip_InvokeMeta(t *testing.T) {
+	respBody := `{"generation":"Paris.","prompt_token_count":12,"generation_token_count":3,"stop_reason":"stop"}`
+	client, exporter := newTracedClient(t, &fakeTransport{body: []byte(respBody)})
+	do(t, client, "meta.llama3-8b-instruct-v1:0", "invoke", `{"prompt":"Capital of France?","max_gen_len":64,"temperature":0.5}`)
+
+	spans := exporter.GetSpans()
+	if v, _ := getSpanAttr(spans, "gen_ai.operation.name"); v != "text_completion" {
+		t.Errorf("gen_ai.operation.name = %q", v)
+	}
+	if v, _ := getSpanAttrInt(spans, "gen_ai.request.max_tokens"); v != 64 {
+		t.Errorf("gen_ai.request.max_tokens = %d", v)
+	}
+	if v, _ := getSpanAttr(spans, "gen_ai.prompt"); !strings.Contains(v, `"content":"Capital of France?"`) {
+		t.Errorf("gen_ai.prompt = %s", v)
+	}
+	if v, _ := getSpanAttr(spans, "gen_ai.completion"); !strings.Contains(v, `"content":"Paris."`) {
+		t.Errorf("gen_ai.completion = %s", v)
+	}
+	if v, _ := getSpanAttrInt(spans, "gen_ai.usage.input_tokens"); v != 12 {
+		t.Errorf("input_tokens = %d, want 12", v)
+	}
+	if v, _ := getSpanAttr(spans, "gen_ai.response.model"); v != "meta.llama3-8b-instruct-v1:0" {
+		t.Errorf("gen_ai.response.model = %q", v)
+	}
+}
+
+func TestRoundTrip_InvokeMistralUsesHeaderUsage(t *testing.T) {
+	respBody := `{"choices":[{"index":0,"message":{"role":"assistant","content":"Bonjour"},"stop_reason":"stop"}]}`
+	header := http.Header{}
+	header.Set(inputTokenCountHeader, "7")
+	header.Set(outputTokenCountHeader, "2")
+	client, exporter := newTracedClient(t, &fakeTransport{body: []byte(respBody), header: header})
+	do(t, client, "mistral.mistral-large-2407-v1:0", "invoke", `{"messages":[{"role":"user","content":"Hello in French"}],"max_tokens":20}`)
+
+	spans := exporter.GetSpans()
+	if v, _ := getSpanAttr(spans, "gen_ai.operation.name"); v != "chat" {
+		t.Errorf("gen_ai.operation.name = %q", v)
+	}
+	if v, _ := getSpanAttr(spans, "gen_ai.completion"); !strings.Contains(v, `"content":"Bonjour"`) {
+		t.Errorf("gen_ai.completion = %s", v)
+	}
+	if v, _ := getSpanAttrInt(spans, "gen_ai.usage.input_tokens"); v != 7 {
+		t.Errorf("input_tokens = %d, want 7", v)
+	}
+	if v, _ := getSpanAttrInt(spans, "gen_ai.usage.output_tokens"); v != 2 {
+		t.Errorf("output_tokens = %d, want 2", v)
+	}
+}
+
+func TestRoundTrip_InvokeStreamMistralUsesInvocationMetrics(t *testing.T) {
+	body := eventStream(
+		chunkFrame(`{"outputs":[{"text":"Bon","stop_reason":null}]}`),
+		chunkFrame(`{"outputs":[{"text":"jour","stop_reason":"stop"}],"amazon-bedrock-invocationMetrics":{"inputTokenCount":5,"outputTokenCount":2}}`),
+	)
+	client, exporter := newTracedClient(t, &fakeTransport{body: body})
+	do(t, client, "mistral.mistral-7b-instruct-v0:2", "invoke-with-response-stream", `{"prompt":"<s>[INST] Hello in French [/INST]"}`)
+
+	spans := exporter.GetSpans()
+	if !hasEvent(spans, "new_token") {
+		t.Error("expected new_token event")
+	}
+	if v, _ := getSpanAttr(spans, "gen_ai.completion"); !strings.Contains(v, `"content":"Bonjour"`) {
+		t.Errorf("gen_ai.completion = %s", v)
+	}
+	if v, _ := getSpanAttrInt(spans, "gen_ai.usage.input_tokens"); v != 5 {
+		t.Errorf("input_tokens = %d, want 5", v)
+	}
+}
+
+func TestRoundTrip_NonBedrockPassesThrough(t *testing.T) {
+	client, exporter := newTracedClient(t, &fakeTransport{body: []byte(`{}`)})
+	req, err := http.NewRequest(http.MethodGet, "https://bedrock.us-east-1.amazonaws.com/foundation-models", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if n := len(exporter.GetSpans()); n != 0 {
+		t.Errorf("got %d spans, want 0", n)
+	}
+}
+
+func TestRoundTrip_RunNameFromContext(t *testing.T) {
+	client, exporter := newTracedClient(t, &fakeTransport{body: []byte(`{}`)})
+	ctx := WithRunNameContext(context.Background(), "my_bedrock_run")
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		"https://bedrock-runtime.us-east-1.amazonaws.com/model/amazon.nova-lite-v1:0/converse", strings.NewReader(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 || spans[0].Name != "my_bedrock_run" {
+		t.Errorf("spans = %v, want one named my_bedrock_run", spans)
+	}
+}
@@ -0,0 +1,229 @@
+package tracegemini
+
+import (
+	"encoding/json"
+	"strings"
+
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+
+	"github.com/langchain-ai/langsmith-go/instrumentation/core"
+)
+
+// field returns the first present value among keys. The REST API accepts
+// both camelCase and snake_case field names, and the SDKs differ in which
+// they send.
+func field(m map[string]any, keys ...string) any {
+	for _, k := range keys {
+		if v, ok := m[k]; ok {
+			return v
+		}
+	}
+	return nil
+}
+
+// contentText joins the text parts of a Gemini Content object.
+func contentText(content map[string]any) string {
+	rawParts, _ := content["parts"].([]any)
+	return strings.Join(parseParts(rawParts).texts, "\n")
+}
+
+// parseEmbedRequest returns the {"input":[...]} JSON of the texts in an
+// embedContent, batchEmbedContents or Vertex AI :predict request.
+func parseEmbedRequest(body []byte) string {
+	var req map[string]any
+	if err := json.Unmarshal(body, &req); err != nil {
+		return ""
+	}
+
+	var inputs []any
+	switch {
+	case req["content"] != nil:
+		// embedContent: {"content": {"parts": [...]}}
+		if c, ok := req["content"].(map[string]any); ok {
+			inputs = append(inputs, contentText(c))
+		}
+	case req["requests"] != nil:
+		// batchEmbedContents: {"requests": [{"content": {...}}, ...]}
+		reqs, _ := req["requests"].([]any)
+		for _, raw := range reqs {
+			if r, ok := raw.(map[string]any); ok {
+				inputs = append(inputs, contentText(core.Map(r, "content")))
+			}
+		}
+	case req["instances"] != nil:
+		// Vertex AI :predict: {"instances": [{"content": "text"}, ...]}
+		instances, _ := req["instances"].([]any)
+		for _, raw := range instances {
+			if inst, ok := raw.(map[string]any); ok {
+				inputs = append(inputs, inst["content"])
+			}
+		}
+	}
+	if len(inputs) == 0 {
+		return ""
+	}
+	b, err := json.Marshal(map[string]any{"input": inputs})
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+// processEmbedResponse summarizes an embeddings response as
+// {"dimensions":D,"embeddings":N}; vectors themselves are not recorded.
+// Vertex AI :predict reports per-instance token counts in
+// predictions[].embeddings.statistics.token_count.
+//
+//	embedContent:       {"embedding": {"values": [...]}}
+//	batchEmbedContents: {"embeddings": [{"values": [...]}, ...]}
+//	:predict:           {"predictions": [{"embeddings": {"values": [...], "statistics": {"token_count": 7}}}]}
+func processEmbedResponse(resp map[string]any) *core.Response {
+	var vectors []map[string]any
+	var inputTokens int64
+	if e := core.Map(resp, "embedding"); e != nil {
+		vectors = append(vectors, e)
+	}
+	if list, ok := resp["embeddings"].([]any); ok {
+		for _, raw := range list {
+			if e, ok := raw.(map[string]any); ok {
+				vectors = append(vectors, e)
+			}
+		}
+	}
+	if preds, ok := resp["predictions"].([]any); ok {
+		for _, raw := range preds {
+			pred, _ := raw.(map[string]any)
+			if e := core.Map(pred, "embeddings"); e != nil {
+				vectors = append(vectors, e)
+				inputTokens += core.Int(core.Map(e, "statistics"), "token_count")
+			}
+		}
+	}
+	if um := core.Map(resp, "usageMetadata"); um != nil {
+		inputTokens = core.Int(um, "promptTokenCount")
+	}
+
+	out := &core.Response{}
+	if len(vectors) > 0 {
+		values, _ := vectors[0]["values"].([]any)
+		if b, err := json.Marshal(map[string]any{"dimensions": len(values), "embeddings": len(vectors)}); err == nil {
+			out.Completion = string(b)
+		}
+		if len(values) > 0 {
+			out.Attributes = append(out.Attributes, semconv.GenAIEmbeddingsDimensionCount(len(values)))
+		}
+	}
+	if inputTokens > 0 {
+		out.Usage = &core.Usage{
+			InputTokens: inputTokens,
+			TotalTokens: inputTokens,
+			Metadata:    core.UsageMetadata(inputTokens, 0, inputTokens, nil, nil),
+		}
+	}
+	return out
+}
+
+// parseCountTokensRequest returns the prompt of a countTokens request, which
+// holds either contents directly or a full generateContentRequest.
+func parseCountTokensRequest(body []byte) string {
+	var req map[string]any
+	if err := json.Unmarshal(body, &req); err != nil {
+		return ""
+	}
+	if inner, ok := field(req, "generateContentRequest", "generate_content_request").(map[string]any); ok {
+		if b, err := json.Marshal(inner); err == nil {
+			body = b
+		}
+	}
+	_, prompt := parseRequestBody(body)
+	return prompt
+}
+
+// processCountTokensResponse records the token count as the run output.
+// Counting is free, so it is not reported as usage.
+func processCountTokensResponse(resp map[string]any) *core.Response {
+	out := map[string]any{"total_tokens": core.Int(resp, "totalTokens")}
+	if v := core.Int(resp, "cachedContentTokenCount"); v > 0 {
+		out["cached_content_tokens"] = v
+	}
+	if v := core.Int(resp, "totalBillableCharacters"); v > 0 {
+		out["total_billable_characters"] = v
+	}
+	b, err := json.Marshal(out)
+	if err != nil {
+		return nil
+	}
+	return &core.Response{Completion: string(b)}
+}
+
+// parseBatchRequest summarizes a batch job creation request: the job's
+// display name and either its inline request count or its input source.
+// Inline requests are not recorded individually; each is traced when its
+// results are fetched, if at all. model is set for Vertex AI jobs, which name
+// the model in the body rather than the URL.
+//
+//	batchGenerateContent: {"batch": {"displayName": ..., "inputConfig": {"requests": {"requests": [...]}} | {"fileName": ...}}}
+//	batchPredictionJobs:  {"displayName": ..., "model": "publishers/google/models/...", "inputConfig": {"gcsSource": {"uris": [...]}}}
+func parseBatchRequest(body []byte) (model, prompt string) {
+	var req map[string]any
+	if err := json.Unmarshal(body, &req); err != nil {
+		return "", ""
+	}
+	if batch, ok := req["batch"].(map[string]any); ok {
+		req = batch
+	}
+	if m, ok := req["model"].(string); ok {
+		model = m[strings.LastIndex(m, "/")+1:]
+	}
+
+	summary := map[string]any{}
+	if name, ok := field(req, "displayName", "display_name").(string); ok && name != "" {
+		summary["display_name"] = name
+	}
+	if cfg, ok := field(req, "inputConfig", "input_config").(map[string]any); ok {
+		if inline, ok := cfg["requests"].(map[string]any); ok {
+			reqs, _ := inline["requests"].([]any)
+			summary["requests"] = len(reqs)
+		}
+		if f, ok := field(cfg, "fileName", "file_name").(string); ok {
+			summary["source"] = f
+		}
+		if gcs, ok := field(cfg, "gcsSource", "gcs_source").(map[string]any); ok {
+			summary["source"] = gcs["uris"]
+		}
+		if bq, ok := field(cfg, "bigquerySource", "bigquery_source").(map[string]any); ok {
+			summary["source"] = field(bq, "inputUri", "input_uri")
+		}
+	}
+	if len(summary) == 0 {
+		return model, ""
+	}
+	b, err := json.Marshal(summary)
+	if err != nil {
+		return model, ""
+	}
+	return model, string(b)
+}
+
+// processBatchResponse records the created job's name and state. Gemini
+// returns a long-running Operation wrapping the batch in its metadata;
+// Vertex AI returns the BatchPredictionJob itself.
+func processBatchResponse(resp map[string]any) *core.Response {
+	job := resp
+	if md := core.Map(resp, "metadata"); md != nil {
+		job = md
+	}
+	name, _ := job["name"].(string)
+	if name == "" {
+		name, _ = resp["name"].(string)
+	}
+	out := map[string]any{"name": name}
+	if state, ok := job["state"].(string); ok {
+		out["state"] = state
+	}
+	b, err := json.Marshal(out)
+	if err != nil {
+		return nil
+	}
+	return &core.Response{ID: name, Completion: string(b)}
+}
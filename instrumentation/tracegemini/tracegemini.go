@@ -1,9 +1,22 @@
 // Package tracegemini provides OpenTelemetry tracing for the
 // Google Gemini API client using LangSmith-compatible spans.
 //
+// generateContent (streaming and not), embedContent, batchEmbedContents,
+// Vertex AI embedding :predict, countTokens and batch job creation
+// (batchGenerateContent, Vertex AI batchPredictionJobs) are traced. The Live
+// API runs over a WebSocket rather than the HTTP client and is not traced.
+//
 // Usage:
 //
-//	// Configure your Gemini client to use a traced HTTP client
+//	// Create the client through tracegemini; genai resolves credentials and
+//	// its HTTP client (including Vertex AI auth) as usual
+//	client, _ := tracegemini.NewClient(ctx, &genai.ClientConfig{
+//		Backend:  genai.BackendVertexAI,
+//		Project:  project,
+//		Location: location,
+//	})
+//
+//	// Or configure your Gemini client to use a traced HTTP client
 //	client, _ := genai.NewClient(ctx, &genai.ClientConfig{
 //		APIKey:     apiKey,
 //		HTTPClient: tracegemini.Client(),
@@ -21,7 +34,6 @@
 package tracegemini
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -29,21 +41,17 @@ import (
 	"net/http"
 	"strings"
 
-	"go.opentelemetry.io/otel"
+	"google.golang.org/genai"
+
 	"go.opentelemetry.io/otel/attribute"
-	"go.opentelemetry.io/otel/codes"
-	"go.opentelemetry.io/otel/propagation"
 	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
 	"go.opentelemetry.io/otel/trace"
 
+	"github.com/langchain-ai/langsmith-go/instrumentation/core"
+	"github.com/langchain-ai/langsmith-go/instrumentation/pricing"
 	"github.com/langchain-ai/langsmith-go/internal/genaiattr"
-	"github.com/langchain-ai/langsmith-go/internal/traceutil"
 )
 
-type contextKey struct{ name string }
-
-var ctxKeyRunName = contextKey{"run_name"}
-
 // WithRunNameContext sets the span (run) name for the next traced request made with ctx.
 // The run name in LangSmith is the OTLP span name; there is no separate field.
 // Use this so one client can emit runs with different names per call, e.g. in tests:
@@ -51,32 +59,51 @@ var ctxKeyRunName = contextKey{"run_name"}
 //	ctx = tracegemini.WithRunNameContext(ctx, "gemini_nonstreaming")
 //	client.Models.GenerateContent(ctx, ...)
 func WithRunNameContext(ctx context.Context, name string) context.Context {
-	return context.WithValue(ctx, ctxKeyRunName, name)
+	return core.WithRunNameContext(ctx, name)
 }
 
 // Option configures a traced HTTP client.
-type Option func(*clientOptions)
-
-type clientOptions struct {
-	tracerProvider trace.TracerProvider
-	runName        string
-}
+type Option func(*core.Config)
 
 // WithTracerProvider returns an Option that sets the tracer provider.
 // If not provided, the global tracer provider is used.
 func WithTracerProvider(tp trace.TracerProvider) Option {
-	return func(opts *clientOptions) {
-		opts.tracerProvider = tp
+	return func(cfg *core.Config) {
+		cfg.TracerProvider = tp
 	}
 }
 
 // WithRunName sets the span (run) name to the given string when non-empty. Used by integration tests to identify runs in a shared project.
 func WithRunName(name string) Option {
-	return func(opts *clientOptions) {
-		opts.runName = name
+	return func(cfg *core.Config) {
+		cfg.RunName = name
 	}
 }
 
+// WithAttachments is [core.WithAttachments].
+func WithAttachments(h core.AttachmentHandler) Option { return Option(core.WithAttachments(h)) }
+
+// WithMaxAttachmentSize is [core.WithMaxAttachmentSize].
+func WithMaxAttachmentSize(n int) Option { return Option(core.WithMaxAttachmentSize(n)) }
+
+// WithCaptureContent is [core.WithCaptureContent].
+func WithCaptureContent(capture bool) Option { return Option(core.WithCaptureContent(capture)) }
+
+// WithRedactor is [core.WithRedactor].
+func WithRedactor(r core.Redactor) Option { return Option(core.WithRedactor(r)) }
+
+// WithMaxContentLength is [core.WithMaxContentLength].
+func WithMaxContentLength(n int) Option { return Option(core.WithMaxContentLength(n)) }
+
+// WithPricing is [core.WithPricing].
+func WithPricing(prices *pricing.Table) Option { return Option(core.WithPricing(prices)) }
+
+// EndPendingRetries is [core.EndPendingRetries]. A run whose last attempt
+// failed stays open until the SDK's retry window passes; call this before
+// shutting down your tracer provider so it is exported. The LangSmith
+// OTelTracer's Shutdown calls it for you.
+func EndPendingRetries() { core.EndPendingRetries() }
+
 // Client returns a new http.Client configured with tracing middleware.
 // Equivalent to WrapClient(nil, opts...), which wraps the default transport.
 func Client(opts ...Option) *http.Client {
@@ -86,74 +113,48 @@ func Client(opts ...Option) *http.Client {
 // WrapClient wraps an existing http.Client with tracing middleware.
 // If client is nil, a new client with the default transport is created.
 func WrapClient(client *http.Client, opts ...Option) *http.Client {
-	options := &clientOptions{}
+	var cfg core.Config
 	for _, opt := range opts {
-		opt(options)
-	}
-
-	if client == nil {
-		client = &http.Client{}
-	}
-	transport := client.Transport
-	if transport == nil {
-		transport = http.DefaultTransport
+		opt(&cfg)
 	}
-	client.Transport = newRoundTripper(transport, options.tracerProvider, options.runName)
-	return client
+	return core.WrapClient(client, provider{}, cfg)
 }
 
-type roundTripper struct {
-	base           http.RoundTripper
-	tracerProvider trace.TracerProvider
-	runName        string
-}
-
-func newRoundTripper(base http.RoundTripper, tp trace.TracerProvider, runName string) http.RoundTripper {
-	return &roundTripper{
-		base:           base,
-		tracerProvider: tp,
-		runName:        runName,
+// NewClient creates a genai client whose requests are traced. genai.HTTPOptions
+// has no middleware hook, so the HTTP client genai settles on is wrapped
+// after construction instead: a caller-supplied cc.HTTPClient keeps its
+// transport, and on Vertex AI the application default credentials client
+// keeps its auth. cc.HTTPClient's transport is modified in place, so do not
+// pass a client that is shared with other code or already traced.
+func NewClient(ctx context.Context, cc *genai.ClientConfig, opts ...Option) (*genai.Client, error) {
+	client, err := genai.NewClient(ctx, cc)
+	if err != nil {
+		return nil, err
 	}
+	WrapClient(client.ClientConfig().HTTPClient, opts...)
+	return client, nil
 }
 
-// RoundTrip intercepts requests/responses to add OpenTelemetry tracing.
-func (rt *roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
-	if !isGeminiEndpoint(req.URL.Path) {
-		return rt.base.RoundTrip(req)
-	}
+// provider adapts the Gemini and Vertex AI model APIs to the core round
+// tripper: generateContent (streaming and not), embeddings, countTokens and
+// batch job creation.
+type provider struct{}
 
-	ctx := req.Context()
-	// Prefer run name from context (per-request); fall back to client option.
-	if v := ctx.Value(ctxKeyRunName); v == nil && rt.runName != "" {
-		ctx = context.WithValue(ctx, ctxKeyRunName, rt.runName)
-		req = req.WithContext(ctx)
-	}
+func (provider) TracerName() string { return "google.golang.org/genai" }
 
-	var tracer trace.Tracer
-	if rt.tracerProvider != nil {
-		tracer = rt.tracerProvider.Tracer("google.golang.org/genai")
-	} else {
-		tracer = otel.Tracer("google.golang.org/genai")
+func (provider) Match(req *http.Request) bool {
+	if isBatchPredictionJobs(req.URL.Path) {
+		return req.Method == http.MethodPost
 	}
+	return isGeminiEndpoint(req.URL.Path)
+}
 
-	ctx = otel.GetTextMapPropagator().Extract(ctx, propagation.HeaderCarrier(req.Header))
-
-	parentSpan := trace.SpanFromContext(ctx)
-
+func (provider) ParseRequest(req *http.Request, body []byte) *core.Request {
 	model, action := parseModelAction(req.URL.Path)
-	streaming := action == "streamGenerateContent"
-
-	spanName := "gemini." + action
-	if v := ctx.Value(ctxKeyRunName); v != nil {
-		if s, ok := v.(string); ok && s != "" {
-			spanName = s
-		}
-	}
-
-	opName := "generate_content"
-	if streaming {
-		opName = "stream_generate_content"
+	if isBatchPredictionJobs(req.URL.Path) {
+		action = actionBatchPredictionJobs
 	}
+	streaming := action == "streamGenerateContent"
 
 	// Detect Vertex AI vs Gemini API from the request host, matching the
 	// official OTel semconv gen_ai.provider.name values.
@@ -162,115 +163,112 @@ func (rt *roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
 		providerName = semconv.GenAIProviderNameGCPVertexAI
 	}
 
-	// Redact API key from URL if present as a query parameter.
-	spanURL := req.URL.String()
-	if req.URL.Query().Has("key") {
-		redacted := *req.URL
-		q := redacted.Query()
-		q.Set("key", "REDACTED")
-		redacted.RawQuery = q.Encode()
-		spanURL = redacted.String()
-	}
-
-	ctx, span := tracer.Start(ctx, spanName,
-		trace.WithAttributes(
+	call := &core.Request{
+		SpanName:  "gemini." + action,
+		Streaming: streaming,
+		State:     action,
+		Attributes: []attribute.KeyValue{
 			providerName,
-			semconv.GenAIOperationNameKey.String(opName),
-			genaiattr.HTTPMethodKey.String(req.Method),
-			genaiattr.HTTPURLKey.String(spanURL),
-		),
-	)
+			semconv.GenAIOperationNameKey.String(operationName(action)),
+		},
+	}
+	if isEmbeddingAction(action) {
+		call.Attributes = append(call.Attributes, genaiattr.SpanKindKey.String("embedding"))
+	}
 	if model != "" {
-		span.SetAttributes(semconv.GenAIRequestModel(model))
+		call.Attributes = append(call.Attributes, semconv.GenAIRequestModel(model))
+	}
+	if len(body) == 0 {
+		return call
 	}
 
-	// Read request body if present
-	var requestBody []byte
-	if req.Body != nil {
-		var err error
-		requestBody, err = io.ReadAll(req.Body)
-		if err != nil {
-			span.RecordError(err)
-			span.SetStatus(codes.Error, fmt.Sprintf("failed to read request body: %v", err))
-			span.End()
-			return rt.base.RoundTrip(req)
+	switch {
+	case isEmbeddingAction(action):
+		call.Prompt = parseEmbedRequest(body)
+	case action == "countTokens":
+		call.Prompt = parseCountTokensRequest(body)
+	case isBatchAction(action):
+		var batchModel string
+		batchModel, call.Prompt = parseBatchRequest(body)
+		if model == "" && batchModel != "" {
+			call.Attributes = append(call.Attributes, semconv.GenAIRequestModel(batchModel))
 		}
-		req.Body = io.NopCloser(bytes.NewBuffer(requestBody))
+	default:
+		attrs, prompt := parseRequestBody(body)
+		call.Attributes = append(call.Attributes, attrs...)
+		call.Prompt = prompt
 	}
+	return call
+}
 
-	if len(requestBody) > 0 {
-		extractRequestAttributes(span, requestBody)
+func (provider) ParseResponse(call *core.Request, body []byte) *core.Response {
+	var resp map[string]any
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil
 	}
+	action, _ := call.State.(string)
+	switch {
+	case isEmbeddingAction(action):
+		return processEmbedResponse(resp)
+	case action == "countTokens":
+		return processCountTokensResponse(resp)
+	case isBatchAction(action):
+		return processBatchResponse(resp)
+	}
+	return processResponse(resp)
+}
 
-	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
-	req = req.WithContext(ctx)
+func (provider) NewStream(*core.Request) core.Stream { return &stream{} }
+
+// actionBatchPredictionJobs names Vertex AI batch prediction job creation,
+// which is a collection endpoint (…/batchPredictionJobs) rather than a
+// model action.
+const actionBatchPredictionJobs = "batchPredictionJobs"
+
+// operationName returns the gen_ai.operation.name for a model action.
+func operationName(action string) string {
+	switch action {
+	case "streamGenerateContent":
+		return "stream_generate_content"
+	case "embedContent", "batchEmbedContents", "predict":
+		return "embeddings"
+	case "countTokens":
+		return "count_tokens"
+	case "batchGenerateContent", "asyncBatchEmbedContent":
+		return "batch"
+	case actionBatchPredictionJobs:
+		return "batch_predict"
+	}
+	return "generate_content"
+}
 
-	resp, err := rt.base.RoundTrip(req)
-	if err != nil {
-		span.RecordError(err)
-		span.SetStatus(codes.Error, err.Error())
-		span.End()
-		return resp, err
-	}
-
-	br := traceutil.NewBufferedReader(resp.Body, func(r io.Reader, readErr error) {
-		data, err := io.ReadAll(r)
-		if err != nil {
-			span.RecordError(err)
-			span.SetStatus(codes.Error, err.Error())
-			span.End()
-			return
-		}
-		if len(data) == 0 {
-			if resp.StatusCode >= 400 {
-				apiErr := fmt.Errorf("HTTP %d", resp.StatusCode)
-				span.RecordError(apiErr)
-				span.SetStatus(codes.Error, apiErr.Error())
-			}
-			if readErr != nil && readErr != io.EOF {
-				span.RecordError(readErr)
-				span.SetStatus(codes.Error, readErr.Error())
-			}
-			span.End()
-			return
-		}
+// isEmbeddingAction reports whether action returns embeddings synchronously.
+// Vertex AI serves embedding models through the generic :predict action.
+func isEmbeddingAction(action string) bool {
+	return action == "embedContent" || action == "batchEmbedContents" || action == "predict"
+}
 
-		bodyText := string(data)
-		if resp.StatusCode >= 400 {
-			msg := bodyText
-			if len(msg) > 500 {
-				msg = msg[:500] + "..."
-			}
-			apiErr := fmt.Errorf("HTTP %d: %s", resp.StatusCode, msg)
-			span.RecordError(apiErr)
-			span.SetStatus(codes.Error, apiErr.Error())
-		}
-		incompleteStream := resp.StatusCode < 400 && streaming && readErr != io.EOF
-		if incompleteStream {
-			endErr := readErr
-			if endErr == nil {
-				endErr = fmt.Errorf("Cancelled")
-			}
-			span.RecordError(endErr)
-			span.SetStatus(codes.Error, endErr.Error())
-		}
+// isBatchAction reports whether action creates a long-running batch job
+// whose response is the job, not model output.
+func isBatchAction(action string) bool {
+	return action == "batchGenerateContent" || action == "asyncBatchEmbedContent" || action == actionBatchPredictionJobs
+}
 
-		if streaming {
-			extractStreamingResponseAttributes(span, data, parentSpan)
-		} else {
-			extractResponseAttributes(span, data, parentSpan)
-		}
-		if resp.StatusCode < 400 && !incompleteStream {
-			span.SetStatus(codes.Ok, "")
-		}
-		span.End()
-	})
-	if streaming && resp.StatusCode < 400 {
-		traceutil.OnFirstSSEMatch(br, isFirstContent, func() { span.AddEvent("new_token") })
-	}
-	resp.Body = br
+// stream merges Gemini SSE chunks (each a GenerateContentResponse) into a
+// single synthetic response, processed through the same path as
+// non-streaming.
+type stream struct {
+	chunks []map[string]any
+}
 
-	return resp, nil
+func (s *stream) IsFirstToken(chunk map[string]any) bool { return isFirstContent(chunk) }
+
+func (s *stream) Chunk(chunk map[string]any) { s.chunks = append(s.chunks, chunk) }
+
+// Finish reports the stream complete only when the body was read to EOF;
+// Gemini has no terminal event.
+func (s *stream) Finish(_ []byte, readErr error) (*core.Response, bool) {
+	return processResponse(mergeStreamingChunks(s.chunks)), readErr == io.EOF
 }
 
 // isFirstContent returns true when the SSE chunk contains generated content
@@ -307,14 +305,32 @@ func isFirstContent(chunk map[string]any) bool {
 	return false
 }
 
-// isGeminiEndpoint returns true if the path matches a Gemini generateContent
-// or streamGenerateContent endpoint.
+// isGeminiEndpoint returns true if the path matches a traced Gemini model
+// action: generateContent, streamGenerateContent, embedContent,
+// batchEmbedContents, countTokens, batchGenerateContent,
+// asyncBatchEmbedContent, or :predict on an embedding model. The Live API
+// runs over a WebSocket and never reaches the HTTP client.
 func isGeminiEndpoint(path string) bool {
 	if !strings.Contains(path, "/models/") {
 		return false
 	}
-	_, action := parseModelAction(path)
-	return action == "generateContent" || action == "streamGenerateContent"
+	model, action := parseModelAction(path)
+	switch action {
+	case "generateContent", "streamGenerateContent",
+		"embedContent", "batchEmbedContents", "countTokens",
+		"batchGenerateContent", "asyncBatchEmbedContent":
+		return true
+	case "predict":
+		// :predict also serves Imagen and other non-embedding models.
+		return strings.Contains(model, "embedding")
+	}
+	return false
+}
+
+// isBatchPredictionJobs returns true for the Vertex AI batch prediction job
+// collection, e.g. /v1/projects/p/locations/l/batchPredictionJobs.
+func isBatchPredictionJobs(path string) bool {
+	return strings.HasSuffix(path, "/batchPredictionJobs")
 }
 
 // parseModelAction extracts the model name and action from a Gemini API path.
@@ -331,23 +347,23 @@ func parseModelAction(path string) (model, action string) {
 	return rest, ""
 }
 
-// extractRequestAttributes extracts attributes from the Gemini request body
-// and sets them on the span. Handles contents, systemInstruction, and generationConfig.
-func extractRequestAttributes(span trace.Span, body []byte) {
+// parseRequestBody extracts sampling attributes and input messages from the
+// Gemini request body. Handles contents, systemInstruction, and generationConfig.
+func parseRequestBody(body []byte) (attrs []attribute.KeyValue, prompt string) {
 	var req map[string]any
 	if err := json.Unmarshal(body, &req); err != nil {
-		return
+		return nil, ""
 	}
 
 	if cfg, ok := req["generationConfig"].(map[string]any); ok {
 		if temp, ok := cfg["temperature"].(float64); ok {
-			span.SetAttributes(semconv.GenAIRequestTemperature(temp))
+			attrs = append(attrs, semconv.GenAIRequestTemperature(temp))
 		}
 		if maxTokens, ok := cfg["maxOutputTokens"].(float64); ok {
-			span.SetAttributes(semconv.GenAIRequestMaxTokens(int(maxTokens)))
+			attrs = append(attrs, semconv.GenAIRequestMaxTokens(int(maxTokens)))
 		}
 		if topP, ok := cfg["topP"].(float64); ok {
-			span.SetAttributes(semconv.GenAIRequestTopP(topP))
+			attrs = append(attrs, semconv.GenAIRequestTopP(topP))
 		}
 	}
 
@@ -376,9 +392,10 @@ func extractRequestAttributes(span trace.Span, body []byte) {
 
 	if len(messages) > 0 {
 		if out, err := json.Marshal(map[string]any{"messages": messages}); err == nil {
-			span.SetAttributes(genaiattr.PromptKey.String(string(out)))
+			prompt = string(out)
 		}
 	}
+	return attrs, prompt
 }
 
 // parsedParts holds the result of scanning Gemini content parts.
@@ -386,10 +403,50 @@ type parsedParts struct {
 	texts         []string
 	toolCalls     []any
 	toolResponses []any
+	// media holds inline and file-referenced images, audio and documents
+	// as OpenAI-compatible content parts.
+	media []any
+}
+
+// content returns the joined text, or an OpenAI-compatible content-part
+// array when the parts include media.
+func (p parsedParts) content(sep string) any {
+	if len(p.media) == 0 {
+		return strings.Join(p.texts, sep)
+	}
+	var parts []any
+	if len(p.texts) > 0 {
+		parts = append(parts, map[string]any{"type": "text", "text": strings.Join(p.texts, sep)})
+	}
+	return append(parts, p.media...)
+}
+
+// mediaPart converts an inlineData or fileData part to an OpenAI-compatible
+// content part: image_url for images, file otherwise. Inline data becomes a
+// base64 data: URL, which the core tracer moves into an attachment.
+func mediaPart(part map[string]any) (map[string]any, bool) {
+	if inline, ok := field(part, "inlineData", "inline_data").(map[string]any); ok {
+		mimeType, _ := field(inline, "mimeType", "mime_type").(string)
+		data, _ := inline["data"].(string)
+		url := "data:" + mimeType + ";base64," + data
+		if strings.HasPrefix(mimeType, "image/") {
+			return map[string]any{"type": "image_url", "image_url": map[string]any{"url": url}}, true
+		}
+		return map[string]any{"type": "file", "file": map[string]any{"file_data": url}}, true
+	}
+	if file, ok := field(part, "fileData", "file_data").(map[string]any); ok {
+		mimeType, _ := field(file, "mimeType", "mime_type").(string)
+		uri, _ := field(file, "fileUri", "file_uri").(string)
+		if strings.HasPrefix(mimeType, "image/") {
+			return map[string]any{"type": "image_url", "image_url": map[string]any{"url": uri}}, true
+		}
+		return map[string]any{"type": "file", "file": map[string]any{"file_uri": uri, "mime_type": mimeType}}, true
+	}
+	return nil, false
 }
 
 // parseParts scans Gemini content parts and returns text, tool calls
-// (OpenAI-compatible format), and tool response messages.
+// (OpenAI-compatible format), tool response messages and media.
 func parseParts(parts []any) parsedParts {
 	var p parsedParts
 	for _, raw := range parts {
@@ -400,6 +457,9 @@ func parseParts(parts []any) parsedParts {
 		if text, ok := part["text"].(string); ok && text != "" {
 			p.texts = append(p.texts, text)
 		}
+		if media, ok := mediaPart(part); ok {
+			p.media = append(p.media, media)
+		}
 		if fc, ok := part["functionCall"].(map[string]any); ok {
 			name, _ := fc["name"].(string)
 			argsJSON := "{}"
@@ -430,11 +490,15 @@ func parseParts(parts []any) parsedParts {
 					respContent = string(rb)
 				}
 			}
-			p.toolResponses = append(p.toolResponses, map[string]any{
+			msg := map[string]any{
 				"role":    "tool",
 				"name":    name,
 				"content": respContent,
-			})
+			}
+			if id, ok := fr["id"].(string); ok && id != "" {
+				msg["tool_call_id"] = id
+			}
+			p.toolResponses = append(p.toolResponses, msg)
 		}
 	}
 	return p
@@ -467,8 +531,8 @@ func contentToMessages(content map[string]any) []any {
 	}
 
 	msg := map[string]any{"role": role}
-	if len(p.texts) > 0 {
-		msg["content"] = strings.Join(p.texts, "\n")
+	if len(p.texts) > 0 || len(p.media) > 0 {
+		msg["content"] = p.content("\n")
 	}
 	return []any{msg}
 }
@@ -489,25 +553,6 @@ func mapGeminiRole(role any) string {
 	}
 }
 
-// extractResponseAttributes extracts attributes from a non-streaming Gemini response body.
-func extractResponseAttributes(span trace.Span, body []byte, parentSpan trace.Span) {
-	var resp map[string]any
-	if err := json.Unmarshal(body, &resp); err != nil {
-		return
-	}
-	processResponse(span, resp, parentSpan)
-}
-
-// extractStreamingResponseAttributes merges SSE chunks into a single
-// synthetic response and processes it through the same path as non-streaming.
-func extractStreamingResponseAttributes(span trace.Span, data []byte, parentSpan trace.Span) {
-	chunks, err := traceutil.ParseSSEChunks(bytes.NewReader(data))
-	if err != nil || len(chunks) == 0 {
-		return
-	}
-	processResponse(span, mergeStreamingChunks(chunks), parentSpan)
-}
-
 // mergeStreamingChunks folds SSE chunks (each a GenerateContentResponse)
 // into one synthetic response by accumulating content parts and keeping the
 // last-seen metadata/usage.
@@ -556,25 +601,21 @@ func mergeStreamingChunks(chunks []map[string]any) map[string]any {
 	return resp
 }
 
-// processResponse sets span attributes from a (possibly merged) Gemini response.
-func processResponse(span trace.Span, resp map[string]any, parentSpan trace.Span) {
-	if mv, ok := resp["modelVersion"].(string); ok {
-		span.SetAttributes(semconv.GenAIResponseModel(mv))
-	}
-	if rid, ok := resp["responseId"].(string); ok {
-		span.SetAttributes(semconv.GenAIResponseID(rid))
-	}
+// processResponse builds a core.Response from a (possibly merged) Gemini response.
+func processResponse(resp map[string]any) *core.Response {
+	out := &core.Response{}
+	out.Model, _ = resp["modelVersion"].(string)
+	out.ID, _ = resp["responseId"].(string)
 
 	if completion, finishReason := buildCompletion(resp); completion != "" {
-		span.SetAttributes(genaiattr.CompletionKey.String(completion))
-		if finishReason != "" {
-			span.SetAttributes(genaiattr.StopReasonKey.String(finishReason))
-		}
+		out.Completion = completion
+		out.StopReason = finishReason
 	}
 
 	if usage, ok := resp["usageMetadata"].(map[string]any); ok {
-		setUsageAttributes(span, usage, parentSpan)
+		out.Usage = buildUsage(usage)
 	}
+	return out
 }
 
 // buildCompletion builds a {"messages":[...]} JSON string from candidates,
@@ -600,7 +641,7 @@ func buildCompletion(resp map[string]any) (completion, finishReason string) {
 	}
 
 	p := parseParts(parts)
-	if len(p.texts) == 0 && len(p.toolCalls) == 0 {
+	if len(p.texts) == 0 && len(p.toolCalls) == 0 && len(p.media) == 0 {
 		return "", finishReason
 	}
 
@@ -616,7 +657,7 @@ func buildCompletion(resp map[string]any) (completion, finishReason string) {
 		}
 		msg["tool_calls"] = p.toolCalls
 	} else {
-		msg["content"] = strings.Join(p.texts, "")
+		msg["content"] = p.content("")
 	}
 
 	out, err := json.Marshal(map[string]any{"messages": []any{msg}})
@@ -684,21 +725,14 @@ func modalityTokens(usage map[string]any, field, modality string) int64 {
 // (https://ai.google.dev/gemini-api/docs/pricing). We route everything into the
 // over_200k / cache_read_over_200k buckets so every token is charged there.
 func buildUsageMetadata(usage map[string]any) (usageMetadata map[string]any, totalInput, outputTokens, totalTokens int64) {
-	getInt := func(key string) int64 {
-		if v, ok := usage[key].(float64); ok {
-			return int64(v)
-		}
-		return 0
-	}
-
-	totalInput = getInt("promptTokenCount")
-	thoughts := getInt("thoughtsTokenCount")
-	outputTokens = getInt("candidatesTokenCount") + thoughts
-	cacheRead := getInt("cachedContentTokenCount")
+	totalInput = core.Int(usage, "promptTokenCount")
+	thoughts := core.Int(usage, "thoughtsTokenCount")
+	outputTokens = core.Int(usage, "candidatesTokenCount") + thoughts
+	cacheRead := core.Int(usage, "cachedContentTokenCount")
 
 	// Prefer the API's totalTokenCount (it also accounts for tool-use prompt
 	// tokens); fall back to input+output when absent.
-	totalTokens = getInt("totalTokenCount")
+	totalTokens = core.Int(usage, "totalTokenCount")
 	if totalTokens == 0 {
 		totalTokens = totalInput + outputTokens
 	}
@@ -749,70 +783,30 @@ func buildUsageMetadata(usage map[string]any) (usageMetadata map[string]any, tot
 		outputTokenDetails["reasoning"] = thoughts
 	}
 
-	usageMetadata = map[string]any{}
-	if totalInput > 0 {
-		usageMetadata["input_tokens"] = totalInput
-	}
-	if outputTokens > 0 {
-		usageMetadata["output_tokens"] = outputTokens
-	}
-	if totalInput > 0 || outputTokens > 0 {
-		usageMetadata["total_tokens"] = totalTokens
-	}
-	if len(inputTokenDetails) > 0 {
-		usageMetadata["input_token_details"] = inputTokenDetails
-	}
-	if len(outputTokenDetails) > 0 {
-		usageMetadata["output_token_details"] = outputTokenDetails
-	}
+	usageMetadata = core.UsageMetadata(totalInput, outputTokens, totalTokens, inputTokenDetails, outputTokenDetails)
 	return usageMetadata, totalInput, outputTokens, totalTokens
 }
 
-// setUsageAttributes records token usage on the span: the cost-driving
-// langsmith.usage_metadata JSON, the flat gen_ai.usage.* token counts, and the
-// legacy underscore-format cache/reasoning attributes. See the inline notes for
-// which consumer each group actually serves.
-func setUsageAttributes(span trace.Span, usage map[string]any, parentSpan trace.Span) {
+// buildUsage maps a Gemini usageMetadata object onto core.Usage. Besides the
+// cost-driving usage_metadata it carries the legacy underscore-format
+// cache/reasoning attributes (subsets of the totals) for the converter's
+// flat-attribute path.
+func buildUsage(usage map[string]any) *core.Usage {
 	usageMetadata, totalInput, outputTokens, totalTokens := buildUsageMetadata(usage)
-
-	if len(usageMetadata) > 0 {
-		if out, err := json.Marshal(usageMetadata); err == nil {
-			span.SetAttributes(genaiattr.UsageMetadataKey.String(string(out)))
-		}
-	}
-
-	// Flat gen_ai.usage.* attributes. On this span they are redundant for
-	// LangSmith (the converter prefers the usage_metadata JSON above and ignores
-	// these), but are kept for OpenTelemetry-standard consumers. Propagating
-	// input/output to the parent IS load-bearing: the root span has no
-	// usage_metadata of its own, so the converter's fallback path turns these
-	// propagated attrs into the root run's token totals, which Thread-list stats
-	// aggregate.
-	setSelfAndParent := func(kv attribute.KeyValue) {
-		span.SetAttributes(kv)
-		if parentSpan.SpanContext().IsValid() && parentSpan.IsRecording() {
-			parentSpan.SetAttributes(kv)
-		}
-	}
-	if totalInput > 0 {
-		setSelfAndParent(genaiattr.UsageInputTokensKey.Int64(totalInput))
-	}
-	if outputTokens > 0 {
-		setSelfAndParent(genaiattr.UsageOutputTokensKey.Int64(outputTokens))
-	}
-	if totalTokens > 0 {
-		span.SetAttributes(genaiattr.UsageTotalTokensKey.Int64(totalTokens))
-	}
-
-	// Legacy flat detail attributes (subsets of the totals above) for the
-	// converter's underscore-format path.
-	if v, ok := usage["cachedContentTokenCount"].(float64); ok && v > 0 {
-		span.SetAttributes(
-			genaiattr.CacheReadInputTokensKey.Int64(int64(v)),
+	u := &core.Usage{
+		InputTokens:  totalInput,
+		OutputTokens: outputTokens,
+		TotalTokens:  totalTokens,
+		Metadata:     usageMetadata,
+	}
+	if v := core.Int(usage, "cachedContentTokenCount"); v > 0 {
+		u.Attributes = append(u.Attributes,
+			genaiattr.CacheReadInputTokensKey.Int64(v),
 			semconv.GenAIUsageCacheReadInputTokens(int(v)),
 		)
 	}
-	if v, ok := usage["thoughtsTokenCount"].(float64); ok && v > 0 {
-		span.SetAttributes(genaiattr.UsageReasoningTokensKey.Int64(int64(v)))
+	if v := core.Int(usage, "thoughtsTokenCount"); v > 0 {
+		u.Attributes = append(u.Attributes, genaiattr.UsageReasoningTokensKey.Int64(v))
 	}
+	return u
 }
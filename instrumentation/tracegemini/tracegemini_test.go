@@ -10,9 +10,14 @@ import (
 	"strings"
 	"testing"
 
+	"google.golang.org/genai"
+
 	"go.opentelemetry.io/otel/attribute"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/langchain-ai/langsmith-go/instrumentation/core"
 )
 
 type fakeTransport struct {
@@ -138,7 +143,14 @@ func TestIsGeminiEndpoint(t *testing.T) {
 		{"/v1beta/models/gemini-2.0-flash:generateContent", true},
 		{"/v1beta/models/gemini-2.0-flash:streamGenerateContent", true},
 		{"/v1beta/models/gemini-1.5-pro:generateContent", true},
-		{"/v1beta/models/gemini-2.0-flash:countTokens", false},
+		{"/v1beta/models/gemini-2.0-flash:countTokens", true},
+		{"/v1beta/models/gemini-embedding-001:embedContent", true},
+		{"/v1beta/models/gemini-embedding-001:batchEmbedContents", true},
+		{"/v1beta/models/gemini-2.0-flash:batchGenerateContent", true},
+		{"/v1beta/models/gemini-embedding-001:asyncBatchEmbedContent", true},
+		{"/v1/projects/p/locations/us-central1/publishers/google/models/text-embedding-005:predict", true},
+		{"/v1/projects/p/locations/us-central1/publishers/google/models/imagen-3.0-generate-002:predict", false},
+		{"/v1beta/models/gemini-2.0-flash:bidiGenerateContent", false},
 		{"/v1beta/models/gemini-2.0-flash", false},
 		{"/v1/chat/completions", false},
 		// Vertex AI paths
@@ -154,6 +166,176 @@ func TestIsGeminiEndpoint(t *testing.T) {
 	}
 }
 
+func TestMatch_BatchPredictionJobs(t *testing.T) {
+	tests := []struct {
+		method string
+		path   string
+		want   bool
+	}{
+		{http.MethodPost, "/v1/projects/p/locations/us-central1/batchPredictionJobs", true},
+		{http.MethodGet, "/v1/projects/p/locations/us-central1/batchPredictionJobs", false},
+		{http.MethodGet, "/v1/projects/p/locations/us-central1/batchPredictionJobs/123", false},
+	}
+	for _, tt := range tests {
+		req, err := http.NewRequest(tt.method, "https://us-central1-aiplatform.googleapis.com"+tt.path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := (provider{}).Match(req); got != tt.want {
+			t.Errorf("Match(%s %s) = %v, want %v", tt.method, tt.path, got, tt.want)
+		}
+	}
+}
+
+// --- Embeddings, countTokens and batch ---
+
+// doAction posts reqBody to rawURL through a traced client answering with
+// respBody and returns the single recorded span.
+func doAction(t *testing.T, rawURL, reqBody, respBody string) tracetest.SpanStubs {
+	t.Helper()
+	client, exporter := newTracedClient(t, []byte(respBody))
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, rawURL, strings.NewReader(reqBody))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.ReadAll(resp.Body); err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	return spans
+}
+
+func TestRoundTrip_Actions(t *testing.T) {
+	const gemini = "https://generativelanguage.googleapis.com/v1beta/models/"
+	const vertex = "https://us-central1-aiplatform.googleapis.com/v1/projects/p/locations/us-central1/"
+	tests := []struct {
+		name     string
+		url      string
+		req      string
+		resp     string
+		want     map[string]string
+		wantInt  map[string]int64
+		wantNone []string
+	}{
+		{
+			name: "embedContent",
+			url:  gemini + "gemini-embedding-001:embedContent",
+			req:  `{"content":{"parts":[{"text":"hello world"}]},"taskType":"RETRIEVAL_QUERY"}`,
+			resp: `{"embedding":{"values":[0.1,0.2,0.3,0.4]}}`,
+			want: map[string]string{
+				"gen_ai.operation.name": "embeddings",
+				"langsmith.span.kind":   "embedding",
+				"gen_ai.request.model":  "gemini-embedding-001",
+				"gen_ai.prompt":         `{"input":["hello world"]}`,
+				"gen_ai.completion":     `{"dimensions":4,"embeddings":1}`,
+			},
+			wantInt:  map[string]int64{"gen_ai.embeddings.dimension.count": 4},
+			wantNone: []string{"gen_ai.usage.input_tokens"},
+		},
+		{
+			name: "batchEmbedContents",
+			url:  gemini + "gemini-embedding-001:batchEmbedContents",
+			req:  `{"requests":[{"model":"models/gemini-embedding-001","content":{"parts":[{"text":"a"}]}},{"model":"models/gemini-embedding-001","content":{"parts":[{"text":"b"}]}}]}`,
+			resp: `{"embeddings":[{"values":[0.1,0.2]},{"values":[0.3,0.4]}]}`,
+			want: map[string]string{
+				"gen_ai.operation.name": "embeddings",
+				"gen_ai.prompt":         `{"input":["a","b"]}`,
+				"gen_ai.completion":     `{"dimensions":2,"embeddings":2}`,
+			},
+		},
+		{
+			name: "vertex predict embeddings",
+			url:  vertex + "publishers/google/models/text-embedding-005:predict",
+			req:  `{"instances":[{"content":"a","task_type":"RETRIEVAL_DOCUMENT"},{"content":"b"}]}`,
+			resp: `{"predictions":[{"embeddings":{"values":[0.1,0.2,0.3],"statistics":{"token_count":3,"truncated":false}}},{"embeddings":{"values":[0.4,0.5,0.6],"statistics":{"token_count":4}}}]}`,
+			want: map[string]string{
+				"gen_ai.provider.name": "gcp.vertex_ai",
+				"langsmith.span.kind":  "embedding",
+				"gen_ai.prompt":        `{"input":["a","b"]}`,
+				"gen_ai.completion":    `{"dimensions":3,"embeddings":2}`,
+			},
+			wantInt: map[string]int64{"gen_ai.usage.input_tokens": 7},
+		},
+		{
+			name: "countTokens",
+			url:  gemini + "gemini-2.0-flash:countTokens",
+			req:  `{"contents":[{"role":"user","parts":[{"text":"how many tokens?"}]}]}`,
+			resp: `{"totalTokens":5,"cachedContentTokenCount":2}`,
+			want: map[string]string{
+				"gen_ai.operation.name": "count_tokens",
+				"gen_ai.prompt":         `{"messages":[{"content":"how many tokens?","role":"user"}]}`,
+				"gen_ai.completion":     `{"cached_content_tokens":2,"total_tokens":5}`,
+			},
+			wantNone: []string{"gen_ai.usage.input_tokens", "langsmith.span.kind"},
+		},
+		{
+			name: "countTokens with generateContentRequest",
+			url:  gemini + "gemini-2.0-flash:countTokens",
+			req:  `{"generateContentRequest":{"model":"models/gemini-2.0-flash","contents":[{"role":"user","parts":[{"text":"hi"}]}]}}`,
+			resp: `{"totalTokens":1}`,
+			want: map[string]string{
+				"gen_ai.prompt":     `{"messages":[{"content":"hi","role":"user"}]}`,
+				"gen_ai.completion": `{"total_tokens":1}`,
+			},
+		},
+		{
+			name: "batchGenerateContent",
+			url:  gemini + "gemini-2.0-flash:batchGenerateContent",
+			req:  `{"batch":{"display_name":"nightly","input_config":{"requests":{"requests":[{"request":{"contents":[]}},{"request":{"contents":[]}}]}}}}`,
+			resp: `{"name":"batches/abc","metadata":{"@type":"type.googleapis.com/google.ai.generativelanguage.v1main.GenerateContentBatch","name":"batches/abc","state":"BATCH_STATE_PENDING"}}`,
+			want: map[string]string{
+				"gen_ai.operation.name": "batch",
+				"gen_ai.prompt":         `{"display_name":"nightly","requests":2}`,
+				"gen_ai.completion":     `{"name":"batches/abc","state":"BATCH_STATE_PENDING"}`,
+				"gen_ai.response.id":    "batches/abc",
+			},
+		},
+		{
+			name: "vertex batchPredictionJobs",
+			url:  vertex + "batchPredictionJobs",
+			req:  `{"displayName":"job","model":"publishers/google/models/gemini-2.0-flash","inputConfig":{"instancesFormat":"jsonl","gcsSource":{"uris":["gs://b/in.jsonl"]}}}`,
+			resp: `{"name":"projects/p/locations/us-central1/batchPredictionJobs/123","state":"JOB_STATE_PENDING"}`,
+			want: map[string]string{
+				"gen_ai.operation.name": "batch_predict",
+				"gen_ai.request.model":  "gemini-2.0-flash",
+				"gen_ai.prompt":         `{"display_name":"job","source":["gs://b/in.jsonl"]}`,
+				"gen_ai.completion":     `{"name":"projects/p/locations/us-central1/batchPredictionJobs/123","state":"JOB_STATE_PENDING"}`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spans := doAction(t, tt.url, tt.req, tt.resp)
+			for k, want := range tt.want {
+				if got, ok := getSpanAttr(spans, k); !ok || got != want {
+					t.Errorf("%s = %q, want %q", k, got, want)
+				}
+			}
+			for k, want := range tt.wantInt {
+				if got, ok := getSpanAttrInt(spans, k); !ok || got != want {
+					t.Errorf("%s = %d, want %d", k, got, want)
+				}
+			}
+			for _, k := range tt.wantNone {
+				if _, ok := getSpanAttr(spans, k); ok {
+					t.Errorf("unexpected attribute %s", k)
+				}
+			}
+			if hasEvent(spans, "new_token") {
+				t.Error("non-streaming action should not record new_token")
+			}
+		})
+	}
+}
+
 func TestParseModelAction(t *testing.T) {
 	tests := []struct {
 		path       string
@@ -228,6 +410,37 @@ func TestRoundTrip_NonStreaming(t *testing.T) {
 	}
 }
 
+func TestNewClient_KeepsHTTPClientTransport(t *testing.T) {
+	respBody := `{"candidates": [{"content": {"parts": [{"text": "Hi"}], "role": "model"}, "finishReason": "STOP"}]}`
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	transport := &fakeTransport{body: []byte(respBody)}
+
+	client, err := NewClient(context.Background(), &genai.ClientConfig{
+		APIKey:     "test-key",
+		Backend:    genai.BackendGeminiAPI,
+		HTTPClient: &http.Client{Transport: transport},
+	}, WithTracerProvider(tp))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Models.GenerateContent(context.Background(), "gemini-2.0-flash", genai.Text("hello"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Text() != "Hi" {
+		t.Errorf("response text = %q, want served by the caller's transport", resp.Text())
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	if v, _ := getSpanAttr(spans, "gen_ai.request.model"); v != "gemini-2.0-flash" {
+		t.Errorf("gen_ai.request.model = %q", v)
+	}
+}
+
 func TestRoundTrip_NonStreamingDoesNotEmitNewToken(t *testing.T) {
 	respBody := `{
 		"candidates": [{"content": {"parts": [{"text": "hi"}]}, "finishReason": "STOP"}],
@@ -434,6 +647,29 @@ func TestIsFirstContent(t *testing.T) {
 
 // --- Request attributes ---
 
+// extractRequestAttributes records the request-body attributes the round
+// tripper would set.
+func extractRequestAttributes(span trace.Span, body []byte) {
+	attrs, prompt := parseRequestBody(body)
+	span.SetAttributes(attrs...)
+	if prompt != "" {
+		span.SetAttributes(attribute.String("gen_ai.prompt", prompt))
+	}
+}
+
+func extractResponseAttributes(span trace.Span, body []byte, parentSpan trace.Span) {
+	core.RecordResponse(span, provider{}.ParseResponse(&core.Request{State: "generateContent"}, body), parentSpan)
+}
+
+func extractStreamingResponseAttributes(span trace.Span, data []byte, parentSpan trace.Span) {
+	resp, _ := core.ParseStream(&stream{}, data, io.EOF)
+	core.RecordResponse(span, resp, parentSpan)
+}
+
+func setUsageAttributes(span trace.Span, usage map[string]any, parentSpan trace.Span) {
+	core.RecordUsage(span, buildUsage(usage), parentSpan)
+}
+
 func TestExtractRequestAttributes_BasicContents(t *testing.T) {
 	span, _ := startTestSpan(t)
 	body := `{
@@ -1053,3 +1289,38 @@ func TestMapGeminiRole(t *testing.T) {
 		}
 	}
 }
+
+func TestRoundTrip_InlineDataBecomesAttachment(t *testing.T) {
+	respBody := `{"candidates": [{"content": {"parts": [{"text": "A cat."}], "role": "model"}, "finishReason": "STOP"}]}`
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	var got map[string]core.Attachment
+	client := WrapClient(&http.Client{Transport: &fakeTransport{body: []byte(respBody)}},
+		WithTracerProvider(tp),
+		WithAttachments(func(_ context.Context, _ trace.SpanContext, a map[string]core.Attachment) { got = a }),
+	)
+
+	body := `{"contents":[{"role":"user","parts":[{"text":"What is this?"},{"inlineData":{"mimeType":"image/png","data":"aGVsbG8="}},{"fileData":{"mimeType":"application/pdf","fileUri":"gs://bucket/a.pdf"}}]}]}`
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost,
+		"https://generativelanguage.googleapis.com/v1beta/models/gemini-2.0-flash:generateContent",
+		strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	spans := exporter.GetSpans()
+	prompt, _ := getSpanAttr(spans, "gen_ai.prompt")
+	want := `{"messages":[{"content":[{"text":"What is this?","type":"text"},{"image_url":{"url":"attachment:input_0"},"type":"image_url"},{"file":{"file_uri":"gs://bucket/a.pdf","mime_type":"application/pdf"},"type":"file"}],"role":"user"}]}`
+	if prompt != want {
+		t.Errorf("gen_ai.prompt = %s\nwant %s", prompt, want)
+	}
+	if a := got["input_0"]; a.ContentType != "image/png" || string(a.Data) != "hello" {
+		t.Errorf("input_0 = %+v", a)
+	}
+}
@@ -0,0 +1,498 @@
+// Package tracehttp traces calls to arbitrary HTTP APIs as LangSmith tool
+// runs, for agents whose tools are REST endpoints.
+//
+// Each matched request becomes a tool run parented under the run in the
+// request's context. The run records the method, URL template, path
+// parameters, sanitized headers and JSON body as inputs, and the status,
+// headers and body as outputs; latency is the run's duration.
+//
+// Routes select which requests are traced and name their runs. Patterns use
+// http.ServeMux syntax ("[METHOD ][HOST]/[PATH]" with {name} wildcards):
+//
+//	client := tracehttp.Client(
+//		tracehttp.WithRoute("GET inventory.internal/items/{sku}", "lookup_item"),
+//		tracehttp.WithRoute("POST tickets.internal/tickets", ""),
+//	)
+//	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "http://inventory.internal/items/A-42", nil)
+//	resp, err := client.Do(req)
+//
+// Without routes every request is traced, named after its method and host.
+package tracehttp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/langchain-ai/langsmith-go/instrumentation/core"
+	"github.com/langchain-ai/langsmith-go/internal/genaiattr"
+	"github.com/langchain-ai/langsmith-go/internal/thread"
+)
+
+const tracerName = "github.com/langchain-ai/langsmith-go/instrumentation/tracehttp"
+
+// DefaultMaxBodySize caps the request and response bodies recorded on a run
+// when WithMaxBodySize is not set.
+const DefaultMaxBodySize = 64 << 10
+
+// sensitiveHeaders are always recorded as "[REDACTED]". Headers whose names
+// contain a sensitiveHeaderWords entry are too.
+var sensitiveHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+	"Set-Cookie":          true,
+}
+
+var sensitiveHeaderWords = []string{"token", "secret", "password", "key", "session", "auth", "signature"}
+
+// propagationHeaders are written by the tracing itself and not recorded.
+var propagationHeaders = map[string]bool{
+	"Traceparent": true,
+	"Tracestate":  true,
+	"Baggage":     true,
+}
+
+type config struct {
+	core        core.Config
+	routes      []*route
+	mux         *http.ServeMux
+	maxBodySize int
+	redact      map[string]bool
+}
+
+// Option configures a traced client.
+type Option func(*config)
+
+// WithTracerProvider returns an Option that sets the tracer provider.
+// If not provided, the global tracer provider is used.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(cfg *config) {
+		cfg.core.TracerProvider = tp
+	}
+}
+
+// WithRoute traces requests matching pattern as tool runs named name.
+// pattern uses http.ServeMux syntax, e.g. "GET api.internal/users/{id}"; its
+// path is recorded as the run's URL template and its wildcards as path
+// parameters. name may reference {method}, {host}, {route} (the URL
+// template) and the pattern's wildcards; when empty it is "{method} {route}".
+//
+// Once any route is set, requests matching no route pass through untraced.
+// Constructing the client panics if pattern is invalid or conflicts with
+// another route, as http.ServeMux.Handle does.
+func WithRoute(pattern, name string) Option {
+	return func(cfg *config) {
+		cfg.routes = append(cfg.routes, newRoute(pattern, name))
+	}
+}
+
+// WithMaxBodySize sets the largest request or response body, in bytes,
+// recorded on a run; longer bodies are truncated. The default is
+// DefaultMaxBodySize. A size of 0 or less records only each body's length.
+func WithMaxBodySize(n int) Option {
+	return func(cfg *config) {
+		cfg.maxBodySize = max(n, 0)
+	}
+}
+
+// WithRedactedHeaders records the named headers as "[REDACTED]", in addition
+// to Authorization, cookies and headers whose names mention a token, key,
+// secret, password or session.
+func WithRedactedHeaders(names ...string) Option {
+	return func(cfg *config) {
+		if cfg.redact == nil {
+			cfg.redact = make(map[string]bool)
+		}
+		for _, name := range names {
+			cfg.redact[http.CanonicalHeaderKey(name)] = true
+		}
+	}
+}
+
+// WithCaptureContent is [core.WithCaptureContent]. Pass false to record only
+// the run's name, URL template, status and timing.
+func WithCaptureContent(capture bool) Option { return coreOption(core.WithCaptureContent(capture)) }
+
+// WithRedactor is [core.WithRedactor]; it rewrites the recorded inputs and
+// outputs.
+func WithRedactor(r core.Redactor) Option { return coreOption(core.WithRedactor(r)) }
+
+// coreOption applies a core option to the client's core configuration.
+func coreOption(opt core.Option) Option {
+	return func(cfg *config) {
+		opt(&cfg.core)
+	}
+}
+
+// Client returns a new http.Client that traces requests as tool runs.
+func Client(opts ...Option) *http.Client {
+	return WrapClient(nil, opts...)
+}
+
+// WrapClient wraps an existing http.Client so its requests are traced as
+// tool runs. If client is nil, a new client with the default transport is
+// created.
+func WrapClient(client *http.Client, opts ...Option) *http.Client {
+	if client == nil {
+		client = &http.Client{}
+	}
+	client.Transport = NewRoundTripper(client.Transport, opts...)
+	return client
+}
+
+// NewRoundTripper returns a RoundTripper that traces requests as tool runs
+// and sends them with base (http.DefaultTransport when nil).
+func NewRoundTripper(base http.RoundTripper, opts ...Option) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &roundTripper{base: base, cfg: newConfig(opts)}
+}
+
+func newConfig(opts []Option) *config {
+	cfg := &config{maxBodySize: DefaultMaxBodySize}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	if len(cfg.routes) > 0 {
+		cfg.mux = http.NewServeMux()
+		for _, r := range cfg.routes {
+			cfg.mux.Handle(r.pattern, r)
+		}
+	}
+	return cfg
+}
+
+// route is a WithRoute registration. It is the ServeMux handler for its
+// pattern, so serving a request through the mux reports which route matched
+// and the values of its wildcards.
+type route struct {
+	pattern   string
+	name      string
+	template  string
+	wildcards []string
+}
+
+// wildcardPattern matches the {name} and {name...} segments of a ServeMux
+// pattern.
+var wildcardPattern = regexp.MustCompile(`\{([^}.$]+)(\.\.\.)?\}`)
+
+func newRoute(pattern, name string) *route {
+	r := &route{pattern: pattern, name: name}
+	path := pattern
+	if i := strings.IndexAny(path, " \t"); i >= 0 {
+		path = strings.TrimSpace(path[i:])
+	}
+	if i := strings.IndexByte(path, '/'); i >= 0 {
+		path = path[i:]
+	}
+	r.template = strings.TrimSuffix(path, "{$}")
+	for _, m := range wildcardPattern.FindAllStringSubmatch(path, -1) {
+		r.wildcards = append(r.wildcards, m[1])
+	}
+	return r
+}
+
+type ctxKeyMatch struct{}
+
+// routeMatch is filled in by the route that serves a request.
+type routeMatch struct {
+	route  *route
+	params map[string]string
+}
+
+func (r *route) ServeHTTP(_ http.ResponseWriter, req *http.Request) {
+	m, _ := req.Context().Value(ctxKeyMatch{}).(*routeMatch)
+	if m == nil {
+		return
+	}
+	m.route = r
+	for _, name := range r.wildcards {
+		if m.params == nil {
+			m.params = make(map[string]string)
+		}
+		m.params[name] = req.PathValue(name)
+	}
+}
+
+// match returns the route req matches, or nil when routes are configured
+// and none matches. ok is false only in the latter case.
+func (c *config) match(req *http.Request) (m *routeMatch, ok bool) {
+	m = &routeMatch{}
+	if c.mux == nil {
+		return m, true
+	}
+	// ServeMux matches server-side requests, which carry the host in Host.
+	r := req.WithContext(context.WithValue(req.Context(), ctxKeyMatch{}, m))
+	if r.Host == "" {
+		r.Host = req.URL.Host
+	}
+	c.mux.ServeHTTP(discardResponse{}, r)
+	return m, m.route != nil
+}
+
+// discardResponse is the ResponseWriter for route matching; redirects and
+// 404s the mux writes for unmatched requests are dropped.
+type discardResponse struct{}
+
+func (discardResponse) Header() http.Header         { return http.Header{} }
+func (discardResponse) Write(p []byte) (int, error) { return len(p), nil }
+func (discardResponse) WriteHeader(int)             {}
+
+// headers returns h with sensitive values redacted and the tracing's own
+// propagation headers dropped.
+func (c *config) headers(h http.Header) map[string]string {
+	out := make(map[string]string, len(h))
+	for name, values := range h {
+		name = http.CanonicalHeaderKey(name)
+		if propagationHeaders[name] {
+			continue
+		}
+		if c.sensitive(name) {
+			out[name] = "[REDACTED]"
+			continue
+		}
+		out[name] = strings.Join(values, ", ")
+	}
+	return out
+}
+
+func (c *config) sensitive(name string) bool {
+	if sensitiveHeaders[name] || c.redact[name] {
+		return true
+	}
+	lower := strings.ToLower(name)
+	for _, word := range sensitiveHeaderWords {
+		if strings.Contains(lower, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// body returns the value recorded for a request or response body of size
+// bytes, of which b holds the first maxBodySize+1: parsed JSON, text, or a
+// placeholder for binary content. Bodies longer than the size cap are
+// recorded as truncated text.
+func (c *config) body(b []byte, size int) any {
+	if size == 0 {
+		return nil
+	}
+	if size <= c.maxBodySize {
+		if json.Valid(b) {
+			return json.RawMessage(b)
+		}
+		if utf8.Valid(b) {
+			return string(b)
+		}
+		return fmt.Sprintf("[binary, %d bytes]", size)
+	}
+	cut := c.maxBodySize
+	for cut > 0 && !utf8.RuneStart(b[cut]) {
+		cut--
+	}
+	if !utf8.Valid(b[:cut]) {
+		return fmt.Sprintf("[binary, %d bytes]", size)
+	}
+	return fmt.Sprintf("%s…[truncated %d bytes]", b[:cut], size-cut)
+}
+
+type roundTripper struct {
+	base http.RoundTripper
+	cfg  *config
+}
+
+// RoundTrip records a matched request as a tool run. The request and
+// response bodies stream through; at most maxBodySize bytes of each are kept
+// for the run, which ends when the response body reaches EOF, is closed, or
+// a read fails.
+func (rt *roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req == nil || req.URL == nil {
+		return rt.base.RoundTrip(req)
+	}
+	m, ok := rt.cfg.match(req)
+	if !ok {
+		return rt.base.RoundTrip(req)
+	}
+	tp := rt.cfg.core.TracerProvider
+	if tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+
+	ctx := otel.GetTextMapPropagator().Extract(req.Context(), propagation.HeaderCarrier(req.Header))
+	name, attrs := rt.cfg.describe(req, m)
+	attrs = append(attrs, genaiattr.HTTPURLKey.String(core.RedactURL(req.URL)))
+	attrs = append(attrs, thread.Attributes(ctx)...)
+	ctx, span := tp.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+
+	inputs := map[string]any{
+		"method": req.Method,
+		"url":    core.RedactURL(req.URL),
+	}
+	if len(m.params) > 0 {
+		inputs["path_params"] = m.params
+	}
+	if h := rt.cfg.headers(req.Header); len(h) > 0 {
+		inputs["headers"] = h
+	}
+
+	// Headers are copied by Clone, so the propagation headers don't leak
+	// into the caller's request.
+	req = req.Clone(ctx)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+	var reqBody *capturedBody
+	if req.Body != nil && req.Body != http.NoBody {
+		reqBody = &capturedBody{ReadCloser: req.Body, max: rt.cfg.maxBodySize}
+		req.Body = reqBody
+	}
+
+	resp, err := rt.base.RoundTrip(req)
+	if reqBody != nil {
+		if b := rt.cfg.body(reqBody.captured()); b != nil {
+			inputs["body"] = b
+		}
+	}
+	rt.cfg.record(span, genaiattr.PromptKey, inputs)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		span.End()
+		return resp, err
+	}
+
+	span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+	outputs := map[string]any{"status": resp.StatusCode}
+	if h := rt.cfg.headers(resp.Header); len(h) > 0 {
+		outputs["headers"] = h
+	}
+	respBody := &capturedBody{ReadCloser: resp.Body, max: rt.cfg.maxBodySize}
+	respBody.done = func(readErr error) {
+		if b := rt.cfg.body(respBody.captured()); b != nil {
+			outputs["body"] = b
+		}
+		rt.cfg.record(span, genaiattr.CompletionKey, outputs)
+		switch {
+		case readErr != nil && readErr != io.EOF:
+			span.RecordError(readErr)
+			span.SetStatus(codes.Error, readErr.Error())
+		case resp.StatusCode >= 400:
+			span.SetStatus(codes.Error, fmt.Sprintf("HTTP %d", resp.StatusCode))
+		default:
+			span.SetStatus(codes.Ok, "")
+		}
+		span.End()
+	}
+	resp.Body = respBody
+	return resp, nil
+}
+
+// describe returns the run name and attributes for a request matching m.
+func (c *config) describe(req *http.Request, m *routeMatch) (string, []attribute.KeyValue) {
+	host := req.URL.Host
+	if host == "" {
+		host = req.Host
+	}
+
+	name := req.Method + " " + host
+	var template string
+	if m.route != nil {
+		template = m.route.template
+		name = m.route.name
+		if name == "" {
+			name = "{method} {route}"
+		}
+		// A single pass, so wildcard values can't inject placeholders.
+		pairs := []string{"{method}", req.Method, "{host}", host, "{route}", template}
+		for k, v := range m.params {
+			pairs = append(pairs, "{"+k+"}", v)
+		}
+		name = strings.NewReplacer(pairs...).Replace(name)
+	}
+
+	attrs := []attribute.KeyValue{
+		genaiattr.SpanKindKey.String("tool"),
+		semconv.GenAIOperationNameExecuteTool,
+		semconv.GenAIToolName(name),
+		semconv.GenAIToolType("extension"),
+		semconv.HTTPRequestMethodKey.String(req.Method),
+	}
+	if template != "" {
+		attrs = append(attrs, semconv.URLTemplate(template))
+	}
+	return name, attrs
+}
+
+// record sets the run's inputs or outputs under key, subject to the
+// content capture options.
+func (c *config) record(span trace.Span, key attribute.Key, v map[string]any) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	if content := c.core.Content(key, string(b)); content != "" {
+		span.SetAttributes(key.String(content))
+	}
+}
+
+// capturedBody passes a request or response body through, keeping its first
+// max+1 bytes and counting the rest.
+type capturedBody struct {
+	io.ReadCloser
+	max int
+
+	mu   sync.Mutex
+	buf  []byte
+	size int
+
+	// done, when set, is called once: at EOF, on a read error, or when the
+	// body is closed.
+	done func(readErr error)
+	once sync.Once
+}
+
+func (b *capturedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.mu.Lock()
+	if keep := min(n, b.max+1-len(b.buf)); keep > 0 {
+		b.buf = append(b.buf, p[:keep]...)
+	}
+	b.size += n
+	b.mu.Unlock()
+	if err != nil {
+		b.finish(err)
+	}
+	return n, err
+}
+
+func (b *capturedBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.finish(nil)
+	return err
+}
+
+func (b *capturedBody) finish(readErr error) {
+	if b.done != nil {
+		b.once.Do(func() { b.done(readErr) })
+	}
+}
+
+// captured returns the kept bytes and the size read so far.
+func (b *capturedBody) captured() ([]byte, int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf, b.size
+}
@@ -0,0 +1,353 @@
+package tracehttp
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func newTracedClient(t *testing.T, handler http.HandlerFunc, opts ...Option) (*http.Client, *httptest.Server, *tracetest.InMemoryExporter, *sdktrace.TracerProvider) {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	client := WrapClient(srv.Client(), append([]Option{WithTracerProvider(tp)}, opts...)...)
+	return client, srv, exporter, tp
+}
+
+func do(t *testing.T, client *http.Client, ctx context.Context, method, url, body string, header http.Header) {
+	t.Helper()
+	var r io.Reader
+	if body != "" {
+		r = strings.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.ReadAll(resp.Body)
+	resp.Body.Close()
+}
+
+func attr(s tracetest.SpanStub, key string) string {
+	for _, kv := range s.Attributes {
+		if string(kv.Key) == key {
+			return kv.Value.Emit()
+		}
+	}
+	return ""
+}
+
+func jsonAttr(t *testing.T, s tracetest.SpanStub, key string) map[string]any {
+	t.Helper()
+	var m map[string]any
+	if err := json.Unmarshal([]byte(attr(s, key)), &m); err != nil {
+		t.Fatalf("%s = %q: %v", key, attr(s, key), err)
+	}
+	return m
+}
+
+func TestRoute_RecordsToolRun(t *testing.T) {
+	client, srv, exporter, tp := newTracedClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Request-Id", "req-1")
+		w.Write([]byte(`{"sku":"A-42","stock":3}`))
+	}, WithRoute("PUT /items/{sku}", "update_item {sku}"))
+
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "agent")
+	do(t, client, ctx, http.MethodPut, srv.URL+"/items/A-42?api_key=secret", `{"stock":3}`, http.Header{
+		"Authorization":   {"Bearer sk-123"},
+		"X-Service-Token": {"abc"},
+		"Content-Type":    {"application/json"},
+	})
+	parent.End()
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(spans))
+	}
+	run := spans[0]
+	if run.Name != "update_item A-42" {
+		t.Errorf("run name = %q", run.Name)
+	}
+	if run.Parent.SpanID() != parent.SpanContext().SpanID() {
+		t.Error("tool run is not parented under the run in the request context")
+	}
+	if run.Status.Code != codes.Ok {
+		t.Errorf("status = %v, want Ok", run.Status)
+	}
+	for key, want := range map[string]string{
+		"langsmith.span.kind":       "tool",
+		"url.template":              "/items/{sku}",
+		"http.request.method":       "PUT",
+		"http.response.status_code": "200",
+		"gen_ai.tool.name":          "update_item A-42",
+	} {
+		if got := attr(run, key); got != want {
+			t.Errorf("%s = %q, want %q", key, got, want)
+		}
+	}
+
+	inputs := jsonAttr(t, run, "gen_ai.prompt")
+	if url, _ := inputs["url"].(string); strings.Contains(url, "secret") {
+		t.Errorf("url %q not redacted", url)
+	}
+	if params, _ := inputs["path_params"].(map[string]any); params["sku"] != "A-42" {
+		t.Errorf("path_params = %v", inputs["path_params"])
+	}
+	headers, _ := inputs["headers"].(map[string]any)
+	if headers["Authorization"] != "[REDACTED]" || headers["X-Service-Token"] != "[REDACTED]" || headers["Content-Type"] != "application/json" {
+		t.Errorf("request headers = %v", headers)
+	}
+	if _, ok := headers["Traceparent"]; ok {
+		t.Error("traceparent recorded as a request header")
+	}
+	if body, _ := inputs["body"].(map[string]any); body["stock"] != float64(3) {
+		t.Errorf("request body = %v", inputs["body"])
+	}
+
+	outputs := jsonAttr(t, run, "gen_ai.completion")
+	if outputs["status"] != float64(200) {
+		t.Errorf("status output = %v", outputs["status"])
+	}
+	if h, _ := outputs["headers"].(map[string]any); h["X-Request-Id"] != "req-1" {
+		t.Errorf("response headers = %v", outputs["headers"])
+	}
+	if body, _ := outputs["body"].(map[string]any); body["sku"] != "A-42" {
+		t.Errorf("response body = %v", outputs["body"])
+	}
+}
+
+func TestRoute_UnmatchedPassesThrough(t *testing.T) {
+	client, srv, exporter, _ := newTracedClient(t, func(w http.ResponseWriter, r *http.Request) {},
+		WithRoute("GET /items/{sku}", ""))
+	do(t, client, context.Background(), http.MethodPost, srv.URL+"/items/A-42", "", nil)
+	do(t, client, context.Background(), http.MethodGet, srv.URL+"/orders/1", "", nil)
+	if n := len(exporter.GetSpans()); n != 0 {
+		t.Errorf("got %d spans for unmatched requests, want 0", n)
+	}
+	do(t, client, context.Background(), http.MethodGet, srv.URL+"/items/A-42", "", nil)
+	spans := exporter.GetSpans()
+	if len(spans) != 1 || spans[0].Name != "GET /items/{sku}" {
+		t.Fatalf("spans = %v, want one run named by the default template", spans)
+	}
+}
+
+func TestNoRoutes_TracesEveryRequest(t *testing.T) {
+	client, srv, exporter, _ := newTracedClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("plain text"))
+	})
+	do(t, client, context.Background(), http.MethodGet, srv.URL+"/anything", "", nil)
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	host := strings.TrimPrefix(srv.URL, "http://")
+	if spans[0].Name != "GET "+host {
+		t.Errorf("run name = %q, want GET %s", spans[0].Name, host)
+	}
+	if attr(spans[0], "url.template") != "" {
+		t.Error("url.template recorded without a route")
+	}
+	if outputs := jsonAttr(t, spans[0], "gen_ai.completion"); outputs["body"] != "plain text" {
+		t.Errorf("body = %v, want the text body", outputs["body"])
+	}
+}
+
+func TestHTTPErrorFailsRun(t *testing.T) {
+	client, srv, exporter, _ := newTracedClient(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":"not found"}`, http.StatusNotFound)
+	})
+	do(t, client, context.Background(), http.MethodGet, srv.URL+"/missing", "", nil)
+	spans := exporter.GetSpans()
+	if len(spans) != 1 || spans[0].Status.Code != codes.Error {
+		t.Fatalf("spans = %v, want one failed run", spans)
+	}
+	if outputs := jsonAttr(t, spans[0], "gen_ai.completion"); outputs["status"] != float64(404) {
+		t.Errorf("status output = %v, want 404", outputs["status"])
+	}
+}
+
+func TestBody(t *testing.T) {
+	cfg := &config{maxBodySize: 8}
+	tests := []struct {
+		body string
+		want any
+	}{
+		{``, nil},
+		{`{"a":1}`, json.RawMessage(`{"a":1}`)},
+		{`{"a":"long"}`, `{"a":"lo…[truncated 4 bytes]`},
+		{"short", "short"},
+		{"\xff\xfe\x00", "[binary, 3 bytes]"},
+	}
+	for _, tt := range tests {
+		got := cfg.body([]byte(tt.body), len(tt.body))
+		if raw, ok := got.(json.RawMessage); ok {
+			got = raw
+		}
+		if gb, _ := json.Marshal(got); string(gb) != mustJSON(tt.want) {
+			t.Errorf("body(%q) = %s, want %s", tt.body, gb, mustJSON(tt.want))
+		}
+	}
+}
+
+func mustJSON(v any) string {
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+func TestRedactedHeaders(t *testing.T) {
+	cfg := newConfig([]Option{WithRedactedHeaders("x-tenant")})
+	h := cfg.headers(http.Header{
+		"X-Tenant":    {"acme"},
+		"X-Api-Key":   {"k"},
+		"Cookie":      {"a=b"},
+		"Accept":      {"application/json", "text/plain"},
+		"Traceparent": {"00-abc"},
+	})
+	want := map[string]string{
+		"X-Tenant":  "[REDACTED]",
+		"X-Api-Key": "[REDACTED]",
+		"Cookie":    "[REDACTED]",
+		"Accept":    "application/json, text/plain",
+	}
+	if len(h) != len(want) {
+		t.Errorf("headers = %v, want %v", h, want)
+	}
+	for k, v := range want {
+		if h[k] != v {
+			t.Errorf("%s = %q, want %q", k, h[k], v)
+		}
+	}
+}
+
+func TestWithRoute_InvalidPatternPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic for an invalid pattern")
+		}
+	}()
+	Client(WithRoute("GET /items/{", ""))
+}
+
+func TestLargeBodiesStreamThrough(t *testing.T) {
+	big := strings.Repeat("x", 100)
+	var received string
+	client, srv, exporter, _ := newTracedClient(t, func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		received = string(b)
+		w.Write([]byte(big + big))
+	}, WithMaxBodySize(10))
+
+	req, err := http.NewRequest(http.MethodPost, srv.URL+"/upload", strings.NewReader(big))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if received != big || string(body) != big+big {
+		t.Fatalf("bodies altered in transit: sent %d bytes, received %d; response %d bytes", len(big), len(received), len(body))
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	if in := jsonAttr(t, spans[0], "gen_ai.prompt"); in["body"] != "xxxxxxxxxx…[truncated 90 bytes]" {
+		t.Errorf("request body = %v", in["body"])
+	}
+	if out := jsonAttr(t, spans[0], "gen_ai.completion"); out["body"] != "xxxxxxxxxx…[truncated 190 bytes]" {
+		t.Errorf("response body = %v", out["body"])
+	}
+}
+
+func TestNegativeMaxBodySize(t *testing.T) {
+	client, srv, exporter, _ := newTracedClient(t, func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		w.Write([]byte("hello"))
+	}, WithMaxBodySize(-1))
+
+	req, err := http.NewRequest(http.MethodPost, srv.URL+"/upload", strings.NewReader("abc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "hello" {
+		t.Fatalf("response body = %q", body)
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	if in := jsonAttr(t, spans[0], "gen_ai.prompt"); in["body"] != "…[truncated 3 bytes]" {
+		t.Errorf("request body = %v", in["body"])
+	}
+	if out := jsonAttr(t, spans[0], "gen_ai.completion"); out["body"] != "…[truncated 5 bytes]" {
+		t.Errorf("response body = %v", out["body"])
+	}
+}
+
+func TestRateLimitEndsRun(t *testing.T) {
+	client, srv, exporter, _ := newTracedClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "30")
+		http.Error(w, `{"error":"slow down"}`, http.StatusTooManyRequests)
+	})
+	do(t, client, context.Background(), http.MethodGet, srv.URL+"/limited", "", nil)
+	do(t, client, context.Background(), http.MethodGet, srv.URL+"/limited", "", nil)
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want a run per request", len(spans))
+	}
+	for _, s := range spans {
+		if s.Status.Code != codes.Error {
+			t.Errorf("status = %v, want Error", s.Status)
+		}
+	}
+}
+
+func TestBodyRecordedVerbatim(t *testing.T) {
+	// A tool payload shaped like an LLM content part is not treated as one.
+	const payload = `{"messages":[{"content":[{"type":"image_url","image_url":{"url":"data:image/png;base64,iVBORw0KGgo="}}]}]}`
+	client, srv, exporter, _ := newTracedClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	})
+	do(t, client, context.Background(), http.MethodPost, srv.URL+"/render", payload, nil)
+	if prompt := attr(onlySpan(t, exporter), "gen_ai.prompt"); !strings.Contains(prompt, `"body":`+payload) {
+		t.Errorf("gen_ai.prompt = %s, want the body %s", prompt, payload)
+	}
+}
+
+func onlySpan(t *testing.T, exporter *tracetest.InMemoryExporter) tracetest.SpanStub {
+	t.Helper()
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	return spans[0]
+}
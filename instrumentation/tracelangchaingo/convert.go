@@ -0,0 +1,197 @@
+package tracelangchaingo
+
+import (
+	"encoding/base64"
+	"strings"
+
+	"github.com/tmc/langchaingo/llms"
+	"github.com/tmc/langchaingo/schema"
+
+	"github.com/langchain-ai/langsmith-go/instrumentation"
+	"github.com/langchain-ai/langsmith-go/instrumentation/core"
+)
+
+// roles maps LangChainGo message types to OpenAI chat roles.
+var roles = map[llms.ChatMessageType]string{
+	llms.ChatMessageTypeAI:       "assistant",
+	llms.ChatMessageTypeHuman:    "user",
+	llms.ChatMessageTypeSystem:   "system",
+	llms.ChatMessageTypeGeneric:  "user",
+	llms.ChatMessageTypeFunction: "function",
+	llms.ChatMessageTypeTool:     "tool",
+}
+
+// convertMessages converts LangChainGo messages to OpenAI chat messages,
+// which LangSmith renders as a conversation. Each tool response becomes its
+// own tool message.
+func convertMessages(ms []llms.MessageContent) []map[string]any {
+	out := make([]map[string]any, 0, len(ms))
+	for _, m := range ms {
+		role := roles[m.Role]
+		if role == "" {
+			role = string(m.Role)
+		}
+		msg := map[string]any{"role": role}
+		var content []map[string]any
+		var toolCalls []map[string]any
+		for _, p := range m.Parts {
+			switch p := p.(type) {
+			case llms.TextContent:
+				content = append(content, map[string]any{"type": "text", "text": p.Text})
+			case llms.ImageURLContent:
+				image := map[string]any{"url": p.URL}
+				if p.Detail != "" {
+					image["detail"] = p.Detail
+				}
+				content = append(content, map[string]any{"type": "image_url", "image_url": image})
+			case llms.BinaryContent:
+				if strings.HasPrefix(p.MIMEType, "image/") {
+					url := "data:" + p.MIMEType + ";base64," + base64.StdEncoding.EncodeToString(p.Data)
+					content = append(content, map[string]any{"type": "image_url", "image_url": map[string]any{"url": url}})
+				} else {
+					content = append(content, map[string]any{"type": "file", "mime_type": p.MIMEType, "size": len(p.Data)})
+				}
+			case llms.ToolCall:
+				toolCalls = append(toolCalls, convertToolCall(p))
+			case llms.ToolCallResponse:
+				out = append(out, map[string]any{"role": "tool", "tool_call_id": p.ToolCallID, "name": p.Name, "content": p.Content})
+			}
+		}
+		if len(content) == 0 && len(toolCalls) == 0 {
+			continue
+		}
+		if len(content) == 1 && content[0]["type"] == "text" {
+			msg["content"] = content[0]["text"]
+		} else if len(content) > 0 {
+			msg["content"] = content
+		}
+		if len(toolCalls) > 0 {
+			msg["tool_calls"] = toolCalls
+		}
+		out = append(out, msg)
+	}
+	return out
+}
+
+func convertToolCall(tc llms.ToolCall) map[string]any {
+	call := map[string]any{"id": tc.ID, "type": tc.Type}
+	if tc.Type == "" {
+		call["type"] = "function"
+	}
+	if tc.FunctionCall != nil {
+		call["function"] = map[string]any{"name": tc.FunctionCall.Name, "arguments": tc.FunctionCall.Arguments}
+	}
+	return call
+}
+
+// convertResponse converts a GenerateContent response to OpenAI's chat
+// completion shape, with the token usage as usage_metadata.
+func convertResponse(res *llms.ContentResponse) map[string]any {
+	if res == nil {
+		return map[string]any{}
+	}
+	choices := make([]map[string]any, len(res.Choices))
+	var usage map[string]any
+	for i, c := range res.Choices {
+		msg := map[string]any{"role": "assistant", "content": c.Content}
+		if c.ReasoningContent != "" {
+			msg["reasoning_content"] = c.ReasoningContent
+		}
+		var toolCalls []map[string]any
+		for _, tc := range c.ToolCalls {
+			toolCalls = append(toolCalls, convertToolCall(tc))
+		}
+		if len(toolCalls) == 0 && c.FuncCall != nil {
+			toolCalls = append(toolCalls, convertToolCall(llms.ToolCall{FunctionCall: c.FuncCall}))
+		}
+		if len(toolCalls) > 0 {
+			msg["tool_calls"] = toolCalls
+		}
+		choice := map[string]any{"index": i, "message": msg}
+		if c.StopReason != "" {
+			choice["finish_reason"] = c.StopReason
+		}
+		choices[i] = choice
+		// Providers copy the call's usage into every choice.
+		if usage == nil {
+			usage = usageMetadata(c.GenerationInfo)
+		}
+	}
+	out := map[string]any{"choices": choices}
+	if usage != nil {
+		out["usage_metadata"] = usage
+	}
+	return out
+}
+
+// Token counts under the GenerationInfo keys the LangChainGo providers use.
+var (
+	inputTokenKeys     = []string{"PromptTokens", "InputTokens", "input_tokens"}
+	outputTokenKeys    = []string{"CompletionTokens", "OutputTokens", "output_tokens"}
+	cacheReadKeys      = []string{"PromptCachedTokens", "CacheReadInputTokens", "CachedTokens"}
+	cacheCreationKeys  = []string{"CacheCreationInputTokens"}
+	reasoningTokenKeys = []string{"ReasoningTokens", "CompletionReasoningTokens", "ThinkingTokens"}
+)
+
+// usageMetadata reads the token usage a provider reports in GenerationInfo,
+// or returns nil if it reports none.
+func usageMetadata(info map[string]any) map[string]any {
+	input, output := tokens(info, inputTokenKeys), tokens(info, outputTokenKeys)
+	if input == 0 && output == 0 {
+		return nil
+	}
+	total := tokens(info, []string{"TotalTokens"})
+	if total == 0 {
+		total = input + output
+	}
+	inputDetails := map[string]any{}
+	if n := tokens(info, cacheReadKeys); n > 0 {
+		inputDetails["cache_read"] = n
+	}
+	if n := tokens(info, cacheCreationKeys); n > 0 {
+		inputDetails["cache_creation"] = n
+	}
+	outputDetails := map[string]any{}
+	if n := tokens(info, reasoningTokenKeys); n > 0 {
+		outputDetails["reasoning"] = n
+	}
+	return core.UsageMetadata(input, output, total, inputDetails, outputDetails)
+}
+
+// tokens returns the first positive count among keys.
+func tokens(info map[string]any, keys []string) int64 {
+	for _, k := range keys {
+		var n int64
+		switch v := info[k].(type) {
+		case int:
+			n = int64(v)
+		case int32:
+			n = int64(v)
+		case int64:
+			n = v
+		case float64:
+			n = int64(v)
+		}
+		if n > 0 {
+			return n
+		}
+	}
+	return 0
+}
+
+// convertDocuments converts retrieved documents to LangSmith's document
+// schema, adding each document's score to its metadata.
+func convertDocuments(docs []schema.Document) []instrumentation.Document {
+	out := make([]instrumentation.Document, len(docs))
+	for i, d := range docs {
+		metadata := make(map[string]any, len(d.Metadata)+1)
+		for k, v := range d.Metadata {
+			metadata[k] = v
+		}
+		if d.Score != 0 {
+			metadata["score"] = d.Score
+		}
+		out[i] = instrumentation.Document{PageContent: d.PageContent, Metadata: metadata}
+	}
+	return out
+}
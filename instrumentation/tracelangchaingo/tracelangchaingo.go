@@ -0,0 +1,457 @@
+// Package tracelangchaingo records LangChainGo (github.com/tmc/langchaingo)
+// chains, agents, models, tools and retrievers as LangSmith runs.
+//
+// Handler implements LangChainGo's callbacks.Handler and writes a run tree
+// through a TracingClient (or a langsmith.Client): chain runs for chains and
+// agent executors, llm runs for model calls with their messages, tool calls
+// and token usage, tool runs named after the agent action that invoked them,
+// and retriever runs whose documents LangSmith renders as a document list.
+//
+// The package is a module of its own, so only programs that use it depend
+// on LangChainGo:
+//
+//	go get github.com/langchain-ai/langsmith-go/instrumentation/tracelangchaingo
+//
+// Usage:
+//
+//	tc, _ := langsmith.NewTracingClient(ctx, langsmith.WithTracingProject("my-app"))
+//	defer tc.Close()
+//	handler := tracelangchaingo.NewHandler(tc)
+//
+//	llm, _ := openai.New(openai.WithCallback(handler))
+//	executor := agents.NewExecutor(agent, agents.WithCallbacksHandler(handler))
+//	answer, err := chains.Run(ctx, executor, "What's 3^7?")
+//
+// LangChainGo callbacks carry no run IDs, but they carry the context of the
+// invocation that made them. A Handler nests the runs started with the same
+// context by the order in which they start and end, and traces invocations
+// made with different contexts separately, so one Handler can serve
+// concurrent requests that each pass their own context. Runs still open
+// when their context is canceled or times out, e.g. because a component
+// panicked or returned without its end callback, are ended as failed with
+// the context's error. Use a context that is canceled when the invocation
+// returns so such runs don't stay open for the Handler's lifetime.
+package tracelangchaingo
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tmc/langchaingo/callbacks"
+	"github.com/tmc/langchaingo/llms"
+	"github.com/tmc/langchaingo/schema"
+	"go.opentelemetry.io/otel/baggage"
+
+	"github.com/langchain-ai/langsmith-go/lib/langsmithtracing"
+)
+
+// RunWriter sends runs to LangSmith. It is implemented by
+// *langsmith.TracingClient and *langsmith.Client.
+type RunWriter interface {
+	CreateRun(*langsmithtracing.RunCreate) error
+	UpdateRun(*langsmithtracing.RunUpdate) error
+}
+
+// Option configures a Handler.
+type Option func(*config)
+
+type config struct {
+	runName     string
+	projectName string
+	tags        []string
+	metadata    map[string]any
+	hideInputs  bool
+	hideOutputs bool
+}
+
+// WithRunName names the root run of each trace, in place of the default
+// "Chain" (or "LLM", "Tool", "Retriever" for a component invoked directly).
+func WithRunName(name string) Option {
+	return func(cfg *config) {
+		cfg.runName = name
+	}
+}
+
+// WithProjectName sends the runs to the named project instead of the
+// client's.
+func WithProjectName(name string) Option {
+	return func(cfg *config) {
+		cfg.projectName = name
+	}
+}
+
+// WithTags adds tags to every run.
+func WithTags(tags ...string) Option {
+	return func(cfg *config) {
+		cfg.tags = append(cfg.tags, tags...)
+	}
+}
+
+// WithMetadata adds metadata to every run, e.g. ls_provider and
+// ls_model_name, which LangChainGo callbacks don't report.
+func WithMetadata(metadata map[string]any) Option {
+	return func(cfg *config) {
+		if cfg.metadata == nil {
+			cfg.metadata = make(map[string]any, len(metadata))
+		}
+		maps.Copy(cfg.metadata, metadata)
+	}
+}
+
+// WithHideInputs omits run inputs, as LANGSMITH_HIDE_INPUTS does.
+func WithHideInputs() Option {
+	return func(cfg *config) {
+		cfg.hideInputs = true
+	}
+}
+
+// WithHideOutputs omits run outputs, as LANGSMITH_HIDE_OUTPUTS does.
+func WithHideOutputs() Option {
+	return func(cfg *config) {
+		cfg.hideOutputs = true
+	}
+}
+
+// Handler is a LangChainGo callbacks.Handler that records LangSmith runs.
+// Create one with NewHandler.
+type Handler struct {
+	writer RunWriter
+	cfg    config
+
+	mu          sync.Mutex
+	invocations map[context.Context]*invocation
+}
+
+// invocation holds the open runs started with one context.
+type invocation struct {
+	stack []*run // open runs, innermost last
+	// action is the agent action whose tool run starts next.
+	action *schema.AgentAction
+	// stop unregisters the cleanup that ends the runs when the context is
+	// done.
+	stop func() bool
+}
+
+var _ callbacks.Handler = (*Handler)(nil)
+
+// run is an open run on an invocation's stack.
+type run struct {
+	id, traceID uuid.UUID
+	dottedOrder string
+	runType     string
+	events      []map[string]any
+}
+
+// NewHandler returns a Handler that writes runs to w.
+func NewHandler(w RunWriter, opts ...Option) *Handler {
+	cfg := config{
+		hideInputs:  envTruthy("LANGSMITH_HIDE_INPUTS"),
+		hideOutputs: envTruthy("LANGSMITH_HIDE_OUTPUTS"),
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return &Handler{writer: w, cfg: cfg, invocations: make(map[context.Context]*invocation)}
+}
+
+// HandleChainStart starts a chain run.
+func (h *Handler) HandleChainStart(ctx context.Context, inputs map[string]any) {
+	h.start(ctx, "chain", "Chain", inputs, nil)
+}
+
+// HandleChainEnd ends the innermost chain run with outputs.
+func (h *Handler) HandleChainEnd(ctx context.Context, outputs map[string]any) {
+	h.end(ctx, "chain", outputs, nil)
+}
+
+// HandleChainError ends the innermost chain run as failed.
+func (h *Handler) HandleChainError(ctx context.Context, err error) {
+	h.end(ctx, "chain", nil, err)
+}
+
+// HandleLLMStart starts an llm run for a completion-style call.
+func (h *Handler) HandleLLMStart(ctx context.Context, prompts []string) {
+	h.start(ctx, "llm", "LLM", map[string]any{"prompts": prompts}, nil)
+}
+
+// HandleLLMGenerateContentStart starts an llm run for a GenerateContent
+// call, recording the messages in OpenAI's chat format.
+func (h *Handler) HandleLLMGenerateContentStart(ctx context.Context, ms []llms.MessageContent) {
+	h.start(ctx, "llm", "LLM", map[string]any{"messages": convertMessages(ms)}, nil)
+}
+
+// HandleLLMGenerateContentEnd ends the innermost llm run with the model's
+// choices and token usage.
+func (h *Handler) HandleLLMGenerateContentEnd(ctx context.Context, res *llms.ContentResponse) {
+	h.end(ctx, "llm", convertResponse(res), nil)
+}
+
+// HandleLLMError ends the innermost llm run as failed.
+func (h *Handler) HandleLLMError(ctx context.Context, err error) {
+	h.end(ctx, "llm", nil, err)
+}
+
+// HandleStreamingFunc records a streamed chunk as a new_token event on the
+// innermost run, which LangSmith uses for time-to-first-token.
+func (h *Handler) HandleStreamingFunc(ctx context.Context, chunk []byte) {
+	h.event(ctx, "new_token", map[string]any{"token": string(chunk)})
+}
+
+// HandleText is a no-op; LangChainGo uses it for logging.
+func (h *Handler) HandleText(context.Context, string) {}
+
+// HandleToolStart starts a tool run, named after the agent action that
+// invoked the tool when there is one.
+func (h *Handler) HandleToolStart(ctx context.Context, input string) {
+	var action *schema.AgentAction
+	h.mu.Lock()
+	if inv := h.invocations[ctx]; inv != nil {
+		action, inv.action = inv.action, nil
+	}
+	h.mu.Unlock()
+
+	name := "Tool"
+	var metadata map[string]any
+	if action != nil && action.Tool != "" {
+		name = action.Tool
+		if action.ToolID != "" {
+			metadata = map[string]any{"tool_call_id": action.ToolID}
+		}
+	}
+	h.start(ctx, "tool", name, map[string]any{"input": input}, metadata)
+}
+
+// HandleToolEnd ends the innermost tool run with output.
+func (h *Handler) HandleToolEnd(ctx context.Context, output string) {
+	h.end(ctx, "tool", map[string]any{"output": output}, nil)
+}
+
+// HandleToolError ends the innermost tool run as failed.
+func (h *Handler) HandleToolError(ctx context.Context, err error) {
+	h.end(ctx, "tool", nil, err)
+}
+
+// HandleRetrieverStart starts a retriever run.
+func (h *Handler) HandleRetrieverStart(ctx context.Context, query string) {
+	h.start(ctx, "retriever", "Retriever", map[string]any{"query": query}, nil)
+}
+
+// HandleRetrieverEnd ends the innermost retriever run with documents.
+func (h *Handler) HandleRetrieverEnd(ctx context.Context, _ string, documents []schema.Document) {
+	h.end(ctx, "retriever", map[string]any{"documents": convertDocuments(documents)}, nil)
+}
+
+// HandleAgentAction records the action as an event on the agent's run and
+// names the tool run that follows after it.
+func (h *Handler) HandleAgentAction(ctx context.Context, action schema.AgentAction) {
+	h.mu.Lock()
+	if inv := h.invocations[ctx]; inv != nil {
+		inv.action = &action
+	}
+	h.mu.Unlock()
+	h.event(ctx, "agent_action", map[string]any{"tool": action.Tool, "tool_input": action.ToolInput, "log": action.Log})
+}
+
+// HandleAgentFinish records the agent's final answer as an event on its run.
+func (h *Handler) HandleAgentFinish(ctx context.Context, finish schema.AgentFinish) {
+	h.event(ctx, "agent_finish", map[string]any{"return_values": finish.ReturnValues, "log": finish.Log})
+}
+
+// start opens a run of runType as a child of the innermost run open on ctx,
+// or as the root of a new trace. Runs record the thread set on ctx with
+// langsmith.WithThread in their metadata.
+func (h *Handler) start(ctx context.Context, runType, name string, inputs, metadata map[string]any) {
+	now := time.Now()
+	id := uuid.New()
+	r := &run{id: id, traceID: id, runType: runType, dottedOrder: dottedOrder(now, id)}
+	create := &langsmithtracing.RunCreate{
+		ID:          id,
+		Name:        name,
+		RunType:     runType,
+		Inputs:      h.inputs(inputs),
+		Tags:        h.cfg.tags,
+		StartTime:   now,
+		SessionName: h.cfg.projectName,
+	}
+	if md := h.metadata(ctx, metadata); len(md) > 0 {
+		create.Extra = map[string]any{"metadata": md}
+	}
+
+	h.mu.Lock()
+	inv := h.invocations[ctx]
+	if inv == nil {
+		inv = &invocation{}
+		inv.stop = context.AfterFunc(ctx, func() { h.abandon(ctx, inv) })
+		h.invocations[ctx] = inv
+	}
+	if n := len(inv.stack); n > 0 {
+		parent := inv.stack[n-1]
+		r.traceID = parent.traceID
+		r.dottedOrder = parent.dottedOrder + "." + r.dottedOrder
+		create.ParentRunID = &parent.id
+	} else if h.cfg.runName != "" {
+		create.Name = h.cfg.runName
+	}
+	inv.stack = append(inv.stack, r)
+	h.mu.Unlock()
+
+	create.TraceID = r.traceID
+	create.DottedOrder = r.dottedOrder
+	_ = h.writer.CreateRun(create)
+}
+
+// end closes the innermost run of runType open on ctx. Runs opened after it
+// that never ended (a component that returned without its end callback) are
+// closed with it.
+func (h *Handler) end(ctx context.Context, runType string, outputs map[string]any, err error) {
+	now := time.Now()
+	h.mu.Lock()
+	inv := h.invocations[ctx]
+	if inv == nil {
+		h.mu.Unlock()
+		return
+	}
+	i := len(inv.stack) - 1
+	for i >= 0 && inv.stack[i].runType != runType {
+		i--
+	}
+	if i < 0 {
+		h.mu.Unlock()
+		return
+	}
+	closed := inv.stack[i:]
+	inv.stack = inv.stack[:i:i]
+	if i == 0 {
+		delete(h.invocations, ctx)
+		inv.stop()
+	}
+	h.mu.Unlock()
+	h.close(closed, now, outputs, err)
+}
+
+// abandon ends the runs still open on ctx once it is done, as failed with
+// its error.
+func (h *Handler) abandon(ctx context.Context, inv *invocation) {
+	now := time.Now()
+	h.mu.Lock()
+	if h.invocations[ctx] != inv {
+		h.mu.Unlock()
+		return
+	}
+	delete(h.invocations, ctx)
+	closed := inv.stack
+	inv.stack = nil
+	h.mu.Unlock()
+	for j := len(closed) - 1; j >= 0; j-- {
+		h.close(closed[j:j+1], now, nil, context.Cause(ctx))
+	}
+}
+
+// close ends runs, innermost first, recording outputs and err on the
+// outermost.
+func (h *Handler) close(closed []*run, now time.Time, outputs map[string]any, err error) {
+	for j := len(closed) - 1; j >= 0; j-- {
+		r := closed[j]
+		update := &langsmithtracing.RunUpdate{
+			ID:          r.id,
+			TraceID:     r.traceID,
+			DottedOrder: r.dottedOrder,
+			EndTime:     now,
+			Events:      r.events,
+		}
+		if j == 0 {
+			update.Outputs = h.outputs(outputs)
+			if err != nil {
+				update.Error = err.Error()
+			}
+		}
+		_ = h.writer.UpdateRun(update)
+	}
+}
+
+// event adds an event to the innermost run open on ctx.
+func (h *Handler) event(ctx context.Context, name string, kwargs map[string]any) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	inv := h.invocations[ctx]
+	if inv == nil || len(inv.stack) == 0 {
+		return
+	}
+	r := inv.stack[len(inv.stack)-1]
+	r.events = append(r.events, map[string]any{
+		"name":   name,
+		"time":   time.Now().UTC().Format(time.RFC3339Nano),
+		"kwargs": kwargs,
+	})
+}
+
+func (h *Handler) inputs(inputs map[string]any) map[string]any {
+	if h.cfg.hideInputs {
+		return map[string]any{}
+	}
+	return inputs
+}
+
+func (h *Handler) outputs(outputs map[string]any) map[string]any {
+	if h.cfg.hideOutputs && outputs != nil {
+		return map[string]any{}
+	}
+	return outputs
+}
+
+func (h *Handler) metadata(ctx context.Context, metadata map[string]any) map[string]any {
+	threadMD := threadMetadata(ctx)
+	if len(h.cfg.metadata) == 0 && len(threadMD) == 0 {
+		return metadata
+	}
+	md := make(map[string]any, len(h.cfg.metadata)+len(threadMD)+len(metadata))
+	for k, v := range threadMD {
+		md[k] = v
+	}
+	maps.Copy(md, h.cfg.metadata)
+	maps.Copy(md, metadata)
+	return md
+}
+
+// threadKeys are the baggage members langsmith.WithThread and other
+// LangSmith integrations carry a thread ID in, which are also the run
+// metadata keys LangSmith groups runs into threads by.
+var threadKeys = []string{"session_id", "thread_id", "conversation_id"}
+
+// threadMetadata returns the thread members of ctx's baggage, keyed as run
+// metadata.
+func threadMetadata(ctx context.Context) map[string]string {
+	bag := baggage.FromContext(ctx)
+	var md map[string]string
+	for _, key := range threadKeys {
+		member := bag.Member(key)
+		if member.Key() != key {
+			continue
+		}
+		if md == nil {
+			md = make(map[string]string, len(threadKeys))
+		}
+		md[key] = member.Value()
+	}
+	return md
+}
+
+// dottedOrder formats one segment of a run's dotted order: the run's start
+// time as YYYYMMDDTHHMMSSffffffZ followed by its ID.
+func dottedOrder(t time.Time, id uuid.UUID) string {
+	t = t.UTC()
+	return fmt.Sprintf("%s%06dZ%s", t.Format("20060102T150405"), t.Nanosecond()/1000, id)
+}
+
+// envTruthy reports whether the environment variable is set to "true", "1"
+// or "yes".
+func envTruthy(key string) bool {
+	v := strings.ToLower(strings.TrimSpace(os.Getenv(key)))
+	return v == "true" || v == "1" || v == "yes"
+}
@@ -0,0 +1,374 @@
+package tracelangchaingo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tmc/langchaingo/llms"
+	"github.com/tmc/langchaingo/schema"
+
+	"github.com/langchain-ai/langsmith-go"
+	"github.com/langchain-ai/langsmith-go/lib/langsmithtracing"
+)
+
+type recorder struct {
+	mu      sync.Mutex
+	creates []*langsmithtracing.RunCreate
+	updates map[uuid.UUID]*langsmithtracing.RunUpdate
+}
+
+func (r *recorder) CreateRun(c *langsmithtracing.RunCreate) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.creates = append(r.creates, c)
+	return nil
+}
+
+func (r *recorder) UpdateRun(u *langsmithtracing.RunUpdate) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.updates == nil {
+		r.updates = make(map[uuid.UUID]*langsmithtracing.RunUpdate)
+	}
+	r.updates[u.ID] = u
+	return nil
+}
+
+// run returns the created run named name and its update.
+func (r *recorder) run(t *testing.T, name string) (*langsmithtracing.RunCreate, *langsmithtracing.RunUpdate) {
+	t.Helper()
+	for _, c := range r.creates {
+		if c.Name == name {
+			u := r.updates[c.ID]
+			if u == nil {
+				t.Fatalf("run %q was not ended", name)
+			}
+			return c, u
+		}
+	}
+	t.Fatalf("no run named %q", name)
+	return nil, nil
+}
+
+func checkParent(t *testing.T, child, parent *langsmithtracing.RunCreate) {
+	t.Helper()
+	if child.ParentRunID == nil || *child.ParentRunID != parent.ID {
+		t.Errorf("%s: parent = %v, want %s", child.Name, child.ParentRunID, parent.ID)
+	}
+	if child.TraceID != parent.TraceID {
+		t.Errorf("%s: trace = %s, want %s", child.Name, child.TraceID, parent.TraceID)
+	}
+	if !strings.HasPrefix(child.DottedOrder, parent.DottedOrder+".") ||
+		!strings.HasSuffix(child.DottedOrder, "Z"+child.ID.String()) {
+		t.Errorf("%s: dotted order %q does not extend %q", child.Name, child.DottedOrder, parent.DottedOrder)
+	}
+}
+
+func TestHandler_AgentRunTree(t *testing.T) {
+	rec := &recorder{}
+	h := NewHandler(rec)
+	ctx := context.Background()
+
+	h.HandleChainStart(ctx, map[string]any{"input": "What's 3^7?"})
+	h.HandleChainStart(ctx, map[string]any{"input": "What's 3^7?", "agent_scratchpad": ""})
+	h.HandleLLMGenerateContentStart(ctx, []llms.MessageContent{
+		llms.TextParts(llms.ChatMessageTypeSystem, "Use tools."),
+		llms.TextParts(llms.ChatMessageTypeHuman, "What's 3^7?"),
+	})
+	h.HandleStreamingFunc(ctx, []byte("Action"))
+	h.HandleLLMGenerateContentEnd(ctx, &llms.ContentResponse{Choices: []*llms.ContentChoice{{
+		Content:    "Action: calculator",
+		StopReason: "stop",
+		GenerationInfo: map[string]any{
+			"PromptTokens": 20, "CompletionTokens": 5, "TotalTokens": 25, "PromptCachedTokens": 8,
+		},
+	}}})
+	h.HandleChainEnd(ctx, map[string]any{"text": "Action: calculator"})
+	h.HandleAgentAction(ctx, schema.AgentAction{Tool: "calculator", ToolInput: "3^7", ToolID: "call_1"})
+	h.HandleToolStart(ctx, "3^7")
+	h.HandleToolEnd(ctx, "2187")
+	h.HandleRetrieverStart(ctx, "powers of three")
+	h.HandleRetrieverEnd(ctx, "powers of three", []schema.Document{
+		{PageContent: "3^7 = 2187", Metadata: map[string]any{"source": "math.txt"}, Score: 0.5},
+	})
+	h.HandleAgentFinish(ctx, schema.AgentFinish{ReturnValues: map[string]any{"output": "2187"}})
+	h.HandleChainEnd(ctx, map[string]any{"output": "2187"})
+
+	if len(rec.creates) != 5 || len(rec.updates) != 5 {
+		t.Fatalf("got %d creates and %d updates, want 5 each", len(rec.creates), len(rec.updates))
+	}
+	root, rootEnd := rec.creates[0], rec.updates[rec.creates[0].ID]
+	llmChain := rec.creates[1]
+	if root.ParentRunID != nil || root.TraceID != root.ID || root.RunType != "chain" || root.Name != "Chain" {
+		t.Errorf("root = %+v", root)
+	}
+	if !reflect.DeepEqual(rootEnd.Outputs, map[string]any{"output": "2187"}) {
+		t.Errorf("root outputs = %v", rootEnd.Outputs)
+	}
+	if len(rootEnd.Events) != 2 || rootEnd.Events[0]["name"] != "agent_action" || rootEnd.Events[1]["name"] != "agent_finish" {
+		t.Errorf("root events = %v", rootEnd.Events)
+	}
+	checkParent(t, llmChain, root)
+
+	llm, llmEnd := rec.run(t, "LLM")
+	checkParent(t, llm, llmChain)
+	if llm.RunType != "llm" {
+		t.Errorf("llm run type = %q", llm.RunType)
+	}
+	wantMessages := []map[string]any{
+		{"role": "system", "content": "Use tools."},
+		{"role": "user", "content": "What's 3^7?"},
+	}
+	if !reflect.DeepEqual(llm.Inputs["messages"], wantMessages) {
+		t.Errorf("llm inputs = %v", llm.Inputs)
+	}
+	wantUsage := map[string]any{
+		"input_tokens": int64(20), "output_tokens": int64(5), "total_tokens": int64(25),
+		"input_token_details": map[string]any{"cache_read": int64(8)},
+	}
+	if !reflect.DeepEqual(llmEnd.Outputs["usage_metadata"], wantUsage) {
+		t.Errorf("usage_metadata = %v", llmEnd.Outputs["usage_metadata"])
+	}
+	choices := llmEnd.Outputs["choices"].([]map[string]any)
+	if choices[0]["finish_reason"] != "stop" || choices[0]["message"].(map[string]any)["content"] != "Action: calculator" {
+		t.Errorf("choices = %v", choices)
+	}
+	if len(llmEnd.Events) != 1 || llmEnd.Events[0]["name"] != "new_token" {
+		t.Errorf("llm events = %v", llmEnd.Events)
+	}
+
+	tool, toolEnd := rec.run(t, "calculator")
+	checkParent(t, tool, root)
+	if tool.RunType != "tool" || tool.Inputs["input"] != "3^7" || toolEnd.Outputs["output"] != "2187" {
+		t.Errorf("tool = %+v, outputs %v", tool, toolEnd.Outputs)
+	}
+	if tool.Extra["metadata"].(map[string]any)["tool_call_id"] != "call_1" {
+		t.Errorf("tool extra = %v", tool.Extra)
+	}
+
+	retriever, retrieverEnd := rec.run(t, "Retriever")
+	checkParent(t, retriever, root)
+	if retriever.RunType != "retriever" || retriever.Inputs["query"] != "powers of three" {
+		t.Errorf("retriever = %+v", retriever)
+	}
+	docs := retrieverEnd.Outputs["documents"]
+	if !strings.Contains(strings.ReplaceAll(toJSON(t, docs), " ", ""), `"page_content":"3^7=2187","metadata":{"score":0.5,"source":"math.txt"}`) {
+		t.Errorf("documents = %s", toJSON(t, docs))
+	}
+}
+
+func TestHandler_ErrorsCloseInnerRuns(t *testing.T) {
+	rec := &recorder{}
+	h := NewHandler(rec)
+	ctx := context.Background()
+
+	h.HandleChainStart(ctx, map[string]any{"input": "hi"})
+	h.HandleLLMStart(ctx, []string{"hi"})
+	h.HandleChainError(ctx, errors.New("boom"))
+
+	chain, chainEnd := rec.run(t, "Chain")
+	llm, llmEnd := rec.run(t, "LLM")
+	checkParent(t, llm, chain)
+	if chainEnd.Error != "boom" {
+		t.Errorf("chain error = %q, want boom", chainEnd.Error)
+	}
+	if llmEnd.Error != "" || llmEnd.Outputs != nil {
+		t.Errorf("unfinished llm run = %+v, want ended without outputs", llmEnd)
+	}
+
+	// The next invocation starts a new trace.
+	h.HandleLLMGenerateContentStart(ctx, []llms.MessageContent{llms.TextParts(llms.ChatMessageTypeHuman, "again")})
+	h.HandleLLMError(ctx, errors.New("rate limited"))
+	last := rec.creates[len(rec.creates)-1]
+	if last.ParentRunID != nil || last.TraceID == chain.TraceID {
+		t.Errorf("second invocation joined the first trace: %+v", last)
+	}
+	if got := rec.updates[last.ID].Error; got != "rate limited" {
+		t.Errorf("llm error = %q", got)
+	}
+
+	// Unmatched end callbacks are ignored.
+	h.HandleToolEnd(ctx, "orphan")
+	if len(rec.updates) != 3 {
+		t.Errorf("got %d updates, want 3", len(rec.updates))
+	}
+}
+
+func TestHandler_ConcurrentInvocations(t *testing.T) {
+	rec := &recorder{}
+	h := NewHandler(rec)
+	type key struct{}
+
+	// Two requests interleave their callbacks; each passes its own context.
+	ctxA := context.WithValue(context.Background(), key{}, "a")
+	ctxB := context.WithValue(context.Background(), key{}, "b")
+	h.HandleChainStart(ctxA, map[string]any{"input": "a"})
+	h.HandleChainStart(ctxB, map[string]any{"input": "b"})
+	h.HandleToolStart(ctxA, "a")
+	h.HandleToolStart(ctxB, "b")
+	h.HandleToolEnd(ctxA, "done a")
+	h.HandleChainEnd(ctxA, map[string]any{"output": "a"})
+	h.HandleToolEnd(ctxB, "done b")
+	h.HandleChainEnd(ctxB, map[string]any{"output": "b"})
+
+	if len(rec.creates) != 4 {
+		t.Fatalf("got %d runs, want 4", len(rec.creates))
+	}
+	chainA, chainB, toolA, toolB := rec.creates[0], rec.creates[1], rec.creates[2], rec.creates[3]
+	if chainA.ParentRunID != nil || chainB.ParentRunID != nil || chainA.TraceID == chainB.TraceID {
+		t.Error("concurrent invocations share a trace")
+	}
+	checkParent(t, toolA, chainA)
+	checkParent(t, toolB, chainB)
+	for _, c := range rec.creates {
+		if u := rec.updates[c.ID]; u == nil || u.Outputs == nil {
+			t.Errorf("%s run %v not ended with its outputs", c.Name, c.Inputs)
+		}
+	}
+	if rec.updates[toolB.ID].Outputs["output"] != "done b" {
+		t.Errorf("tool b outputs = %v", rec.updates[toolB.ID].Outputs)
+	}
+	if len(h.invocations) != 0 {
+		t.Errorf("%d invocations still open", len(h.invocations))
+	}
+}
+
+func TestHandler_CanceledInvocationEndsRuns(t *testing.T) {
+	rec := &recorder{}
+	h := NewHandler(rec)
+
+	// The tool panics: neither its end callback nor the chain's is called.
+	ctx, cancel := context.WithCancel(context.Background())
+	h.HandleChainStart(ctx, map[string]any{"input": "hi"})
+	h.HandleToolStart(ctx, "boom")
+	cancel()
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		h.mu.Lock()
+		n := len(h.invocations)
+		h.mu.Unlock()
+		if n == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d invocations still open after their context was canceled", n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	for _, c := range rec.creates {
+		if u := rec.updates[c.ID]; u == nil || u.Error != context.Canceled.Error() {
+			t.Errorf("%s run update = %+v, want it ended as canceled", c.Name, u)
+		}
+	}
+}
+
+func TestHandler_Options(t *testing.T) {
+	rec := &recorder{}
+	h := NewHandler(rec,
+		WithRunName("qa"),
+		WithProjectName("proj"),
+		WithTags("prod"),
+		WithMetadata(map[string]any{"ls_provider": "openai"}),
+		WithHideInputs(),
+		WithHideOutputs(),
+	)
+	ctx := context.Background()
+	h.HandleChainStart(ctx, map[string]any{"input": "secret"})
+	h.HandleRetrieverStart(ctx, "secret")
+	h.HandleRetrieverEnd(ctx, "secret", nil)
+	h.HandleChainEnd(ctx, map[string]any{"output": "secret"})
+
+	root, rootEnd := rec.run(t, "qa")
+	retriever, _ := rec.run(t, "Retriever")
+	for _, c := range []*langsmithtracing.RunCreate{root, retriever} {
+		if len(c.Inputs) != 0 {
+			t.Errorf("%s inputs = %v, want hidden", c.Name, c.Inputs)
+		}
+		if c.SessionName != "proj" || !reflect.DeepEqual(c.Tags, []string{"prod"}) {
+			t.Errorf("%s project/tags = %q %v", c.Name, c.SessionName, c.Tags)
+		}
+		if c.Extra["metadata"].(map[string]any)["ls_provider"] != "openai" {
+			t.Errorf("%s extra = %v", c.Name, c.Extra)
+		}
+	}
+	if len(rootEnd.Outputs) != 0 {
+		t.Errorf("outputs = %v, want hidden", rootEnd.Outputs)
+	}
+}
+
+func TestConvertMessages_ToolCallsAndImages(t *testing.T) {
+	got := convertMessages([]llms.MessageContent{
+		{Role: llms.ChatMessageTypeHuman, Parts: []llms.ContentPart{
+			llms.TextContent{Text: "What's in this image?"},
+			llms.BinaryContent{MIMEType: "image/png", Data: []byte{1, 2}},
+		}},
+		{Role: llms.ChatMessageTypeAI, Parts: []llms.ContentPart{
+			llms.ToolCall{ID: "call_1", FunctionCall: &llms.FunctionCall{Name: "lookup", Arguments: `{"q":"cat"}`}},
+		}},
+		{Role: llms.ChatMessageTypeTool, Parts: []llms.ContentPart{
+			llms.ToolCallResponse{ToolCallID: "call_1", Name: "lookup", Content: "a cat"},
+		}},
+	})
+	want := []map[string]any{
+		{"role": "user", "content": []map[string]any{
+			{"type": "text", "text": "What's in this image?"},
+			{"type": "image_url", "image_url": map[string]any{"url": "data:image/png;base64,AQI="}},
+		}},
+		{"role": "assistant", "tool_calls": []map[string]any{
+			{"id": "call_1", "type": "function", "function": map[string]any{"name": "lookup", "arguments": `{"q":"cat"}`}},
+		}},
+		{"role": "tool", "tool_call_id": "call_1", "name": "lookup", "content": "a cat"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("convertMessages =\n%s\nwant\n%s", toJSON(t, got), toJSON(t, want))
+	}
+}
+
+func TestUsageMetadata_AnthropicKeys(t *testing.T) {
+	got := usageMetadata(map[string]any{
+		"InputTokens": 10, "OutputTokens": 4, "CacheCreationInputTokens": 6, "ThinkingTokens": 0,
+	})
+	want := map[string]any{
+		"input_tokens": int64(10), "output_tokens": int64(4), "total_tokens": int64(14),
+		"input_token_details": map[string]any{"cache_creation": int64(6)},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("usageMetadata = %v, want %v", got, want)
+	}
+	if got := usageMetadata(map[string]any{"ThinkingTokens": 0}); got != nil {
+		t.Errorf("usageMetadata without tokens = %v, want nil", got)
+	}
+}
+
+func toJSON(t *testing.T, v any) string {
+	t.Helper()
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestHandler_ThreadMetadata(t *testing.T) {
+	rec := &recorder{}
+	h := NewHandler(rec, WithMetadata(map[string]any{"env": "prod"}))
+	ctx := langsmith.WithThread(context.Background(), "thread-1")
+	h.HandleChainStart(ctx, map[string]any{"input": "hi"})
+	h.HandleChainEnd(ctx, map[string]any{"output": "hello"})
+
+	chain, _ := rec.run(t, "Chain")
+	want := map[string]any{"thread_id": "thread-1", "env": "prod"}
+	if got := chain.Extra["metadata"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("metadata = %v, want %v", got, want)
+	}
+}
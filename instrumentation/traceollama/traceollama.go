@@ -0,0 +1,461 @@
+// Package traceollama provides OpenTelemetry tracing for Ollama's native
+// REST API (/api/chat, /api/generate, /api/embed) using LangSmith-compatible
+// spans.
+//
+// Ollama streams newline-delimited JSON by default and reports token usage
+// as prompt_eval_count/eval_count on the final chunk; both are mapped onto
+// the same gen_ai attributes as the other LLM instrumentations. For Ollama's
+// OpenAI-compatible /v1 endpoints use traceopenai with
+// traceopenai.WithProviderName("ollama") instead.
+//
+// Usage:
+//
+//	// Configure the Ollama client to use a traced HTTP client
+//	base, _ := url.Parse("http://localhost:11434")
+//	client := api.NewClient(base, traceollama.Client())
+//
+//	// Or use a custom tracer provider:
+//	tp := sdktrace.NewTracerProvider(...)
+//	client := api.NewClient(base, traceollama.Client(traceollama.WithTracerProvider(tp)))
+//
+//	// Your Ollama API calls will now be automatically traced with LangSmith attrs
+//	// err := client.Chat(ctx, &api.ChatRequest{...}, fn)
+package traceollama
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/langchain-ai/langsmith-go/instrumentation/core"
+	"github.com/langchain-ai/langsmith-go/instrumentation/pricing"
+	"github.com/langchain-ai/langsmith-go/internal/traceutil"
+)
+
+// WithRunNameContext sets the span (run) name for the next traced request made with ctx.
+// The run name in LangSmith is the OTLP span name; there is no separate field.
+// Use this so one client can emit runs with different names per call, e.g. in tests:
+//
+//	ctx = traceollama.WithRunNameContext(ctx, "ollama_chat")
+//	client.Chat(ctx, ...)
+func WithRunNameContext(ctx context.Context, name string) context.Context {
+	return core.WithRunNameContext(ctx, name)
+}
+
+// Option configures a traced HTTP client.
+type Option func(*core.Config)
+
+// WithTracerProvider returns an Option that sets the tracer provider.
+// If not provided, the global tracer provider is used.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(cfg *core.Config) {
+		cfg.TracerProvider = tp
+	}
+}
+
+// WithRunName sets the span (run) name to the given string when non-empty.
+func WithRunName(name string) Option {
+	return func(cfg *core.Config) {
+		cfg.RunName = name
+	}
+}
+
+// WithAttachments is [core.WithAttachments].
+func WithAttachments(h core.AttachmentHandler) Option { return Option(core.WithAttachments(h)) }
+
+// WithMaxAttachmentSize is [core.WithMaxAttachmentSize].
+func WithMaxAttachmentSize(n int) Option { return Option(core.WithMaxAttachmentSize(n)) }
+
+// WithCaptureContent is [core.WithCaptureContent].
+func WithCaptureContent(capture bool) Option { return Option(core.WithCaptureContent(capture)) }
+
+// WithRedactor is [core.WithRedactor].
+func WithRedactor(r core.Redactor) Option { return Option(core.WithRedactor(r)) }
+
+// WithMaxContentLength is [core.WithMaxContentLength].
+func WithMaxContentLength(n int) Option { return Option(core.WithMaxContentLength(n)) }
+
+// WithPricing is [core.WithPricing].
+func WithPricing(prices *pricing.Table) Option { return Option(core.WithPricing(prices)) }
+
+// EndPendingRetries is [core.EndPendingRetries]. A run whose last attempt
+// failed stays open until the SDK's retry window passes; call this before
+// shutting down your tracer provider so it is exported. The LangSmith
+// OTelTracer's Shutdown calls it for you.
+func EndPendingRetries() { core.EndPendingRetries() }
+
+// Client returns a new http.Client configured with tracing middleware.
+// Equivalent to WrapClient(nil, opts...), which wraps the default transport.
+func Client(opts ...Option) *http.Client {
+	return WrapClient(nil, opts...)
+}
+
+// WrapClient wraps an existing http.Client with tracing middleware.
+// If client is nil, a new client with the default transport is created.
+func WrapClient(client *http.Client, opts ...Option) *http.Client {
+	var cfg core.Config
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return core.WrapClient(client, provider{}, cfg)
+}
+
+// providerName is the gen_ai.provider.name for Ollama; semconv defines no
+// well-known value for it.
+var providerName = semconv.GenAIProviderNameKey.String("ollama")
+
+// apiKind identifies the Ollama endpoint a request targets.
+type apiKind int
+
+const (
+	apiChat apiKind = iota
+	apiGenerate
+	apiEmbed
+	apiEmbeddings // legacy single-prompt /api/embeddings
+)
+
+// apiForPath returns the endpoint for an Ollama API path, or false when the
+// path is not traced.
+func apiForPath(path string) (apiKind, bool) {
+	switch {
+	case strings.HasSuffix(path, "/api/chat"):
+		return apiChat, true
+	case strings.HasSuffix(path, "/api/generate"):
+		return apiGenerate, true
+	case strings.HasSuffix(path, "/api/embed"):
+		return apiEmbed, true
+	case strings.HasSuffix(path, "/api/embeddings"):
+		return apiEmbeddings, true
+	}
+	return 0, false
+}
+
+// provider adapts Ollama's native API to the core round tripper.
+type provider struct{}
+
+func (provider) TracerName() string { return "github.com/ollama/ollama/api" }
+
+func (provider) Match(req *http.Request) bool {
+	_, ok := apiForPath(req.URL.Path)
+	return ok
+}
+
+func (provider) ParseRequest(req *http.Request, body []byte) *core.Request {
+	api, _ := apiForPath(req.URL.Path)
+	call := &core.Request{
+		SpanName:   spanName(api),
+		State:      api,
+		Attributes: []attribute.KeyValue{providerName, operationName(api)},
+	}
+	if len(body) > 0 {
+		fields := parseRequestBody(api, body)
+		call.Attributes = append(call.Attributes, fields.attrs...)
+		call.Prompt = fields.prompt
+		call.Streaming = fields.streaming
+	}
+	return call
+}
+
+func (provider) ParseResponse(call *core.Request, body []byte) *core.Response {
+	var resp map[string]any
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil
+	}
+	switch call.State {
+	case apiEmbed, apiEmbeddings:
+		return parseEmbedResponse(resp)
+	}
+	var s stream
+	s.Chunk(resp)
+	out, _ := s.Finish(nil, nil)
+	return out
+}
+
+func (provider) NewStream(*core.Request) core.Stream { return &stream{} }
+
+func spanName(api apiKind) string {
+	switch api {
+	case apiGenerate:
+		return "ollama.generate"
+	case apiEmbed, apiEmbeddings:
+		return "ollama.embed"
+	}
+	return "ollama.chat"
+}
+
+func operationName(api apiKind) attribute.KeyValue {
+	switch api {
+	case apiGenerate:
+		return semconv.GenAIOperationNameTextCompletion
+	case apiEmbed, apiEmbeddings:
+		return semconv.GenAIOperationNameEmbeddings
+	}
+	return semconv.GenAIOperationNameChat
+}
+
+// requestFields holds fields extracted from the request body.
+type requestFields struct {
+	attrs     []attribute.KeyValue
+	prompt    string
+	streaming bool
+}
+
+// parseRequestBody extracts model, sampling options, input and the
+// streaming flag from an Ollama request body. Chat and generate requests
+// stream unless "stream": false is set.
+func parseRequestBody(api apiKind, body []byte) requestFields {
+	var req map[string]any
+	if err := json.Unmarshal(body, &req); err != nil {
+		return requestFields{}
+	}
+
+	var fields requestFields
+	if model, ok := req["model"].(string); ok && model != "" {
+		fields.attrs = append(fields.attrs, semconv.GenAIRequestModel(model))
+	}
+
+	if api == apiEmbed || api == apiEmbeddings {
+		input, ok := req["input"]
+		if !ok {
+			input, ok = req["prompt"]
+		}
+		if ok {
+			if b, err := json.Marshal(map[string]any{"input": input}); err == nil {
+				fields.prompt = string(b)
+			}
+		}
+		return fields
+	}
+
+	fields.streaming = true
+	if s, ok := req["stream"].(bool); ok {
+		fields.streaming = s
+	}
+	fields.attrs = append(fields.attrs, optionAttributes(core.Map(req, "options"))...)
+
+	var messages []any
+	if sys, ok := req["system"].(string); ok && sys != "" {
+		messages = append(messages, map[string]any{"role": "system", "content": sys})
+	}
+	if api == apiGenerate {
+		if p, ok := req["prompt"].(string); ok && p != "" {
+			messages = append(messages, map[string]any{"role": "user", "content": p})
+		}
+	} else if msgs, ok := req["messages"].([]any); ok {
+		for _, raw := range msgs {
+			if m, ok := raw.(map[string]any); ok {
+				messages = append(messages, toOpenAIMessage(m))
+			}
+		}
+	}
+	if len(messages) > 0 {
+		if b, err := json.Marshal(map[string]any{"messages": messages}); err == nil {
+			fields.prompt = string(b)
+		}
+	}
+	return fields
+}
+
+// optionAttributes maps Ollama model options onto gen_ai.request.* attributes.
+func optionAttributes(opts map[string]any) []attribute.KeyValue {
+	var attrs []attribute.KeyValue
+	if v, ok := opts["num_predict"].(float64); ok {
+		attrs = append(attrs, semconv.GenAIRequestMaxTokens(int(v)))
+	}
+	if v, ok := opts["temperature"].(float64); ok {
+		attrs = append(attrs, semconv.GenAIRequestTemperature(v))
+	}
+	if v, ok := opts["top_p"].(float64); ok {
+		attrs = append(attrs, semconv.GenAIRequestTopP(v))
+	}
+	if v, ok := opts["top_k"].(float64); ok {
+		attrs = append(attrs, semconv.GenAIRequestTopK(v))
+	}
+	if v, ok := opts["seed"].(float64); ok {
+		attrs = append(attrs, semconv.GenAIRequestSeed(int(v)))
+	}
+	if stops, ok := opts["stop"].([]any); ok && len(stops) > 0 {
+		var ss []string
+		for _, s := range stops {
+			if str, ok := s.(string); ok {
+				ss = append(ss, str)
+			}
+		}
+		attrs = append(attrs, semconv.GenAIRequestStopSequences(ss...))
+	}
+	return attrs
+}
+
+// toOpenAIMessage converts an Ollama chat message to the OpenAI-compatible
+// shape: tool_calls get ids and JSON-string arguments, and tool results carry
+// their tool_name as name.
+func toOpenAIMessage(m map[string]any) map[string]any {
+	out := map[string]any{"role": m["role"], "content": m["content"]}
+	if name, ok := m["tool_name"].(string); ok && name != "" {
+		out["name"] = name
+	}
+	if calls, ok := m["tool_calls"].([]any); ok && len(calls) > 0 {
+		out["tool_calls"] = toOpenAIToolCalls(calls, 0)
+	}
+	return out
+}
+
+// toOpenAIToolCalls converts Ollama tool calls, numbering those without an
+// id from offset.
+func toOpenAIToolCalls(calls []any, offset int) []any {
+	var out []any
+	for i, raw := range calls {
+		tc, ok := raw.(map[string]any)
+		if !ok {
+			continue
+		}
+		fn := core.Map(tc, "function")
+		name, _ := fn["name"].(string)
+		args := "{}"
+		if a, ok := fn["arguments"]; ok {
+			if b, err := json.Marshal(a); err == nil {
+				args = string(b)
+			}
+		}
+		id, _ := tc["id"].(string)
+		if id == "" {
+			id = fmt.Sprintf("call_%d", offset+i)
+		}
+		out = append(out, map[string]any{
+			"id":    id,
+			"type":  "function",
+			"index": offset + i,
+			"function": map[string]any{
+				"name":      name,
+				"arguments": args,
+			},
+		})
+	}
+	return out
+}
+
+// parseEmbedResponse summarizes an /api/embed or legacy /api/embeddings
+// response: vectors are reduced to their count and dimensions.
+func parseEmbedResponse(resp map[string]any) *core.Response {
+	out := &core.Response{}
+	out.Model, _ = resp["model"].(string)
+
+	var vectors []any
+	if v, ok := resp["embeddings"].([]any); ok {
+		vectors = v
+	} else if v, ok := resp["embedding"].([]any); ok {
+		vectors = []any{v}
+	}
+	if len(vectors) > 0 {
+		dims := 0
+		if first, ok := vectors[0].([]any); ok {
+			dims = len(first)
+		}
+		if b, err := json.Marshal(map[string]any{"embeddings": len(vectors), "dimensions": dims}); err == nil {
+			out.Completion = string(b)
+		}
+	}
+	out.Usage = tokenUsage(resp)
+	return out
+}
+
+// tokenUsage maps Ollama's prompt_eval_count/eval_count onto core.Usage, or
+// returns nil when neither is present.
+func tokenUsage(m map[string]any) *core.Usage {
+	input := core.Int(m, "prompt_eval_count")
+	output := core.Int(m, "eval_count")
+	if input == 0 && output == 0 {
+		return nil
+	}
+	return &core.Usage{
+		InputTokens:  input,
+		OutputTokens: output,
+		TotalTokens:  input + output,
+		Metadata:     core.UsageMetadata(input, output, input+output, nil, nil),
+	}
+}
+
+// stream accumulates an Ollama NDJSON stream. Each line carries an
+// incremental message (chat) or response (generate); the final line has
+// "done": true with done_reason and token counts. A line with "error" ends
+// the stream. Non-streaming responses are a single such object.
+type stream struct {
+	model      string
+	content    strings.Builder
+	toolCalls  []any
+	stopReason string
+	usage      *core.Usage
+	done       bool
+	err        error
+}
+
+func (s *stream) NewScanner(onEvent func(map[string]any)) core.Scanner {
+	return traceutil.NewNDJSONScanner(onEvent)
+}
+
+func (s *stream) IsFirstToken(chunk map[string]any) bool {
+	if r, _ := chunk["response"].(string); r != "" {
+		return true
+	}
+	if t, _ := chunk["thinking"].(string); t != "" {
+		return true
+	}
+	msg := core.Map(chunk, "message")
+	if c, _ := msg["content"].(string); c != "" {
+		return true
+	}
+	if t, _ := msg["thinking"].(string); t != "" {
+		return true
+	}
+	calls, _ := msg["tool_calls"].([]any)
+	return len(calls) > 0
+}
+
+func (s *stream) Chunk(chunk map[string]any) {
+	if msg, ok := chunk["error"].(string); ok && msg != "" {
+		s.err = errors.New(msg)
+		return
+	}
+	if model, ok := chunk["model"].(string); ok && model != "" {
+		s.model = model
+	}
+	if r, ok := chunk["response"].(string); ok {
+		s.content.WriteString(r)
+	}
+	if msg := core.Map(chunk, "message"); msg != nil {
+		if c, ok := msg["content"].(string); ok {
+			s.content.WriteString(c)
+		}
+		if calls, ok := msg["tool_calls"].([]any); ok {
+			s.toolCalls = append(s.toolCalls, toOpenAIToolCalls(calls, len(s.toolCalls))...)
+		}
+	}
+	if done, _ := chunk["done"].(bool); done {
+		s.done = true
+		s.stopReason, _ = chunk["done_reason"].(string)
+		s.usage = tokenUsage(chunk)
+	}
+}
+
+// Finish assembles the accumulated output into one assistant message. The
+// stream is complete once the done line or an error has been received.
+func (s *stream) Finish([]byte, error) (*core.Response, bool) {
+	resp := &core.Response{Model: s.model, StopReason: s.stopReason, Usage: s.usage, Err: s.err}
+	if s.content.Len() > 0 || len(s.toolCalls) > 0 {
+		msg := map[string]any{"role": "assistant", "content": s.content.String()}
+		if len(s.toolCalls) > 0 {
+			msg["tool_calls"] = s.toolCalls
+		}
+		if b, err := json.Marshal(map[string]any{"messages": []any{msg}}); err == nil {
+			resp.Completion = string(b)
+		}
+	}
+	return resp, s.done || s.err != nil
+}
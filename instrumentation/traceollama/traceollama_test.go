@@ -0,0 +1,235 @@
+package traceollama
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+type fakeTransport struct {
+	body []byte
+}
+
+func (t *fakeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       io.NopCloser(bytes.NewReader(t.body)),
+		Header:     make(http.Header),
+		Request:    req,
+	}, nil
+}
+
+func newTracedClient(t *testing.T, body string) (*http.Client, *tracetest.InMemoryExporter) {
+	t.Helper()
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	client := WrapClient(&http.Client{Transport: &fakeTransport{body: []byte(body)}}, WithTracerProvider(tp))
+	return client, exporter
+}
+
+func do(t *testing.T, client *http.Client, path, body string) {
+	t.Helper()
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, "http://localhost:11434"+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.ReadAll(resp.Body); err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+}
+
+func onlySpan(t *testing.T, exporter *tracetest.InMemoryExporter) tracetest.SpanStub {
+	t.Helper()
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	return spans[0]
+}
+
+func getAttr(span tracetest.SpanStub, key string) (string, bool) {
+	for _, kv := range span.Attributes {
+		if string(kv.Key) == key {
+			return kv.Value.Emit(), true
+		}
+	}
+	return "", false
+}
+
+func hasEvent(span tracetest.SpanStub, name string) bool {
+	for _, e := range span.Events {
+		if e.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func TestApiForPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want apiKind
+		ok   bool
+	}{
+		{"/api/chat", apiChat, true},
+		{"/api/generate", apiGenerate, true},
+		{"/api/embed", apiEmbed, true},
+		{"/api/embeddings", apiEmbeddings, true},
+		{"/ollama/api/chat", apiChat, true},
+		{"/api/tags", 0, false},
+		{"/v1/chat/completions", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := apiForPath(tt.path)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("apiForPath(%q) = (%v, %v), want (%v, %v)", tt.path, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestRoundTrip_ChatStreamingByDefault(t *testing.T) {
+	ndjson := `{"model":"llama3.2","message":{"role":"assistant","content":""},"done":false}
+{"model":"llama3.2","message":{"role":"assistant","content":"Hel"},"done":false}
+{"model":"llama3.2","message":{"role":"assistant","content":"lo"},"done":false}
+{"model":"llama3.2","message":{"role":"assistant","content":""},"done":true,"done_reason":"stop","prompt_eval_count":26,"eval_count":3,"total_duration":1200000}
+`
+	client, exporter := newTracedClient(t, ndjson)
+	do(t, client, "/api/chat", `{"model":"llama3.2","messages":[{"role":"system","content":"Be brief."},{"role":"user","content":"hi"}],"options":{"temperature":0.1,"num_predict":64}}`)
+
+	span := onlySpan(t, exporter)
+	if span.Name != "ollama.chat" {
+		t.Errorf("span name = %q", span.Name)
+	}
+	if span.Status.Code != codes.Ok {
+		t.Errorf("status = %v, want Ok", span.Status)
+	}
+	if !hasEvent(span, "new_token") {
+		t.Error("expected new_token event")
+	}
+	want := map[string]string{
+		"gen_ai.provider.name":           "ollama",
+		"gen_ai.operation.name":          "chat",
+		"gen_ai.request.model":           "llama3.2",
+		"gen_ai.request.max_tokens":      "64",
+		"gen_ai.response.model":          "llama3.2",
+		"langsmith.metadata.stop_reason": "stop",
+		"gen_ai.usage.input_tokens":      "26",
+		"gen_ai.usage.output_tokens":     "3",
+		"gen_ai.completion":              `{"messages":[{"content":"Hello","role":"assistant"}]}`,
+	}
+	for k, v := range want {
+		if got, _ := getAttr(span, k); got != v {
+			t.Errorf("%s = %q, want %q", k, got, v)
+		}
+	}
+	if got, _ := getAttr(span, "gen_ai.prompt"); !strings.Contains(got, `"content":"Be brief.","role":"system"`) {
+		t.Errorf("gen_ai.prompt = %s", got)
+	}
+}
+
+func TestRoundTrip_ChatNonStreamingWithToolCalls(t *testing.T) {
+	resp := `{"model":"qwen3","message":{"role":"assistant","content":"","tool_calls":[{"function":{"name":"get_weather","arguments":{"city":"Paris"}}}]},"done":true,"done_reason":"stop","prompt_eval_count":90,"eval_count":20}`
+	client, exporter := newTracedClient(t, resp)
+	do(t, client, "/api/chat", `{"model":"qwen3","stream":false,"messages":[
+		{"role":"user","content":"Weather?"},
+		{"role":"assistant","tool_calls":[{"function":{"name":"get_weather","arguments":{"city":"Oslo"}}}]},
+		{"role":"tool","tool_name":"get_weather","content":"cold"}
+	]}`)
+
+	span := onlySpan(t, exporter)
+	if hasEvent(span, "new_token") {
+		t.Error("non-streaming span should not record new_token")
+	}
+	completion, _ := getAttr(span, "gen_ai.completion")
+	if !strings.Contains(completion, `"arguments":"{\"city\":\"Paris\"}"`) || !strings.Contains(completion, `"id":"call_0"`) {
+		t.Errorf("gen_ai.completion = %s", completion)
+	}
+	prompt, _ := getAttr(span, "gen_ai.prompt")
+	if !strings.Contains(prompt, `"name":"get_weather","role":"tool"`) || !strings.Contains(prompt, `"arguments":"{\"city\":\"Oslo\"}"`) {
+		t.Errorf("gen_ai.prompt = %s", prompt)
+	}
+}
+
+func TestRoundTrip_Generate(t *testing.T) {
+	ndjson := `{"model":"llama3.2","response":"The sky","done":false}
+{"model":"llama3.2","response":" is blue.","done":false}
+{"model":"llama3.2","response":"","done":true,"done_reason":"stop","prompt_eval_count":8,"eval_count":5}
+`
+	client, exporter := newTracedClient(t, ndjson)
+	do(t, client, "/api/generate", `{"model":"llama3.2","prompt":"Why is the sky blue?","system":"Answer in one line."}`)
+
+	span := onlySpan(t, exporter)
+	if got, _ := getAttr(span, "gen_ai.operation.name"); got != "text_completion" {
+		t.Errorf("gen_ai.operation.name = %q", got)
+	}
+	if got, _ := getAttr(span, "gen_ai.completion"); !strings.Contains(got, `"content":"The sky is blue."`) {
+		t.Errorf("gen_ai.completion = %s", got)
+	}
+	if got, _ := getAttr(span, "gen_ai.prompt"); !strings.Contains(got, `"content":"Why is the sky blue?","role":"user"`) {
+		t.Errorf("gen_ai.prompt = %s", got)
+	}
+}
+
+func TestRoundTrip_StreamErrorLine(t *testing.T) {
+	ndjson := `{"model":"llama3.2","message":{"role":"assistant","content":"Hi"},"done":false}
+{"error":"an error was encountered while running the model"}
+`
+	client, exporter := newTracedClient(t, ndjson)
+	do(t, client, "/api/chat", `{"model":"llama3.2","messages":[{"role":"user","content":"hi"}]}`)
+
+	span := onlySpan(t, exporter)
+	if span.Status.Code != codes.Error || !strings.Contains(span.Status.Description, "running the model") {
+		t.Errorf("status = %v, want error from stream", span.Status)
+	}
+}
+
+func TestRoundTrip_StreamWithoutDoneIsCancelled(t *testing.T) {
+	ndjson := `{"model":"llama3.2","message":{"role":"assistant","content":"Hi"},"done":false}
+`
+	client, exporter := newTracedClient(t, ndjson)
+	do(t, client, "/api/chat", `{"model":"llama3.2","messages":[{"role":"user","content":"hi"}]}`)
+
+	span := onlySpan(t, exporter)
+	if span.Status.Code != codes.Error || span.Status.Description != "Cancelled" {
+		t.Errorf("status = %v, want Cancelled", span.Status)
+	}
+}
+
+func TestRoundTrip_Embed(t *testing.T) {
+	resp := `{"model":"all-minilm","embeddings":[[0.1,0.2,0.3],[0.4,0.5,0.6]],"prompt_eval_count":8}`
+	client, exporter := newTracedClient(t, resp)
+	do(t, client, "/api/embed", `{"model":"all-minilm","input":["a","b"]}`)
+
+	span := onlySpan(t, exporter)
+	want := map[string]string{
+		"gen_ai.operation.name":     "embeddings",
+		"gen_ai.prompt":             `{"input":["a","b"]}`,
+		"gen_ai.completion":         `{"dimensions":3,"embeddings":2}`,
+		"gen_ai.usage.input_tokens": "8",
+	}
+	for k, v := range want {
+		if got, _ := getAttr(span, k); got != v {
+			t.Errorf("%s = %q, want %q", k, got, v)
+		}
+	}
+}
+
+func TestRoundTrip_UnmatchedPassesThrough(t *testing.T) {
+	client, exporter := newTracedClient(t, `{"models":[]}`)
+	do(t, client, "/api/tags", ``)
+	if n := len(exporter.GetSpans()); n != 0 {
+		t.Errorf("got %d spans, want 0", n)
+	}
+}
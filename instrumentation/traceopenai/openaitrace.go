@@ -2,40 +2,31 @@
 package traceopenai
 
 import (
-	"bytes"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"strings"
 
-	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
-	"go.opentelemetry.io/otel/baggage"
-	"go.opentelemetry.io/otel/codes"
-	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
 	"go.opentelemetry.io/otel/trace"
 
-	"github.com/langchain-ai/langsmith-go/internal/traceutil"
+	"github.com/langchain-ai/langsmith-go/instrumentation/core"
+	"github.com/langchain-ai/langsmith-go/internal/genaiattr"
 )
 
-// LangSmith/OTel span attribute keys emitted for OpenAI usage. Defined locally
-// for now (rather than in internal/genaiattr) until they are shared across
-// instrumentations. These are the keys the LangSmith OTLP converter reads.
+// openAILongContextInputThreshold is the input-token cliff above which OpenAI
+// bills the long-context rate for the full session.
+// https://developers.openai.com/api/docs/pricing
+const openAILongContextInputThreshold = 272_000
+
 const (
-	usageInputTokensKey  = attribute.Key("gen_ai.usage.input_tokens")
-	usageOutputTokensKey = attribute.Key("gen_ai.usage.output_tokens")
-	usageTotalTokensKey  = attribute.Key("gen_ai.usage.total_tokens")
-	// usageMetadataKey is the converter's preferred, cost-driving path: a
-	// JSON-serialized LangSmith usage_metadata object.
-	usageMetadataKey = attribute.Key("langsmith.usage_metadata")
-	// serviceTierKey is a price modifier recorded as run metadata, not a token count.
-	serviceTierKey = attribute.Key("langsmith.metadata.service_tier")
-
-	// openAILongContextInputThreshold is the input-token cliff above which OpenAI
-	// bills the long-context rate for the full session.
-	// https://developers.openai.com/api/docs/pricing
-	openAILongContextInputThreshold = 272_000
+	// genAIOutputTypeKey is the semconv gen_ai.output.type: "json" when the
+	// request asks for structured output, "text" otherwise.
+	genAIOutputTypeKey = attribute.Key("gen_ai.output.type")
+	// structuredOutputSchemaKey records the name of the JSON schema a
+	// structured-output request is constrained to.
+	structuredOutputSchemaKey = attribute.Key("langsmith.metadata.structured_output_schema")
 )
 
 // MiddlewareNext is a function which is called by the middleware to pass an HTTP request
@@ -55,238 +46,90 @@ func Middleware(req *http.Request, next MiddlewareNext) (*http.Response, error)
 // and then calls next to make the actual HTTP request.
 // If tp is nil, uses the global tracer provider.
 func MiddlewareWithTracerProvider(req *http.Request, next MiddlewareNext, tp trace.TracerProvider) (*http.Response, error) {
-	// Only trace known OpenAI-compatible API endpoints (path-based, works with
-	// OpenRouter, Azure, local proxies, etc.)
-	if !isOpenAIEndpoint(req.URL.Path) {
-		return next(req)
-	}
-
-	ctx := req.Context()
-	var tracer trace.Tracer
-	if tp != nil {
-		tracer = tp.Tracer("github.com/sashabaranov/go-openai")
-	} else {
-		tracer = otel.Tracer("github.com/sashabaranov/go-openai")
-	}
+	return core.Middleware(req, core.Next(next), provider{}, core.Config{TracerProvider: tp})
+}
 
-	// Extract span context from request headers
-	ctx = otel.GetTextMapPropagator().Extract(ctx, propagation.HeaderCarrier(req.Header))
+// provider adapts the OpenAI Chat Completions, legacy Completions,
+// Embeddings and Responses APIs to the core round tripper. Matching is
+// path-based, so it also works with OpenRouter, Azure, local proxies, etc.
+type provider struct{}
 
-	// Capture parent span before creating child span (for token propagation)
-	parentSpan := trace.SpanFromContext(ctx)
+func (provider) TracerName() string { return "github.com/sashabaranov/go-openai" }
 
-	spanName := getSpanName(req.URL.Path)
-	if v := req.Context().Value(ctxKeyRunName); v != nil {
-		if s, ok := v.(string); ok && s != "" {
-			spanName = s
-		}
-	}
+func (provider) Match(req *http.Request) bool { return isOpenAIEndpoint(req.URL.Path) }
 
-	// Build attributes for child span
-	spanAttrs := []attribute.KeyValue{
-		attribute.String("gen_ai.system", "openai"),
-		attribute.String("gen_ai.operation.name", getOperationName(req.URL.Path)),
-		attribute.String("http.method", req.Method),
-		attribute.String("http.url", req.URL.String()),
-	}
-
-	// Propagate thread metadata from baggage
-	// This ensures all child spans are part of the same thread
-	// According to LangSmith docs: thread metadata must be on ALL spans including children
-	// LangSmith looks for: session_id, thread_id, or conversation_id in span attributes/metadata
-	// Set in multiple formats: standard (session_id), LangSmith metadata format (langsmith.metadata.session_id), and compatibility (session.id)
-	bag := baggage.FromContext(ctx)
-	// Check for thread metadata keys in baggage and propagate to span attributes
-	if member := bag.Member("session_id"); member.Key() == "session_id" {
-		value := member.Value()
-		spanAttrs = append(spanAttrs,
-			attribute.String("session_id", value),                    // Standard format per docs
-			attribute.String("langsmith.metadata.session_id", value), // LangSmith metadata format
-			attribute.String("session.id", value),                    // Compatibility format
-		)
-	}
-	if member := bag.Member("thread_id"); member.Key() == "thread_id" {
-		value := member.Value()
-		spanAttrs = append(spanAttrs,
-			attribute.String("thread_id", value),
-			attribute.String("langsmith.metadata.thread_id", value),
-			attribute.String("thread.id", value),
-		)
-	}
-	if member := bag.Member("conversation_id"); member.Key() == "conversation_id" {
-		value := member.Value()
-		spanAttrs = append(spanAttrs,
-			attribute.String("conversation_id", value),
-			attribute.String("langsmith.metadata.conversation_id", value),
-			attribute.String("conversation.id", value),
-		)
-	}
-
-	// Start span (child span)
-	ctx, span := tracer.Start(ctx, spanName, trace.WithAttributes(spanAttrs...))
-
-	// Read request body if present
-	var requestBody []byte
-	if req.Body != nil {
-		var err error
-		requestBody, err = io.ReadAll(req.Body)
-		if err != nil {
-			span.RecordError(err)
-			span.SetStatus(codes.Error, fmt.Sprintf("failed to read request body: %v", err))
-			span.End()
-			return next(req)
-		}
-		req.Body = io.NopCloser(bytes.NewBuffer(requestBody))
+func (provider) ParseRequest(req *http.Request, body []byte) *core.Request {
+	api := apiForPath(req.URL.Path)
+	call := &core.Request{
+		SpanName: getSpanName(req.URL.Path),
+		Attributes: []attribute.KeyValue{
+			attribute.String("gen_ai.system", "openai"),
+			semconv.GenAIProviderNameOpenAI,
+			attribute.String("gen_ai.operation.name", getOperationName(req.URL.Path)),
+		},
+		State: api,
 	}
-
-	// Extract request attributes
-	var streaming bool
-	if len(requestBody) > 0 {
-		reqFields := parseRequestBody(requestBody)
-		if reqFields.inputMessages != "" {
-			span.SetAttributes(attribute.String("gen_ai.prompt", reqFields.inputMessages))
+	if len(body) > 0 {
+		reqFields := parseRequestBody(body)
+		call.Prompt = reqFields.inputMessages
+		if api == apiEmbeddings {
+			call.Prompt = reqFields.embeddingInput
 		}
 		if reqFields.model != "" {
-			span.SetAttributes(attribute.String("gen_ai.request.model", reqFields.model))
+			call.Attributes = append(call.Attributes, attribute.String("gen_ai.request.model", reqFields.model))
+		}
+		if reqFields.outputType != "" {
+			call.Attributes = append(call.Attributes, genAIOutputTypeKey.String(reqFields.outputType))
+		}
+		if reqFields.outputSchema != "" {
+			call.Attributes = append(call.Attributes, structuredOutputSchemaKey.String(reqFields.outputSchema))
 		}
-		streaming = reqFields.streaming
+		call.Streaming = reqFields.streaming
 	}
+	return call
+}
 
-	responsesAPI := strings.HasSuffix(req.URL.Path, "/responses") || strings.HasSuffix(req.URL.Path, "/responses/compact")
-
-	// Inject span context into request headers and update request context
-	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
-	req = req.WithContext(ctx)
+func (provider) ParseResponse(call *core.Request, body []byte) *core.Response {
+	switch call.State {
+	case apiResponses:
+		return toResponse(extractResponsesCompletion(body))
+	case apiEmbeddings:
+		return toResponse(extractEmbeddingsOutput(body))
+	}
+	return toResponse(extractCompletionFromResponse(body))
+}
 
-	// Make the actual request via next middleware/transport
-	resp, err := next(req)
-	if err != nil {
-		span.RecordError(err)
-		span.SetStatus(codes.Error, err.Error())
-		span.End()
-		return resp, err
-	}
-
-	br := traceutil.NewBufferedReader(resp.Body, func(r io.Reader, readErr error) {
-		data, err := io.ReadAll(r)
-		if err != nil {
-			span.RecordError(err)
-			span.SetStatus(codes.Error, err.Error())
-			span.End()
-			return
-		}
-		if len(data) == 0 {
-			if resp.StatusCode >= 400 {
-				apiErr := fmt.Errorf("HTTP %d", resp.StatusCode)
-				span.RecordError(apiErr)
-				span.SetStatus(codes.Error, apiErr.Error())
-			}
-			// readErr is the error that ended the read (e.g. context.Canceled); record it so run has real error
-			if readErr != nil && readErr != io.EOF {
-				span.RecordError(readErr)
-				span.SetStatus(codes.Error, readErr.Error())
-			}
-			span.End()
-			return
-		}
+func (provider) NewStream(call *core.Request) core.Stream {
+	if call.State == apiResponses {
+		return &responsesStream{}
+	}
+	return &chatStream{}
+}
 
-		bodyText := string(data)
-		if resp.StatusCode >= 400 {
-			// Record an error so backends (e.g. LangSmith) show the trace as failed and populate run.error
-			msg := bodyText
-			if len(msg) > 500 {
-				msg = msg[:500] + "..."
-			}
-			apiErr := fmt.Errorf("HTTP %d: %s", resp.StatusCode, msg)
-			span.RecordError(apiErr)
-			span.SetStatus(codes.Error, apiErr.Error())
-		}
-		// Early stream termination: use real error from read (e.g. context.Canceled) or synthetic "Cancelled".
-		// Chat Completions streams end with "data: [DONE]"; Responses API streams
-		// end with one of three terminal events: response.completed,
-		// response.incomplete (e.g. max_output_tokens), or response.failed.
-		// See https://developers.openai.com/api/docs/guides/streaming-responses.
-		var incompleteStream bool
-		if resp.StatusCode < 400 && streaming {
-			if responsesAPI {
-				incompleteStream = !strings.Contains(bodyText, `"response.completed"`) &&
-					!strings.Contains(bodyText, `"response.incomplete"`) &&
-					!strings.Contains(bodyText, `"response.failed"`)
-			} else {
-				incompleteStream = !strings.Contains(bodyText, "[DONE]")
-			}
-		}
-		if incompleteStream {
-			endErr := readErr
-			if endErr == nil || endErr == io.EOF {
-				endErr = fmt.Errorf("Cancelled")
-			}
-			span.RecordError(endErr)
-			span.SetStatus(codes.Error, endErr.Error())
-		}
+// apiKind identifies the OpenAI API surface a request targets; request,
+// response and stream shapes differ between them.
+type apiKind int
 
-		var completion string
-		var usage usageInfo
-		if responsesAPI {
-			if streaming {
-				completion, usage = extractStreamingResponsesCompletion(data)
-			} else {
-				completion, usage = extractResponsesCompletion(data)
-			}
-		} else if streaming {
-			completion, usage = extractStreamingCompletion(data)
-		} else {
-			completion, usage = extractCompletionFromResponse(data)
-		}
+const (
+	apiChat apiKind = iota // chat and legacy completions
+	apiResponses
+	apiEmbeddings
+)
 
-		if completion != "" {
-			span.SetAttributes(attribute.String("gen_ai.completion", completion))
-		}
-		if usage.HasUsage {
-			// langsmith.usage_metadata is the converter's preferred,
-			// cost-driving path (token-detail breakdown for cache/reasoning/audio).
-			if len(usage.UsageMetadata) > 0 {
-				if out, err := json.Marshal(usage.UsageMetadata); err == nil {
-					span.SetAttributes(usageMetadataKey.String(string(out)))
-				}
-			}
-			// Flat gen_ai.usage.* attributes drive Thread-list aggregation, which
-			// reads token counts from root spans; input/output propagate to the parent.
-			inputTokens := int64(usage.InputTokens)
-			span.SetAttributes(usageInputTokensKey.Int64(inputTokens))
-			outputTokens := int64(usage.OutputTokens)
-			span.SetAttributes(usageOutputTokensKey.Int64(outputTokens))
-			if usage.TotalTokens > 0 {
-				span.SetAttributes(usageTotalTokensKey.Int64(int64(usage.TotalTokens)))
-			}
-			if parentSpan.SpanContext().IsValid() && parentSpan.IsRecording() {
-				parentSpan.SetAttributes(
-					usageInputTokensKey.Int64(inputTokens),
-					usageOutputTokensKey.Int64(outputTokens),
-				)
-			}
-			// service_tier is a price modifier, not a token count, so it goes in metadata.
-			if usage.ServiceTier != "" {
-				span.SetAttributes(serviceTierKey.String(usage.ServiceTier))
-			}
-		}
-		if resp.StatusCode < 400 && !incompleteStream {
-			span.SetStatus(codes.Ok, "")
-		}
-		span.End()
-	})
-	// LangSmith ingest reads new_token to derive first_token_time; skip on
-	// HTTP errors so an error body doesn't inflate it.
-	if streaming && resp.StatusCode < 400 {
-		isMatch := isFirstContentChat
-		if responsesAPI {
-			isMatch = isFirstContentResponses
-		}
-		traceutil.OnFirstSSEMatch(br, isMatch, func() { span.AddEvent("new_token") })
+// apiForPath returns the API surface for an OpenAI endpoint path.
+func apiForPath(path string) apiKind {
+	switch {
+	case strings.HasSuffix(path, "/responses") || strings.HasSuffix(path, "/responses/compact"):
+		return apiResponses
+	case strings.HasSuffix(path, "/embeddings"):
+		return apiEmbeddings
 	}
-	resp.Body = br
+	return apiChat
+}
 
-	return resp, nil
+// toResponse converts an extracted completion and usage into a core.Response.
+func toResponse(completion string, usage usageInfo) *core.Response {
+	return &core.Response{Completion: completion, Usage: usage.coreUsage()}
 }
 
 // Chat streams open with a delta.role-only preamble; with n>1, content can
@@ -380,6 +223,12 @@ type requestFields struct {
 	inputMessages string
 	model         string
 	streaming     bool
+	// embeddingInput is the {"input": ...} JSON of an embeddings request.
+	embeddingInput string
+	// outputType and outputSchema describe a structured-output request
+	// (response_format on Chat Completions, text.format on Responses).
+	outputType   string
+	outputSchema string
 }
 
 // parseRequestBody extracts input messages, model, and streaming flag from
@@ -398,6 +247,21 @@ func parseRequestBody(body []byte) requestFields {
 	// Streaming
 	fields.streaming, _ = req["stream"].(bool)
 
+	// Structured outputs
+	format, _ := req["response_format"].(map[string]any)
+	if text, ok := req["text"].(map[string]any); ok && format == nil {
+		format, _ = text["format"].(map[string]any)
+	}
+	fields.outputType, fields.outputSchema = parseOutputFormat(format)
+
+	// Embeddings input is recorded verbatim; the messages view below would
+	// misread a []string input as Responses items.
+	if input, ok := req["input"]; ok {
+		if out, err := json.Marshal(map[string]any{"input": input}); err == nil {
+			fields.embeddingInput = string(out)
+		}
+	}
+
 	// Input messages — chat completions
 	if messages, ok := req["messages"].([]any); ok && len(messages) > 0 {
 		fields.inputMessages = marshalMessages(messages)
@@ -439,6 +303,54 @@ func marshalMessages(messages []any) string {
 	return string(out)
 }
 
+// parseOutputFormat maps a response_format (Chat Completions) or text.format
+// (Responses) object to a gen_ai.output.type value and the JSON schema name,
+// if any. Chat Completions nests the schema under json_schema; Responses
+// inlines name and schema on the format object.
+func parseOutputFormat(format map[string]any) (outputType, schemaName string) {
+	switch t, _ := format["type"].(string); t {
+	case "json_schema":
+		schemaName, _ = format["name"].(string)
+		if js, ok := format["json_schema"].(map[string]any); ok {
+			schemaName, _ = js["name"].(string)
+		}
+		return "json", schemaName
+	case "json_object":
+		return "json", ""
+	case "text":
+		return "text", ""
+	}
+	return "", ""
+}
+
+// extractEmbeddingsOutput summarizes an embeddings response. Vectors are
+// not recorded: they are large and not human-readable, so the output holds
+// the vector count and dimensionality instead.
+func extractEmbeddingsOutput(body []byte) (string, usageInfo) {
+	var resp map[string]any
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return "", usageInfo{}
+	}
+	usage := extractResponsesUsage(resp)
+	data, ok := resp["data"].([]any)
+	if !ok {
+		return "", usage
+	}
+	summary := map[string]any{"embeddings": len(data)}
+	if len(data) > 0 {
+		if first, ok := data[0].(map[string]any); ok {
+			if vec, ok := first["embedding"].([]any); ok {
+				summary["dimensions"] = len(vec)
+			}
+		}
+	}
+	out, err := json.Marshal(summary)
+	if err != nil {
+		return "", usage
+	}
+	return string(out), usage
+}
+
 // normalizeResponsesInput converts Responses API input items into
 // chat-completions messages that LangSmith can render.
 func normalizeResponsesInput(items []any) []any {
@@ -459,7 +371,9 @@ func normalizeResponsesInput(items []any) []any {
 			case string:
 				msg["content"] = content
 			case []any:
-				if text := flattenContentParts(content); text != "" {
+				if parts, ok := responsesMediaParts(content); ok {
+					msg["content"] = parts
+				} else if text := flattenContentParts(content); text != "" {
 					msg["content"] = text
 				} else {
 					b, _ := json.Marshal(content)
@@ -705,6 +619,45 @@ func extractSummaryText(m map[string]any) string {
 }
 
 // flattenContentParts extracts text from Responses API content part arrays.
+// responsesMediaParts converts Responses API content that includes images,
+// files or audio into chat-completions content parts, so the media is kept
+// rather than flattened away. ok is false when content is text only.
+func responsesMediaParts(content []any) (parts []any, ok bool) {
+	for _, raw := range content {
+		pm, _ := raw.(map[string]any)
+		switch pm["type"] {
+		case "input_text", "output_text", "text":
+			parts = append(parts, map[string]any{"type": "text", "text": pm["text"]})
+		case "input_image":
+			ok = true
+			image := map[string]any{}
+			for _, k := range []string{"image_url", "file_id", "detail"} {
+				if v, found := pm[k].(string); found {
+					image[k] = v
+				}
+			}
+			if url, found := image["image_url"]; found {
+				delete(image, "image_url")
+				image["url"] = url
+			}
+			parts = append(parts, map[string]any{"type": "image_url", "image_url": image})
+		case "input_file":
+			ok = true
+			file := map[string]any{}
+			for _, k := range []string{"file_data", "file_id", "file_url", "filename"} {
+				if v, found := pm[k].(string); found {
+					file[k] = v
+				}
+			}
+			parts = append(parts, map[string]any{"type": "file", "file": file})
+		case "input_audio":
+			ok = true
+			parts = append(parts, map[string]any{"type": "input_audio", "input_audio": pm["input_audio"]})
+		}
+	}
+	return parts, ok
+}
+
 func flattenContentParts(parts []any) string {
 	var b strings.Builder
 	for _, part := range parts {
@@ -732,90 +685,95 @@ func flattenContentParts(parts []any) string {
 	return b.String()
 }
 
-// extractStreamingCompletion parses an SSE response body, aggregates
-// delta.content and delta.tool_calls across chunks, and extracts usage from
-// the chunk that contains it (last chunk when stream_options.include_usage is set).
-func extractStreamingCompletion(data []byte) (string, usageInfo) {
-	chunks, err := traceutil.ParseSSEChunks(bytes.NewReader(data))
-	if err != nil || len(chunks) == 0 {
-		return "", usageInfo{}
-	}
+// chatStream aggregates delta.content and delta.tool_calls across Chat
+// Completions chunks, and extracts usage from the chunk that contains it
+// (last chunk when stream_options.include_usage is set).
+type chatStream struct {
+	content strings.Builder
+	usage   usageInfo
+	// toolCalls tracks tool calls by index.
+	toolCalls []*toolCallAcc
+}
 
-	var content strings.Builder
-	var usage usageInfo
+// toolCallAcc holds a streamed tool call's id, type, function name, and a
+// builder that accumulates the streamed function arguments.
+type toolCallAcc struct {
+	ID   string
+	Type string
+	Name string
+	Args strings.Builder
+}
 
-	// Track tool calls by index. Each entry holds id, type, function name,
-	// and a builder that accumulates the streamed function arguments.
-	type toolCallAcc struct {
-		ID   string
-		Type string
-		Name string
-		Args strings.Builder
-	}
-	var toolCalls []*toolCallAcc
+func (s *chatStream) IsFirstToken(chunk map[string]any) bool { return isFirstContentChat(chunk) }
 
-	for _, chunk := range chunks {
-		if choices, ok := chunk["choices"].([]any); ok && len(choices) > 0 {
-			if choice, ok := choices[0].(map[string]any); ok {
-				if delta, ok := choice["delta"].(map[string]any); ok {
-					// Aggregate text content
-					if text, ok := delta["content"].(string); ok {
-						content.WriteString(text)
-					}
-					// Aggregate tool call deltas
-					if tcs, ok := delta["tool_calls"].([]any); ok {
-						for _, tc := range tcs {
-							tcMap, ok := tc.(map[string]any)
-							if !ok {
-								continue
-							}
-							idx := 0
-							if idxF, ok := tcMap["index"].(float64); ok {
-								idx = int(idxF)
-							}
-							for len(toolCalls) <= idx {
-								toolCalls = append(toolCalls, &toolCallAcc{})
-							}
-							if id, ok := tcMap["id"].(string); ok {
-								toolCalls[idx].ID = id
-							}
-							if typ, ok := tcMap["type"].(string); ok {
-								toolCalls[idx].Type = typ
-							}
-							if fn, ok := tcMap["function"].(map[string]any); ok {
-								if name, ok := fn["name"].(string); ok {
-									toolCalls[idx].Name = name
-								}
-								if args, ok := fn["arguments"].(string); ok {
-									toolCalls[idx].Args.WriteString(args)
-								}
-							}
-						}
+func (s *chatStream) Chunk(chunk map[string]any) {
+	if choices, ok := chunk["choices"].([]any); ok && len(choices) > 0 {
+		if choice, ok := choices[0].(map[string]any); ok {
+			if delta, ok := choice["delta"].(map[string]any); ok {
+				// Aggregate text content
+				if text, ok := delta["content"].(string); ok {
+					s.content.WriteString(text)
+				}
+				// Aggregate tool call deltas
+				if tcs, ok := delta["tool_calls"].([]any); ok {
+					for _, tc := range tcs {
+						s.addToolCallDelta(tc)
 					}
 				}
 			}
 		}
+	}
+
+	// service_tier rides at the top level of each chunk.
+	if st, ok := chunk["service_tier"].(string); ok && st != "" {
+		s.usage.ServiceTier = st
+	}
+	// Extract usage (present in the last chunk when include_usage is set)
+	if usageMap, ok := chunk["usage"].(map[string]any); ok {
+		s.usage = buildOpenAIUsage(usageMap, s.usage.ServiceTier)
+	}
+}
 
-		// service_tier rides at the top level of each chunk.
-		if st, ok := chunk["service_tier"].(string); ok && st != "" {
-			usage.ServiceTier = st
+func (s *chatStream) addToolCallDelta(tc any) {
+	tcMap, ok := tc.(map[string]any)
+	if !ok {
+		return
+	}
+	idx := 0
+	if idxF, ok := tcMap["index"].(float64); ok {
+		idx = int(idxF)
+	}
+	for len(s.toolCalls) <= idx {
+		s.toolCalls = append(s.toolCalls, &toolCallAcc{})
+	}
+	if id, ok := tcMap["id"].(string); ok {
+		s.toolCalls[idx].ID = id
+	}
+	if typ, ok := tcMap["type"].(string); ok {
+		s.toolCalls[idx].Type = typ
+	}
+	if fn, ok := tcMap["function"].(map[string]any); ok {
+		if name, ok := fn["name"].(string); ok {
+			s.toolCalls[idx].Name = name
 		}
-		// Extract usage (present in the last chunk when include_usage is set)
-		if usageMap, ok := chunk["usage"].(map[string]any); ok {
-			usage = buildOpenAIUsage(usageMap, usage.ServiceTier)
+		if args, ok := fn["arguments"].(string); ok {
+			s.toolCalls[idx].Args.WriteString(args)
 		}
 	}
+}
 
-	text := content.String()
+// result builds the assistant message from the aggregated deltas.
+func (s *chatStream) result() (string, usageInfo) {
+	text := s.content.String()
 
 	// Build assistant message
 	msg := map[string]any{"role": "assistant"}
 	if text != "" {
 		msg["content"] = text
 	}
-	if len(toolCalls) > 0 {
-		tcOut := make([]map[string]any, len(toolCalls))
-		for i, tc := range toolCalls {
+	if len(s.toolCalls) > 0 {
+		tcOut := make([]map[string]any, len(s.toolCalls))
+		for i, tc := range s.toolCalls {
 			tcOut[i] = map[string]any{
 				"id":   tc.ID,
 				"type": tc.Type,
@@ -830,9 +788,15 @@ func extractStreamingCompletion(data []byte) (string, usageInfo) {
 
 	if len(msg) == 1 {
 		// Only "role" — no content or tool calls
-		return "", usage
+		return "", s.usage
 	}
-	return marshalMessages([]any{msg}), usage
+	return marshalMessages([]any{msg}), s.usage
+}
+
+// Finish reports the stream complete once the "data: [DONE]" sentinel that
+// ends Chat Completions streams has been received.
+func (s *chatStream) Finish(data []byte, _ error) (*core.Response, bool) {
+	return toResponse(s.result()), strings.Contains(string(data), "[DONE]")
 }
 
 // usageInfo holds token usage information.
@@ -851,6 +815,24 @@ type usageInfo struct {
 	ServiceTier string
 }
 
+// coreUsage converts u to core.Usage, or nil when the provider omitted usage.
+func (u usageInfo) coreUsage() *core.Usage {
+	if !u.HasUsage {
+		return nil
+	}
+	out := &core.Usage{
+		InputTokens:  int64(u.InputTokens),
+		OutputTokens: int64(u.OutputTokens),
+		TotalTokens:  int64(u.TotalTokens),
+		Metadata:     u.UsageMetadata,
+	}
+	// service_tier is a price modifier, not a token count, so it goes in metadata.
+	if u.ServiceTier != "" {
+		out.Attributes = append(out.Attributes, genaiattr.ServiceTierKey.String(u.ServiceTier))
+	}
+	return out
+}
+
 // openAIUsagePricingBucket is the model_price_map bucket for tiered or long-context
 // billing. Empty when default rates apply. Metadata-only tiers like "default" are excluded.
 func openAIUsagePricingBucket(serviceTier string, inputTokens int) string {
@@ -1044,26 +1026,47 @@ func extractResponsesCompletion(body []byte) (string, usageInfo) {
 	return extractResponsesOutput(resp), extractResponsesUsage(resp)
 }
 
-// extractStreamingResponsesCompletion extracts completion and usage from a
-// streaming Responses API response. The OpenAI spec defines three terminal
-// events that carry the full response object (including usage):
-// response.completed, response.incomplete, and response.failed.
-// See https://platform.openai.com/docs/api-reference/responses-streaming
-func extractStreamingResponsesCompletion(data []byte) (string, usageInfo) {
-	chunks, err := traceutil.ParseSSEChunks(bytes.NewReader(data))
-	if err != nil || len(chunks) == 0 {
-		return "", usageInfo{}
-	}
+// responsesStream extracts completion and usage from a streaming Responses
+// API response. The OpenAI spec defines three terminal events that carry the
+// full response object (including usage): response.completed,
+// response.incomplete (e.g. max_output_tokens), and response.failed.
+// See https://developers.openai.com/api/docs/guides/streaming-responses.
+type responsesStream struct {
+	// terminal is the response object from the first terminal event.
+	terminal map[string]any
+}
 
-	for _, chunk := range chunks {
-		switch msgType, _ := chunk["type"].(string); msgType {
-		case "response.completed", "response.incomplete", "response.failed":
-			if response, ok := chunk["response"].(map[string]any); ok {
-				return extractResponsesOutput(response), extractResponsesUsage(response)
-			}
+func (s *responsesStream) IsFirstToken(chunk map[string]any) bool {
+	return isFirstContentResponses(chunk)
+}
+
+func (s *responsesStream) Chunk(chunk map[string]any) {
+	if s.terminal != nil {
+		return
+	}
+	switch msgType, _ := chunk["type"].(string); msgType {
+	case "response.completed", "response.incomplete", "response.failed":
+		if response, ok := chunk["response"].(map[string]any); ok {
+			s.terminal = response
 		}
 	}
-	return "", usageInfo{}
+}
+
+func (s *responsesStream) result() (string, usageInfo) {
+	if s.terminal == nil {
+		return "", usageInfo{}
+	}
+	return extractResponsesOutput(s.terminal), extractResponsesUsage(s.terminal)
+}
+
+// Finish reports the stream complete once one of the terminal events has
+// been received.
+func (s *responsesStream) Finish(data []byte, _ error) (*core.Response, bool) {
+	bodyText := string(data)
+	complete := strings.Contains(bodyText, `"response.completed"`) ||
+		strings.Contains(bodyText, `"response.incomplete"`) ||
+		strings.Contains(bodyText, `"response.failed"`)
+	return toResponse(s.result()), complete
 }
 
 // extractResponsesUsage extracts usage from a Responses API response object.
@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/json"
 	"io"
+	"math"
 	"net/http"
 	"reflect"
 	"strings"
@@ -13,8 +14,43 @@ import (
 	"go.opentelemetry.io/otel/codes"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/langchain-ai/langsmith-go/instrumentation/core"
+	"github.com/langchain-ai/langsmith-go/instrumentation/pricing"
+	"github.com/langchain-ai/langsmith-go/internal/traceutil"
 )
 
+// extractStreamingCompletion feeds a buffered Chat Completions SSE body
+// through chatStream.
+func extractStreamingCompletion(data []byte) (string, usageInfo) {
+	s := &chatStream{}
+	if !feedSSE(s, data) {
+		return "", usageInfo{}
+	}
+	return s.result()
+}
+
+// extractStreamingResponsesCompletion feeds a buffered Responses API SSE
+// body through responsesStream.
+func extractStreamingResponsesCompletion(data []byte) (string, usageInfo) {
+	s := &responsesStream{}
+	if !feedSSE(s, data) {
+		return "", usageInfo{}
+	}
+	return s.result()
+}
+
+func feedSSE(s core.Stream, data []byte) bool {
+	chunks, err := traceutil.ParseSSEChunks(bytes.NewReader(data))
+	if err != nil || len(chunks) == 0 {
+		return false
+	}
+	for _, c := range chunks {
+		s.Chunk(c)
+	}
+	return true
+}
+
 // fakeTransport returns a canned response, capturing the request for inspection.
 type fakeTransport struct {
 	body []byte
@@ -134,6 +170,121 @@ func TestRoundTrip_NonStreamingDoesNotEmitNewTokenEvent(t *testing.T) {
 	}
 }
 
+func TestRoundTrip_CaptureContentDisabled(t *testing.T) {
+	resp := `{"model":"gpt-4o","choices":[{"message":{"role":"assistant","content":"hi"}}],"usage":{"prompt_tokens":4,"completion_tokens":1}}`
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	client := WrapClient(&http.Client{Transport: &fakeTransport{body: []byte(resp)}}, WithTracerProvider(tp), WithCaptureContent(false))
+
+	body := `{"model":"gpt-4o","messages":[{"role":"user","content":"confidential"}]}`
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost,
+		"https://api.openai.com/v1/chat/completions", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	httpResp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.ReadAll(httpResp.Body)
+	httpResp.Body.Close()
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	var sawModel bool
+	for _, kv := range spans[0].Attributes {
+		switch kv.Key {
+		case "gen_ai.prompt", "gen_ai.completion":
+			t.Errorf("%s recorded with content capture disabled", kv.Key)
+		case "gen_ai.request.model":
+			sawModel = true
+		}
+	}
+	if !sawModel {
+		t.Error("gen_ai.request.model should still be recorded")
+	}
+}
+
+func TestRoundTrip_WithPricing(t *testing.T) {
+	resp := `{"model":"gpt-4o-mini-2024-07-18","choices":[{"message":{"role":"assistant","content":"hi"}}],` +
+		`"usage":{"prompt_tokens":2000,"completion_tokens":1000,"prompt_tokens_details":{"cached_tokens":1000}}}`
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	client := WrapClient(&http.Client{Transport: &fakeTransport{body: []byte(resp)}},
+		WithTracerProvider(tp), WithPricing(pricing.Default()))
+
+	body := `{"model":"gpt-4o-mini","messages":[{"role":"user","content":"hello"}]}`
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost,
+		"https://api.openai.com/v1/chat/completions", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	httpResp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.ReadAll(httpResp.Body)
+	httpResp.Body.Close()
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	var um map[string]any
+	for _, kv := range spans[0].Attributes {
+		if kv.Key == "langsmith.usage_metadata" {
+			if err := json.Unmarshal([]byte(kv.Value.AsString()), &um); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	// 1000 cached tokens at $0.075/M, 1000 uncached at $0.15/M, 1000 output at $0.60/M.
+	wantInput, wantOutput := 0.000225, 0.0006
+	if got, _ := um["input_cost"].(float64); math.Abs(got-wantInput) > 1e-12 {
+		t.Errorf("input_cost = %v, want %v", um["input_cost"], wantInput)
+	}
+	if got, _ := um["output_cost"].(float64); math.Abs(got-wantOutput) > 1e-12 {
+		t.Errorf("output_cost = %v, want %v", um["output_cost"], wantOutput)
+	}
+	if got, _ := um["total_cost"].(float64); math.Abs(got-(wantInput+wantOutput)) > 1e-12 {
+		t.Errorf("total_cost = %v", um["total_cost"])
+	}
+}
+
+func TestRoundTrip_ProviderNameOverride(t *testing.T) {
+	resp := `{"model":"Qwen/Qwen2.5-7B-Instruct","choices":[{"message":{"role":"assistant","content":"hi"}}],"usage":{"prompt_tokens":4,"completion_tokens":1}}`
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	client := WrapClient(&http.Client{Transport: &fakeTransport{body: []byte(resp)}}, WithTracerProvider(tp), WithProviderName("vllm"))
+
+	body := `{"model":"Qwen/Qwen2.5-7B-Instruct","messages":[{"role":"user","content":"hello"}]}`
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost,
+		"http://localhost:8000/v1/chat/completions", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	httpResp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.ReadAll(httpResp.Body); err != nil {
+		t.Fatal(err)
+	}
+	httpResp.Body.Close()
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	for _, kv := range spans[0].Attributes {
+		if (kv.Key == "gen_ai.system" || kv.Key == "gen_ai.provider.name") && kv.Value.AsString() != "vllm" {
+			t.Errorf("%s = %q, want vllm", kv.Key, kv.Value.AsString())
+		}
+	}
+}
+
 // errStatusTransport returns a non-streaming JSON error body with a 429.
 type errStatusTransport struct{ body []byte }
 
@@ -477,6 +628,56 @@ func TestParseRequestBody_LegacyCompletion(t *testing.T) {
 	}
 }
 
+func TestParseRequestBody_StructuredOutput(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantType   string
+		wantSchema string
+	}{
+		{
+			name:       "chat json_schema",
+			body:       `{"model":"gpt-4o","messages":[{"role":"user","content":"x"}],"response_format":{"type":"json_schema","json_schema":{"name":"person","schema":{}}}}`,
+			wantType:   "json",
+			wantSchema: "person",
+		},
+		{
+			name:     "chat json_object",
+			body:     `{"model":"gpt-4o","messages":[{"role":"user","content":"x"}],"response_format":{"type":"json_object"}}`,
+			wantType: "json",
+		},
+		{
+			name:       "responses text.format",
+			body:       `{"model":"gpt-4o","input":"x","text":{"format":{"type":"json_schema","name":"invoice","schema":{}}}}`,
+			wantType:   "json",
+			wantSchema: "invoice",
+		},
+		{
+			name: "unconstrained",
+			body: `{"model":"gpt-4o","messages":[{"role":"user","content":"x"}]}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields := parseRequestBody([]byte(tt.body))
+			if fields.outputType != tt.wantType || fields.outputSchema != tt.wantSchema {
+				t.Errorf("got (%q, %q), want (%q, %q)", fields.outputType, fields.outputSchema, tt.wantType, tt.wantSchema)
+			}
+		})
+	}
+}
+
+func TestExtractEmbeddingsOutput(t *testing.T) {
+	body := `{"data":[{"embedding":[0.1,0.2]},{"embedding":[0.3,0.4]},{"embedding":[0.5,0.6]}],"usage":{"prompt_tokens":6,"total_tokens":6}}`
+	completion, usage := extractEmbeddingsOutput([]byte(body))
+	if completion != `{"dimensions":2,"embeddings":3}` {
+		t.Errorf("completion = %s", completion)
+	}
+	if usage.InputTokens != 6 || usage.TotalTokens != 6 {
+		t.Errorf("usage = %+v", usage)
+	}
+}
+
 func TestParseRequestBody_InvalidJSON(t *testing.T) {
 	fields := parseRequestBody([]byte("not json"))
 	if fields.model != "" || fields.streaming || fields.inputMessages != "" {
@@ -1905,3 +2106,22 @@ func TestExtractStreamingCompletion_UsageMetadataAndServiceTier(t *testing.T) {
 		t.Errorf("usage mismatch:\n got: %#v\nwant: %#v", got, want)
 	}
 }
+
+func TestNormalizeResponsesInput_KeepsMediaParts(t *testing.T) {
+	items := []any{
+		map[string]any{
+			"role": "user",
+			"content": []any{
+				map[string]any{"type": "input_text", "text": "Describe this"},
+				map[string]any{"type": "input_image", "image_url": "data:image/png;base64,aGVsbG8=", "detail": "low"},
+				map[string]any{"type": "input_file", "filename": "a.pdf", "file_data": "data:application/pdf;base64,aGVsbG8="},
+			},
+		},
+	}
+	result := normalizeResponsesInput(items)
+	b, _ := json.Marshal(result)
+	want := `[{"content":[{"text":"Describe this","type":"text"},{"image_url":{"detail":"low","url":"data:image/png;base64,aGVsbG8="},"type":"image_url"},{"file":{"file_data":"data:application/pdf;base64,aGVsbG8=","filename":"a.pdf"},"type":"file"}],"role":"user"}]`
+	if string(b) != want {
+		t.Errorf("got  %s\nwant %s", b, want)
+	}
+}
@@ -22,11 +22,10 @@ import (
 	"net/http"
 
 	"go.opentelemetry.io/otel/trace"
-)
-
-type contextKey struct{ name string }
 
-var ctxKeyRunName = contextKey{"run_name"}
+	"github.com/langchain-ai/langsmith-go/instrumentation/core"
+	"github.com/langchain-ai/langsmith-go/instrumentation/pricing"
+)
 
 // WithRunNameContext sets the span (run) name for the next traced request made with ctx.
 // The run name in LangSmith is the OTLP span name; there is no separate field.
@@ -35,29 +34,63 @@ var ctxKeyRunName = contextKey{"run_name"}
 //	ctx = traceopenai.WithRunNameContext(ctx, "openai_nonstreaming")
 //	client.CreateChatCompletion(ctx, ...)
 func WithRunNameContext(ctx context.Context, name string) context.Context {
-	return context.WithValue(ctx, ctxKeyRunName, name)
+	return core.WithRunNameContext(ctx, name)
 }
 
 // Option configures a traced HTTP client.
-type Option func(*clientOptions)
-
-type clientOptions struct {
-	tracerProvider trace.TracerProvider
-	runName        string
-}
+type Option func(*core.Config)
 
 // WithTracerProvider returns an Option that sets the tracer provider.
 // If not provided, the global tracer provider is used.
 func WithTracerProvider(tp trace.TracerProvider) Option {
-	return func(opts *clientOptions) {
-		opts.tracerProvider = tp
+	return func(cfg *core.Config) {
+		cfg.TracerProvider = tp
 	}
 }
 
 // WithRunName sets the span (run) name to the given string when non-empty. Used by integration tests to identify runs in a shared project.
 func WithRunName(name string) Option {
-	return func(opts *clientOptions) {
-		opts.runName = name
+	return func(cfg *core.Config) {
+		cfg.RunName = name
+	}
+}
+
+// WithAttachments is [core.WithAttachments].
+func WithAttachments(h core.AttachmentHandler) Option { return Option(core.WithAttachments(h)) }
+
+// WithMaxAttachmentSize is [core.WithMaxAttachmentSize].
+func WithMaxAttachmentSize(n int) Option { return Option(core.WithMaxAttachmentSize(n)) }
+
+// WithCaptureContent is [core.WithCaptureContent].
+func WithCaptureContent(capture bool) Option { return Option(core.WithCaptureContent(capture)) }
+
+// WithRedactor is [core.WithRedactor].
+func WithRedactor(r core.Redactor) Option { return Option(core.WithRedactor(r)) }
+
+// WithMaxContentLength is [core.WithMaxContentLength].
+func WithMaxContentLength(n int) Option { return Option(core.WithMaxContentLength(n)) }
+
+// WithPricing is [core.WithPricing].
+func WithPricing(prices *pricing.Table) Option { return Option(core.WithPricing(prices)) }
+
+// EndPendingRetries is [core.EndPendingRetries]. A run whose last attempt
+// failed stays open until the SDK's retry window passes; call this before
+// shutting down your tracer provider so it is exported. The LangSmith
+// OTelTracer's Shutdown calls it for you.
+func EndPendingRetries() { core.EndPendingRetries() }
+
+// WithProviderName records name as the span's provider (gen_ai.system and
+// gen_ai.provider.name) instead of "openai". Use it when pointing the client
+// at an OpenAI-compatible server such as vLLM, llama.cpp or Ollama's /v1
+// endpoints, so LangSmith attributes runs and model prices to the right
+// provider:
+//
+//	cfg := openai.DefaultConfig("")
+//	cfg.BaseURL = "http://localhost:8000/v1"
+//	cfg.HTTPClient = traceopenai.Client(traceopenai.WithProviderName("vllm"))
+func WithProviderName(name string) Option {
+	return func(cfg *core.Config) {
+		cfg.ProviderName = name
 	}
 }
 
@@ -70,46 +103,16 @@ func Client(opts ...Option) *http.Client {
 // WrapClient wraps an existing http.Client with tracing middleware.
 // If client is nil, a new client with the default transport is created.
 func WrapClient(client *http.Client, opts ...Option) *http.Client {
-	options := &clientOptions{}
+	var cfg core.Config
 	for _, opt := range opts {
-		opt(options)
+		opt(&cfg)
 	}
-
-	if client == nil {
-		client = &http.Client{}
-	}
-	transport := client.Transport
-	if transport == nil {
-		transport = http.DefaultTransport
-	}
-	client.Transport = newRoundTripper(transport, options.tracerProvider, options.runName)
-	return client
+	return core.WrapClient(client, provider{}, cfg)
 }
 
-type roundTripper struct {
-	base           http.RoundTripper
-	tracerProvider trace.TracerProvider
-	runName        string
-}
-
-func newRoundTripper(base http.RoundTripper, tp trace.TracerProvider, runName string) http.RoundTripper {
-	return &roundTripper{
-		base:           base,
-		tracerProvider: tp,
-		runName:        runName,
-	}
-}
-
-// RoundTrip intercepts requests/responses to add tracing via the OpenAI middleware.
-func (rt *roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
-	ctx := req.Context()
-	// Prefer run name from context (per-request); fall back to client option.
-	if v := ctx.Value(ctxKeyRunName); v == nil && rt.runName != "" {
-		ctx = context.WithValue(ctx, ctxKeyRunName, rt.runName)
-		req = req.WithContext(ctx)
-	}
-	next := func(r *http.Request) (*http.Response, error) {
-		return rt.base.RoundTrip(r)
-	}
-	return MiddlewareWithTracerProvider(req, next, rt.tracerProvider)
+// Provider returns the core.Provider that parses OpenAI API traffic. It is
+// exported so other OpenAI clients, such as the official openai-go SDK, can
+// share the same parsing.
+func Provider() core.Provider {
+	return provider{}
 }
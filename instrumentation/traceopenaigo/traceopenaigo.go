@@ -0,0 +1,126 @@
+// Package traceopenaigo provides OpenTelemetry tracing for the official
+// github.com/openai/openai-go SDK using LangSmith-compatible spans.
+//
+// It plugs into the SDK's middleware chain, so the client keeps its own HTTP
+// client, retries and timeouts. Chat Completions, the Responses API
+// (including tool calls and reasoning summaries), Embeddings, streaming and
+// structured outputs are traced.
+//
+// Usage:
+//
+//	client := openai.NewClient(
+//		option.WithAPIKey(apiKey),
+//		option.WithMiddleware(traceopenaigo.Middleware()),
+//	)
+//
+//	// Or use a custom tracer provider:
+//	tp := sdktrace.NewTracerProvider(...)
+//	client := openai.NewClient(
+//		option.WithMiddleware(traceopenaigo.Middleware(traceopenaigo.WithTracerProvider(tp))),
+//	)
+//
+//	// Your OpenAI API calls will now be automatically traced with LangSmith attrs
+//	// resp, err := client.Chat.Completions.New(ctx, ...)
+package traceopenaigo
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/openai/openai-go/v3/option"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/langchain-ai/langsmith-go/instrumentation/core"
+	"github.com/langchain-ai/langsmith-go/instrumentation/pricing"
+	"github.com/langchain-ai/langsmith-go/instrumentation/traceopenai"
+)
+
+// WithRunNameContext sets the span (run) name for the next traced request made with ctx.
+// The run name in LangSmith is the OTLP span name; there is no separate field.
+// Use this so one client can emit runs with different names per call:
+//
+//	ctx = traceopenaigo.WithRunNameContext(ctx, "summarize")
+//	client.Chat.Completions.New(ctx, ...)
+func WithRunNameContext(ctx context.Context, name string) context.Context {
+	return core.WithRunNameContext(ctx, name)
+}
+
+// Option configures the tracing middleware.
+type Option func(*core.Config)
+
+// WithTracerProvider returns an Option that sets the tracer provider.
+// If not provided, the global tracer provider is used.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(cfg *core.Config) {
+		cfg.TracerProvider = tp
+	}
+}
+
+// WithRunName sets the span (run) name to the given string when non-empty.
+func WithRunName(name string) Option {
+	return func(cfg *core.Config) {
+		cfg.RunName = name
+	}
+}
+
+// WithAttachments is [core.WithAttachments].
+func WithAttachments(h core.AttachmentHandler) Option { return Option(core.WithAttachments(h)) }
+
+// WithMaxAttachmentSize is [core.WithMaxAttachmentSize].
+func WithMaxAttachmentSize(n int) Option { return Option(core.WithMaxAttachmentSize(n)) }
+
+// WithCaptureContent is [core.WithCaptureContent].
+func WithCaptureContent(capture bool) Option { return Option(core.WithCaptureContent(capture)) }
+
+// WithRedactor is [core.WithRedactor].
+func WithRedactor(r core.Redactor) Option { return Option(core.WithRedactor(r)) }
+
+// WithMaxContentLength is [core.WithMaxContentLength].
+func WithMaxContentLength(n int) Option { return Option(core.WithMaxContentLength(n)) }
+
+// WithPricing is [core.WithPricing].
+func WithPricing(prices *pricing.Table) Option { return Option(core.WithPricing(prices)) }
+
+// EndPendingRetries is [core.EndPendingRetries]. A run whose last attempt
+// failed stays open until the SDK's retry window passes; call this before
+// shutting down your tracer provider so it is exported. The LangSmith
+// OTelTracer's Shutdown calls it for you.
+func EndPendingRetries() { core.EndPendingRetries() }
+
+// WithProviderName records name as the span's provider (gen_ai.system and
+// gen_ai.provider.name) instead of "openai". Use it when pointing the client
+// at an OpenAI-compatible server such as vLLM, llama.cpp or Ollama's /v1
+// endpoints, so LangSmith attributes runs and model prices to the right
+// provider:
+//
+//	client := openai.NewClient(
+//		option.WithBaseURL("http://localhost:8000/v1"),
+//		option.WithMiddleware(traceopenaigo.Middleware(traceopenaigo.WithProviderName("vllm"))),
+//	)
+func WithProviderName(name string) Option {
+	return func(cfg *core.Config) {
+		cfg.ProviderName = name
+	}
+}
+
+// Middleware returns an openai-go middleware that traces OpenAI API
+// requests. Pass it to option.WithMiddleware, either when constructing the
+// client or on individual calls.
+func Middleware(opts ...Option) option.Middleware {
+	var cfg core.Config
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	p := provider{traceopenai.Provider()}
+	return func(req *http.Request, next option.MiddlewareNext) (*http.Response, error) {
+		return core.Middleware(req, next, p, cfg)
+	}
+}
+
+// provider reuses the traceopenai wire-format parsing, reporting spans under
+// the openai-go instrumentation scope.
+type provider struct {
+	core.Provider
+}
+
+func (provider) TracerName() string { return "github.com/openai/openai-go" }
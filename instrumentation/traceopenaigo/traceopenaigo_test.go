@@ -0,0 +1,312 @@
+package traceopenaigo
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/option"
+	"github.com/openai/openai-go/v3/responses"
+	"github.com/openai/openai-go/v3/shared"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+const chatCompletionBody = `{
+	"id": "chatcmpl-1",
+	"object": "chat.completion",
+	"created": 1700000000,
+	"model": "gpt-4o-mini",
+	"choices": [{"index": 0, "finish_reason": "stop", "message": {"role": "assistant", "content": "Hello there"}}],
+	"usage": {"prompt_tokens": 9, "completion_tokens": 2, "total_tokens": 11}
+}`
+
+const chatStreamBody = "data: {\"id\":\"c\",\"object\":\"chat.completion.chunk\",\"created\":1,\"model\":\"gpt-4o-mini\",\"choices\":[{\"index\":0,\"delta\":{\"role\":\"assistant\"}}]}\n\n" +
+	"data: {\"id\":\"c\",\"object\":\"chat.completion.chunk\",\"created\":1,\"model\":\"gpt-4o-mini\",\"choices\":[{\"index\":0,\"delta\":{\"content\":\"Hel\"}}]}\n\n" +
+	"data: {\"id\":\"c\",\"object\":\"chat.completion.chunk\",\"created\":1,\"model\":\"gpt-4o-mini\",\"choices\":[{\"index\":0,\"delta\":{\"content\":\"lo\"},\"finish_reason\":\"stop\"}]}\n\n" +
+	"data: {\"id\":\"c\",\"object\":\"chat.completion.chunk\",\"created\":1,\"model\":\"gpt-4o-mini\",\"choices\":[],\"usage\":{\"prompt_tokens\":4,\"completion_tokens\":2,\"total_tokens\":6}}\n\n" +
+	"data: [DONE]\n\n"
+
+const responsesBody = `{
+	"id": "resp_1",
+	"object": "response",
+	"created_at": 1700000000,
+	"model": "o4-mini",
+	"status": "completed",
+	"output": [
+		{"type": "reasoning", "id": "rs_1", "summary": [{"type": "summary_text", "text": "Need the weather tool"}]},
+		{"type": "function_call", "id": "fc_1", "call_id": "call_1", "name": "get_weather", "arguments": "{\"city\":\"Paris\"}", "status": "completed"}
+	],
+	"usage": {"input_tokens": 20, "input_tokens_details": {"cached_tokens": 0}, "output_tokens": 30, "output_tokens_details": {"reasoning_tokens": 16}, "total_tokens": 50}
+}`
+
+const embeddingsBody = `{
+	"object": "list",
+	"model": "text-embedding-3-small",
+	"data": [
+		{"object": "embedding", "index": 0, "embedding": [0.1, 0.2, 0.3]},
+		{"object": "embedding", "index": 1, "embedding": [0.4, 0.5, 0.6]}
+	],
+	"usage": {"prompt_tokens": 4, "total_tokens": 4}
+}`
+
+// newFakeServer serves canned OpenAI responses keyed by request path.
+func newFakeServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/chat/completions") && strings.Contains(string(body), `"stream":true`):
+			w.Header().Set("Content-Type", "text/event-stream")
+			io.WriteString(w, chatStreamBody)
+		case strings.HasSuffix(r.URL.Path, "/chat/completions") && strings.Contains(string(body), "fail"):
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			io.WriteString(w, `{"error":{"message":"bad request","type":"invalid_request_error"}}`)
+		case strings.HasSuffix(r.URL.Path, "/chat/completions"):
+			w.Header().Set("Content-Type", "application/json")
+			io.WriteString(w, chatCompletionBody)
+		case strings.HasSuffix(r.URL.Path, "/responses"):
+			w.Header().Set("Content-Type", "application/json")
+			io.WriteString(w, responsesBody)
+		case strings.HasSuffix(r.URL.Path, "/embeddings"):
+			w.Header().Set("Content-Type", "application/json")
+			io.WriteString(w, embeddingsBody)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func newTracedClient(t *testing.T, opts ...Option) (openai.Client, *tracetest.InMemoryExporter) {
+	t.Helper()
+	srv := newFakeServer(t)
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	client := openai.NewClient(
+		option.WithAPIKey("test-key"),
+		option.WithBaseURL(srv.URL+"/v1/"),
+		option.WithMaxRetries(0),
+		option.WithMiddleware(Middleware(append([]Option{WithTracerProvider(tp)}, opts...)...)),
+	)
+	return client, exporter
+}
+
+func onlySpan(t *testing.T, exporter *tracetest.InMemoryExporter) tracetest.SpanStub {
+	t.Helper()
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	return spans[0]
+}
+
+func getAttr(s tracetest.SpanStub, key string) (string, bool) {
+	for _, kv := range s.Attributes {
+		if string(kv.Key) == key {
+			return kv.Value.Emit(), true
+		}
+	}
+	return "", false
+}
+
+func TestChatCompletion(t *testing.T) {
+	client, exporter := newTracedClient(t)
+	_, err := client.Chat.Completions.New(context.Background(), openai.ChatCompletionNewParams{
+		Model:    openai.ChatModelGPT4oMini,
+		Messages: []openai.ChatCompletionMessageParamUnion{openai.UserMessage("Say hello")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	span := onlySpan(t, exporter)
+	if span.Name != "openai.chat.completion" {
+		t.Errorf("span name = %q, want openai.chat.completion", span.Name)
+	}
+	if span.InstrumentationScope.Name != "github.com/openai/openai-go" {
+		t.Errorf("scope = %q", span.InstrumentationScope.Name)
+	}
+	if span.Status.Code != codes.Ok {
+		t.Errorf("status = %v %q", span.Status.Code, span.Status.Description)
+	}
+	if v, _ := getAttr(span, "gen_ai.request.model"); v != "gpt-4o-mini" {
+		t.Errorf("gen_ai.request.model = %q", v)
+	}
+	if v, _ := getAttr(span, "gen_ai.prompt"); !strings.Contains(v, "Say hello") {
+		t.Errorf("gen_ai.prompt = %q", v)
+	}
+	if v, _ := getAttr(span, "gen_ai.completion"); !strings.Contains(v, "Hello there") {
+		t.Errorf("gen_ai.completion = %q", v)
+	}
+	if v, _ := getAttr(span, "gen_ai.usage.total_tokens"); v != "11" {
+		t.Errorf("gen_ai.usage.total_tokens = %q, want 11", v)
+	}
+}
+
+func TestChatCompletionStreaming(t *testing.T) {
+	client, exporter := newTracedClient(t)
+	stream := client.Chat.Completions.NewStreaming(context.Background(), openai.ChatCompletionNewParams{
+		Model:         openai.ChatModelGPT4oMini,
+		Messages:      []openai.ChatCompletionMessageParamUnion{openai.UserMessage("Say hello")},
+		StreamOptions: openai.ChatCompletionStreamOptionsParam{IncludeUsage: openai.Bool(true)},
+	})
+	var acc openai.ChatCompletionAccumulator
+	for stream.Next() {
+		acc.AddChunk(stream.Current())
+	}
+	if err := stream.Err(); err != nil {
+		t.Fatal(err)
+	}
+	stream.Close()
+
+	if got := acc.Choices[0].Message.Content; got != "Hello" {
+		t.Fatalf("client saw %q, want Hello", got)
+	}
+
+	span := onlySpan(t, exporter)
+	if span.Status.Code != codes.Ok {
+		t.Errorf("status = %v %q", span.Status.Code, span.Status.Description)
+	}
+	if v, _ := getAttr(span, "gen_ai.completion"); !strings.Contains(v, `"content":"Hello"`) {
+		t.Errorf("gen_ai.completion = %q", v)
+	}
+	if v, _ := getAttr(span, "gen_ai.usage.input_tokens"); v != "4" {
+		t.Errorf("gen_ai.usage.input_tokens = %q, want 4", v)
+	}
+	var newTokens int
+	for _, e := range span.Events {
+		if e.Name == "new_token" {
+			newTokens++
+		}
+	}
+	if newTokens != 1 {
+		t.Errorf("got %d new_token events, want 1", newTokens)
+	}
+}
+
+func TestResponsesToolCallAndReasoning(t *testing.T) {
+	client, exporter := newTracedClient(t)
+	_, err := client.Responses.New(context.Background(), responses.ResponseNewParams{
+		Model:        "o4-mini",
+		Instructions: openai.String("Be brief"),
+		Input:        responses.ResponseNewParamsInputUnion{OfString: openai.String("Weather in Paris?")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	span := onlySpan(t, exporter)
+	if span.Name != "openai.responses" {
+		t.Errorf("span name = %q, want openai.responses", span.Name)
+	}
+	prompt, _ := getAttr(span, "gen_ai.prompt")
+	if !strings.Contains(prompt, `"role":"system"`) || !strings.Contains(prompt, "Weather in Paris?") {
+		t.Errorf("gen_ai.prompt = %q", prompt)
+	}
+	completion, _ := getAttr(span, "gen_ai.completion")
+	for _, want := range []string{`"name":"get_weather"`, `"id":"call_1"`, `"reasoning":"Need the weather tool"`} {
+		if !strings.Contains(completion, want) {
+			t.Errorf("gen_ai.completion missing %s: %s", want, completion)
+		}
+	}
+	um, _ := getAttr(span, "langsmith.usage_metadata")
+	if !strings.Contains(um, `"reasoning":16`) {
+		t.Errorf("langsmith.usage_metadata = %s", um)
+	}
+}
+
+func TestEmbeddings(t *testing.T) {
+	client, exporter := newTracedClient(t)
+	_, err := client.Embeddings.New(context.Background(), openai.EmbeddingNewParams{
+		Model: openai.EmbeddingModelTextEmbedding3Small,
+		Input: openai.EmbeddingNewParamsInputUnion{OfArrayOfStrings: []string{"alpha", "beta"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	span := onlySpan(t, exporter)
+	if span.Name != "openai.embedding" {
+		t.Errorf("span name = %q, want openai.embedding", span.Name)
+	}
+	if v, _ := getAttr(span, "gen_ai.prompt"); v != `{"input":["alpha","beta"]}` {
+		t.Errorf("gen_ai.prompt = %q", v)
+	}
+	if v, _ := getAttr(span, "gen_ai.completion"); v != `{"dimensions":3,"embeddings":2}` {
+		t.Errorf("gen_ai.completion = %q", v)
+	}
+	if v, _ := getAttr(span, "gen_ai.usage.input_tokens"); v != "4" {
+		t.Errorf("gen_ai.usage.input_tokens = %q, want 4", v)
+	}
+}
+
+func TestStructuredOutput(t *testing.T) {
+	client, exporter := newTracedClient(t)
+	_, err := client.Chat.Completions.New(context.Background(), openai.ChatCompletionNewParams{
+		Model:    openai.ChatModelGPT4oMini,
+		Messages: []openai.ChatCompletionMessageParamUnion{openai.UserMessage("Extract")},
+		ResponseFormat: openai.ChatCompletionNewParamsResponseFormatUnion{
+			OfJSONSchema: &shared.ResponseFormatJSONSchemaParam{
+				JSONSchema: shared.ResponseFormatJSONSchemaJSONSchemaParam{
+					Name:   "person",
+					Schema: map[string]any{"type": "object"},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	span := onlySpan(t, exporter)
+	if v, _ := getAttr(span, "gen_ai.output.type"); v != "json" {
+		t.Errorf("gen_ai.output.type = %q, want json", v)
+	}
+	if v, _ := getAttr(span, "langsmith.metadata.structured_output_schema"); v != "person" {
+		t.Errorf("structured_output_schema = %q, want person", v)
+	}
+}
+
+func TestRunNameAndPerRequestMiddleware(t *testing.T) {
+	srv := newFakeServer(t)
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	client := openai.NewClient(option.WithAPIKey("k"), option.WithBaseURL(srv.URL+"/v1/"))
+
+	ctx := WithRunNameContext(context.Background(), "per_call")
+	_, err := client.Chat.Completions.New(ctx, openai.ChatCompletionNewParams{
+		Model:    openai.ChatModelGPT4oMini,
+		Messages: []openai.ChatCompletionMessageParamUnion{openai.UserMessage("hi")},
+	}, option.WithMiddleware(Middleware(WithTracerProvider(tp), WithRunName("from_option"))))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if span := onlySpan(t, exporter); span.Name != "per_call" {
+		t.Errorf("span name = %q, want per_call", span.Name)
+	}
+}
+
+func TestHTTPError(t *testing.T) {
+	client, exporter := newTracedClient(t)
+	_, err := client.Chat.Completions.New(context.Background(), openai.ChatCompletionNewParams{
+		Model:    openai.ChatModelGPT4oMini,
+		Messages: []openai.ChatCompletionMessageParamUnion{openai.UserMessage("fail")},
+	})
+	if err == nil {
+		t.Fatal("expected API error")
+	}
+
+	span := onlySpan(t, exporter)
+	if span.Status.Code != codes.Error || !strings.HasPrefix(span.Status.Description, "HTTP 400") {
+		t.Errorf("status = %v %q", span.Status.Code, span.Status.Description)
+	}
+}
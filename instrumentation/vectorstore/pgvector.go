@@ -0,0 +1,171 @@
+package vectorstore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/langchain-ai/langsmith-go/instrumentation"
+)
+
+// Distance is a pgvector distance operator. Only the operators declared
+// below are accepted.
+type Distance string
+
+const (
+	// DistanceCosine orders by cosine distance (1 - cosine similarity).
+	DistanceCosine Distance = "<=>"
+	// DistanceL2 orders by Euclidean distance.
+	DistanceL2 Distance = "<->"
+	// DistanceInnerProduct orders by negative inner product.
+	DistanceInnerProduct Distance = "<#>"
+	// DistanceL1 orders by taxicab distance.
+	DistanceL1 Distance = "<+>"
+)
+
+// valid reports whether d is one of pgvector's distance operators.
+func (d Distance) valid() bool {
+	switch d {
+	case DistanceCosine, DistanceL2, DistanceInnerProduct, DistanceL1:
+		return true
+	}
+	return false
+}
+
+// PGVector retrieves documents from a PostgreSQL table with a pgvector
+// column. The database driver is the caller's (pgx's stdlib, lib/pq, ...).
+// Each document's metadata includes the match's "distance".
+type PGVector struct {
+	// DB is the database holding Table.
+	DB *sql.DB
+
+	// Table is the table to search, optionally schema-qualified
+	// ("rag.documents").
+	Table string
+
+	// IDColumn, ContentColumn and EmbeddingColumn name the table's columns,
+	// defaulting to "id", "content" and "embedding". MetadataColumn names an
+	// optional json or jsonb column of document metadata.
+	IDColumn        string
+	ContentColumn   string
+	EmbeddingColumn string
+	MetadataColumn  string
+
+	// Distance is the distance operator to order by, DistanceCosine by
+	// default. It should match the column's index.
+	Distance Distance
+
+	// Embed embeds the query.
+	Embed Embedder
+
+	// K is the number of documents to retrieve, DefaultK if zero.
+	K int
+
+	// Options configure the retriever run.
+	Options []instrumentation.Option
+}
+
+// Retrieve returns the K rows nearest to query's embedding, nearest first.
+func (s *PGVector) Retrieve(ctx context.Context, query string) ([]instrumentation.Document, error) {
+	return traceSearch(ctx, "PGVector", s.Table, query, s.Options, func(ctx context.Context) ([]instrumentation.Document, error) {
+		if s.DB == nil || s.Embed == nil {
+			return nil, fmt.Errorf("vectorstore: PGVector requires DB and Embed")
+		}
+		q, err := s.query()
+		if err != nil {
+			return nil, err
+		}
+		vec, err := s.Embed(ctx, query)
+		if err != nil {
+			return nil, fmt.Errorf("vectorstore: embed query: %w", err)
+		}
+		rows, err := s.DB.QueryContext(ctx, q, vectorLiteral(vec), k(s.K))
+		if err != nil {
+			return nil, fmt.Errorf("vectorstore: pgvector query: %w", err)
+		}
+		defer rows.Close()
+
+		var docs []instrumentation.Document
+		for rows.Next() {
+			var (
+				id, content sql.NullString
+				metadata    sql.NullString
+				distance    float64
+			)
+			dest := []any{&id, &content, &distance}
+			if s.MetadataColumn != "" {
+				dest = append(dest, &metadata)
+			}
+			if err := rows.Scan(dest...); err != nil {
+				return nil, fmt.Errorf("vectorstore: pgvector scan: %w", err)
+			}
+			doc := instrumentation.Document{ID: id.String, PageContent: content.String, Metadata: map[string]any{}}
+			if metadata.Valid && metadata.String != "" {
+				if err := json.Unmarshal([]byte(metadata.String), &doc.Metadata); err != nil {
+					return nil, fmt.Errorf("vectorstore: pgvector metadata for %q: %w", id.String, err)
+				}
+			}
+			doc.Metadata["distance"] = distance
+			docs = append(docs, doc)
+		}
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("vectorstore: pgvector query: %w", err)
+		}
+		return docs, nil
+	})
+}
+
+// query builds the nearest-neighbour query; $1 is the query vector and $2
+// the row limit. The distance operator is part of the SQL text, so it must
+// be one of pgvector's.
+func (s *PGVector) query() (string, error) {
+	embedding := quoteIdent(or(s.EmbeddingColumn, "embedding"))
+	op := s.Distance
+	if op == "" {
+		op = DistanceCosine
+	}
+	if !op.valid() {
+		return "", fmt.Errorf("vectorstore: unknown pgvector distance operator %q", op)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "SELECT %s::text, %s, %s %s $1::vector",
+		quoteIdent(or(s.IDColumn, "id")), quoteIdent(or(s.ContentColumn, "content")), embedding, op)
+	if s.MetadataColumn != "" {
+		fmt.Fprintf(&b, ", %s::text", quoteIdent(s.MetadataColumn))
+	}
+	fmt.Fprintf(&b, " FROM %s ORDER BY %s %s $1::vector LIMIT $2", quoteIdent(s.Table), embedding, op)
+	return b.String(), nil
+}
+
+// quoteIdent quotes a possibly schema-qualified SQL identifier.
+func quoteIdent(name string) string {
+	parts := strings.Split(name, ".")
+	for i, p := range parts {
+		parts[i] = `"` + strings.ReplaceAll(p, `"`, `""`) + `"`
+	}
+	return strings.Join(parts, ".")
+}
+
+// vectorLiteral formats v in pgvector's text representation, "[1,2,3]".
+func vectorLiteral(v []float32) string {
+	var b strings.Builder
+	b.WriteByte('[')
+	for i, f := range v {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(strconv.FormatFloat(float64(f), 'g', -1, 32))
+	}
+	b.WriteByte(']')
+	return b.String()
+}
+
+func or(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
@@ -0,0 +1,104 @@
+package vectorstore
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/langchain-ai/langsmith-go/instrumentation"
+)
+
+// Qdrant retrieves documents from a Qdrant collection through its REST API.
+// Points are read in the payload layout LangChain writes: the text under
+// ContentKey and metadata under MetadataKey. Each document's metadata
+// includes the match's "score".
+type Qdrant struct {
+	// URL is the Qdrant server, e.g. "http://localhost:6333".
+	URL string
+
+	// Collection is the collection to search.
+	Collection string
+
+	// APIKey, if set, is sent in the api-key header.
+	APIKey string
+
+	// Client sends the requests, http.DefaultClient if nil.
+	Client *http.Client
+
+	// Embed embeds the query.
+	Embed Embedder
+
+	// K is the number of documents to retrieve, DefaultK if zero.
+	K int
+
+	// Filter is an optional Qdrant filter, e.g.
+	// {"must": [{"key": "metadata.lang", "match": {"value": "en"}}]}.
+	Filter map[string]any
+
+	// ContentKey and MetadataKey are the payload keys holding the document
+	// text and metadata, defaulting to "page_content" and "metadata". Points
+	// without a MetadataKey object use the rest of the payload as metadata.
+	ContentKey  string
+	MetadataKey string
+
+	// Options configure the retriever run.
+	Options []instrumentation.Option
+}
+
+type qdrantResponse struct {
+	Result struct {
+		Points []struct {
+			ID      any            `json:"id"`
+			Score   float64        `json:"score"`
+			Payload map[string]any `json:"payload"`
+		} `json:"points"`
+	} `json:"result"`
+}
+
+// Retrieve returns the K points nearest to query's embedding, best first.
+func (s *Qdrant) Retrieve(ctx context.Context, query string) ([]instrumentation.Document, error) {
+	return traceSearch(ctx, "Qdrant", s.Collection, query, s.Options, func(ctx context.Context) ([]instrumentation.Document, error) {
+		if s.Embed == nil {
+			return nil, fmt.Errorf("vectorstore: Qdrant requires Embed")
+		}
+		vec, err := s.Embed(ctx, query)
+		if err != nil {
+			return nil, fmt.Errorf("vectorstore: embed query: %w", err)
+		}
+		body := map[string]any{"query": vec, "limit": k(s.K), "with_payload": true}
+		if s.Filter != nil {
+			body["filter"] = s.Filter
+		}
+		header := http.Header{}
+		if s.APIKey != "" {
+			header.Set("api-key", s.APIKey)
+		}
+		endpoint := strings.TrimSuffix(s.URL, "/") + "/collections/" + url.PathEscape(s.Collection) + "/points/query"
+		var resp qdrantResponse
+		if err := postJSON(ctx, s.Client, endpoint, header, body, &resp); err != nil {
+			return nil, fmt.Errorf("vectorstore: qdrant query: %w", err)
+		}
+
+		contentKey, metadataKey := or(s.ContentKey, "page_content"), or(s.MetadataKey, "metadata")
+		docs := make([]instrumentation.Document, 0, len(resp.Result.Points))
+		for _, p := range resp.Result.Points {
+			doc := instrumentation.Document{ID: fmt.Sprint(p.ID)}
+			doc.PageContent, _ = p.Payload[contentKey].(string)
+			if m, ok := p.Payload[metadataKey].(map[string]any); ok {
+				doc.Metadata = m
+			} else {
+				doc.Metadata = make(map[string]any, len(p.Payload))
+				for k, v := range p.Payload {
+					if k != contentKey {
+						doc.Metadata[k] = v
+					}
+				}
+			}
+			doc.Metadata["score"] = p.Score
+			docs = append(docs, doc)
+		}
+		return docs, nil
+	})
+}
@@ -0,0 +1,100 @@
+// Package vectorstore provides retrievers over common vector databases that
+// record each search as a LangSmith retriever run (see
+// instrumentation.TraceRetriever): pgvector through database/sql, and the
+// Qdrant and Weaviate HTTP APIs.
+//
+// Each retriever embeds the query with an Embedder, searches for the K
+// nearest documents and returns them as instrumentation.Document values,
+// with the match's score or distance in the document metadata:
+//
+//	store := &vectorstore.PGVector{
+//		DB:             db,
+//		Table:          "documents",
+//		MetadataColumn: "metadata",
+//		Embed:          embed, // e.g. an OpenAI embeddings call
+//	}
+//	docs, err := store.Retrieve(ctx, "How do I rotate API keys?")
+//
+// An embedding call traced by an instrumentation package appears as a child
+// of the retriever run.
+package vectorstore
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+
+	"github.com/langchain-ai/langsmith-go/instrumentation"
+	"github.com/langchain-ai/langsmith-go/internal/genaiattr"
+)
+
+// DefaultK is the number of documents retrieved when K is not set.
+const DefaultK = 4
+
+// Embedder returns the embedding vector of text.
+type Embedder func(ctx context.Context, text string) ([]float32, error)
+
+// Retriever returns the documents most relevant to a query.
+type Retriever interface {
+	Retrieve(ctx context.Context, query string) ([]instrumentation.Document, error)
+}
+
+// traceSearch runs search as a retriever run named after the store, tagged
+// with the metadata LangChain records on vector store retrievers. opts are
+// the caller's options and take precedence.
+func traceSearch(ctx context.Context, store, collection, query string, opts []instrumentation.Option,
+	search func(context.Context) ([]instrumentation.Document, error)) ([]instrumentation.Document, error) {
+	base := []instrumentation.Option{
+		instrumentation.WithRunName(store),
+		instrumentation.WithAttributes(
+			genaiattr.RetrieverNameKey.String("vectorstore"),
+			genaiattr.VectorStoreProviderKey.String(store),
+			semconv.DBCollectionName(collection),
+		),
+	}
+	return instrumentation.TraceRetriever(ctx, query, search, append(base, opts...)...)
+}
+
+func k(n int) int {
+	if n <= 0 {
+		return DefaultK
+	}
+	return n
+}
+
+// postJSON sends body as JSON to url and decodes the JSON response into out.
+func postJSON(ctx context.Context, client *http.Client, url string, header http.Header, body, out any) error {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, data)
+	}
+	return json.Unmarshal(data, out)
+}
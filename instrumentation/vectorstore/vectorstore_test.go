@@ -0,0 +1,277 @@
+package vectorstore
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/langchain-ai/langsmith-go/instrumentation"
+)
+
+func newExporter() (*tracetest.InMemoryExporter, []instrumentation.Option) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	return exporter, []instrumentation.Option{instrumentation.WithTracerProvider(tp)}
+}
+
+func getAttr(span tracetest.SpanStub, key string) string {
+	for _, kv := range span.Attributes {
+		if string(kv.Key) == key {
+			return kv.Value.Emit()
+		}
+	}
+	return ""
+}
+
+func embed(_ context.Context, text string) ([]float32, error) {
+	return []float32{0.5, -1, 0.25}, nil
+}
+
+// checkRun verifies the single retriever run recorded by a store.
+func checkRun(t *testing.T, exporter *tracetest.InMemoryExporter, name, collection string) tracetest.SpanStub {
+	t.Helper()
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	span := spans[0]
+	if span.Name != name {
+		t.Errorf("name = %q, want %q", span.Name, name)
+	}
+	if got := getAttr(span, "langsmith.span.kind"); got != "retriever" {
+		t.Errorf("span kind = %q, want retriever", got)
+	}
+	if got := getAttr(span, "langsmith.metadata.ls_vector_store_provider"); got != name {
+		t.Errorf("ls_vector_store_provider = %q, want %q", got, name)
+	}
+	if got := getAttr(span, "langsmith.metadata.ls_retriever_name"); got != "vectorstore" {
+		t.Errorf("ls_retriever_name = %q, want vectorstore", got)
+	}
+	if got := getAttr(span, "db.collection.name"); got != collection {
+		t.Errorf("db.collection.name = %q, want %q", got, collection)
+	}
+	return span
+}
+
+// fakeDriver answers every query with its rows and records the query.
+type fakeDriver struct {
+	mu    sync.Mutex
+	query string
+	args  []driver.Value
+	rows  [][]driver.Value
+}
+
+func (d *fakeDriver) Open(string) (driver.Conn, error) { return fakeConn{d}, nil }
+
+type fakeConn struct{ d *fakeDriver }
+
+func (c fakeConn) Prepare(query string) (driver.Stmt, error) { return fakeStmt{c.d, query}, nil }
+func (c fakeConn) Close() error                              { return nil }
+func (c fakeConn) Begin() (driver.Tx, error)                 { return nil, driver.ErrSkip }
+
+type fakeStmt struct {
+	d     *fakeDriver
+	query string
+}
+
+func (s fakeStmt) Close() error                               { return nil }
+func (s fakeStmt) NumInput() int                              { return -1 }
+func (s fakeStmt) Exec([]driver.Value) (driver.Result, error) { return nil, driver.ErrSkip }
+func (s fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	s.d.query, s.d.args = s.query, args
+	return &fakeRows{rows: s.d.rows}, nil
+}
+
+type fakeRows struct{ rows [][]driver.Value }
+
+func (r *fakeRows) Columns() []string { return []string{"id", "content", "distance", "metadata"} }
+func (r *fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+func TestPGVector_Retrieve(t *testing.T) {
+	d := &fakeDriver{rows: [][]driver.Value{
+		{"1", "Rotate keys in settings.", 0.125, `{"source":"faq.md"}`},
+		{"2", "Keys expire after 90 days.", 0.5, nil},
+	}}
+	sql.Register("fake-pgvector", d)
+	db, err := sql.Open("fake-pgvector", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	exporter, opts := newExporter()
+	store := &PGVector{DB: db, Table: "rag.documents", MetadataColumn: "metadata", Embed: embed, K: 2, Options: opts}
+	docs, err := store.Retrieve(context.Background(), "rotate keys")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantQuery := `SELECT "id"::text, "content", "embedding" <=> $1::vector, "metadata"::text FROM "rag"."documents" ORDER BY "embedding" <=> $1::vector LIMIT $2`
+	if d.query != wantQuery {
+		t.Errorf("query = %s\nwant    %s", d.query, wantQuery)
+	}
+	if len(d.args) != 2 || d.args[0] != "[0.5,-1,0.25]" || d.args[1] != int64(2) {
+		t.Errorf("args = %v", d.args)
+	}
+	if len(docs) != 2 {
+		t.Fatalf("got %d docs, want 2", len(docs))
+	}
+	if docs[0].ID != "1" || docs[0].PageContent != "Rotate keys in settings." ||
+		docs[0].Metadata["source"] != "faq.md" || docs[0].Metadata["distance"] != 0.125 {
+		t.Errorf("docs[0] = %+v", docs[0])
+	}
+	if docs[1].Metadata["distance"] != 0.5 || len(docs[1].Metadata) != 1 {
+		t.Errorf("docs[1] = %+v", docs[1])
+	}
+	checkRun(t, exporter, "PGVector", "rag.documents")
+}
+
+func TestPGVector_QueryOptions(t *testing.T) {
+	store := &PGVector{Table: `odd"name`, IDColumn: "uuid", ContentColumn: "document", EmbeddingColumn: "vec", Distance: DistanceInnerProduct}
+	want := `SELECT "uuid"::text, "document", "vec" <#> $1::vector FROM "odd""name" ORDER BY "vec" <#> $1::vector LIMIT $2`
+	if got, err := store.query(); err != nil || got != want {
+		t.Errorf("query = %s, %v\nwant    %s", got, err, want)
+	}
+}
+
+func TestPGVector_RejectsUnknownDistance(t *testing.T) {
+	store := &PGVector{Table: "docs", Distance: "<=> $1::vector; DROP TABLE docs; --"}
+	if q, err := store.query(); err == nil {
+		t.Errorf("query = %s, want an error", q)
+	}
+}
+
+func TestQdrant_Retrieve(t *testing.T) {
+	var got struct {
+		path, apiKey string
+		body         map[string]any
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got.path, got.apiKey = r.URL.Path, r.Header.Get("api-key")
+		json.NewDecoder(r.Body).Decode(&got.body)
+		io.WriteString(w, `{"status":"ok","result":{"points":[
+			{"id":"a1","score":0.92,"payload":{"page_content":"Rotate keys in settings.","metadata":{"source":"faq.md"}}},
+			{"id":7,"score":0.5,"payload":{"page_content":"Flat payload.","lang":"en"}}
+		]}}`)
+	}))
+	defer srv.Close()
+
+	exporter, opts := newExporter()
+	store := &Qdrant{URL: srv.URL, Collection: "docs", APIKey: "secret", Embed: embed,
+		Filter: map[string]any{"must": []any{}}, Options: opts}
+	docs, err := store.Retrieve(context.Background(), "rotate keys")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.path != "/collections/docs/points/query" || got.apiKey != "secret" {
+		t.Errorf("request = %s api-key=%q", got.path, got.apiKey)
+	}
+	if got.body["limit"] != float64(DefaultK) || got.body["with_payload"] != true || got.body["filter"] == nil {
+		t.Errorf("body = %v", got.body)
+	}
+	if len(docs) != 2 {
+		t.Fatalf("got %d docs, want 2", len(docs))
+	}
+	if docs[0].ID != "a1" || docs[0].PageContent != "Rotate keys in settings." ||
+		docs[0].Metadata["source"] != "faq.md" || docs[0].Metadata["score"] != 0.92 {
+		t.Errorf("docs[0] = %+v", docs[0])
+	}
+	if docs[1].ID != "7" || docs[1].Metadata["lang"] != "en" || docs[1].Metadata["page_content"] != nil {
+		t.Errorf("docs[1] = %+v", docs[1])
+	}
+	checkRun(t, exporter, "Qdrant", "docs")
+}
+
+func TestQdrant_HTTPError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		io.WriteString(w, `{"status":{"error":"Collection missing"}}`)
+	}))
+	defer srv.Close()
+
+	exporter, opts := newExporter()
+	store := &Qdrant{URL: srv.URL, Collection: "missing", Embed: embed, Options: opts}
+	_, err := store.Retrieve(context.Background(), "q")
+	if err == nil || !strings.Contains(err.Error(), "Collection missing") {
+		t.Fatalf("err = %v", err)
+	}
+	if span := exporter.GetSpans()[0]; span.Status.Description != err.Error() {
+		t.Errorf("status = %q, want %q", span.Status.Description, err)
+	}
+}
+
+func TestWeaviate_Retrieve(t *testing.T) {
+	var gql, auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct{ Query string }
+		json.NewDecoder(r.Body).Decode(&body)
+		gql, auth = body.Query, r.Header.Get("Authorization")
+		io.WriteString(w, `{"data":{"Get":{"Doc":[
+			{"text":"Rotate keys in settings.","source":"faq.md","_additional":{"id":"uuid-1","distance":0.1}}
+		]}}}`)
+	}))
+	defer srv.Close()
+
+	exporter, opts := newExporter()
+	store := &Weaviate{URL: srv.URL, Class: "Doc", APIKey: "secret", Embed: embed, K: 3,
+		MetadataProperties: []string{"source"}, Options: opts}
+	docs, err := store.Retrieve(context.Background(), "rotate keys")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantGQL := `{Get {Doc(nearVector: {vector: [0.5,-1,0.25]}, limit: 3) {text source _additional {id distance}}}}`
+	if gql != wantGQL || auth != "Bearer secret" {
+		t.Errorf("query = %s (auth %q)\nwant    %s", gql, auth, wantGQL)
+	}
+	if len(docs) != 1 || docs[0].ID != "uuid-1" || docs[0].PageContent != "Rotate keys in settings." ||
+		docs[0].Metadata["source"] != "faq.md" || docs[0].Metadata["distance"] != 0.1 {
+		t.Errorf("docs = %+v", docs)
+	}
+	checkRun(t, exporter, "Weaviate", "Doc")
+}
+
+func TestWeaviate_NearTextAndErrors(t *testing.T) {
+	var gql string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct{ Query string }
+		json.NewDecoder(r.Body).Decode(&body)
+		gql = body.Query
+		io.WriteString(w, `{"errors":[{"message":"no vectorizer configured"}]}`)
+	}))
+	defer srv.Close()
+
+	_, opts := newExporter()
+	store := &Weaviate{URL: srv.URL, Class: "Doc", Options: opts}
+	_, err := store.Retrieve(context.Background(), `say "hi"`)
+	if err == nil || !strings.Contains(err.Error(), "no vectorizer configured") {
+		t.Fatalf("err = %v", err)
+	}
+	if !strings.Contains(gql, `nearText: {concepts: ["say \"hi\""]}`) {
+		t.Errorf("query = %s", gql)
+	}
+
+	store.MetadataProperties = []string{"source } bad"}
+	if _, err := store.Retrieve(context.Background(), "q"); err == nil || !strings.Contains(err.Error(), "invalid") {
+		t.Errorf("invalid property: err = %v", err)
+	}
+}
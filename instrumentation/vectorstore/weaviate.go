@@ -0,0 +1,116 @@
+package vectorstore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/langchain-ai/langsmith-go/instrumentation"
+)
+
+// Weaviate retrieves objects of a Weaviate class through its GraphQL API.
+// Each document's metadata holds MetadataProperties and the match's
+// "distance".
+type Weaviate struct {
+	// URL is the Weaviate server, e.g. "http://localhost:8080".
+	URL string
+
+	// Class is the class (collection) to search.
+	Class string
+
+	// APIKey, if set, is sent as a bearer token.
+	APIKey string
+
+	// Client sends the requests, http.DefaultClient if nil.
+	Client *http.Client
+
+	// Embed embeds the query for a nearVector search. If nil, the query is
+	// sent as a nearText search for the class's vectorizer module to embed.
+	Embed Embedder
+
+	// K is the number of documents to retrieve, DefaultK if zero.
+	K int
+
+	// TextProperty holds the document text, "text" by default.
+	TextProperty string
+
+	// MetadataProperties are the properties returned as document metadata.
+	MetadataProperties []string
+
+	// Options configure the retriever run.
+	Options []instrumentation.Option
+}
+
+// graphQLName matches valid GraphQL names; class and property names are
+// interpolated into the query, so anything else is rejected.
+var graphQLName = regexp.MustCompile(`^[_A-Za-z][_0-9A-Za-z]*$`)
+
+type weaviateResponse struct {
+	Data struct {
+		Get map[string][]map[string]any `json:"Get"`
+	} `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+// Retrieve returns the K objects nearest to query, nearest first.
+func (s *Weaviate) Retrieve(ctx context.Context, query string) ([]instrumentation.Document, error) {
+	return traceSearch(ctx, "Weaviate", s.Class, query, s.Options, func(ctx context.Context) ([]instrumentation.Document, error) {
+		textProp := or(s.TextProperty, "text")
+		for _, name := range append([]string{s.Class, textProp}, s.MetadataProperties...) {
+			if !graphQLName.MatchString(name) {
+				return nil, fmt.Errorf("vectorstore: invalid Weaviate class or property name %q", name)
+			}
+		}
+		var near string
+		if s.Embed != nil {
+			vec, err := s.Embed(ctx, query)
+			if err != nil {
+				return nil, fmt.Errorf("vectorstore: embed query: %w", err)
+			}
+			b, _ := json.Marshal(vec)
+			near = "nearVector: {vector: " + string(b) + "}"
+		} else {
+			b, _ := json.Marshal(query)
+			near = "nearText: {concepts: [" + string(b) + "]}"
+		}
+		fields := strings.Join(append([]string{textProp}, s.MetadataProperties...), " ")
+		gql := fmt.Sprintf("{Get {%s(%s, limit: %d) {%s _additional {id distance}}}}", s.Class, near, k(s.K), fields)
+
+		header := http.Header{}
+		if s.APIKey != "" {
+			header.Set("Authorization", "Bearer "+s.APIKey)
+		}
+		var resp weaviateResponse
+		if err := postJSON(ctx, s.Client, strings.TrimSuffix(s.URL, "/")+"/v1/graphql", header, map[string]string{"query": gql}, &resp); err != nil {
+			return nil, fmt.Errorf("vectorstore: weaviate query: %w", err)
+		}
+		if len(resp.Errors) > 0 {
+			return nil, fmt.Errorf("vectorstore: weaviate query: %s", resp.Errors[0].Message)
+		}
+
+		objects := resp.Data.Get[s.Class]
+		docs := make([]instrumentation.Document, 0, len(objects))
+		for _, obj := range objects {
+			doc := instrumentation.Document{Metadata: map[string]any{}}
+			doc.PageContent, _ = obj[textProp].(string)
+			for _, p := range s.MetadataProperties {
+				if v, ok := obj[p]; ok {
+					doc.Metadata[p] = v
+				}
+			}
+			if add, ok := obj["_additional"].(map[string]any); ok {
+				doc.ID, _ = add["id"].(string)
+				if d, ok := add["distance"].(float64); ok {
+					doc.Metadata["distance"] = d
+				}
+			}
+			docs = append(docs, doc)
+		}
+		return docs, nil
+	})
+}
@@ -52,6 +52,14 @@ const (
 	UsageMetadataKey = attribute.Key("langsmith.usage_metadata")
 )
 
+// LangSmith run attribute keys.
+const (
+	// SpanKindKey sets the LangSmith run type (llm, chain, tool, retriever,
+	// embedding, prompt, parser). Spans without it are typed from
+	// gen_ai.operation.name, which has no value for every run type.
+	SpanKindKey = attribute.Key("langsmith.span.kind")
+)
+
 // HTTP semantic convention attribute keys.
 const (
 	HTTPMethodKey = attribute.Key("http.method")
@@ -77,6 +85,31 @@ const (
 	// token count, so it lives in metadata rather than usage_metadata.
 	SpeedKey = attribute.Key("langsmith.metadata.speed")
 
+	// PriceTableVersionKey records the version of the client-side price
+	// table that produced a run's costs.
+	PriceTableVersionKey = attribute.Key("langsmith.metadata.price_table_version")
+
+	// AttemptKey numbers an HTTP attempt span (1-based) under a retried
+	// model call, and AttemptsKey counts the attempts on the call's run.
+	AttemptKey  = attribute.Key("langsmith.metadata.attempt")
+	AttemptsKey = attribute.Key("langsmith.metadata.attempts")
+
+	// RetryAfterKey records the Retry-After delay, in seconds, a failed
+	// attempt's response asked for.
+	RetryAfterKey = attribute.Key("langsmith.metadata.retry_after_seconds")
+
+	// FallbackIndexKey is the position of the candidate a model call was
+	// made by in a fallback chain (0 for the primary), and FallbackFromKey
+	// names the candidate that failed before it.
+	FallbackIndexKey = attribute.Key("langsmith.metadata.fallback_index")
+	FallbackFromKey  = attribute.Key("langsmith.metadata.fallback_from")
+
+	// RetrieverNameKey and VectorStoreProviderKey are the metadata LangChain
+	// records on vector store retriever runs (ls_retriever_name "vectorstore"
+	// and the store, e.g. "PGVector").
+	RetrieverNameKey       = attribute.Key("langsmith.metadata.ls_retriever_name")
+	VectorStoreProviderKey = attribute.Key("langsmith.metadata.ls_vector_store_provider")
+
 	// ServerToolUseMetadataKeyPrefix is prefixed to server-side tool request
 	// counts (e.g. web_search_requests). These are billed on a separate
 	// dimension from tokens, so they are recorded in metadata.
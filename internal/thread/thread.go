@@ -0,0 +1,83 @@
+// Package thread carries LangSmith thread (conversation) IDs on a context as
+// OpenTelemetry baggage, and turns them into run metadata and span
+// attributes.
+package thread
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+)
+
+// Keys are the baggage members and run metadata keys LangSmith groups runs
+// into threads by.
+var Keys = []string{"session_id", "thread_id", "conversation_id"}
+
+// With returns a copy of ctx whose baggage carries threadID as thread_id.
+// An empty threadID removes it.
+func With(ctx context.Context, threadID string) context.Context {
+	bag := baggage.FromContext(ctx)
+	if threadID == "" {
+		return baggage.ContextWithBaggage(ctx, bag.DeleteMember("thread_id"))
+	}
+	m, err := baggage.NewMemberRaw("thread_id", threadID)
+	if err != nil {
+		return ctx
+	}
+	if bag, err = bag.SetMember(m); err != nil {
+		return ctx
+	}
+	return baggage.ContextWithBaggage(ctx, bag)
+}
+
+// ID returns the thread ID carried by ctx: its thread_id baggage member, or
+// else session_id or conversation_id.
+func ID(ctx context.Context) string {
+	md := Metadata(ctx)
+	for _, key := range []string{"thread_id", "session_id", "conversation_id"} {
+		if v := md[key]; v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// Metadata returns the thread members of ctx's baggage, keyed as run
+// metadata.
+func Metadata(ctx context.Context) map[string]string {
+	bag := baggage.FromContext(ctx)
+	var md map[string]string
+	for _, key := range Keys {
+		member := bag.Member(key)
+		if member.Key() != key {
+			continue
+		}
+		if md == nil {
+			md = make(map[string]string, len(Keys))
+		}
+		md[key] = member.Value()
+	}
+	return md
+}
+
+// Attributes returns ctx's thread metadata as span attributes. LangSmith
+// requires thread metadata on every span in a thread, including children,
+// so it is recorded in the standard (session_id), LangSmith metadata
+// (langsmith.metadata.session_id) and compatibility (session.id) formats.
+func Attributes(ctx context.Context) []attribute.KeyValue {
+	md := Metadata(ctx)
+	var attrs []attribute.KeyValue
+	for _, key := range Keys {
+		value, ok := md[key]
+		if !ok {
+			continue
+		}
+		attrs = append(attrs,
+			attribute.String(key, value),
+			attribute.String("langsmith.metadata."+key, value),
+			attribute.String(key[:len(key)-len("_id")]+".id", value),
+		)
+	}
+	return attrs
+}
@@ -0,0 +1,39 @@
+package traceutil
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// NDJSONScanner incrementally parses newline-delimited JSON objects, the
+// stream format of Ollama's native API. Blank and malformed lines are
+// skipped. Not safe for concurrent use.
+type NDJSONScanner struct {
+	onChunk func(map[string]any)
+	buf     bytes.Buffer
+}
+
+func NewNDJSONScanner(onChunk func(map[string]any)) *NDJSONScanner {
+	return &NDJSONScanner{onChunk: onChunk}
+}
+
+func (s *NDJSONScanner) Feed(p []byte) {
+	s.buf.Write(p)
+	for {
+		idx := bytes.IndexByte(s.buf.Bytes(), '\n')
+		if idx < 0 {
+			return
+		}
+		line := bytes.TrimSpace(s.buf.Next(idx + 1))
+		if len(line) == 0 {
+			continue
+		}
+		var chunk map[string]any
+		if err := json.Unmarshal(line, &chunk); err != nil {
+			continue
+		}
+		if s.onChunk != nil {
+			s.onChunk(chunk)
+		}
+	}
+}
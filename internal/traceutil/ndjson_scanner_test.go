@@ -0,0 +1,25 @@
+package traceutil
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNDJSONScanner_ParsesAcrossWriteBoundaries(t *testing.T) {
+	var got []map[string]any
+	s := NewNDJSONScanner(func(c map[string]any) { got = append(got, c) })
+
+	s.Feed([]byte("{\"a\""))
+	s.Feed([]byte(":1}\n\n"))
+	s.Feed([]byte("not json\r\n{\"b\":2}\r\n{\"c\":3}"))
+
+	// {"c":3} is held until its newline arrives.
+	want := []map[string]any{{"a": float64(1)}, {"b": float64(2)}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	s.Feed([]byte("\n"))
+	if len(got) != 3 {
+		t.Errorf("got %d chunks after final newline, want 3", len(got))
+	}
+}
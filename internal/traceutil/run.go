@@ -0,0 +1,25 @@
+package traceutil
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DottedOrder formats one segment of a run's dotted order: the run's start
+// time as YYYYMMDDTHHMMSSffffffZ followed by its ID. A root run's dotted
+// order is its segment; a child's is its parent's, a dot, and its own.
+func DottedOrder(t time.Time, id uuid.UUID) string {
+	t = t.UTC()
+	return fmt.Sprintf("%s%06dZ%s", t.Format("20060102T150405"), t.Nanosecond()/1000, id)
+}
+
+// EnvTruthy reports whether the environment variable is set to "true", "1"
+// or "yes".
+func EnvTruthy(key string) bool {
+	v := strings.ToLower(strings.TrimSpace(os.Getenv(key)))
+	return v == "true" || v == "1" || v == "yes"
+}
@@ -45,11 +45,23 @@ func (s *SSEScanner) handle(line string) {
 	}
 }
 
+// Scanner incrementally decodes a stream body fed to it in arbitrary pieces.
+type Scanner interface {
+	Feed(p []byte)
+}
+
 // OnFirstSSEMatch fires once on the first SSE chunk satisfying isMatch, then
 // detaches from br. Safe only when br is read by a single goroutine.
 func OnFirstSSEMatch(br *BufferedReader, isMatch func(map[string]any) bool, fire func()) {
+	OnFirstMatch(br, func(onChunk func(map[string]any)) Scanner { return NewSSEScanner(onChunk) }, isMatch, fire)
+}
+
+// OnFirstMatch is OnFirstSSEMatch for an arbitrary stream wire format:
+// newScanner builds an incremental decoder that reports each decoded event
+// to onChunk. Safe only when br is read by a single goroutine.
+func OnFirstMatch(br *BufferedReader, newScanner func(onChunk func(map[string]any)) Scanner, isMatch func(map[string]any) bool, fire func()) {
 	var fired bool
-	scanner := NewSSEScanner(func(chunk map[string]any) {
+	scanner := newScanner(func(chunk map[string]any) {
 		if fired || !isMatch(chunk) {
 			return
 		}
@@ -57,5 +69,5 @@ func OnFirstSSEMatch(br *BufferedReader, isMatch func(map[string]any) bool, fire
 		fire()
 		br.onBytes = nil
 	})
-	br.onBytes = func(b []byte) { scanner.Feed(b) }
+	br.onBytes = scanner.Feed
 }
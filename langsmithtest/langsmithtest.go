@@ -0,0 +1,386 @@
+// Package langsmithtest records Go tests as LangSmith evaluations, like the
+// pytest and vitest integrations of the other LangSmith SDKs. Each test case
+// run with [Run] becomes an example of a dataset named after the test's
+// package, and each `go test` invocation an experiment over that dataset,
+// with a run per case and a "pass" feedback that is false if the case
+// failed:
+//
+//	func TestMain(m *testing.M) { langsmithtest.Main(m) }
+//
+//	func TestAnswer(t *testing.T) {
+//		langsmithtest.Run(t, "capital of France", func(t *langsmithtest.T) {
+//			inputs := map[string]any{"question": "What is the capital of France?"}
+//			t.LogInputs(inputs)
+//			t.LogReferenceOutputs(map[string]any{"answer": "Paris"})
+//			answer, err := app.Answer(t.Context(), inputs["question"].(string))
+//			if err != nil {
+//				t.Fatal(err)
+//			}
+//			t.LogOutputs(map[string]any{"answer": answer})
+//			t.LogFeedback(langsmith.EvaluationResult{Key: "concise", Score: len(answer) < 100})
+//			if !strings.Contains(answer, "Paris") {
+//				t.Errorf("answer %q does not mention Paris", answer)
+//			}
+//		})
+//	}
+//
+// Cases skipped with t.Skip have no outcome and are not recorded. [Main]
+// ends the experiment and flushes its runs once the tests have run.
+//
+// Tests are recorded only when LANGSMITH_API_KEY is set and
+// LANGSMITH_TEST_TRACKING is not "false"; otherwise they run as plain tests.
+// LANGSMITH_TEST_SUITE overrides the dataset name and LANGSMITH_EXPERIMENT
+// the experiment name. Problems recording a case are logged with t.Logf and
+// do not fail it.
+package langsmithtest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"os"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/langchain-ai/langsmith-go"
+	"github.com/langchain-ai/langsmith-go/internal/traceutil"
+)
+
+// T is the testing.T of a case run with [Run], with methods to log what is
+// recorded in LangSmith.
+type T struct {
+	*testing.T
+
+	mu        sync.Mutex
+	inputs    map[string]any
+	outputs   map[string]any
+	reference map[string]any
+	feedback  []langsmith.EvaluationResult
+}
+
+// LogInputs sets the case's inputs, which are the example's inputs.
+func (t *T) LogInputs(inputs map[string]any) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.inputs = inputs
+}
+
+// LogOutputs sets the outputs of the case's run.
+func (t *T) LogOutputs(outputs map[string]any) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.outputs = outputs
+}
+
+// LogReferenceOutputs sets the example's reference outputs.
+func (t *T) LogReferenceOutputs(outputs map[string]any) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.reference = outputs
+}
+
+// LogFeedback adds feedback on the case's run, in addition to "pass".
+func (t *T) LogFeedback(results ...langsmith.EvaluationResult) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.feedback = append(t.feedback, results...)
+}
+
+// Suite records test cases as examples of a dataset and runs of an
+// experiment over it. The zero Suite is configured from the environment; the
+// package-level [Run] and [Main] use one for the whole test binary.
+type Suite struct {
+	// Name is the dataset's name. Defaults to LANGSMITH_TEST_SUITE or else
+	// the import path of the package of the first test run.
+	Name string
+	// Experiment is the experiment's name. Defaults to LANGSMITH_EXPERIMENT
+	// or else Name followed by a random suffix.
+	Experiment string
+	// Metadata is recorded on the experiment.
+	Metadata map[string]any
+	// Client records the suite. If nil, a client configured from the
+	// environment is created, and closed by [Suite.Close]. Setting Client
+	// records the suite regardless of the environment.
+	Client *langsmith.Client
+
+	once      sync.Once
+	err       error
+	client    *langsmith.Client
+	datasetID uuid.UUID
+	session   *langsmith.TracerSessionWithoutVirtualFields
+}
+
+var defaultSuite Suite
+
+// Run runs fn as a subtest of t named name, and records it in the suite of
+// the test binary. It reports whether the case passed.
+func Run(t *testing.T, name string, fn func(t *T)) bool {
+	t.Helper()
+	return defaultSuite.run(t, name, fn, 2)
+}
+
+// Main runs the tests and then ends the experiment and flushes its runs.
+// Call it from TestMain:
+//
+//	func TestMain(m *testing.M) { langsmithtest.Main(m) }
+func Main(m *testing.M) {
+	code := m.Run()
+	defaultSuite.Close()
+	os.Exit(code)
+}
+
+// Run runs fn as a subtest of t named name and records it in s. It reports
+// whether the case passed.
+func (s *Suite) Run(t *testing.T, name string, fn func(t *T)) bool {
+	t.Helper()
+	return s.run(t, name, fn, 2)
+}
+
+// run implements Run. skip is the number of frames between the test and run,
+// for naming the suite after the test's package.
+func (s *Suite) run(t *testing.T, name string, fn func(t *T), skip int) bool {
+	t.Helper()
+	pkg := callerPackage(skip)
+	return t.Run(name, func(tt *testing.T) {
+		lt := &T{T: tt}
+		start := time.Now()
+		defer func() {
+			r := recover()
+			failed := tt.Failed() || r != nil
+			// A skipped case didn't run, so it has no outcome to record.
+			skipped := tt.Skipped() && !failed
+			if s.tracking() && !skipped {
+				if err := s.record(tt.Context(), pkg, tt.Name(), lt, start, failed); err != nil {
+					tt.Logf("langsmith: %v", err)
+				}
+			}
+			if r != nil {
+				panic(r)
+			}
+		}()
+		fn(lt)
+	})
+}
+
+// Close ends the experiment and closes the client the suite created,
+// flushing its runs.
+func (s *Suite) Close() {
+	if s.session != nil {
+		// Ending the experiment is best effort: its runs are recorded.
+		_, _ = s.client.Sessions.Update(context.Background(), s.session.ID, langsmith.SessionUpdateParams{EndTime: langsmith.F(time.Now())})
+	}
+	if s.client != nil && s.Client == nil {
+		s.client.Close()
+	}
+}
+
+// tracking reports whether the suite records test cases.
+func (s *Suite) tracking() bool {
+	if s.Client != nil {
+		return true
+	}
+	return os.Getenv("LANGSMITH_TEST_TRACKING") != "false" && os.Getenv("LANGSMITH_API_KEY") != ""
+}
+
+// setup finds or creates the dataset and creates the experiment, once.
+func (s *Suite) setup(ctx context.Context, pkg string) error {
+	s.once.Do(func() {
+		s.client = s.Client
+		if s.client == nil {
+			s.client = langsmith.NewClient()
+		}
+		name := s.Name
+		if name == "" {
+			name = os.Getenv("LANGSMITH_TEST_SUITE")
+		}
+		if name == "" {
+			name = pkg
+		}
+		s.datasetID, s.err = s.dataset(ctx, name)
+		if s.err != nil {
+			return
+		}
+
+		experiment := s.Experiment
+		if experiment == "" {
+			experiment = os.Getenv("LANGSMITH_EXPERIMENT")
+		}
+		if experiment == "" {
+			experiment = name + "-" + uuid.NewString()[:8]
+		}
+		metadata := maps.Clone(s.Metadata)
+		if metadata == nil {
+			metadata = map[string]any{}
+		}
+		metadata["test_suite"] = true
+		s.session, s.err = s.client.Sessions.New(ctx, langsmith.SessionNewParams{
+			Name:               langsmith.F(experiment),
+			ReferenceDatasetID: langsmith.F(s.datasetID.String()),
+			StartTime:          langsmith.F(time.Now()),
+			Extra:              langsmith.F(map[string]any{"metadata": metadata}),
+		})
+		if s.err != nil {
+			s.err = fmt.Errorf("create experiment %q: %w", experiment, s.err)
+		}
+	})
+	return s.err
+}
+
+// dataset returns the ID of the dataset named name, creating it if needed.
+func (s *Suite) dataset(ctx context.Context, name string) (uuid.UUID, error) {
+	page, err := s.client.Datasets.List(ctx, langsmith.DatasetListParams{Name: langsmith.F(name), Limit: langsmith.F(int64(1))})
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("find dataset %q: %w", name, err)
+	}
+	if len(page.Items) > 0 {
+		return uuid.Parse(page.Items[0].ID)
+	}
+	dataset, err := s.client.Datasets.New(ctx, langsmith.DatasetNewParams{
+		Name:        langsmith.F(name),
+		Description: langsmith.F("Test cases of " + name),
+	})
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("create dataset %q: %w", name, err)
+	}
+	return uuid.Parse(dataset.ID)
+}
+
+// record syncs the case named name to its example and records its run and
+// feedback in the experiment.
+func (s *Suite) record(ctx context.Context, pkg, name string, t *T, start time.Time, failed bool) error {
+	end := time.Now()
+	if err := s.setup(ctx, pkg); err != nil {
+		return err
+	}
+	t.mu.Lock()
+	inputs, outputs, reference := t.inputs, t.outputs, t.reference
+	feedback := t.feedback
+	t.mu.Unlock()
+	if inputs == nil {
+		inputs = map[string]any{}
+	}
+
+	exampleID := uuid.NewSHA1(s.datasetID, []byte(name))
+	var errs []error
+	if err := s.syncExample(ctx, exampleID.String(), name, inputs, reference); err != nil {
+		errs = append(errs, err)
+	}
+
+	runID := uuid.New()
+	sessionID, err := uuid.Parse(s.session.ID)
+	if err != nil {
+		return fmt.Errorf("experiment ID: %w", err)
+	}
+	run := &langsmith.RunCreate{
+		ID:                 runID,
+		TraceID:            runID,
+		Name:               name,
+		RunType:            "chain",
+		Inputs:             inputs,
+		Outputs:            outputs,
+		StartTime:          start,
+		EndTime:            end,
+		DottedOrder:        traceutil.DottedOrder(start, runID),
+		SessionName:        s.session.Name,
+		SessionID:          &sessionID,
+		ReferenceExampleID: &exampleID,
+	}
+	if failed {
+		run.Error = "test failed"
+	} else if run.Outputs == nil {
+		run.Outputs = map[string]any{}
+	}
+	if err := s.client.CreateRunContext(ctx, run); err != nil {
+		errs = append(errs, fmt.Errorf("record run: %w", err))
+	}
+
+	view := langsmith.RunView{ID: runID.String(), TraceID: runID.String(), SessionID: s.session.ID}
+	for _, r := range append([]langsmith.EvaluationResult{{Key: "pass", Score: !failed}}, feedback...) {
+		fb, err := r.FeedbackParam(view)
+		if err == nil {
+			_, err = s.client.Feedback.New(ctx, langsmith.FeedbackNewParams{FeedbackCreateSchema: fb})
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("record feedback %q: %w", r.Key, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// syncExample creates the case's example, or updates it if its inputs or
+// reference outputs changed.
+func (s *Suite) syncExample(ctx context.Context, id, name string, inputs, reference map[string]any) error {
+	existing, err := s.client.Examples.Get(ctx, id, langsmith.ExampleGetParams{})
+	var apiErr *langsmith.Error
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+		params := langsmith.ExampleNewParams{
+			ID:        langsmith.F(id),
+			DatasetID: langsmith.F(s.datasetID.String()),
+			Inputs:    langsmith.F(inputs),
+			Metadata:  langsmith.F(map[string]any{"test_name": name}),
+		}
+		if reference != nil {
+			params.Outputs = langsmith.F(reference)
+		}
+		if _, err := s.client.Examples.New(ctx, params); err != nil {
+			return fmt.Errorf("create example: %w", err)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("get example: %w", err)
+	}
+	if sameJSON(existing.Inputs, inputs) && (reference == nil || sameJSON(existing.Outputs, reference)) {
+		return nil
+	}
+	params := langsmith.ExampleUpdateParams{Inputs: langsmith.F(inputs)}
+	if reference != nil {
+		params.Outputs = langsmith.F(reference)
+	}
+	if _, err := s.client.Examples.Update(ctx, id, params); err != nil {
+		return fmt.Errorf("update example: %w", err)
+	}
+	return nil
+}
+
+// sameJSON reports whether a and b have the same JSON encoding, as
+// decoded values.
+func sameJSON(a, b any) bool {
+	var x, y any
+	ab, err := json.Marshal(a)
+	if err != nil || json.Unmarshal(ab, &x) != nil {
+		return false
+	}
+	bb, err := json.Marshal(b)
+	if err != nil || json.Unmarshal(bb, &y) != nil {
+		return false
+	}
+	return reflect.DeepEqual(x, y)
+}
+
+// callerPackage returns the import path of the package of the function skip
+// frames above its caller, without the _test suffix of external tests.
+func callerPackage(skip int) string {
+	pc, _, _, ok := runtime.Caller(skip + 1)
+	if !ok {
+		return "tests"
+	}
+	fn := runtime.FuncForPC(pc).Name()
+	dir, base := "", fn
+	if i := strings.LastIndex(fn, "/"); i >= 0 {
+		dir, base = fn[:i+1], fn[i+1:]
+	}
+	if i := strings.Index(base, "."); i >= 0 {
+		base = base[:i]
+	}
+	return dir + strings.TrimSuffix(base, "_test")
+}
@@ -0,0 +1,219 @@
+package langsmithtest
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/langchain-ai/langsmith-go"
+	"github.com/langchain-ai/langsmith-go/option"
+)
+
+const (
+	datasetID = "00000000-0000-0000-0000-0000000000d1"
+	sessionID = "00000000-0000-0000-0000-0000000000e1"
+)
+
+// server fakes the LangSmith endpoints a suite uses, recording what it is
+// sent.
+type server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	datasets string
+	dataset  map[string]any
+	session  map[string]any
+	ended    bool
+	examples map[string]map[string]any
+	updates  []map[string]any
+	runs     []map[string]any
+	feedback []map[string]any
+}
+
+func newServer(t *testing.T) *server {
+	s := &server{examples: map[string]map[string]any{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v1/datasets":
+			s.datasets = r.URL.Query().Get("name")
+			_, _ = w.Write([]byte(`[]`))
+		case r.Method == http.MethodPost && r.URL.Path == "/api/v1/datasets":
+			_ = json.NewDecoder(r.Body).Decode(&s.dataset)
+			_ = json.NewEncoder(w).Encode(map[string]any{"id": datasetID, "name": s.dataset["name"], "tenant_id": datasetID})
+		case r.Method == http.MethodPost && r.URL.Path == "/api/v1/sessions":
+			_ = json.NewDecoder(r.Body).Decode(&s.session)
+			_ = json.NewEncoder(w).Encode(map[string]any{"id": sessionID, "name": s.session["name"], "tenant_id": sessionID})
+		case r.Method == http.MethodPatch && r.URL.Path == "/api/v1/sessions/"+sessionID:
+			s.ended = true
+			_, _ = w.Write([]byte(`{}`))
+		case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/api/v1/examples/"):
+			ex, ok := s.examples[strings.TrimPrefix(r.URL.Path, "/api/v1/examples/")]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = w.Write([]byte(`{"detail": "not found"}`))
+				return
+			}
+			_ = json.NewEncoder(w).Encode(ex)
+		case r.Method == http.MethodPost && r.URL.Path == "/api/v1/examples":
+			var ex map[string]any
+			_ = json.NewDecoder(r.Body).Decode(&ex)
+			s.examples[ex["id"].(string)] = ex
+			_ = json.NewEncoder(w).Encode(ex)
+		case r.Method == http.MethodPatch && strings.HasPrefix(r.URL.Path, "/api/v1/examples/"):
+			var update map[string]any
+			_ = json.NewDecoder(r.Body).Decode(&update)
+			s.updates = append(s.updates, update)
+			_, _ = w.Write([]byte(`{}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/api/v1/feedback":
+			var fb map[string]any
+			_ = json.NewDecoder(r.Body).Decode(&fb)
+			s.feedback = append(s.feedback, fb)
+			_, _ = w.Write([]byte(`{}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/runs/multipart":
+			_, params, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+			mr := multipart.NewReader(r.Body, params["boundary"])
+			for {
+				part, err := mr.NextPart()
+				if err != nil {
+					break
+				}
+				if name := part.FormName(); strings.HasPrefix(name, "post.") && strings.Count(name, ".") == 1 {
+					var run map[string]any
+					b, _ := io.ReadAll(part)
+					_ = json.Unmarshal(b, &run)
+					s.runs = append(s.runs, run)
+				}
+			}
+			w.WriteHeader(http.StatusAccepted)
+		default:
+			t.Errorf("unexpected %s %s", r.Method, r.URL.Path)
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TestSuite(t *testing.T) {
+	t.Setenv("LANGSMITH_DISABLE_RUN_COMPRESSION", "true")
+	srv := newServer(t)
+	client := langsmith.NewClient(option.WithBaseURL(srv.URL), option.WithAPIKey("test-api-key"), option.WithMaxRetries(0))
+	s := &Suite{Client: client, Experiment: "ci", Metadata: map[string]any{"commit": "abc"}}
+
+	passed := s.Run(t, "addition", func(t *T) {
+		t.LogInputs(map[string]any{"a": 1, "b": 2})
+		t.LogReferenceOutputs(map[string]any{"sum": 3})
+		t.LogOutputs(map[string]any{"sum": 3})
+		t.LogFeedback(langsmith.EvaluationResult{Key: "exact", Score: 1.0})
+	})
+	if !passed {
+		t.Fatal("case failed")
+	}
+	// The same case failing, with a new reference output.
+	lt := &T{T: t}
+	lt.LogInputs(map[string]any{"a": 1, "b": 2})
+	lt.LogReferenceOutputs(map[string]any{"sum": 4})
+	if err := s.record(context.Background(), "", "TestSuite/addition", lt, time.Now(), true); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+	client.Close()
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	const pkg = "github.com/langchain-ai/langsmith-go/langsmithtest"
+	if srv.datasets != pkg || srv.dataset["name"] != pkg {
+		t.Errorf("dataset %q created as %v, want it named %q", srv.datasets, srv.dataset, pkg)
+	}
+	if srv.session["name"] != "ci" || srv.session["reference_dataset_id"] != datasetID || !srv.ended {
+		t.Errorf("experiment = %v (ended %v)", srv.session, srv.ended)
+	}
+	if metadata, _ := srv.session["extra"].(map[string]any)["metadata"].(map[string]any); metadata["commit"] != "abc" || metadata["test_suite"] != true {
+		t.Errorf("experiment metadata = %v", metadata)
+	}
+	if len(srv.examples) != 1 {
+		t.Fatalf("got %d examples, want 1", len(srv.examples))
+	}
+	var exampleID string
+	for id, ex := range srv.examples {
+		exampleID = id
+		if ex["dataset_id"] != datasetID || ex["outputs"].(map[string]any)["sum"] != 3.0 {
+			t.Errorf("example = %v", ex)
+		}
+	}
+	if len(srv.updates) != 1 || srv.updates[0]["outputs"].(map[string]any)["sum"] != 4.0 {
+		t.Errorf("example updates = %v", srv.updates)
+	}
+	if len(srv.runs) != 2 {
+		t.Fatalf("got %d runs, want 2", len(srv.runs))
+	}
+	for _, run := range srv.runs {
+		if run["name"] != "TestSuite/addition" || run["reference_example_id"] != exampleID || run["session_id"] != sessionID {
+			t.Errorf("run = %v", run)
+		}
+	}
+	scores := map[string][]any{}
+	for _, fb := range srv.feedback {
+		scores[fb["key"].(string)] = append(scores[fb["key"].(string)], fb["score"])
+	}
+	if p := scores["pass"]; len(p) != 2 || p[0] != true || p[1] != false {
+		t.Errorf("pass feedback = %v", p)
+	}
+	if e := scores["exact"]; len(e) != 1 || e[0] != 1.0 {
+		t.Errorf("exact feedback = %v", e)
+	}
+}
+
+func TestSuiteSkippedCase(t *testing.T) {
+	t.Setenv("LANGSMITH_DISABLE_RUN_COMPRESSION", "true")
+	srv := newServer(t)
+	client := langsmith.NewClient(option.WithBaseURL(srv.URL), option.WithAPIKey("test-api-key"), option.WithMaxRetries(0))
+	s := &Suite{Client: client, Experiment: "ci"}
+
+	if !s.Run(t, "skipped", func(t *T) {
+		t.LogInputs(map[string]any{"a": 1})
+		t.Skip("not today")
+	}) {
+		t.Error("skipped case reported as failed")
+	}
+	s.Close()
+	client.Close()
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if len(srv.examples) != 0 || len(srv.runs) != 0 || len(srv.feedback) != 0 {
+		t.Errorf("skipped case recorded: examples %v, runs %v, feedback %v", srv.examples, srv.runs, srv.feedback)
+	}
+}
+
+func TestSuiteNotTracking(t *testing.T) {
+	t.Setenv("LANGSMITH_API_KEY", "")
+	var s Suite
+	ran := false
+	if !s.Run(t, "local", func(t *T) {
+		ran = true
+		t.LogOutputs(map[string]any{"ok": true})
+	}) || !ran {
+		t.Error("case did not run")
+	}
+	if s.client != nil {
+		t.Error("suite recorded the case without an API key")
+	}
+}
+
+func TestCallerPackage(t *testing.T) {
+	if got, want := callerPackage(0), "github.com/langchain-ai/langsmith-go/langsmithtest"; got != want {
+		t.Errorf("callerPackage = %q, want %q", got, want)
+	}
+}
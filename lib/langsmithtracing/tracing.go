@@ -12,6 +12,7 @@ import (
 
 	"github.com/google/uuid"
 
+	"github.com/langchain-ai/langsmith-go/internal/thread"
 	"github.com/langchain-ai/langsmith-go/lib/langsmithtracing/internal/env"
 	ilog "github.com/langchain-ai/langsmith-go/lib/langsmithtracing/internal/logger"
 	"github.com/langchain-ai/langsmith-go/lib/langsmithtracing/internal/models"
@@ -313,6 +314,32 @@ func (c *TracingClient) CreateRun(r *RunCreate) error {
 	return nil
 }
 
+// CreateRunContext is CreateRun for a run made on behalf of ctx: the thread
+// set on ctx with langsmith.WithThread (or thread_id, session_id or
+// conversation_id baggage) is added to the run's metadata, so the run is
+// grouped into that thread. Metadata already on the run takes precedence.
+func (c *TracingClient) CreateRunContext(ctx context.Context, r *RunCreate) error {
+	md := thread.Metadata(ctx)
+	if len(md) == 0 {
+		return c.CreateRun(r)
+	}
+	withThread := *r
+	withThread.Extra = make(map[string]any, len(r.Extra)+1)
+	for k, v := range r.Extra {
+		withThread.Extra[k] = v
+	}
+	oldMeta, _ := r.Extra["metadata"].(map[string]any)
+	metadata := make(map[string]any, len(md)+len(oldMeta))
+	for k, v := range md {
+		metadata[k] = v
+	}
+	for k, v := range oldMeta {
+		metadata[k] = v
+	}
+	withThread.Extra["metadata"] = metadata
+	return c.CreateRun(&withThread)
+}
+
 // UpdateRun enqueues a run update (patch) for multipart ingestion.
 // If the run's trace was sampled out during CreateRun, the update is dropped.
 func (c *TracingClient) UpdateRun(r *RunUpdate) error {
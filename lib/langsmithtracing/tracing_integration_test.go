@@ -18,6 +18,7 @@ import (
 
 	"github.com/klauspost/compress/zstd"
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/baggage"
 
 	"github.com/langchain-ai/langsmith-go/lib/langsmithtracing"
 )
@@ -1823,3 +1824,56 @@ func formatDottedOrder(t time.Time, id uuid.UUID) string {
 		id.String(),
 	)
 }
+
+// TestCreateRunContextAddsThread verifies that CreateRunContext records the
+// thread carried by ctx's baggage in the run's metadata, keeping metadata the
+// caller set.
+func TestCreateRunContextAddsThread(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	var mu sync.Mutex
+	var ops []langsmithtracing.RunOp
+	client := mustTracingClient(t, context.Background(),
+		langsmithtracing.WithAPIURL(srv.URL),
+		langsmithtracing.WithAPIKey("test-key"),
+		langsmithtracing.WithRunTransform(func(batch []langsmithtracing.RunOp) []langsmithtracing.RunOp {
+			mu.Lock()
+			ops = append(ops, batch...)
+			mu.Unlock()
+			return batch
+		}),
+	)
+
+	member, _ := baggage.NewMember("thread_id", "thread-1")
+	bag, _ := baggage.New(member)
+	ctx := baggage.ContextWithBaggage(context.Background(), bag)
+
+	id := uuid.New()
+	now := time.Now().UTC()
+	extra := map[string]any{"metadata": map[string]any{"user": "u-1"}}
+	if err := client.CreateRunContext(ctx, &langsmithtracing.RunCreate{
+		ID: id, TraceID: id, Name: "turn", RunType: "chain",
+		StartTime: now, EndTime: now, DottedOrder: formatDottedOrder(now, id),
+		Outputs: map[string]any{}, Extra: extra,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	client.Close()
+
+	if _, ok := extra["metadata"].(map[string]any)["thread_id"]; ok {
+		t.Error("CreateRunContext modified the caller's Extra")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(ops) != 1 {
+		t.Fatalf("got %d ops, want 1", len(ops))
+	}
+	extraOut, _ := ops[0].Data["extra"].(map[string]any)
+	metadata, _ := extraOut["metadata"].(map[string]any)
+	if metadata["thread_id"] != "thread-1" || metadata["user"] != "u-1" {
+		t.Errorf("metadata = %v, want thread_id and user", metadata)
+	}
+}
@@ -0,0 +1,301 @@
+package langsmith
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/langchain-ai/langsmith-go/internal/traceutil"
+	"github.com/langchain-ai/langsmith-go/shared"
+)
+
+// RunView is a run of the target as seen by evaluators.
+type RunView struct {
+	ID      string         `json:"id"`
+	TraceID string         `json:"trace_id"`
+	Name    string         `json:"name"`
+	RunType string         `json:"run_type"`
+	Inputs  map[string]any `json:"inputs"`
+	Outputs map[string]any `json:"outputs"`
+	// Error is the target's error message, or empty if it succeeded.
+	Error     string    `json:"error,omitempty"`
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+	// PromptTokens, CompletionTokens and TotalTokens are the run's token
+	// usage, when known. LangSmith totals them over the run's children, so
+	// they are set on runs read back from it, as by EvaluateExisting, but
+	// not on the runs Evaluate scores as the target returns.
+	PromptTokens     int64 `json:"prompt_tokens,omitempty"`
+	CompletionTokens int64 `json:"completion_tokens,omitempty"`
+	TotalTokens      int64 `json:"total_tokens,omitempty"`
+	// Metadata is the run's extra.metadata.
+	Metadata           map[string]any `json:"metadata,omitempty"`
+	ReferenceExampleID string         `json:"reference_example_id"`
+	// SessionID is the experiment the run belongs to.
+	SessionID string `json:"session_id"`
+}
+
+// runView returns the view of a run fetched from the API.
+func runView(r Run) RunView {
+	metadata := r.Metadata
+	if metadata == nil {
+		metadata, _ = r.Extra["metadata"].(map[string]any)
+	}
+	return RunView{
+		ID:                 r.ID,
+		TraceID:            r.TraceID,
+		Name:               r.Name,
+		RunType:            string(r.RunType),
+		Inputs:             r.Inputs,
+		Outputs:            r.Outputs,
+		Error:              r.Error,
+		StartTime:          r.StartTime,
+		EndTime:            r.EndTime,
+		PromptTokens:       r.PromptTokens,
+		CompletionTokens:   r.CompletionTokens,
+		TotalTokens:        r.TotalTokens,
+		Metadata:           metadata,
+		ReferenceExampleID: r.ReferenceExampleID,
+		SessionID:          r.ProjectID,
+	}
+}
+
+// EvaluationResult is one piece of feedback an evaluator produces for a run.
+// [EvaluationResult.FeedbackParam] maps it onto the feedback it records.
+type EvaluationResult struct {
+	// Key names the metric, e.g. "correctness".
+	Key string `json:"key"`
+	// Score is a number or a bool; nil records no score.
+	Score any `json:"score,omitempty"`
+	// Value is a non-numeric result such as a label or a map.
+	Value any `json:"value,omitempty"`
+	// Comment explains the result.
+	Comment string `json:"comment,omitempty"`
+	// Correction is what the output should have been, as a string or a map.
+	Correction any `json:"correction,omitempty"`
+	// SourceRunID is the run that produced the result, such as an LLM
+	// judge's call. When empty, [Evaluate] sets it to the evaluator's run.
+	SourceRunID string `json:"-"`
+	// FeedbackConfig describes the feedback key's scale or categories.
+	FeedbackConfig *FeedbackCreateSchemaFeedbackConfigParam `json:"-"`
+}
+
+// FeedbackParam returns the feedback that records r on run, attributed to
+// a model (evaluator) source linked to r.SourceRunID. For a run with no ID,
+// it returns feedback on the run's session (experiment) as a whole.
+func (r EvaluationResult) FeedbackParam(run RunView) (FeedbackCreateSchemaParam, error) {
+	if r.Key == "" {
+		return FeedbackCreateSchemaParam{}, errors.New("missing feedback key")
+	}
+	fb := FeedbackCreateSchemaParam{Key: F(r.Key)}
+	if run.ID != "" {
+		fb.RunID = F(run.ID)
+	}
+	if run.TraceID != "" {
+		fb.TraceID = F(run.TraceID)
+	}
+	if run.SessionID != "" {
+		fb.SessionID = F(run.SessionID)
+	}
+	if r.Comment != "" {
+		fb.Comment = F(r.Comment)
+	}
+	if r.Score != nil {
+		score, err := feedbackScore(r.Score)
+		if err != nil {
+			return FeedbackCreateSchemaParam{}, err
+		}
+		fb.Score = F(score)
+	}
+	if r.Value != nil {
+		fb.Value = F(feedbackValue(r.Value))
+	}
+	switch c := r.Correction.(type) {
+	case nil:
+	case string:
+		fb.Correction = F[FeedbackCreateSchemaCorrectionUnionParam](shared.UnionString(c))
+	case map[string]any:
+		fb.Correction = F[FeedbackCreateSchemaCorrectionUnionParam](FeedbackCreateSchemaCorrectionMapParam(c))
+	default:
+		return FeedbackCreateSchemaParam{}, fmt.Errorf("correction %v (%T) is not a string or map", c, c)
+	}
+	if r.FeedbackConfig != nil {
+		fb.FeedbackConfig = F(*r.FeedbackConfig)
+	}
+	source := ModelFeedbackSourceParam{Type: F(ModelFeedbackSourceTypeModel)}
+	if r.SourceRunID != "" {
+		source.Metadata = F(map[string]any{"__run": map[string]any{"run_id": r.SourceRunID}})
+	}
+	fb.FeedbackSource = F[FeedbackCreateSchemaFeedbackSourceUnionParam](source)
+	return fb, nil
+}
+
+// feedbackUpdate returns the update that sets an existing feedback's
+// score, value, comment and correction to r's.
+func (r EvaluationResult) feedbackUpdate() (FeedbackUpdateParams, error) {
+	update := FeedbackUpdateParams{Comment: F(r.Comment)}
+	if r.Score != nil {
+		score, err := feedbackScore(r.Score)
+		if err != nil {
+			return FeedbackUpdateParams{}, err
+		}
+		update.Score = F(score.(FeedbackUpdateParamsScoreUnion))
+	}
+	if r.Value != nil {
+		switch v := feedbackValue(r.Value).(type) {
+		case FeedbackCreateSchemaValueMapParam:
+			update.Value = F[FeedbackUpdateParamsValueUnion](FeedbackUpdateParamsValueMap(v))
+		default:
+			update.Value = F(v.(FeedbackUpdateParamsValueUnion))
+		}
+	}
+	switch c := r.Correction.(type) {
+	case nil:
+	case string:
+		update.Correction = F[FeedbackUpdateParamsCorrectionUnion](shared.UnionString(c))
+	case map[string]any:
+		update.Correction = F[FeedbackUpdateParamsCorrectionUnion](FeedbackUpdateParamsCorrectionMap(c))
+	default:
+		return FeedbackUpdateParams{}, fmt.Errorf("correction %v (%T) is not a string or map", c, c)
+	}
+	return update, nil
+}
+
+// feedbackNamespace is the UUID namespace of [feedbackID].
+var feedbackNamespace = uuid.MustParse("5a0c6c3e-1f0e-4b7e-9c55-0d2f3b8a6e41")
+
+// feedbackID returns the ID of the feedback keyed key on run, or on its
+// session for a run with no ID, so that scoring it again finds the feedback
+// already recorded.
+func feedbackID(run RunView, key string) string {
+	target := run.ID
+	if target == "" {
+		target = run.SessionID
+	}
+	return uuid.NewSHA1(feedbackNamespace, []byte(target+"/"+key)).String()
+}
+
+// feedbackScore converts an evaluator's score to the feedback score union.
+func feedbackScore(v any) (FeedbackCreateSchemaScoreUnionParam, error) {
+	switch s := v.(type) {
+	case bool:
+		return shared.UnionBool(s), nil
+	case float64:
+		return shared.UnionFloat(s), nil
+	case float32:
+		return shared.UnionFloat(s), nil
+	case int:
+		return shared.UnionFloat(s), nil
+	case int64:
+		return shared.UnionFloat(s), nil
+	case int32:
+		return shared.UnionFloat(s), nil
+	}
+	return nil, fmt.Errorf("score %v (%T) is not a number or bool", v, v)
+}
+
+// feedbackValue converts an evaluator's value to the feedback value union.
+func feedbackValue(v any) FeedbackCreateSchemaValueUnionParam {
+	switch val := v.(type) {
+	case string:
+		return shared.UnionString(val)
+	case map[string]any:
+		return FeedbackCreateSchemaValueMapParam(val)
+	}
+	if s, err := feedbackScore(v); err == nil {
+		return s.(FeedbackCreateSchemaValueUnionParam)
+	}
+	return shared.UnionString(fmt.Sprint(v))
+}
+
+// RunEvaluator scores a run of the target against the example it ran on.
+// An evaluator with a Name() string method is traced under that name.
+//
+// (The name Evaluator is taken by the online evaluator rules the API
+// returns.)
+type RunEvaluator interface {
+	Evaluate(ctx context.Context, run RunView, example *Example) ([]EvaluationResult, error)
+}
+
+// RunEvaluatorFunc adapts a function to a [RunEvaluator].
+type RunEvaluatorFunc func(ctx context.Context, run RunView, example *Example) ([]EvaluationResult, error)
+
+// Evaluate calls f(ctx, run, example).
+func (f RunEvaluatorFunc) Evaluate(ctx context.Context, run RunView, example *Example) ([]EvaluationResult, error) {
+	return f(ctx, run, example)
+}
+
+// NewRunEvaluator returns a [RunEvaluator] named name that calls fn.
+func NewRunEvaluator(name string, fn RunEvaluatorFunc) RunEvaluator {
+	return namedEvaluator{name, fn}
+}
+
+type namedEvaluator struct {
+	name string
+	RunEvaluatorFunc
+}
+
+func (e namedEvaluator) Name() string { return e.name }
+
+// evaluatorName returns the name ev, a run or summary evaluator, is traced
+// under.
+func evaluatorName(ev any) string {
+	if n, ok := ev.(interface{ Name() string }); ok && n.Name() != "" {
+		return n.Name()
+	}
+	return "evaluator"
+}
+
+// traceEvaluator runs ev on run as a root run in the project named project,
+// with the run and example as its inputs and the results as its outputs.
+// Results without a SourceRunID are attributed to the evaluator's run.
+func traceEvaluator(ctx context.Context, client *Client, project string, ev RunEvaluator, run RunView, example *Example) ([]EvaluationResult, error) {
+	inputs := map[string]any{"run": run, "example": example}
+	metadata := map[string]any{"target_run_id": run.ID}
+	return traceEvaluation(ctx, client, project, evaluatorName(ev), inputs, metadata, func(ctx context.Context) ([]EvaluationResult, error) {
+		return ev.Evaluate(ctx, run, example)
+	})
+}
+
+// traceEvaluation calls evaluate as a root run named name in the project
+// named project. Its context belongs to that run, so the evaluator can trace
+// its own calls under it with [StartChildRun]. Results without a
+// SourceRunID are attributed to the run.
+func traceEvaluation(ctx context.Context, client *Client, project, name string, inputs, metadata map[string]any, evaluate func(context.Context) ([]EvaluationResult, error)) ([]EvaluationResult, error) {
+	id := uuid.New()
+	start := time.Now()
+	trace := &ChildRun{
+		client:      client,
+		id:          id,
+		traceID:     id,
+		dottedOrder: traceutil.DottedOrder(start, id),
+		project:     project,
+	}
+	err := client.CreateRunContext(ctx, &RunCreate{
+		ID:          id,
+		TraceID:     id,
+		Name:        name,
+		RunType:     "chain",
+		Inputs:      inputs,
+		Extra:       map[string]any{"metadata": metadata},
+		StartTime:   start,
+		DottedOrder: trace.dottedOrder,
+		SessionName: project,
+	})
+	// An untraced evaluation is still recorded, without a source run.
+	if err != nil {
+		return evaluate(ctx)
+	}
+
+	results, err := evaluate(withChildRun(ctx, trace))
+	trace.End(map[string]any{"results": results}, err)
+	for i := range results {
+		if results[i].SourceRunID == "" {
+			results[i].SourceRunID = id.String()
+		}
+	}
+	return results, err
+}
@@ -0,0 +1,70 @@
+package langsmith
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// SummaryEvaluator scores an experiment as a whole, for metrics such as
+// precision and recall, pass rate or cost per success that only make sense
+// across examples. It is called once every example has run, with the runs
+// and the examples they ran on: runs[i] ran on examples[i]. Its results are
+// recorded as feedback on the experiment rather than on a run. A summary
+// evaluator with a Name() string method is traced under that name.
+type SummaryEvaluator interface {
+	EvaluateSummary(ctx context.Context, runs []RunView, examples []Example) ([]EvaluationResult, error)
+}
+
+// SummaryEvaluatorFunc adapts a function to a [SummaryEvaluator].
+type SummaryEvaluatorFunc func(ctx context.Context, runs []RunView, examples []Example) ([]EvaluationResult, error)
+
+// EvaluateSummary calls f(ctx, runs, examples).
+func (f SummaryEvaluatorFunc) EvaluateSummary(ctx context.Context, runs []RunView, examples []Example) ([]EvaluationResult, error) {
+	return f(ctx, runs, examples)
+}
+
+// NewSummaryEvaluator returns a [SummaryEvaluator] named name that calls fn.
+func NewSummaryEvaluator(name string, fn SummaryEvaluatorFunc) SummaryEvaluator {
+	return namedSummaryEvaluator{name, fn}
+}
+
+type namedSummaryEvaluator struct {
+	name string
+	SummaryEvaluatorFunc
+}
+
+func (e namedSummaryEvaluator) Name() string { return e.name }
+
+// summarize runs the summary evaluators over rows and records their results
+// as feedback on the experiment.
+func (e *experiment) summarize(ctx context.Context, rows []ExperimentResultRow) ([]EvaluationResult, error) {
+	runs := make([]RunView, len(rows))
+	examples := make([]Example, len(rows))
+	runIDs := make([]string, len(rows))
+	for i, row := range rows {
+		runs[i], examples[i], runIDs[i] = row.Run, row.Example, row.Run.ID
+	}
+	inputs := map[string]any{"run_ids": runIDs}
+	metadata := map[string]any{"experiment_id": e.session.ID}
+
+	var all []EvaluationResult
+	var errs []error
+	for _, ev := range e.summaryEvaluators {
+		name := evaluatorName(ev)
+		res, err := traceEvaluation(ctx, e.client, e.evalsProject, name, inputs, metadata, func(ctx context.Context) ([]EvaluationResult, error) {
+			return ev.EvaluateSummary(ctx, runs, examples)
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("summary evaluator %s: %w", name, err))
+			continue
+		}
+		for _, r := range res {
+			if err := e.createFeedback(ctx, RunView{SessionID: e.session.ID}, r); err != nil {
+				errs = append(errs, fmt.Errorf("record feedback %q: %w", r.Key, err))
+			}
+		}
+		all = append(all, res...)
+	}
+	return all, errors.Join(errs...)
+}
@@ -0,0 +1,70 @@
+package langsmith
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// TargetCache stores an experiment target's outputs. See
+// [EvaluateOptions.Cache]. Implementations must be safe for concurrent use.
+type TargetCache interface {
+	// Get returns the outputs stored under key, if any.
+	Get(key string) (map[string]any, bool)
+	// Put stores outputs under key.
+	Put(key string, outputs map[string]any) error
+}
+
+// FileCache is a [TargetCache] that keeps each output as a JSON file in a
+// directory, so that it survives the process.
+type FileCache struct {
+	// Dir is the directory the files are in. It is created when needed.
+	Dir string
+}
+
+// Get implements [TargetCache]. Entries that cannot be read are misses.
+func (c FileCache) Get(key string) (map[string]any, bool) {
+	b, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return nil, false
+	}
+	var outputs map[string]any
+	if json.Unmarshal(b, &outputs) != nil {
+		return nil, false
+	}
+	return outputs, true
+}
+
+// Put implements [TargetCache]. It writes the entry to a temporary file and
+// renames it, so readers never see a partial entry.
+func (c FileCache) Put(key string, outputs map[string]any) error {
+	b, err := json.Marshal(outputs)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(c.Dir, 0o755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(c.Dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(b)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), c.path(key))
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+	}
+	return err
+}
+
+func (c FileCache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.Dir, hex.EncodeToString(sum[:])+".json")
+}
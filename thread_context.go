@@ -0,0 +1,44 @@
+package langsmith
+
+import (
+	"context"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+
+	"github.com/langchain-ai/langsmith-go/internal/thread"
+)
+
+// WithThread returns a copy of ctx that groups everything traced with it
+// into the LangSmith thread (conversation) threadID. Call it once per turn
+// of a multi-turn chat, with the same ID for every turn:
+//
+//	ctx = langsmith.WithThread(ctx, conversationID)
+//	resp, err := client.Chat.Completions.New(ctx, params) // traced by traceopenai
+//
+// The ID travels as the thread_id OpenTelemetry baggage member, so it is
+// also propagated to downstream services. It is recorded as thread_id run
+// metadata by the LangSmith span processor ([NewOTel], [NewOTelTracer]),
+// the instrumentation packages, [TracingClient.CreateRunContext] and
+// [Client.CreateRunContext].
+func WithThread(ctx context.Context, threadID string) context.Context {
+	return thread.With(ctx, threadID)
+}
+
+// ThreadFromContext returns the thread ID set on ctx with [WithThread], or
+// carried as session_id or conversation_id baggage, or "" if there is none.
+func ThreadFromContext(ctx context.Context) string {
+	return thread.ID(ctx)
+}
+
+// threadSpanProcessor records the thread carried by a span's parent context
+// on the span before handing it to the wrapped processor.
+type threadSpanProcessor struct {
+	sdktrace.SpanProcessor
+}
+
+func (p threadSpanProcessor) OnStart(parent context.Context, s sdktrace.ReadWriteSpan) {
+	if attrs := thread.Attributes(parent); len(attrs) > 0 {
+		s.SetAttributes(attrs...)
+	}
+	p.SpanProcessor.OnStart(parent, s)
+}
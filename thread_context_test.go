@@ -0,0 +1,58 @@
+package langsmith
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/baggage"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestWithThread(t *testing.T) {
+	ctx := WithThread(context.Background(), "thread-1")
+	if got := ThreadFromContext(ctx); got != "thread-1" {
+		t.Errorf("ThreadFromContext = %q, want thread-1", got)
+	}
+	if got := baggage.FromContext(ctx).Member("thread_id").Value(); got != "thread-1" {
+		t.Errorf("thread_id baggage = %q, want thread-1", got)
+	}
+	if got := ThreadFromContext(WithThread(ctx, "")); got != "" {
+		t.Errorf("ThreadFromContext after clearing = %q, want empty", got)
+	}
+
+	m, _ := baggage.NewMember("session_id", "s-1")
+	bag, _ := baggage.New(m)
+	if got := ThreadFromContext(baggage.ContextWithBaggage(context.Background(), bag)); got != "s-1" {
+		t.Errorf("ThreadFromContext(session_id baggage) = %q, want s-1", got)
+	}
+}
+
+func TestThreadSpanProcessor(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(threadSpanProcessor{sdktrace.NewSimpleSpanProcessor(exporter)}))
+	tracer := tp.Tracer("test")
+
+	ctx, parent := tracer.Start(WithThread(context.Background(), "thread-1"), "turn")
+	_, child := tracer.Start(ctx, "llm")
+	child.End()
+	parent.End()
+	_, other := tracer.Start(context.Background(), "unthreaded")
+	other.End()
+
+	for _, span := range exporter.GetSpans() {
+		var got string
+		for _, kv := range span.Attributes {
+			if kv.Key == "langsmith.metadata.thread_id" {
+				got = kv.Value.AsString()
+			}
+		}
+		want := "thread-1"
+		if span.Name == "unthreaded" {
+			want = ""
+		}
+		if got != want {
+			t.Errorf("%s: langsmith.metadata.thread_id = %q, want %q", span.Name, got, want)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package langsmith
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/langchain-ai/langsmith-go/option"
+)
+
+// ThreadGetHistoryParams selects the thread whose history
+// [ThreadService.GetHistory] reconstructs.
+type ThreadGetHistoryParams struct {
+	// ProjectID is the UUID of the tracing project holding the thread.
+	ProjectID string
+	// Filter optionally narrows which turns (root runs) are included, e.g.
+	// eq(status, "success").
+	Filter string
+}
+
+// ThreadMessage is one message of a thread's conversation, in OpenAI chat
+// format.
+type ThreadMessage struct {
+	// Role is "system", "user", "assistant" or "tool".
+	Role string `json:"role"`
+	// Content is the message text, or a list of content parts.
+	Content any `json:"content"`
+	// Message is the message as recorded, including any tool_calls,
+	// tool_call_id or name, with Role in place of LangChain message types.
+	Message map[string]any `json:"-"`
+	// TraceID is the turn (root run) that recorded the message.
+	TraceID string `json:"-"`
+}
+
+// GetHistory reconstructs the ordered message history of a thread from the
+// inputs and outputs of its root runs, one per turn, for replaying the
+// conversation in evaluations.
+//
+// Each turn contributes the messages in its inputs (a "messages" list, or an
+// "input", "question" or similar string as a user message) followed by its
+// outputs (a chat completion, a "messages" list, or an "output", "answer" or
+// similar string as an assistant message). Apps that send the whole
+// conversation with every turn are handled: messages already in the history
+// are not repeated.
+func (r *ThreadService) GetHistory(ctx context.Context, threadID string, params ThreadGetHistoryParams, opts ...option.RequestOption) ([]ThreadMessage, error) {
+	if params.ProjectID == "" {
+		return nil, errors.New("missing required ProjectID parameter")
+	}
+	query := ThreadListTracesParams{
+		ProjectID: F(params.ProjectID),
+		PageSize:  F(int64(100)),
+		Selects: F([]ThreadListTracesParamsSelect{
+			ThreadListTracesParamsSelectTraceID,
+			ThreadListTracesParamsSelectStartTime,
+			ThreadListTracesParamsSelectInputs,
+			ThreadListTracesParamsSelectOutputs,
+		}),
+	}
+	if params.Filter != "" {
+		query.Filter = F(params.Filter)
+	}
+	var traces []ThreadTrace
+	iter := r.ListTracesAutoPaging(ctx, threadID, query, opts...)
+	for iter.Next() {
+		traces = append(traces, iter.Current())
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+	slices.SortStableFunc(traces, func(a, b ThreadTrace) int {
+		return a.StartTime.Compare(b.StartTime)
+	})
+
+	var history []ThreadMessage
+	for _, t := range traces {
+		history = appendNew(history, historyMessages(t.Inputs, "user", inputTextKeys, t.TraceID))
+		history = appendNew(history, historyMessages(t.Outputs, "assistant", outputTextKeys, t.TraceID))
+	}
+	return history, nil
+}
+
+// Keys whose string value is a turn's user input or assistant output when
+// the run did not record chat messages.
+var (
+	inputTextKeys  = []string{"input", "question", "query", "prompt", "message", "text", "content"}
+	outputTextKeys = []string{"output", "answer", "result", "response", "text", "content"}
+)
+
+// appendNew appends msgs to history, skipping the leading messages that
+// repeat the history so far.
+func appendNew(history, msgs []ThreadMessage) []ThreadMessage {
+	if len(msgs) >= len(history) && slices.EqualFunc(history, msgs[:len(history)], sameMessage) {
+		msgs = msgs[len(history):]
+	}
+	return append(history, msgs...)
+}
+
+func sameMessage(a, b ThreadMessage) bool {
+	if a.Role != b.Role {
+		return false
+	}
+	ja, _ := json.Marshal(a.Content)
+	jb, _ := json.Marshal(b.Content)
+	return string(ja) == string(jb)
+}
+
+// historyMessages extracts the chat messages in a run's inputs or outputs.
+func historyMessages(payload any, role string, textKeys []string, traceID string) []ThreadMessage {
+	var msgs []ThreadMessage
+	add := func(v any) {
+		if m, ok := v.(map[string]any); ok {
+			if msg, ok := normalizeMessage(m); ok {
+				msg.TraceID = traceID
+				msgs = append(msgs, msg)
+			}
+		}
+	}
+	switch p := payload.(type) {
+	case string:
+		if p != "" {
+			msgs = append(msgs, ThreadMessage{Role: role, Content: p, Message: map[string]any{"role": role, "content": p}, TraceID: traceID})
+		}
+	case map[string]any:
+		if list, ok := p["messages"].([]any); ok {
+			// LangChain chat model inputs are batched: [[messages...]].
+			if len(list) == 1 {
+				if inner, ok := list[0].([]any); ok {
+					list = inner
+				}
+			}
+			for _, m := range list {
+				add(m)
+			}
+			return msgs
+		}
+		if choices, ok := p["choices"].([]any); ok && len(choices) > 0 {
+			if c, ok := choices[0].(map[string]any); ok {
+				add(c["message"])
+			}
+			return msgs
+		}
+		for _, k := range textKeys {
+			switch v := p[k].(type) {
+			case string:
+				return historyMessages(v, role, nil, traceID)
+			case map[string]any:
+				add(v)
+				if len(msgs) > 0 {
+					return msgs
+				}
+			}
+		}
+		if len(p) == 1 {
+			for _, v := range p {
+				if s, ok := v.(string); ok {
+					return historyMessages(s, role, nil, traceID)
+				}
+			}
+		}
+	}
+	return msgs
+}
+
+// messageRoles maps LangChain message types and classes to chat roles.
+var messageRoles = map[string]string{
+	"human":     "user",
+	"user":      "user",
+	"ai":        "assistant",
+	"assistant": "assistant",
+	"system":    "system",
+	"developer": "system",
+	"tool":      "tool",
+	"function":  "tool",
+}
+
+// normalizeMessage converts an OpenAI-style message, a LangChain message
+// dict ({"type": "human", ...}) or a serialized LangChain message
+// ({"lc": 1, "id": [..., "HumanMessage"], "kwargs": {...}}) to a
+// ThreadMessage.
+func normalizeMessage(m map[string]any) (ThreadMessage, bool) {
+	var role string
+	if kwargs, ok := m["kwargs"].(map[string]any); ok {
+		if id, ok := m["id"].([]any); ok && len(id) > 0 {
+			class, _ := id[len(id)-1].(string)
+			role = strings.ToLower(strings.TrimSuffix(strings.TrimSuffix(class, "Chunk"), "Message"))
+		}
+		m = kwargs
+	}
+	if r, ok := m["role"].(string); ok {
+		role = r
+	} else if t, ok := m["type"].(string); ok && role == "" {
+		role = t
+	}
+	if r, ok := messageRoles[strings.ToLower(role)]; ok {
+		role = r
+	}
+	content, hasContent := m["content"]
+	if role == "" || (!hasContent && m["tool_calls"] == nil) {
+		return ThreadMessage{}, false
+	}
+	msg := make(map[string]any, len(m))
+	for k, v := range m {
+		if k != "type" {
+			msg[k] = v
+		}
+	}
+	msg["role"] = role
+	return ThreadMessage{Role: role, Content: content, Message: msg}, true
+}
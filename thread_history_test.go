@@ -0,0 +1,109 @@
+package langsmith_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/langchain-ai/langsmith-go"
+	"github.com/langchain-ai/langsmith-go/option"
+)
+
+func TestThreadGetHistory(t *testing.T) {
+	pages := map[string]any{
+		"": map[string]any{
+			"next_cursor": "page-2",
+			"items": []any{
+				// Second turn: the app resends the whole conversation.
+				map[string]any{
+					"trace_id":   "trace-2",
+					"start_time": "2025-01-01T00:01:00Z",
+					"inputs": map[string]any{"messages": []any{
+						map[string]any{"role": "system", "content": "Be brief."},
+						map[string]any{"role": "user", "content": "Hi"},
+						map[string]any{"role": "assistant", "content": "Hello!"},
+						map[string]any{"role": "user", "content": "What's 2+2?"},
+					}},
+					"outputs": map[string]any{"choices": []any{
+						map[string]any{"message": map[string]any{"role": "assistant", "content": "4"}},
+					}},
+				},
+			},
+		},
+		"page-2": map[string]any{
+			"items": []any{
+				// First turn, recorded as LangChain chat model messages.
+				map[string]any{
+					"trace_id":   "trace-1",
+					"start_time": "2025-01-01T00:00:00Z",
+					"inputs": map[string]any{"messages": []any{[]any{
+						map[string]any{"lc": 1, "type": "constructor", "id": []any{"langchain", "schema", "messages", "SystemMessage"},
+							"kwargs": map[string]any{"content": "Be brief.", "type": "system"}},
+						map[string]any{"type": "human", "content": "Hi"},
+					}}},
+					"outputs": map[string]any{"output": "Hello!"},
+				},
+			},
+		},
+		"page-3": map[string]any{"items": []any{}},
+	}
+	var query []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/api/v2/threads/thread-1/traces" {
+			http.Error(w, "unexpected "+r.Method+" "+r.URL.Path, http.StatusInternalServerError)
+			return
+		}
+		query = append(query, r.URL.RawQuery)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(pages[r.URL.Query().Get("cursor")])
+	}))
+	defer srv.Close()
+
+	client := langsmith.NewClient(
+		option.WithBaseURL(srv.URL),
+		option.WithAPIKey("test-api-key"),
+		option.WithMaxRetries(0),
+	)
+	history, err := client.Threads.GetHistory(context.Background(), "thread-1", langsmith.ThreadGetHistoryParams{
+		ProjectID: "00000000-0000-0000-0000-000000000001",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type msg struct{ Role, Content, TraceID string }
+	var got []msg
+	for _, m := range history {
+		s, _ := m.Content.(string)
+		got = append(got, msg{m.Role, s, m.TraceID})
+	}
+	want := []msg{
+		{"system", "Be brief.", "trace-1"},
+		{"user", "Hi", "trace-1"},
+		{"assistant", "Hello!", "trace-1"},
+		{"user", "What's 2+2?", "trace-2"},
+		{"assistant", "4", "trace-2"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("history =\n%v\nwant\n%v", got, want)
+	}
+	if history[0].Message["role"] != "system" || history[0].Message["type"] != nil {
+		t.Errorf("normalized message = %v", history[0].Message)
+	}
+	if len(query) == 0 {
+		t.Fatal("no requests made")
+	}
+	for _, sel := range []string{"selects=INPUTS", "selects=OUTPUTS", "selects=START_TIME", "project_id="} {
+		if !strings.Contains(query[0], sel) {
+			t.Errorf("query %q missing %s", query[0], sel)
+		}
+	}
+
+	if _, err := client.Threads.GetHistory(context.Background(), "thread-1", langsmith.ThreadGetHistoryParams{}); err == nil {
+		t.Error("GetHistory without ProjectID: want error")
+	}
+}
@@ -13,6 +13,8 @@ import (
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
 	"go.opentelemetry.io/otel/trace"
+
+	"github.com/langchain-ai/langsmith-go/instrumentation/core"
 )
 
 const (
@@ -199,9 +201,9 @@ func createProcessor(cfg *tracerConfig) (sdktrace.SpanProcessor, error) {
 		return nil, fmt.Errorf("creating OTLP exporter: %w", err)
 	}
 
-	return sdktrace.NewBatchSpanProcessor(exporter,
+	return threadSpanProcessor{sdktrace.NewBatchSpanProcessor(exporter,
 		sdktrace.WithBatchTimeout(cfg.batchTimeout),
-	), nil
+	)}, nil
 }
 
 // TracerProvider returns the underlying trace.TracerProvider.
@@ -217,7 +219,10 @@ func (t *OTelTracer) Tracer(name string) trace.Tracer {
 // Shutdown gracefully shuts down the tracer.
 // If the OTelTracer was created with [NewOTel], only the LangSmith processor is shut down.
 // If it was created with [NewOTelTracer], the entire TracerProvider is shut down.
+// Runs from the instrumentation packages still waiting for an SDK retry are
+// ended first so they are exported; see [core.EndPendingRetries].
 func (t *OTelTracer) Shutdown(ctx context.Context) error {
+	core.EndPendingRetries()
 	shutdownCtx, cancel := context.WithTimeout(ctx, defaultShutdownTimeout)
 	defer cancel()
 	if t.ownsTP {
@@ -0,0 +1,73 @@
+package langsmith_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/langchain-ai/langsmith-go"
+	"github.com/langchain-ai/langsmith-go/instrumentation/traceopenai"
+)
+
+func TestOTelTracerShutdownEndsPendingRetries(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_INSECURE", "true")
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer collector.Close()
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":{"message":"rate limited"}}`, http.StatusTooManyRequests)
+	}))
+	defer api.Close()
+
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	defer tp.Shutdown(context.Background())
+	ls, err := langsmith.NewOTel(tp,
+		langsmith.WithAPIKey("test-api-key"),
+		langsmith.WithEndpoint(strings.TrimPrefix(collector.URL, "http://")),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The final attempt of an SDK configured with two retries.
+	client := traceopenai.Client(traceopenai.WithTracerProvider(tp))
+	req, err := http.NewRequest(http.MethodPost, api.URL+"/v1/chat/completions",
+		bytes.NewReader([]byte(`{"model":"gpt-4o-mini","messages":[{"role":"user","content":"hi"}]}`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("X-Stainless-Retry-Count", "2")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	start := time.Now()
+	if err := ls.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Shutdown took %v, want it not to wait for the retry window", elapsed)
+	}
+	var runs int
+	for _, s := range exporter.GetSpans() {
+		if s.Parent.IsValid() {
+			continue
+		}
+		runs++
+		if s.Status.Description == "" {
+			t.Errorf("run %q status = %v, want the 429", s.Name, s.Status)
+		}
+	}
+	if runs != 1 {
+		t.Errorf("got %d runs exported after Shutdown, want 1", runs)
+	}
+}